@@ -475,3 +475,10 @@ func (fb *filterBackend) BloomStatus() (uint64, uint64) { return 4096, 0 }
 func (fb *filterBackend) ServiceFilter(ctx context.Context, ms *bloombits.MatcherSession) {
 	panic("not supported")
 }
+
+func (fb *filterBackend) SideBackend(appId string) (filters.Backend, error) {
+	if appId != "" {
+		return nil, errors.New("side chains are not supported")
+	}
+	return fb, nil
+}
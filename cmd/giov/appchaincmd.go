@@ -0,0 +1,347 @@
+// Copyright 2018 The giov Authors
+// This file is part of giov.
+//
+// giov is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// giov is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with giov. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"sort"
+	"time"
+
+	"github.com/CarLiveChainCo/goiov/cmd/utils"
+	"github.com/CarLiveChainCo/goiov/common"
+	"github.com/CarLiveChainCo/goiov/console"
+	"github.com/CarLiveChainCo/goiov/core/rawdb"
+	"github.com/CarLiveChainCo/goiov/eth"
+	"github.com/CarLiveChainCo/goiov/ethdb"
+	"github.com/CarLiveChainCo/goiov/log"
+	"github.com/CarLiveChainCo/goiov/params"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	appchainCommand = cli.Command{
+		Name:      "appchain",
+		Usage:     "Manage app chains",
+		ArgsUsage: "",
+		Category:  "BLOCKCHAIN COMMANDS",
+		Description: `
+    giov appchain list
+
+Manage the app chains of the database of the node while the node is stopped:
+list them, show one, join or leave one at the next start, export, import or
+dump its blocks or remove all its data.
+
+The app chains joined are the ones joined when the node stopped, unless the
+node is started with --appchains.`,
+		Subcommands: []cli.Command{
+			{
+				Name:   "list",
+				Usage:  "Print summary of the app chains",
+				Action: utils.MigrateFlags(appchainList),
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+				},
+				Description: `
+Print the appId, head block and whether the node joins it of every app chain`,
+			},
+			{
+				Name:      "info",
+				Usage:     "Print the details of an app chain",
+				ArgsUsage: "<appId>",
+				Action:    utils.MigrateFlags(appchainInfo),
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+				},
+				Description: `
+Print the chain config, genesis hash, head block and total difficulty of the
+app chain as JSON`,
+			},
+			{
+				Name:      "join",
+				Usage:     "Join app chains at the next start of the node",
+				ArgsUsage: "<appId> (<appId 2> ... <appId N>)",
+				Action:    utils.MigrateFlags(appchainJoin),
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+				},
+				Description: `
+Add the app chains to the ones the node joins at start-up`,
+			},
+			{
+				Name:      "leave",
+				Usage:     "Leave app chains at the next start of the node",
+				ArgsUsage: "<appId> (<appId 2> ... <appId N>)",
+				Action:    utils.MigrateFlags(appchainLeave),
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+				},
+				Description: `
+Remove the app chains from the ones the node joins at start-up, keeping their data`,
+			},
+			{
+				Name:      "export",
+				Usage:     "Export an app chain into file",
+				ArgsUsage: "<appId> <filename> [<blockNumFirst> <blockNumLast>]",
+				Action:    utils.MigrateFlags(appchainExport),
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					utils.CacheFlag,
+				},
+				Description: `
+Requires the appId of the app chain and the file to write to.
+Optional third and fourth arguments control the first and
+last block to write. In this mode, the file will be appended
+if already existing.`,
+			},
+			{
+				Name:      "import",
+				Usage:     "Import an app chain file",
+				ArgsUsage: "<appId> <filename> (<filename 2> ... <filename N>)",
+				Action:    utils.MigrateFlags(appchainImport),
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					utils.CacheFlag,
+					utils.GCModeFlag,
+					utils.CacheDatabaseFlag,
+					utils.CacheGCFlag,
+				},
+				Description: `
+The import command imports blocks of the app chain from an RLP-encoded form,
+verifying them against the main chain of the database.`,
+			},
+			{
+				Name:      "dump",
+				Usage:     "Dump a specific block of an app chain from storage",
+				ArgsUsage: "<appId> [<blockHash> | <blockNum>]...",
+				Action:    utils.MigrateFlags(appchainDump),
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					utils.CacheFlag,
+				},
+				Description: `
+The arguments following the appId are interpreted as block numbers or hashes.`,
+			},
+			{
+				Name:      "removedb",
+				Usage:     "Remove all the data of an app chain",
+				ArgsUsage: "<appId>",
+				Action:    utils.MigrateFlags(appchainRemoveDB),
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+				},
+				Description: `
+Remove the blocks, state and records of the app chain and forget it`,
+			},
+		},
+	}
+)
+
+// appChainInfo is the summary of an app chain printed by appchain info.
+type appChainInfo struct {
+	AppId   string              `json:"appId"`
+	Joined  bool                `json:"joined"`
+	Genesis common.Hash         `json:"genesis"`
+	Number  uint64              `json:"number"`
+	Hash    common.Hash         `json:"hash"`
+	TD      *big.Int            `json:"totalDifficulty"`
+	Config  *params.ChainConfig `json:"config"`
+}
+
+// makeAppChainDatabase opens the chain database of the node.
+func makeAppChainDatabase(ctx *cli.Context) ethdb.Database {
+	stack, _ := makeConfigNode(ctx)
+	return utils.MakeChainDatabase(ctx, stack)
+}
+
+// readAppChainInfo reads the summary of the app chain appId, nil if the app
+// chain doesn't exist.
+func readAppChainInfo(db ethdb.Database, appId string) *appChainInfo {
+	genesis := rawdb.ReadCanonicalHash(db, 0, appId)
+	config := rawdb.ReadChainConfig(db, genesis, appId)
+	if config == nil {
+		return nil
+	}
+	info := &appChainInfo{
+		AppId:   appId,
+		Genesis: genesis,
+		Hash:    rawdb.ReadHeadBlockHash(db, appId),
+		Config:  config,
+	}
+	for _, id := range rawdb.ReadAppId(db) {
+		if id == appId {
+			info.Joined = true
+		}
+	}
+	if number := rawdb.ReadHeaderNumber(db, info.Hash, appId); number != nil {
+		info.Number = *number
+		info.TD = rawdb.ReadTd(db, info.Hash, *number, appId)
+	}
+	return info
+}
+
+func appchainList(ctx *cli.Context) error {
+	db := makeAppChainDatabase(ctx)
+	defer db.Close()
+
+	var appIds []string
+	for appId := range rawdb.ReadAllChainConfig(db) {
+		if appId != "" {
+			appIds = append(appIds, appId)
+		}
+	}
+	sort.Strings(appIds)
+	for _, appId := range appIds {
+		info := readAppChainInfo(db, appId)
+		if info == nil {
+			continue
+		}
+		fmt.Printf("App chain %s: head #%d [%x…], joined: %v\n", appId, info.Number, info.Hash[:4], info.Joined)
+	}
+	return nil
+}
+
+func appchainInfo(ctx *cli.Context) error {
+	if len(ctx.Args()) != 1 {
+		utils.Fatalf("This command requires the appId as argument.")
+	}
+	db := makeAppChainDatabase(ctx)
+	defer db.Close()
+
+	info := readAppChainInfo(db, ctx.Args().First())
+	if info == nil {
+		utils.Fatalf("App chain %q does not exist", ctx.Args().First())
+	}
+	out, err := json.MarshalIndent(info, "", "  ")
+	if err != nil {
+		utils.Fatalf("Failed to encode app chain: %v", err)
+	}
+	fmt.Println(string(out))
+	return nil
+}
+
+func appchainJoin(ctx *cli.Context) error {
+	if len(ctx.Args()) < 1 {
+		utils.Fatalf("This command requires the appIds as arguments.")
+	}
+	db := makeAppChainDatabase(ctx)
+	defer db.Close()
+
+	joined := rawdb.ReadAppId(db)
+	for _, appId := range ctx.Args() {
+		if appId == "" || readAppChainInfo(db, appId) == nil {
+			utils.Fatalf("App chain %q does not exist", appId)
+		}
+		known := false
+		for _, id := range joined {
+			known = known || id == appId
+		}
+		if !known {
+			joined = append(joined, appId)
+		}
+	}
+	rawdb.WriteAppId(db, joined)
+	log.Info("Joined app chains at the next start", "appIds", joined)
+	return nil
+}
+
+func appchainLeave(ctx *cli.Context) error {
+	if len(ctx.Args()) < 1 {
+		utils.Fatalf("This command requires the appIds as arguments.")
+	}
+	db := makeAppChainDatabase(ctx)
+	defer db.Close()
+
+	var joined []string
+	for _, id := range rawdb.ReadAppId(db) {
+		left := false
+		for _, appId := range ctx.Args() {
+			left = left || id == appId
+		}
+		if !left {
+			joined = append(joined, id)
+		}
+	}
+	rawdb.WriteAppId(db, joined)
+	log.Info("Joined app chains at the next start", "appIds", joined)
+	return nil
+}
+
+func appchainExport(ctx *cli.Context) error {
+	if len(ctx.Args()) < 2 {
+		utils.Fatalf("This command requires the appId and a file as arguments.")
+	}
+	stack := makeFullNode(ctx)
+	chain, _ := utils.MakeAppChain(ctx, stack, ctx.Args().First())
+
+	return exportBlocks(chain, ctx.Args().Tail())
+}
+
+func appchainImport(ctx *cli.Context) error {
+	if len(ctx.Args()) < 2 {
+		utils.Fatalf("This command requires the appId and a file as arguments.")
+	}
+	stack := makeFullNode(ctx)
+	chain, chainDb := utils.MakeAppChain(ctx, stack, ctx.Args().First())
+	defer chainDb.Close()
+
+	return importBlocks(ctx, chain, chainDb, ctx.Args().Tail())
+}
+
+func appchainDump(ctx *cli.Context) error {
+	if len(ctx.Args()) < 1 {
+		utils.Fatalf("This command requires the appId as argument.")
+	}
+	appId := ctx.Args().First()
+
+	stack := makeFullNode(ctx)
+	chain, chainDb := utils.MakeAppChain(ctx, stack, appId)
+	dumpBlocks(chain, rawdb.AppChainDatabase(chainDb, appId), ctx.Args().Tail())
+	chainDb.Close()
+	return nil
+}
+
+func appchainRemoveDB(ctx *cli.Context) error {
+	if len(ctx.Args()) != 1 {
+		utils.Fatalf("This command requires the appId as argument.")
+	}
+	appId := ctx.Args().First()
+
+	db := makeAppChainDatabase(ctx)
+	defer db.Close()
+
+	if readAppChainInfo(db, appId) == nil {
+		utils.Fatalf("App chain %q does not exist", appId)
+	}
+	// Confirm removal and execute
+	fmt.Println(appId)
+	confirm, err := console.Stdin.PromptConfirm("Remove all the data of this app chain?")
+	switch {
+	case err != nil:
+		utils.Fatalf("%v", err)
+	case !confirm:
+		log.Warn("App chain deletion aborted", "appId", appId)
+	default:
+		start := time.Now()
+		if err := eth.DeleteAppChain(db, appId); err != nil {
+			utils.Fatalf("Failed to delete app chain: %v", err)
+		}
+		log.Info("App chain successfully deleted", "appId", appId, "elapsed", common.PrettyDuration(time.Since(start)))
+	}
+	return nil
+}
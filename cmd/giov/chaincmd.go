@@ -213,6 +213,12 @@ func importChain(ctx *cli.Context) error {
 	chain, chainDb := utils.MakeChain(ctx, stack)
 	defer chainDb.Close()
 
+	return importBlocks(ctx, chain, chainDb, ctx.Args())
+}
+
+// importBlocks imports the blocks of the files into the chain and prints the
+// statistics of the database and the memory used by the import.
+func importBlocks(ctx *cli.Context, chain *core.BlockChain, chainDb ethdb.Database, files []string) error {
 	// Start periodically gathering memory profiles
 	var peakMemAlloc, peakMemSys uint64
 	go func() {
@@ -231,12 +237,12 @@ func importChain(ctx *cli.Context) error {
 	// Import the chain
 	start := time.Now()
 
-	if len(ctx.Args()) == 1 {
-		if err := utils.ImportChain(chain, ctx.Args().First()); err != nil {
+	if len(files) == 1 {
+		if err := utils.ImportChain(chain, files[0]); err != nil {
 			log.Error("Import error", "err", err)
 		}
 	} else {
-		for _, arg := range ctx.Args() {
+		for _, arg := range files {
 			if err := utils.ImportChain(chain, arg); err != nil {
 				log.Error("Import error", "file", arg, "err", err)
 			}
@@ -305,16 +311,23 @@ func exportChain(ctx *cli.Context) error {
 	}
 	stack := makeFullNode(ctx)
 	chain, _ := utils.MakeChain(ctx, stack)
+
+	return exportBlocks(chain, ctx.Args())
+}
+
+// exportBlocks exports the chain into the file of the first argument, the blocks
+// from the optional second to the optional third argument only.
+func exportBlocks(chain *core.BlockChain, args cli.Args) error {
 	start := time.Now()
 
 	var err error
-	fp := ctx.Args().First()
-	if len(ctx.Args()) < 3 {
+	fp := args.First()
+	if len(args) < 3 {
 		err = utils.ExportChain(chain, fp)
 	} else {
 		// This can be improved to allow for numbers larger than 9223372036854775807
-		first, ferr := strconv.ParseInt(ctx.Args().Get(1), 10, 64)
-		last, lerr := strconv.ParseInt(ctx.Args().Get(2), 10, 64)
+		first, ferr := strconv.ParseInt(args.Get(1), 10, 64)
+		last, lerr := strconv.ParseInt(args.Get(2), 10, 64)
 		if ferr != nil || lerr != nil {
 			utils.Fatalf("Export error in parsing parameters: block number not an integer\n")
 		}
@@ -443,7 +456,15 @@ func removeDB(ctx *cli.Context) error {
 func dump(ctx *cli.Context) error {
 	stack := makeFullNode(ctx)
 	chain, chainDb := utils.MakeChain(ctx, stack)
-	for _, arg := range ctx.Args() {
+	dumpBlocks(chain, chainDb, ctx.Args())
+	chainDb.Close()
+	return nil
+}
+
+// dumpBlocks prints the states of the blocks of the chain, read from stateDb,
+// the arguments being their numbers or hashes.
+func dumpBlocks(chain *core.BlockChain, stateDb ethdb.Database, args []string) {
+	for _, arg := range args {
 		var block *types.Block
 		if hashish(arg) {
 			block = chain.GetBlockByHash(common.HexToHash(arg))
@@ -455,15 +476,13 @@ func dump(ctx *cli.Context) error {
 			fmt.Println("{}")
 			utils.Fatalf("block not found")
 		} else {
-			state, err := state.New(block.Root(), state.NewDatabase(chainDb))
+			state, err := state.New(block.Root(), state.NewDatabase(stateDb))
 			if err != nil {
 				utils.Fatalf("could not create new state: %v", err)
 			}
 			fmt.Printf("%s\n", state.Dump())
 		}
 	}
-	chainDb.Close()
-	return nil
 }
 
 // hashish returns true for strings that look like hashes.
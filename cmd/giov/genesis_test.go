@@ -21,6 +21,13 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+
+	"github.com/CarLiveChainCo/goiov/common"
+	"github.com/CarLiveChainCo/goiov/core"
+	"github.com/CarLiveChainCo/goiov/core/rawdb"
+	"github.com/CarLiveChainCo/goiov/core/types"
+	"github.com/CarLiveChainCo/goiov/ethdb"
+	"github.com/CarLiveChainCo/goiov/params"
 )
 
 var customGenesisTests = []struct {
@@ -108,3 +115,67 @@ func TestCustomGenesis(t *testing.T) {
 		geth.ExpectExit()
 	}
 }
+
+// commitAppGenesis commits the genesis of an app chain into the database, the
+// one of the main chain if appId is empty.
+func commitAppGenesis(t *testing.T, db ethdb.Database, appId string) *types.Block {
+	config := *params.TestChainConfig
+	config.AppId = appId
+
+	genesis, err := (&core.Genesis{Config: &config, Difficulty: common.Big1}).Commit(db)
+	if err != nil {
+		t.Fatalf("failed to commit genesis of app chain %q: %v", appId, err)
+	}
+	return genesis
+}
+
+// Tests that the app chains are summarized from their records in the database.
+func TestReadAppChainInfo(t *testing.T) {
+	db := ethdb.NewMemDatabase()
+	commitAppGenesis(t, db, "")
+	genesis := commitAppGenesis(t, db, "app")
+
+	info := readAppChainInfo(db, "app")
+	if info == nil {
+		t.Fatalf("app chain not found")
+	}
+	if info.Joined || info.Number != 0 || info.Hash != genesis.Hash() || info.Genesis != genesis.Hash() || info.Config.AppId != "app" {
+		t.Errorf("app chain info mismatch: have %+v", info)
+	}
+	rawdb.WriteAppId(db, []string{"app"})
+	if info := readAppChainInfo(db, "app"); info == nil || !info.Joined {
+		t.Errorf("joined app chain not reported joined: %+v", info)
+	}
+	if info := readAppChainInfo(db, "other"); info != nil {
+		t.Errorf("unknown app chain found: %+v", info)
+	}
+}
+
+// Tests that the app chains are joined and left at the next start.
+func TestAppChainJoinLeave(t *testing.T) {
+	datadir := tmpdir(t)
+	defer os.RemoveAll(datadir)
+
+	db, err := ethdb.NewLDBDatabase(filepath.Join(datadir, clientIdentifier, "chaindata"), 0, 0)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	commitAppGenesis(t, db, "")
+	commitAppGenesis(t, db, "app")
+	db.Close()
+
+	runGeth(t, "--datadir", datadir, "appchain", "join", "app").WaitExit()
+	giov := runGeth(t, "--datadir", datadir, "appchain", "list")
+	giov.ExpectRegexp(`App chain app: head #0 \[[0-9a-f]+…\], joined: true`)
+	giov.ExpectExit()
+
+	runGeth(t, "--datadir", datadir, "appchain", "leave", "app").WaitExit()
+	giov = runGeth(t, "--datadir", datadir, "appchain", "list")
+	giov.ExpectRegexp(`App chain app: head #0 \[[0-9a-f]+…\], joined: false`)
+	giov.ExpectExit()
+
+	// Unknown app chains can't be joined
+	giov = runGeth(t, "--datadir", datadir, "appchain", "join", "other")
+	giov.ExpectRegexp(`Fatal: App chain "other" does not exist`)
+	giov.ExpectExit()
+}
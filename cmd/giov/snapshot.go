@@ -0,0 +1,88 @@
+// Copyright 2018 The giov Authors
+// This file is part of giov.
+//
+// giov is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// giov is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with giov. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"github.com/CarLiveChainCo/goiov/cmd/utils"
+	"github.com/CarLiveChainCo/goiov/core/state/pruner"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	pruneBlocksFlag = cli.Uint64Flag{
+		Name:  "blocks",
+		Usage: "Number of recent blocks of every chain to keep the state of",
+		Value: 128,
+	}
+	bloomFilterSizeFlag = cli.Uint64Flag{
+		Name:  "bloomfilter.size",
+		Usage: "Megabytes of memory allocated to the bloom filter of the state kept",
+		Value: 2048,
+	}
+)
+
+var (
+	snapshotCommand = cli.Command{
+		Name:      "snapshot",
+		Usage:     "Manage the state of the chains",
+		ArgsUsage: "",
+		Category:  "BLOCKCHAIN COMMANDS",
+		Description: `
+    giov snapshot prune-state
+
+Manage the state of the main chain and of the app chains stored in the database
+of the node while the node is stopped.`,
+		Subcommands: []cli.Command{
+			{
+				Name:   "prune-state",
+				Usage:  "Prune the state not reachable from the recent blocks",
+				Action: utils.MigrateFlags(pruneState),
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					utils.CacheFlag,
+					pruneBlocksFlag,
+					bloomFilterSizeFlag,
+				},
+				Description: `
+Delete the trie nodes and contract codes no longer reachable from the state of
+the genesis and of the recent blocks of the main chain and of every app chain,
+keeping the state of the last --blocks blocks of every chain. The state
+reachable is first marked in a bloom filter of --bloomfilter.size megabytes,
+then the rest is swept from the database. A larger filter keeps less of the
+unreachable state. The pruning can be interrupted and run again.`,
+			},
+		},
+	}
+)
+
+func pruneState(ctx *cli.Context) error {
+	stack, _ := makeConfigNode(ctx)
+	db := utils.MakeChainDatabase(ctx, stack)
+	defer db.Close()
+
+	p, err := pruner.NewPruner(db, pruner.Config{
+		Blocks:    ctx.Uint64(pruneBlocksFlag.Name),
+		BloomSize: ctx.Uint64(bloomFilterSizeFlag.Name),
+	})
+	if err != nil {
+		utils.Fatalf("Failed to create state pruner: %v", err)
+	}
+	if err := p.Prune(); err != nil {
+		utils.Fatalf("Failed to prune state: %v", err)
+	}
+	return nil
+}
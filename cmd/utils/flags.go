@@ -19,6 +19,7 @@ package utils
 
 import (
 	"crypto/ecdsa"
+	"errors"
 	"fmt"
 	"github.com/CarLiveChainCo/goiov/contracts/appmanager"
 	"io/ioutil"
@@ -39,6 +40,7 @@ import (
 	"github.com/CarLiveChainCo/goiov/consensus/clique"
 	"github.com/CarLiveChainCo/goiov/consensus/ethash"
 	"github.com/CarLiveChainCo/goiov/core"
+	"github.com/CarLiveChainCo/goiov/core/rawdb"
 	"github.com/CarLiveChainCo/goiov/core/state"
 	"github.com/CarLiveChainCo/goiov/core/vm"
 	"github.com/CarLiveChainCo/goiov/crypto"
@@ -48,6 +50,7 @@ import (
 	"github.com/CarLiveChainCo/goiov/eth/gasprice"
 	"github.com/CarLiveChainCo/goiov/ethdb"
 	"github.com/CarLiveChainCo/goiov/ethstats"
+	"github.com/CarLiveChainCo/goiov/graphql"
 	"github.com/CarLiveChainCo/goiov/les"
 	"github.com/CarLiveChainCo/goiov/log"
 	"github.com/CarLiveChainCo/goiov/metrics"
@@ -192,6 +195,19 @@ var (
 		Usage: "Maximum number of LES client peers",
 		Value: eth.DefaultConfig.LightPeers,
 	}
+	AlienRewardIndexFlag = cli.BoolFlag{
+		Name:  "alien.rewardindex",
+		Usage: "Record the miner and voter rewards of every block for the reward history APIs",
+	}
+	AlienRewardRetainFlag = cli.Uint64Flag{
+		Name:  "alien.rewardretain",
+		Usage: "Number of recent blocks to keep reward records for (0 = keep all)",
+		Value: eth.DefaultConfig.AlienRewardRetain,
+	}
+	AppChainsFlag = cli.StringFlag{
+		Name:  "appchains",
+		Usage: "Comma separated appIds of the app chains to join at start-up (default = the ones joined when the node stopped)",
+	}
 	LightKDFFlag = cli.BoolFlag{
 		Name:  "lightkdf",
 		Usage: "Reduce key-derivation RAM & CPU usage at some expense of KDF strength",
@@ -414,6 +430,35 @@ var (
 		Usage: "API's offered over the HTTP-RPC interface",
 		Value: "",
 	}
+	RPCEVMTimeoutFlag = cli.DurationFlag{
+		Name:  "rpc.evmtimeout",
+		Usage: "Timeout of the EVM calls of eth_call and eth_callMany (0 = no timeout)",
+		Value: eth.DefaultConfig.RPCEVMTimeout,
+	}
+	GraphQLEnabledFlag = cli.BoolFlag{
+		Name:  "graphql",
+		Usage: "Enable the GraphQL server",
+	}
+	GraphQLListenAddrFlag = cli.StringFlag{
+		Name:  "graphql.addr",
+		Usage: "GraphQL server listening interface",
+		Value: node.DefaultGraphQLHost,
+	}
+	GraphQLPortFlag = cli.IntFlag{
+		Name:  "graphql.port",
+		Usage: "GraphQL server listening port",
+		Value: node.DefaultGraphQLPort,
+	}
+	GraphQLCORSDomainFlag = cli.StringFlag{
+		Name:  "graphql.corsdomain",
+		Usage: "Comma separated list of domains from which to accept cross origin requests (browser enforced)",
+		Value: "",
+	}
+	GraphQLVirtualHostsFlag = cli.StringFlag{
+		Name:  "graphql.vhosts",
+		Usage: "Comma separated list of virtual hostnames from which to accept requests (server enforced). Accepts '*' wildcard.",
+		Value: strings.Join(node.DefaultConfig.GraphQLVirtualHosts, ","),
+	}
 	IPCDisabledFlag = cli.BoolFlag{
 		Name:  "ipcdisable",
 		Usage: "Disable the IPC-RPC server",
@@ -730,6 +775,27 @@ func setHTTP(ctx *cli.Context, cfg *node.Config) {
 	}
 }
 
+// setGraphQL creates the GraphQL listener interface string from the set
+// command line flags, returning empty if the GraphQL endpoint is disabled.
+func setGraphQL(ctx *cli.Context, cfg *node.Config) {
+	if ctx.GlobalBool(GraphQLEnabledFlag.Name) && cfg.GraphQLHost == "" {
+		cfg.GraphQLHost = "127.0.0.1"
+		if ctx.GlobalIsSet(GraphQLListenAddrFlag.Name) {
+			cfg.GraphQLHost = ctx.GlobalString(GraphQLListenAddrFlag.Name)
+		}
+	}
+
+	if ctx.GlobalIsSet(GraphQLPortFlag.Name) {
+		cfg.GraphQLPort = ctx.GlobalInt(GraphQLPortFlag.Name)
+	}
+	if ctx.GlobalIsSet(GraphQLCORSDomainFlag.Name) {
+		cfg.GraphQLCors = splitAndTrim(ctx.GlobalString(GraphQLCORSDomainFlag.Name))
+	}
+	if ctx.GlobalIsSet(GraphQLVirtualHostsFlag.Name) {
+		cfg.GraphQLVirtualHosts = splitAndTrim(ctx.GlobalString(GraphQLVirtualHostsFlag.Name))
+	}
+}
+
 // setWS creates the WebSocket RPC listener interface string from the set
 // command line flags, returning empty if the HTTP endpoint is disabled.
 func setWS(ctx *cli.Context, cfg *node.Config) {
@@ -908,6 +974,7 @@ func SetNodeConfig(ctx *cli.Context, cfg *node.Config) {
 	SetP2PConfig(ctx, &cfg.P2P)
 	setIPC(ctx, cfg)
 	setHTTP(ctx, cfg)
+	setGraphQL(ctx, cfg)
 	setWS(ctx, cfg)
 	setNodeUserIdent(ctx, cfg)
 
@@ -1107,6 +1174,23 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *eth.Config) {
 		// TODO(fjl): force-enable this in --dev mode
 		cfg.EnablePreimageRecording = ctx.GlobalBool(VMEnableDebugFlag.Name)
 	}
+	if ctx.GlobalIsSet(AlienRewardIndexFlag.Name) {
+		cfg.AlienRewardIndex = ctx.GlobalBool(AlienRewardIndexFlag.Name)
+	}
+	if ctx.GlobalIsSet(AlienRewardRetainFlag.Name) {
+		cfg.AlienRewardRetain = ctx.GlobalUint64(AlienRewardRetainFlag.Name)
+	}
+	if ctx.GlobalIsSet(AppChainsFlag.Name) {
+		cfg.AppChains = nil
+		for _, appId := range strings.Split(ctx.GlobalString(AppChainsFlag.Name), ",") {
+			if appId = strings.TrimSpace(appId); appId != "" {
+				cfg.AppChains = append(cfg.AppChains, appId)
+			}
+		}
+	}
+	if ctx.GlobalIsSet(RPCEVMTimeoutFlag.Name) {
+		cfg.RPCEVMTimeout = ctx.GlobalDuration(RPCEVMTimeoutFlag.Name)
+	}
 
 	// Override any default configs for hard coded networks.
 	switch {
@@ -1218,6 +1302,26 @@ func RegisterEthStatsService(stack *node.Node, url string) {
 	}
 }
 
+// RegisterGraphQLService adds a GraphQL service answering queries from the eth
+// or les backend on the endpoint to the given node.
+func RegisterGraphQLService(stack *node.Node, endpoint string, cors, vhosts []string) {
+	if err := stack.Register(func(ctx *node.ServiceContext) (node.Service, error) {
+		// Try to construct the GraphQL service backed by a full node
+		var ethServ *eth.Ethereum
+		if err := ctx.Service(&ethServ); err == nil {
+			return graphql.New(ethServ.APIBackend, endpoint, cors, vhosts)
+		}
+		// Try to construct the GraphQL service backed by a light node
+		var lesServ *les.LightEthereum
+		if err := ctx.Service(&lesServ); err == nil {
+			return graphql.New(lesServ.ApiBackend, endpoint, cors, vhosts)
+		}
+		return nil, errors.New("no Ethereum service")
+	}); err != nil {
+		Fatalf("Failed to register the GraphQL service: %v", err)
+	}
+}
+
 // SetupNetwork configures the system for either the main net or some test network.
 func SetupNetwork(ctx *cli.Context) {
 	// TODO(fjl): move target gas limit into config
@@ -1238,6 +1342,11 @@ func MakeChainDatabase(ctx *cli.Context, stack *node.Node) ethdb.Database {
 	if err != nil {
 		Fatalf("Could not open database: %v", err)
 	}
+	if !ctx.GlobalBool(LightModeFlag.Name) {
+		if err := eth.UpgradeDatabase(chainDb); err != nil {
+			Fatalf("Could not upgrade database: %v", err)
+		}
+	}
 	return chainDb
 }
 
@@ -1295,6 +1404,66 @@ func MakeChain(ctx *cli.Context, stack *node.Node) (chain *core.BlockChain, chai
 		}
 	}
 
+	vmcfg := vm.Config{EnablePreimageRecording: ctx.GlobalBool(VMEnableDebugFlag.Name)}
+	chain, err = core.NewBlockChain(chainDb, makeCacheConfig(ctx), config, engine, vmcfg)
+	if err != nil {
+		Fatalf("Can't create BlockChain: %v", err)
+	}
+	return chain, chainDb
+}
+
+// MakeAppChain creates a chain manager of the app chain appId from set command
+// line flags, along with the one of the main chain its engine reads the signers
+// of the main chain from.
+func MakeAppChain(ctx *cli.Context, stack *node.Node, appId string) (chain *core.BlockChain, chainDb ethdb.Database) {
+	main, chainDb := MakeChain(ctx, stack)
+
+	config := rawdb.ReadChainConfig(chainDb, rawdb.ReadCanonicalHash(chainDb, 0, appId), appId)
+	if appId == "" || config == nil {
+		Fatalf("App chain %q does not exist", appId)
+	}
+	if config.Alien == nil {
+		Fatalf("App chain %q is not run by the alien engine", appId)
+	}
+	config.Alien.AppId = config.AppId
+	backend := &appChainBackend{chains: map[string]*core.BlockChain{"": main}}
+	engine := alien.New(config.Alien, chainDb, ctx.GlobalBool(TestgiovFlag.Name), backend)
+
+	vmcfg := vm.Config{EnablePreimageRecording: ctx.GlobalBool(VMEnableDebugFlag.Name)}
+	chain, err := core.NewBlockChain(chainDb, makeCacheConfig(ctx), config, engine, vmcfg)
+	if err != nil {
+		Fatalf("Can't create BlockChain: %v", err)
+	}
+	backend.chains[appId] = chain
+	return chain, chainDb
+}
+
+// errOffline is returned if the chains handled offline are asked to mine or to
+// join an app chain.
+var errOffline = errors.New("chains handled offline")
+
+// appChainBackend gives the engine of an app chain handled offline access to
+// the chains, without any transaction pools or miners.
+type appChainBackend struct {
+	chains map[string]*core.BlockChain
+}
+
+func (b *appChainBackend) SideBlockChain(appId string) (*core.BlockChain, bool) {
+	chain, ok := b.chains[appId]
+	return chain, ok
+}
+
+func (b *appChainBackend) TxPool() *core.TxPool                         { return nil }
+func (b *appChainBackend) SideTxPool(appId string) *core.TxPool         { return nil }
+func (b *appChainBackend) StartMining(local bool, id string) error      { return errOffline }
+func (b *appChainBackend) IsMining() bool                               { return false }
+func (b *appChainBackend) NewSideChain(isSync bool, appId string) error { return errOffline }
+func (b *appChainBackend) SideMiner(appId string) core.Miner            { return nil }
+func (b *appChainBackend) NewPassiveMiner(id string)                    {}
+
+// makeCacheConfig creates the caching options of a chain manager from set
+// command line flags.
+func makeCacheConfig(ctx *cli.Context) *core.CacheConfig {
 	if gcmode := ctx.GlobalString(GCModeFlag.Name); gcmode != "full" && gcmode != "archive" {
 		Fatalf("--%s must be either 'full' or 'archive'", GCModeFlag.Name)
 	}
@@ -1306,12 +1475,7 @@ func MakeChain(ctx *cli.Context, stack *node.Node) (chain *core.BlockChain, chai
 	if ctx.GlobalIsSet(CacheFlag.Name) || ctx.GlobalIsSet(CacheGCFlag.Name) {
 		cache.TrieNodeLimit = ctx.GlobalInt(CacheFlag.Name) * ctx.GlobalInt(CacheGCFlag.Name) / 100
 	}
-	vmcfg := vm.Config{EnablePreimageRecording: ctx.GlobalBool(VMEnableDebugFlag.Name)}
-	chain, err = core.NewBlockChain(chainDb, cache, config, engine, vmcfg)
-	if err != nil {
-		Fatalf("Can't create BlockChain: %v", err)
-	}
-	return chain, chainDb
+	return cache
 }
 
 // MakeConsolePreloads retrieves the absolute paths for the console JavaScript
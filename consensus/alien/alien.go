@@ -29,6 +29,7 @@ import (
 
 	"github.com/CarLiveChainCo/goiov/accounts"
 	"github.com/CarLiveChainCo/goiov/common"
+	"github.com/CarLiveChainCo/goiov/common/hexutil"
 	"github.com/CarLiveChainCo/goiov/consensus"
 	"github.com/CarLiveChainCo/goiov/core"
 	"github.com/CarLiveChainCo/goiov/core/rawdb"
@@ -37,6 +38,7 @@ import (
 	"github.com/CarLiveChainCo/goiov/crypto"
 	"github.com/CarLiveChainCo/goiov/crypto/sha3"
 	"github.com/CarLiveChainCo/goiov/ethdb"
+	"github.com/CarLiveChainCo/goiov/event"
 	"github.com/CarLiveChainCo/goiov/log"
 	"github.com/CarLiveChainCo/goiov/node"
 	"github.com/CarLiveChainCo/goiov/params"
@@ -48,6 +50,7 @@ import (
 const (
 	inMemorySnapshots  = 128                 // Number of recent vote snapshots to keep in memory
 	inMemorySignatures = 4096                // Number of recent block signatures to keep in memory
+	inMemoryTxStatuses = 128                 // Number of recent finalized blocks to keep the custom tx statuses of
 	SecondsPerYear     = 2 * 365 * 24 * 3600 // Number of seconds for one year
 	checkpointInterval = 360                   // About N hours if config.period is N
 )
@@ -61,6 +64,10 @@ var (
 	defaultMaxSignerCount            = uint64(21)                                         //
 	defaultMinVoteValue              = new(big.Int).Mul(big.NewInt(100), big.NewInt(1e+18))
 	defaultSelfVoteValue             = new(big.Int).Mul(big.NewInt(5000000), big.NewInt(1e+18))
+	defaultDoubleSignSlashRate       = uint64(100)  // Default per mille of the stake slashed for double signing
+	defaultMissingSlashRate          = uint64(10)   // Default per mille of the stake slashed for chronic missing
+	defaultMissingSlashThreshold     = uint64(2000) // Default punished credit from which a missing signer is slashed
+	defaultProposalDeadline          = uint64(7 * 24 * 3600) // Default number of seconds the candidates may declare for a proposal
 	extraVanity                      = 32                       // Fixed number of extra-data prefix bytes reserved for signer vanity
 	extraSeal                        = 65                       // Fixed number of extra-data suffix bytes reserved for signer seal
 	uncleHash                        = types.CalcUncleHash(nil) // Always Keccak256(RLP([])) as uncles are meaningless outside of PoW.
@@ -82,6 +89,14 @@ var (
 	// that is not part of the local blockchain.
 	errUnknownBlock = errors.New("unknown block")
 
+	// errNotAlienChain is returned if the alien state of a side chain is requested
+	// but the side chain is not run by the alien engine.
+	errNotAlienChain = errors.New("side chain not run by the alien engine")
+
+	// errUnknownTransaction is returned if a transaction is not in any canonical
+	// block of the chain.
+	errUnknownTransaction = errors.New("unknown transaction")
+
 	// errMissingVanity is returned if a block's extra-data section is shorter than
 	// 32 bytes, which is required to store the signer vanity.
 	errMissingVanity = errors.New("extra-data 32 byte vanity prefix missing")
@@ -110,6 +125,10 @@ var (
 	// errPunishedMissing is returned if a header calculate punished signer is wrong.
 	errPunishedMissing = errors.New("punished signer missing")
 
+	// errInvalidSlash is returned if the slashes of a header do not match the
+	// missing signers which reached the slash threshold.
+	errInvalidSlash = errors.New("invalid slash")
+
 	// errWaitTransactions is returned if an empty block is attempted to be sealed
 	// on an instant chain (0 second period). It's important to refuse these as the
 	// block reward is zero, so an empty block just bloats the chain... fast.
@@ -126,20 +145,43 @@ var (
 
 	// errSignerQueueEmpty is returned if no signer when calculate
 	errSignerQueueEmpty = errors.New("signer queue is empty")
+
+	// errInvalidMainAnchor is returned if the main chain anchor of a side chain
+	// header is not a block confirmed by the main chain.
+	errInvalidMainAnchor = errors.New("invalid main chain anchor")
 )
 
 // Alien is the delegated-proof-of-stake consensus engine.
 type Alien struct {
 	config     *params.AlienConfig // Consensus engine configuration parameters
 	db         ethdb.Database      // Database to store and retrieve snapshot checkpoints
+	store      ethdb.Database      // Database of the records of the chain of the engine, the table of an app chain
 	recents    *lru.ARCCache       // Snapshots for recent block to speed up reorgs
 	signatures *lru.ARCCache       // Signatures of recent blocks to speed up mining
+	txStatuses *lru.ARCCache       // Custom tx statuses of recently finalized blocks by seal hash, until imported
 	signer     common.Address      // Ethereum address of the signing key
 	signFn     SignerFn            // Signer function to authorize hashes with
 	signTxFn   SignTxFn            // Sign transaction function to sign tx
 	lock       sync.RWMutex        // Protects the signer fields
 	lcsc       uint64              // Last confirmed side chain
 	eth        core.Backend        // 用于侧链通向主链
+
+	rewardIndex  bool       // Whether the reward split of each block is recorded
+	rewardRetain uint64     // Number of recent blocks to keep reward records for, 0 keeps all
+	rewardLock   sync.Mutex // Protects the reward index
+
+	confirmPool map[common.Hash]map[common.Address]*SignedConfirmation // Signed confirmations to include in the next headers, by block hash
+	confirmFeed event.Feed                                             // Feed of the confirmations added to the pool
+	confirmLock sync.Mutex                                             // Protects the confirmation pool
+
+	checkpoint     *SideCheckpoint // Latest checkpoint of the app chain anchored on the main chain
+	checkpointTime time.Time       // Time the checkpoint was last asked from the main chain
+	checkpointLock sync.Mutex      // Protects the checkpoint
+
+	relayOnce sync.Once // Starts the message relayer once the node signs transactions
+
+	remoteMainChain *rpcMainChain // Main chain of a side chain running outside of the node of the main chain
+	mainChainLock   sync.Mutex    // Protects the remote main chain
 }
 
 // SignerFn is a signer callback function to request a hash to be signed by a
@@ -225,6 +267,21 @@ func New(config *params.AlienConfig, db ethdb.Database, testFlag bool, eth ...co
 	if conf.Freeze == 0 {
 		conf.Freeze = 20
 	}
+	if conf.RedelegateCooldown == 0 {
+		conf.RedelegateCooldown = conf.Freeze
+	}
+	if conf.DoubleSignSlashRate == 0 {
+		conf.DoubleSignSlashRate = defaultDoubleSignSlashRate
+	}
+	if conf.MissingSlashRate == 0 {
+		conf.MissingSlashRate = defaultMissingSlashRate
+	}
+	if conf.MissingSlashThreshold == 0 {
+		conf.MissingSlashThreshold = defaultMissingSlashThreshold
+	}
+	if conf.ProposalDeadline == 0 {
+		conf.ProposalDeadline = defaultProposalDeadline
+	}
 
 	if (len(conf.SelfVoteSigners) == 0) && conf.AppId == "" {
 		if testFlag {
@@ -246,6 +303,7 @@ func New(config *params.AlienConfig, db ethdb.Database, testFlag bool, eth ...co
 	// Allocate the snapshot caches and create the engine
 	recents, _ := lru.NewARC(inMemorySnapshots)
 	signatures, _ := lru.NewARC(inMemorySignatures)
+	txStatuses, _ := lru.NewARC(inMemoryTxStatuses)
 
 	var backend core.Backend
 	if len(eth) > 0 {
@@ -254,8 +312,10 @@ func New(config *params.AlienConfig, db ethdb.Database, testFlag bool, eth ...co
 	return &Alien{
 		config:     conf,
 		db:         db,
+		store:      rawdb.AppChainDatabase(db, conf.AppId),
 		recents:    recents,
 		signatures: signatures,
+		txStatuses: txStatuses,
 		eth:        backend,
 	}
 }
@@ -384,7 +444,7 @@ func (a *Alien) snapshot(chain consensus.ChainReader, number uint64, hash common
 		}
 		// If an on-disk checkpoint snapshot can be found, use that
 		if number%checkpointInterval == 0 {
-			if s, err := loadSnapshot(a.config, a.signatures, a.db, hash); err == nil {
+			if s, err := loadSnapshot(a.config, a.signatures, a.store, hash); err == nil {
 				log.Trace("Loaded voting snapshot from disk", "number", number, "hash", hash)
 				snap = s
 				break
@@ -398,7 +458,7 @@ func (a *Alien) snapshot(chain consensus.ChainReader, number uint64, hash common
 			}
 			a.config.Period = chain.Config().Alien.Period
 			snap = newSnapshot(a.config, a.signatures, genesis.Hash(), genesisVotes, lcrs)
-			if err := snap.store(a.db); err != nil {
+			if err := snap.store(a.store); err != nil {
 				return nil, err
 			}
 			log.Trace("Stored genesis voting snapshot to disk")
@@ -438,7 +498,7 @@ func (a *Alien) snapshot(chain consensus.ChainReader, number uint64, hash common
 
 	// If we've generated a new checkpoint snapshot, save to disk
 	if snap.Number%checkpointInterval == 0 && len(headers) > 0 {
-		if err = snap.store(a.db); err != nil {
+		if err = snap.store(a.store); err != nil {
 			return nil, err
 		}
 		log.Trace("Stored voting snapshot to disk", "number", snap.Number, "hash", snap.Hash)
@@ -489,7 +549,7 @@ func (a *Alien) verifySeal(chain consensus.ChainReader, header *types.Header, pa
 
 	if !chain.Config().Alien.SideChain {
 
-		if number > a.config.MaxSignerCount {
+		if number > snap.Params.MaxSignerCount {
 			var parent *types.Header
 			if len(parents) > 0 {
 				parent = parents[len(parents)-1]
@@ -509,14 +569,17 @@ func (a *Alien) verifySeal(chain consensus.ChainReader, header *types.Header, pa
 				return err
 			}
 			// verify signerqueue
-			if number%a.config.MaxSignerCount == 0 {
+			if number%snap.Params.MaxSignerCount == 0 {
 				err := snap.verifySignerQueue(currentHeaderExtra.SignerQueue, a.eth)
 				if err != nil {
 					return err
 				}
 
 			} else {
-				for i := 0; i < int(a.config.MaxSignerCount); i++ {
+				if len(parentHeaderExtra.SignerQueue) != len(currentHeaderExtra.SignerQueue) {
+					return errInvalidSignerQueue
+				}
+				for i := range parentHeaderExtra.SignerQueue {
 					if parentHeaderExtra.SignerQueue[i] != currentHeaderExtra.SignerQueue[i] {
 						return errInvalidSignerQueue
 					}
@@ -533,20 +596,72 @@ func (a *Alien) verifySeal(chain consensus.ChainReader, header *types.Header, pa
 					return errPunishedMissing
 				}
 			}
+
+			// verify slashes for missing signer
+			if err := a.verifyMissingSlashes(snap, header, currentHeaderExtra); err != nil {
+				return err
+			}
+		}
+
+		// verify confirmations and the confirmed block number below which no reorg is allowed
+		if a.config.IsFinality(header.Number) {
+			currentHeaderExtra := HeaderExtra{}
+			if err := rlp.DecodeBytes(header.Extra[extraVanity:len(header.Extra)-extraSeal], &currentHeaderExtra); err != nil {
+				return err
+			}
+			if err := a.verifySignedConfirmations(chain, header, parents, snap, currentHeaderExtra); err != nil {
+				return err
+			}
 		}
 
 		if !snap.inturn(signer, header) {
 			return errUnauthorized
 		}
 	} else {
-		if !a.mcInturn(chain, signer, header.Time.Uint64()) {
+		inturn, err := a.mcInturn(chain, signer, header.Time.Uint64())
+		if err != nil {
+			return err
+		}
+		if !inturn {
 			return errUnauthorized
-		} else {
-			// send tx to main chain to confirm this block
-			a.mcConfirmBlock(chain, header)
 		}
 	}
 
+	// verify the main chain block anchored by the side chain
+	if a.config.AppId != "" {
+		if err := a.verifyMainAnchors(header); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// verifyMissingSlashes checks that the header slashes exactly the missing signers
+// which reached the missing slash threshold.
+func (a *Alien) verifyMissingSlashes(snap *Snapshot, header *types.Header, headerExtra HeaderExtra) error {
+	var doubleSigns, missing, expected []Slash
+	for _, slash := range headerExtra.CurrentBlockSlashes {
+		switch slash.Reason {
+		case slashReasonDoubleSign:
+			doubleSigns = append(doubleSigns, slash)
+		case slashReasonMissing:
+			missing = append(missing, slash)
+		default:
+			return errInvalidSlash
+		}
+	}
+	if a.config.IsSlash(header.Number) {
+		expected = snap.missingSlashes(headerExtra.SignerMissing, header.Coinbase, doubleSigns)
+	}
+	if len(expected) != len(missing) {
+		return errInvalidSlash
+	}
+	for i := range expected {
+		if expected[i] != missing[i] {
+			return errInvalidSlash
+		}
+	}
 	return nil
 }
 
@@ -561,10 +676,24 @@ func (a *Alien) Prepare(chain consensus.ChainReader, header *types.Header) error
 	if parent == nil {
 		return consensus.ErrUnknownAncestor
 	}
-	header.Time = new(big.Int).Add(parent.Time, new(big.Int).SetUint64(a.config.Period))
+	period := a.config.Period
+	var snap *Snapshot
+	if a.config.IsProposal(header.Number) && !chain.Config().Alien.SideChain {
+		var err error
+		if snap, err = a.snapshot(chain, number-1, header.ParentHash, nil, nil, DefaultLoopCntRecalculateSigners); err != nil {
+			return err
+		}
+		period = snap.Period
+	}
+	header.Time = new(big.Int).Add(parent.Time, new(big.Int).SetUint64(period))
 	if header.Time.Int64() < time.Now().Unix() {
 		header.Time = big.NewInt(time.Now().Unix())
 	}
+	// once the period was changed by a proposal, the last block of a loop is
+	// no longer a period before the start of the next one
+	if snap != nil && header.Time.Uint64() < snap.LoopStartTime {
+		header.Time = new(big.Int).SetUint64(snap.LoopStartTime)
+	}
 
 	// If now is later than genesis timestamp, skip prepare
 	if a.config.GenesisTimestamp < uint64(time.Now().Unix()) {
@@ -592,59 +721,84 @@ func (a *Alien) Prepare(chain consensus.ChainReader, header *types.Header) error
 	return nil
 }
 
-func (a *Alien) mcInturn(chain consensus.ChainReader, signer common.Address, headerTime uint64) bool {
-	if chain.Config().Alien.SideChain {
-		ms, err := a.getMainChainSnapshotByTime(chain, headerTime)
-		if err != nil {
-			log.Info("Main chain snapshot query fail ", "err", err)
-			return false
-		}
-		// calculate the coinbase by loopStartTime & signers slice
-		loopIndex := int((headerTime-ms.LoopStartTime)/ms.Period) % len(ms.Signers)
-		if loopIndex >= len(ms.Signers) {
-			return false
-		} else if *ms.Signers[loopIndex] != signer {
-			return false
-		}
-		return true
+// mcInturn returns whether the signer is in turn to seal the block of a side
+// chain with the given time, by the signer queue of the main chain.
+func (a *Alien) mcInturn(chain consensus.ChainReader, signer common.Address, headerTime uint64) (bool, error) {
+	if !chain.Config().Alien.SideChain {
+		return false, nil
+	}
+	main, err := a.mainChain()
+	if err != nil {
+		return false, err
+	}
+	ms, err := main.snapshotByTime(headerTime)
+	if err != nil {
+		return false, err
+	}
+	if len(ms.Signers) == 0 || ms.Period == 0 || headerTime < ms.LoopStartTime {
+		return false, errMainChainSnapshot
 	}
-	return false
+	// calculate the coinbase by loopStartTime & signers slice
+	loopIndex := int((headerTime-ms.LoopStartTime)/ms.Period) % len(ms.Signers)
+	return *ms.Signers[loopIndex] == signer, nil
 }
 
+// mcConfirmBlock sends the header to the main chain in a sc confirm transaction
+// signed by the local signer, at the gas price and for the chain id of the main
+// chain.
 func (a *Alien) mcConfirmBlock(chain consensus.ChainReader, header *types.Header) {
-
 	a.lock.RLock()
 	signer, signTxFn := a.signer, a.signTxFn
 	a.lock.RUnlock()
 
-	if signer != (common.Address{}) {
-		nonce, err := a.getTransactionCountFromMainChain(chain, signer)
-		if err != nil {
-			log.Info("confirm tx sign fail", "err", err)
-		}
-		// todo update gaslimit , gasprice ,and get ChainID need to get from mainchain
-		if header.Number.Uint64() > a.lcsc {
-
-			tx := types.NewTransaction(nonce,
-				header.Coinbase, big.NewInt(0),
-				uint64(100000), big.NewInt(100000),
-				[]byte(fmt.Sprintf("ufo:1:sc:confirm:%s:%d", chain.GetHeaderByNumber(1).Hash().Hex(), header.Number.Uint64())))
-
-			signedTx, err := signTxFn(accounts.Account{Address: signer}, tx, big.NewInt(1014))
-			if err != nil {
-				log.Info("confirm tx sign fail", "err", err)
-			}
-			res, err := a.sendTransactionToMainChain(chain, signedTx)
-			if err != nil {
-
-				log.Info("confirm tx send fail", "err", err)
-			} else {
-				log.Info("confirm tx result", "hash", res)
-				a.lcsc = header.Number.Uint64()
-			}
-		}
+	if signer == (common.Address{}) || signTxFn == nil || header.Number.Uint64() <= a.lcsc {
+		return
+	}
+	main, err := a.mainChain()
+	if err != nil {
+		log.Info("confirm tx main chain unavailable", "err", err)
+		return
+	}
+	nonce, err := main.transactionCount(signer)
+	if err != nil {
+		log.Info("confirm tx nonce fail", "err", err)
+		return
+	}
+	chainId, err := main.chainId()
+	if err != nil {
+		log.Info("confirm tx chain id fail", "err", err)
+		return
+	}
+	gasPrice, err := main.gasPrice()
+	if err != nil {
+		log.Info("confirm tx gas price fail", "err", err)
+		return
+	}
+	enc, err := rlp.EncodeToBytes(header)
+	if err != nil {
+		log.Info("confirm tx encode fail", "err", err)
+		return
 	}
+	data := []byte(fmt.Sprintf("ufo:1:sc:confirm:%s:%d:%s", chain.GetHeaderByNumber(0).Hash().Hex(), header.Number.Uint64(), hexutil.Encode(enc)))
+	gas, err := core.IntrinsicGas(data, false, true)
+	if err != nil {
+		log.Info("confirm tx gas fail", "err", err)
+		return
+	}
+	tx := types.NewTransaction(nonce, header.Coinbase, big.NewInt(0), gas, gasPrice, data)
 
+	signedTx, err := signTxFn(accounts.Account{Address: signer}, tx, chainId)
+	if err != nil {
+		log.Info("confirm tx sign fail", "err", err)
+		return
+	}
+	res, err := main.sendTransaction(signedTx)
+	if err != nil {
+		log.Info("confirm tx send fail", "err", err)
+	} else {
+		log.Info("confirm tx result", "hash", res)
+		a.lcsc = header.Number.Uint64()
+	}
 }
 
 // Finalize implements consensus.Engine, ensuring no uncles are set, nor block
@@ -663,6 +817,9 @@ func (a *Alien) Finalize(chain consensus.ChainReader, header *types.Header, stat
 		return nil, consensus.ErrUnknownAncestor
 	}
 
+	// The main chain anchors of an imported block were verified with its header
+	sealedAnchors, sealed := sealedMainAnchors(header)
+
 	// Ensure the extra data has all it's components
 	if len(header.Extra) < extraVanity {
 		header.Extra = append(header.Extra, bytes.Repeat([]byte{0x00}, extraVanity-len(header.Extra))...)
@@ -706,9 +863,21 @@ func (a *Alien) Finalize(chain consensus.ChainReader, header *types.Header, stat
 		currentHeaderExtra.LoopStartTime = parentHeaderExtra.LoopStartTime
 		currentHeaderExtra.SignerMissing = getSignerMissing(parent.Coinbase, header.Coinbase, parentHeaderExtra)
 	}
+	// record the main chain blocks anchored by the parent, before the deposits
+	// and messages proven against them are processed
+	if a.config.AppId != "" {
+		a.recordMainAnchors(state, parentHeaderExtra.CurrentBlockMainAnchors)
+		if a.config.IsBridge(header.Number) || a.config.IsCrossApp(header.Number) {
+			if sealed {
+				currentHeaderExtra.CurrentBlockMainAnchors = sealedAnchors
+			} else {
+				currentHeaderExtra.CurrentBlockMainAnchors = a.newMainAnchors(state)
+			}
+		}
+	}
 	// calculate votes write into header.extra
 	//区分各种交易
-	currentHeaderExtra, err := a.processCustomTx(currentHeaderExtra, chain, header, state, txs)
+	currentHeaderExtra, statuses, err := a.processCustomTx(currentHeaderExtra, chain, header, state, txs)
 	if err != nil {
 		return nil, err
 	}
@@ -719,21 +888,24 @@ func (a *Alien) Finalize(chain consensus.ChainReader, header *types.Header, stat
 	}
 
 	if !chain.Config().Alien.SideChain {
-		currentHeaderExtra.ConfirmedBlockNumber = snap.getLastConfirmedBlockNumber(currentHeaderExtra.CurrentBlockConfirmations).Uint64()
+		if a.config.IsFinality(header.Number) {
+			currentHeaderExtra.CurrentBlockSignedConfirmations = a.pendingConfirmations(chain, header, snap)
+			currentHeaderExtra.ConfirmedBlockNumber = snap.getFinalizedBlockNumber(currentHeaderExtra.confirmations())
+		} else {
+			currentHeaderExtra.ConfirmedBlockNumber = snap.getLastConfirmedBlockNumber(currentHeaderExtra.CurrentBlockConfirmations).Uint64()
+		}
 
 		// write signerQueue in first header, from self vote signers in genesis block
 		if number == 1 {
 			currentHeaderExtra.LoopStartTime = a.config.GenesisTimestamp
-			for i := 0; i < int(a.config.MaxSignerCount); i++ {
+			for i := 0; i < int(snap.Params.MaxSignerCount); i++ {
 				currentHeaderExtra.SignerQueue = append(currentHeaderExtra.SignerQueue, a.config.SelfVoteSigners[i%len(a.config.SelfVoteSigners)])
 			}
 		}
 
 		// add balance for cancels
 		for canceler, cancel := range snap.Cancels {
-			number := header.Number.Uint64()
-			if (cancel.Passive && (number == 1+snap.Cancelers[canceler].Uint64())) ||
-				!cancel.Passive && (number+2 == snap.Cancelers[canceler].Uint64()+snap.config.Freeze/snap.config.Period) {
+			if number >= snap.CancelUnlocks[canceler] {
 				if vote, ok := snap.Votes[canceler]; ok {
 					a.lock.Lock()
 					state.AddBalance(cancel.Canceler, vote.Stake)
@@ -742,9 +914,26 @@ func (a *Alien) Finalize(chain consensus.ChainReader, header *types.Header, stat
 			}
 		}
 
-		if number%a.config.MaxSignerCount == 0 {
+		// slash the signers which reached the missing threshold in this block
+		if a.config.IsSlash(header.Number) {
+			currentHeaderExtra.CurrentBlockSlashes = append(currentHeaderExtra.CurrentBlockSlashes,
+				snap.missingSlashes(currentHeaderExtra.SignerMissing, header.Coinbase, currentHeaderExtra.CurrentBlockSlashes)...)
+		}
+
+		// add balance for unstakes
+		for voter, unstakes := range snap.Unstakes {
+			for _, unstake := range unstakes {
+				if number >= unstake.UnlockNumber {
+					a.lock.Lock()
+					state.AddBalance(voter, unstake.Amount)
+					a.lock.Unlock()
+				}
+			}
+		}
+
+		if number%snap.Params.MaxSignerCount == 0 {
 			//currentHeaderExtra.LoopStartTime = header.Time.Uint64()
-			currentHeaderExtra.LoopStartTime = currentHeaderExtra.LoopStartTime + a.config.Period*a.config.MaxSignerCount
+			currentHeaderExtra.LoopStartTime = currentHeaderExtra.LoopStartTime + snap.Period*snap.Params.MaxSignerCount
 			// create random signersQueue in currentHeaderExtra by snapshot.Tally
 			currentHeaderExtra.SignerQueue = []common.Address{}
 			newSignerQueue, err := snap.createSignerQueue(a.eth)
@@ -759,8 +948,16 @@ func (a *Alien) Finalize(chain consensus.ChainReader, header *types.Header, stat
 	} else {
 		// use currentHeaderExtra.SignerQueue as signer queue
 		currentHeaderExtra.SignerQueue = append([]common.Address{header.Coinbase}, parentHeaderExtra.SignerQueue...)
-		if len(currentHeaderExtra.SignerQueue) > int(a.config.MaxSignerCount) {
-			currentHeaderExtra.SignerQueue = currentHeaderExtra.SignerQueue[:int(a.config.MaxSignerCount)]
+		if len(currentHeaderExtra.SignerQueue) > int(snap.Params.MaxSignerCount) {
+			currentHeaderExtra.SignerQueue = currentHeaderExtra.SignerQueue[:int(snap.Params.MaxSignerCount)]
+		}
+	}
+	// pay the slashed stake to the reporters, otherwise it is burned
+	if a.config.SlashRedistribute {
+		for _, slash := range currentHeaderExtra.CurrentBlockSlashes {
+			a.lock.Lock()
+			state.AddBalance(slash.Reporter, snap.slashAmount(slash.Signer, slash.Reason))
+			a.lock.Unlock()
 		}
 	}
 	// encode header.extra
@@ -775,18 +972,23 @@ func (a *Alien) Finalize(chain consensus.ChainReader, header *types.Header, stat
 	// Set the correct difficulty
 	header.Difficulty = new(big.Int).Set(defaultDifficulty)
 	// Accumulate any block rewards and commit the final state root
-	accumulateRewards(chain.Config(), state, header, snap)
+	record := accumulateRewards(state, header, snap)
+	a.storeRewardRecord(record)
 
 	header.Root = state.IntermediateRoot(chain.Config().IsEIP158(header.Number))
 	// No uncle block
 	header.UncleHash = types.CalcUncleHash(nil)
 	// Assemble and return the final block for sealing
-	return types.NewBlock(header, txs, nil, receipts), nil
+	block := types.NewBlock(header, txs, nil, receipts)
+	if len(statuses) > 0 {
+		a.txStatuses.Add(sigHash(block.Header()), statuses)
+	}
+	return block, nil
 }
 
 func (a *Alien) automaticMining(number uint64,snap *Snapshot){
 	isMainMinerNil := reflect.ValueOf(a.eth.SideMiner("")).IsNil()
-	isTimeToChangeSinger := (number+1)%(snap.config.MaxSignerCount*snap.LCRS) == 0
+	isTimeToChangeSinger := (number+1)%(snap.Params.MaxSignerCount*snap.LCRS) == 0
 	if a.config.AppId == "" && isTimeToChangeSinger && !isMainMinerNil && a.eth.IsMining() {
 		sideMap := rawdb.ReadAllChainConfig(a.db)
 		for id := range sideMap {
@@ -830,6 +1032,11 @@ func (a *Alien) SignTx(signTxFn SignTxFn) {
 	a.lock.Lock()
 	defer a.lock.Unlock()
 	a.signTxFn = signTxFn
+
+	// relay the app messages between the chains of the node
+	if a.config.AppId == "" && a.eth != nil {
+		a.relayOnce.Do(func() { go a.relayMessages() })
+	}
 }
 
 // Seal implements consensus.Engine, attempting to create a sealed block using
@@ -863,7 +1070,12 @@ func (a *Alien) Seal(chain consensus.ChainReader, block *types.Block, stop <-cha
 			return nil, errUnauthorized
 		}
 	} else {
-		if !a.mcInturn(chain, signer, header.Time.Uint64()) {
+		inturn, err := a.mcInturn(chain, signer, header.Time.Uint64())
+		if err != nil {
+			log.Warn("Main chain snapshot unavailable", "number", number, "err", err)
+			return nil, err
+		}
+		if !inturn {
 			<-stop
 			return nil, errUnauthorized
 		}
@@ -883,6 +1095,11 @@ func (a *Alien) Seal(chain consensus.ChainReader, block *types.Block, stop <-cha
 	}
 	copy(header.Extra[len(header.Extra)-extraSeal:], sighash)
 
+	// send tx to main chain to confirm this block, for a side chain scheduled by
+	// the main chain every block, for an app chain at the loop boundaries only
+	if chain.Config().Alien.SideChain || (a.config.AppId != "" && number%snap.Params.MaxSignerCount == 0) {
+		a.mcConfirmBlock(chain, header)
+	}
 	return block.WithSeal(header), nil
 }
 
@@ -905,30 +1122,29 @@ func (a *Alien) APIs(chain consensus.ChainReader) []rpc.API {
 	}}
 }
 
-// AccumulateRewards credits the coinbase of the given block with the mining reward.
-func accumulateRewards(config *params.ChainConfig, state *state.StateDB, header *types.Header, snap *Snapshot) {
-	// Calculate the block reword by year
-	blockNumPerYear := SecondsPerYear / config.Alien.Period
-	yearCount := header.Number.Uint64() / blockNumPerYear
-	blockReward := new(big.Int).Rsh(SignerBlockReward, uint(yearCount))
-
-	if !config.Alien.SideChain {
-
-		minerReward := new(big.Int).Set(blockReward)
-		minerReward.Mul(minerReward, big.NewInt(int64(MinerRewardPerThousand)))
-		minerReward.Div(minerReward, big.NewInt(1000)) // cause the reward is calculate by cnt per thousand
-
-		votersReward := blockReward.Sub(blockReward, minerReward)
-
-		// rewards for the voters
-		for voter, reward := range snap.calculateReward(header.Coinbase, votersReward) {
-			state.AddBalance(voter, reward)
-		}
-		// rewards for the miner
-		state.AddBalance(header.Coinbase, minerReward)
-	} else {
-		state.AddBalance(header.Coinbase, blockReward)
+// AccumulateRewards credits the treasury, the coinbase of the given block and
+// its voters with the block reward, returning the reward split for the reward index.
+func accumulateRewards(state *state.StateDB, header *types.Header, snap *Snapshot) *RewardRecord {
+	reward := snap.BlockReward(header)
+
+	record := &RewardRecord{
+		Number:      header.Number.Uint64(),
+		Time:        header.Time.Uint64(),
+		ParentHash:  header.ParentHash,
+		Miner:       header.Coinbase,
+		MinerReward: reward.MinerReward,
+	}
+	if reward.TreasuryReward.Sign() > 0 {
+		state.AddBalance(reward.Treasury, reward.TreasuryReward)
+	}
+	// rewards for the voters
+	for voter, voterReward := range reward.VoterRewards {
+		state.AddBalance(voter, voterReward)
+		record.VoterRewards = append(record.VoterRewards, VoterReward{voter, voterReward})
 	}
+	// rewards for the miner
+	state.AddBalance(header.Coinbase, reward.MinerReward)
+	return record
 }
 
 // Get the signer missing from last signer till header.Coinbase
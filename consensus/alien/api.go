@@ -21,7 +21,10 @@ package alien
 import (
 	"fmt"
 	"github.com/CarLiveChainCo/goiov/common"
+	"github.com/CarLiveChainCo/goiov/common/hexutil"
 	"github.com/CarLiveChainCo/goiov/consensus"
+	"github.com/CarLiveChainCo/goiov/core"
+	"github.com/CarLiveChainCo/goiov/core/rawdb"
 	"github.com/CarLiveChainCo/goiov/core/types"
 	"github.com/CarLiveChainCo/goiov/rpc"
 	"math/big"
@@ -57,7 +60,10 @@ func (api *API) GetSideFreezeBalance(address common.Address, appId string) (uint
 		if header == nil {
 			return 0, errUnknownBlock
 		}
-		sideAlien, _ := sideChain.Engine().(*Alien)
+		sideAlien, ok := sideChain.Engine().(*Alien)
+		if !ok {
+			return 0, errNotAlienChain
+		}
 		snapshot, err := sideAlien.snapshot(sideChain, header.Number.Uint64(), header.Hash(), nil, nil, DefaultLoopCntRecalculateSigners)
 		if err != nil {
 			return 0, err
@@ -85,17 +91,12 @@ func (api *API) GetRemainingFreezeTime(address common.Address) (uint64, error) {
 	}
 
 
-	cancel := snapshot.Cancelers[address]
-	if cancel != nil {
-		cancelTime := cancel.Uint64()
-		currentTime := header.Number.Uint64()
-		freezeTime := api.alien.config.Freeze / api.alien.config.Period
-		unfreezeTime := cancelTime + freezeTime
-		remaining := unfreezeTime - currentTime
-		if remaining <= 0 {
-			remaining = 0
+	if unfreezeTime, ok := snapshot.CancelUnlocks[address]; ok {
+		var remaining uint64
+		if currentTime := header.Number.Uint64(); unfreezeTime > currentTime {
+			remaining = unfreezeTime - currentTime
 		}
-		return remaining * api.alien.config.Period, nil
+		return remaining * snapshot.Period, nil
 	} else {
 		return 0, fmt.Errorf("No cancel for %x", address)
 	}
@@ -107,22 +108,20 @@ func (api *API) GetSideRemainingFreezeTime(address common.Address, appId string)
 		if header == nil {
 			return 0, errUnknownBlock
 		}
-		sideAlien, _ := sideChain.Engine().(*Alien)
+		sideAlien, ok := sideChain.Engine().(*Alien)
+		if !ok {
+			return 0, errNotAlienChain
+		}
 		snapshot, err := sideAlien.snapshot(sideChain, header.Number.Uint64(), header.Hash(), nil, nil, DefaultLoopCntRecalculateSigners)
 		if err != nil {
 			return 0, err
 		}
-		cancel := snapshot.Cancelers[address]
-		if cancel != nil {
-			cancelTime := cancel.Uint64()
-			currentTime := header.Number.Uint64()
-			freezeTime := api.alien.config.Freeze / api.alien.config.Period
-			unfreezeTime := cancelTime + freezeTime
-			remaining := unfreezeTime - currentTime
-			if remaining <= 0 {
-				remaining = 0
+		if unfreezeTime, ok := snapshot.CancelUnlocks[address]; ok {
+			var remaining uint64
+			if currentTime := header.Number.Uint64(); unfreezeTime > currentTime {
+				remaining = unfreezeTime - currentTime
 			}
-			return remaining * api.alien.config.Period, nil
+			return remaining * snapshot.Period, nil
 		} else {
 			return 0, fmt.Errorf("No cancel for %x", address)
 		}
@@ -152,7 +151,10 @@ func (api *API) GetSideVote(address common.Address, appId string) (*Vote, error)
 		if header == nil {
 			return nil, errUnknownBlock
 		}
-		sideAlien, _ := sideChain.Engine().(*Alien)
+		sideAlien, ok := sideChain.Engine().(*Alien)
+		if !ok {
+			return nil, errNotAlienChain
+		}
 		snapshot, err := sideAlien.snapshot(sideChain, header.Number.Uint64(), header.Hash(), nil, nil, DefaultLoopCntRecalculateSigners)
 		if err != nil {
 			return nil, err
@@ -187,7 +189,10 @@ func (api *API) GetSideTally(address common.Address, appId string) (uint64, erro
 		if header == nil {
 			return 0, errUnknownBlock
 		}
-		sideAlien, _ := sideChain.Engine().(*Alien)
+		sideAlien, ok := sideChain.Engine().(*Alien)
+		if !ok {
+			return 0, errNotAlienChain
+		}
 		snapshot, err :=  sideAlien.snapshot(sideChain, header.Number.Uint64(), header.Hash(), nil, nil, DefaultLoopCntRecalculateSigners)
 		if err != nil {
 			return 0, err
@@ -222,7 +227,10 @@ func (api *API) GetSideCandidatesAndTally(appId string) (map[common.Address]*big
 		if header == nil {
 			return nil, errUnknownBlock
 		}
-		sideAlien, _ := sideChain.Engine().(*Alien)
+		sideAlien, ok := sideChain.Engine().(*Alien)
+		if !ok {
+			return nil, errNotAlienChain
+		}
 		snapshot, err := sideAlien.snapshot(sideChain, header.Number.Uint64(), header.Hash(), nil, nil, DefaultLoopCntRecalculateSigners)
 		if err != nil {
 			return nil, err
@@ -236,7 +244,10 @@ func (api *API) GetSideSnapshot(appId string) (*Snapshot, error) {
 	// Retrieve the requested block number (or current if none requested)
 	if sideChain, ok := api.alien.eth.SideBlockChain(appId); ok {
 		header := sideChain.CurrentHeader()
-		sideAlien, _ := sideChain.Engine().(*Alien)
+		sideAlien, ok := sideChain.Engine().(*Alien)
+		if !ok {
+			return nil, errNotAlienChain
+		}
 		return sideAlien.snapshot(sideChain, header.Number.Uint64(), header.Hash(), nil, nil, DefaultLoopCntRecalculateSigners)
 	} else {
 		return nil, fmt.Errorf("appId %s does not exist", appId)
@@ -273,7 +284,10 @@ func (api *API) GetSideSnapshotAtNumber(number uint64, appId string) (*Snapshot,
 
 	if sideChain, ok := api.alien.eth.SideBlockChain(appId); ok {
 		header := sideChain.GetHeaderByNumber(number)
-		sideAlien, _ := sideChain.Engine().(*Alien)
+		sideAlien, ok := sideChain.Engine().(*Alien)
+		if !ok {
+			return nil, errNotAlienChain
+		}
 		return sideAlien.snapshot(sideChain, header.Number.Uint64(), header.Hash(), nil, nil, DefaultLoopCntRecalculateSigners)
 	} else {
 		return nil, fmt.Errorf("appId %s does not exist", appId)
@@ -292,41 +306,412 @@ func (api *API) GetSnapshotAtNumber(number uint64) (*Snapshot, error) {
 // GetSnapshotByHeaderTime retrieves the state snapshot by timestamp of header.
 // snapshot.header.time <= targetTime < snapshot.header.time + period
 func (api *API) GetSnapshotByHeaderTime(targetTime uint64) (*Snapshot, error) {
-	period := api.chain.Config().Alien.Period
+	return api.alien.snapshotByHeaderTime(api.chain, targetTime)
+}
+
+// GetCustomTxStatus retrieves the outcome of processing a custom transaction,
+// including the reason it was rejected by the engine.
+func (api *API) GetCustomTxStatus(hash common.Hash) (*CustomTxStatus, error) {
+	status, err := api.alien.customTxStatus(api.chain, hash)
+	if err != nil {
+		return nil, fmt.Errorf("no custom tx status for %x", hash)
+	}
+	return status, nil
+}
+
+// GetSideCustomTxStatus retrieves the outcome of processing a custom transaction
+// on the given side chain.
+func (api *API) GetSideCustomTxStatus(hash common.Hash, appId string) (*CustomTxStatus, error) {
+	if sideChain, ok := api.alien.eth.SideBlockChain(appId); ok {
+		sideAlien, ok := sideChain.Engine().(*Alien)
+		if !ok {
+			return nil, errNotAlienChain
+		}
+		status, err := sideAlien.customTxStatus(sideChain, hash)
+		if err != nil {
+			return nil, fmt.Errorf("no custom tx status for %x", hash)
+		}
+		return status, nil
+	} else {
+		return nil, fmt.Errorf("appId %s does not exist", appId)
+	}
+}
+
+// GetUnlockSchedule retrieves the frozen stake of the address which is still
+// to be returned, from partial unstakes and from a cancel of its vote.
+func (api *API) GetUnlockSchedule(address common.Address) ([]*UnlockItem, error) {
 	header := api.chain.CurrentHeader()
-	if header == nil || targetTime > header.Time.Uint64()+period {
+	if header == nil {
 		return nil, errUnknownBlock
 	}
-	minN := uint64(0)
-	maxN := header.Number.Uint64()
-	for {
-		if targetTime >= header.Time.Uint64() && targetTime < header.Time.Uint64()+period {
-			return api.alien.snapshot(api.chain, header.Number.Uint64(), header.Hash(), nil, nil, DefaultLoopCntRecalculateSigners)
-		} else {
-			if maxN == minN || maxN == minN+1 {
-				break
-			}
-			// calculate next number
-			nextN := uint64(int64(header.Number.Uint64()) + (int64(targetTime)-int64(header.Time.Uint64()))/int64(period))
-			if nextN >= maxN || nextN <= minN {
-				nextN = (maxN + minN) / 2
-			}
-			// get new header
-			header = api.chain.GetHeaderByNumber(nextN)
-			if header == nil {
-				break
-			}
-			// update maxN & minN
-			if header.Time.Uint64() >= targetTime {
-				if header.Number.Uint64() < maxN {
-					maxN = header.Number.Uint64()
-				}
-			} else if header.Time.Uint64() <= targetTime {
-				if header.Number.Uint64() > minN {
-					minN = header.Number.Uint64()
-				}
-			}
+	snapshot, err := api.alien.snapshot(api.chain, header.Number.Uint64(), header.Hash(), nil, nil, DefaultLoopCntRecalculateSigners)
+	if err != nil {
+		return nil, err
+	}
+	return snapshot.unlockSchedule(address), nil
+}
+
+// GetSideUnlockSchedule retrieves the frozen stake of the address which is
+// still to be returned on the given side chain.
+func (api *API) GetSideUnlockSchedule(address common.Address, appId string) ([]*UnlockItem, error) {
+	if sideChain, ok := api.alien.eth.SideBlockChain(appId); ok {
+		header := sideChain.CurrentHeader()
+		if header == nil {
+			return nil, errUnknownBlock
+		}
+		sideAlien, ok := sideChain.Engine().(*Alien)
+		if !ok {
+			return nil, errNotAlienChain
+		}
+		snapshot, err := sideAlien.snapshot(sideChain, header.Number.Uint64(), header.Hash(), nil, nil, DefaultLoopCntRecalculateSigners)
+		if err != nil {
+			return nil, err
+		}
+		return snapshot.unlockSchedule(address), nil
+	} else {
+		return nil, fmt.Errorf("appId %s does not exist", appId)
+	}
+}
+
+// GetRemainingRedelegateTime retrieves the number of seconds left before the
+// vote of the address may be moved to another candidate.
+func (api *API) GetRemainingRedelegateTime(address common.Address) (uint64, error) {
+	header := api.chain.CurrentHeader()
+	if header == nil {
+		return 0, errUnknownBlock
+	}
+	snapshot, err := api.alien.snapshot(api.chain, header.Number.Uint64(), header.Hash(), nil, nil, DefaultLoopCntRecalculateSigners)
+	if err != nil {
+		return 0, err
+	}
+	if _, ok := snapshot.Votes[address]; !ok {
+		return 0, fmt.Errorf("No vote for %x", address)
+	}
+	return snapshot.remainingRedelegateTime(address, header.Number.Uint64()+1), nil
+}
+
+// GetSideRemainingRedelegateTime retrieves the number of seconds left before
+// the vote of the address may be moved to another candidate on the given side chain.
+func (api *API) GetSideRemainingRedelegateTime(address common.Address, appId string) (uint64, error) {
+	if sideChain, ok := api.alien.eth.SideBlockChain(appId); ok {
+		header := sideChain.CurrentHeader()
+		if header == nil {
+			return 0, errUnknownBlock
+		}
+		sideAlien, ok := sideChain.Engine().(*Alien)
+		if !ok {
+			return 0, errNotAlienChain
+		}
+		snapshot, err := sideAlien.snapshot(sideChain, header.Number.Uint64(), header.Hash(), nil, nil, DefaultLoopCntRecalculateSigners)
+		if err != nil {
+			return 0, err
+		}
+		if _, ok := snapshot.Votes[address]; !ok {
+			return 0, fmt.Errorf("No vote for %x", address)
+		}
+		return snapshot.remainingRedelegateTime(address, header.Number.Uint64()+1), nil
+	} else {
+		return 0, fmt.Errorf("appId %s does not exist", appId)
+	}
+}
+
+// GetRewardHistory retrieves the rewards credited to the address in each block
+// of the given range, as recorded by the reward index.
+func (api *API) GetRewardHistory(address common.Address, fromBlock uint64, toBlock uint64) ([]*RewardEntry, error) {
+	return api.alien.rewardHistory(api.chain, address, fromBlock, toBlock)
+}
+
+// GetSideRewardHistory retrieves the rewards credited to the address in each
+// block of the given range on the given side chain.
+func (api *API) GetSideRewardHistory(address common.Address, fromBlock uint64, toBlock uint64, appId string) ([]*RewardEntry, error) {
+	if sideChain, ok := api.alien.eth.SideBlockChain(appId); ok {
+		sideAlien, ok := sideChain.Engine().(*Alien)
+		if !ok {
+			return nil, errNotAlienChain
+		}
+		return sideAlien.rewardHistory(sideChain, address, fromBlock, toBlock)
+	} else {
+		return nil, fmt.Errorf("appId %s does not exist", appId)
+	}
+}
+
+// GetRewardSummary retrieves the rewards credited to the address during the
+// last day, week, month or year.
+func (api *API) GetRewardSummary(address common.Address, period string) (*RewardSummary, error) {
+	return api.alien.rewardSummary(api.chain, address, period)
+}
+
+// GetSideRewardSummary retrieves the rewards credited to the address during the
+// last day, week, month or year on the given side chain.
+func (api *API) GetSideRewardSummary(address common.Address, period string, appId string) (*RewardSummary, error) {
+	if sideChain, ok := api.alien.eth.SideBlockChain(appId); ok {
+		sideAlien, ok := sideChain.Engine().(*Alien)
+		if !ok {
+			return nil, errNotAlienChain
+		}
+		return sideAlien.rewardSummary(sideChain, address, period)
+	} else {
+		return nil, fmt.Errorf("appId %s does not exist", appId)
+	}
+}
+
+// GetSignerSchedule forecasts the next count slots from fromTime on, now if it
+// is 0, with the signers expected to seal their blocks.
+func (api *API) GetSignerSchedule(fromTime uint64, count uint64) ([]*SignerSlot, error) {
+	return api.alien.signerSchedule(api.chain, fromTime, count)
+}
+
+// GetSideSignerSchedule forecasts the next count slots from fromTime on of the
+// given side chain with the signers expected to seal their blocks.
+func (api *API) GetSideSignerSchedule(fromTime uint64, count uint64, appId string) ([]*SignerSlot, error) {
+	if sideChain, ok := api.alien.eth.SideBlockChain(appId); ok {
+		sideAlien, ok := sideChain.Engine().(*Alien)
+		if !ok {
+			return nil, errNotAlienChain
+		}
+		return sideAlien.signerSchedule(sideChain, fromTime, count)
+	} else {
+		return nil, fmt.Errorf("appId %s does not exist", appId)
+	}
+}
+
+// GetSignerStats retrieves the blocks produced, the slots missed, the block
+// confirmations and the punishment credit history of the signer in the given
+// range of blocks.
+func (api *API) GetSignerStats(address common.Address, fromBlock uint64, toBlock uint64) (*SignerStats, error) {
+	return api.alien.signerStats(api.chain, address, fromBlock, toBlock)
+}
+
+// GetSideSignerStats retrieves the activity of the signer in the given range of
+// blocks of the given side chain.
+func (api *API) GetSideSignerStats(address common.Address, fromBlock uint64, toBlock uint64, appId string) (*SignerStats, error) {
+	if sideChain, ok := api.alien.eth.SideBlockChain(appId); ok {
+		sideAlien, ok := sideChain.Engine().(*Alien)
+		if !ok {
+			return nil, errNotAlienChain
+		}
+		return sideAlien.signerStats(sideChain, address, fromBlock, toBlock)
+	} else {
+		return nil, fmt.Errorf("appId %s does not exist", appId)
+	}
+}
+
+// GetProposals retrieves the proposals to change the engine parameters, with
+// the declares of the candidates for them.
+func (api *API) GetProposals() ([]*ProposalRecord, error) {
+	header := api.chain.CurrentHeader()
+	if header == nil {
+		return nil, errUnknownBlock
+	}
+	snapshot, err := api.alien.snapshot(api.chain, header.Number.Uint64(), header.Hash(), nil, nil, DefaultLoopCntRecalculateSigners)
+	if err != nil {
+		return nil, err
+	}
+	return snapshot.proposalList(), nil
+}
+
+// GetSideProposals retrieves the proposals to change the engine parameters of
+// the given side chain.
+func (api *API) GetSideProposals(appId string) ([]*ProposalRecord, error) {
+	if sideChain, ok := api.alien.eth.SideBlockChain(appId); ok {
+		header := sideChain.CurrentHeader()
+		if header == nil {
+			return nil, errUnknownBlock
+		}
+		sideAlien, ok := sideChain.Engine().(*Alien)
+		if !ok {
+			return nil, errNotAlienChain
+		}
+		snapshot, err := sideAlien.snapshot(sideChain, header.Number.Uint64(), header.Hash(), nil, nil, DefaultLoopCntRecalculateSigners)
+		if err != nil {
+			return nil, err
+		}
+		return snapshot.proposalList(), nil
+	} else {
+		return nil, fmt.Errorf("appId %s does not exist", appId)
+	}
+}
+
+// GetProposal retrieves the proposal made by the transaction with the given hash.
+func (api *API) GetProposal(hash common.Hash) (*ProposalRecord, error) {
+	header := api.chain.CurrentHeader()
+	if header == nil {
+		return nil, errUnknownBlock
+	}
+	snapshot, err := api.alien.snapshot(api.chain, header.Number.Uint64(), header.Hash(), nil, nil, DefaultLoopCntRecalculateSigners)
+	if err != nil {
+		return nil, err
+	}
+	if proposal, ok := snapshot.proposal(hash); ok {
+		return proposal, nil
+	}
+	return nil, fmt.Errorf("No proposal for %x", hash)
+}
+
+// GetSideProposal retrieves the proposal made by the transaction with the given
+// hash on the given side chain.
+func (api *API) GetSideProposal(hash common.Hash, appId string) (*ProposalRecord, error) {
+	if sideChain, ok := api.alien.eth.SideBlockChain(appId); ok {
+		header := sideChain.CurrentHeader()
+		if header == nil {
+			return nil, errUnknownBlock
+		}
+		sideAlien, ok := sideChain.Engine().(*Alien)
+		if !ok {
+			return nil, errNotAlienChain
+		}
+		snapshot, err := sideAlien.snapshot(sideChain, header.Number.Uint64(), header.Hash(), nil, nil, DefaultLoopCntRecalculateSigners)
+		if err != nil {
+			return nil, err
+		}
+		if proposal, ok := snapshot.proposal(hash); ok {
+			return proposal, nil
+		}
+		return nil, fmt.Errorf("No proposal for %x", hash)
+	} else {
+		return nil, fmt.Errorf("appId %s does not exist", appId)
+	}
+}
+
+// GetSideChainCheckpoint retrieves the checkpoint of the given block of the side
+// chain appId anchored on the main chain, or its latest checkpoint if number is
+// nil or latest.
+func (api *API) GetSideChainCheckpoint(appId string, number *rpc.BlockNumber) (*SideCheckpoint, error) {
+	if number == nil || *number == rpc.LatestBlockNumber || *number == rpc.PendingBlockNumber {
+		header := api.chain.CurrentHeader()
+		if header == nil {
+			return nil, errUnknownBlock
+		}
+		snapshot, err := api.alien.snapshot(api.chain, header.Number.Uint64(), header.Hash(), nil, nil, DefaultLoopCntRecalculateSigners)
+		if err != nil {
+			return nil, err
+		}
+		if checkpoint, ok := snapshot.Checkpoints[appId]; ok {
+			return checkpoint.copy(), nil
 		}
+		return nil, fmt.Errorf("no checkpoint of appId %s", appId)
+	}
+	chain, ok := api.chain.(*core.BlockChain)
+	if !ok {
+		return nil, errUnknownBlock
+	}
+	statedb, err := chain.State()
+	if err != nil {
+		return nil, err
+	}
+	if checkpoint := readCheckpoint(api.chain, statedb, appId, uint64(number.Int64())); checkpoint != nil {
+		return checkpoint, nil
+	}
+	return nil, fmt.Errorf("no checkpoint of block %d of appId %s", number.Int64(), appId)
+}
+
+// GetBridgeMintData retrieves the data of the side chain transaction minting the
+// value of the given deposit to the bridge address of the main chain. The mint
+// is accepted once the side chain, which must run in the node, anchored the
+// block of the deposit or a later main chain block.
+func (api *API) GetBridgeMintData(hash common.Hash) (hexutil.Bytes, error) {
+	tx, blockHash, number, index := rawdb.ReadTransaction(api.alien.db, hash, "")
+	if tx == nil {
+		return nil, fmt.Errorf("no transaction %x", hash)
+	}
+	ctx, err := parseCustomTx(api.alien.config, tx.Data(), new(big.Int).SetUint64(number))
+	if err != nil {
+		return nil, err
+	}
+	deposit, ok := ctx.params.(*depositParams)
+	if !ok {
+		return nil, fmt.Errorf("transaction %x is not a deposit", hash)
+	}
+	block := api.chain.GetBlock(blockHash, number)
+	receipts := rawdb.ReadReceipts(api.alien.db, blockHash, number)
+	if block == nil || len(receipts) != len(block.Transactions()) {
+		return nil, errUnknownBlock
+	}
+	headers, err := api.alien.mainAnchoredHeaders(deposit.AppId, number)
+	if err != nil {
+		return nil, err
+	}
+	if headers[0].Hash() != blockHash {
+		return nil, errUnknownBlock
+	}
+	proof, err := makeBridgeProof(headers, block.Transactions(), receipts, index)
+	if err != nil {
+		return nil, err
+	}
+	return encodeBridgeTx(ufoEventMint, proof)
+}
+
+// GetSideBridgeReleaseData retrieves the data of the main chain transaction
+// releasing the value of the given burn on the side chain appId. The block of
+// the burn must be anchored by the latest checkpoint of the side chain.
+func (api *API) GetSideBridgeReleaseData(hash common.Hash, appId string) (hexutil.Bytes, error) {
+	sideChain, ok := api.alien.eth.SideBlockChain(appId)
+	if !ok || appId == "" {
+		return nil, fmt.Errorf("appId %s does not exist", appId)
+	}
+	tx, blockHash, number, index := rawdb.ReadTransaction(api.alien.db, hash, appId)
+	if tx == nil {
+		return nil, fmt.Errorf("no transaction %x", hash)
+	}
+	checkpoint, err := api.GetSideChainCheckpoint(appId, nil)
+	if err != nil {
+		return nil, err
+	}
+	headers, err := anchoredHeaders(sideChain, number, checkpoint)
+	if err != nil {
+		return nil, err
+	}
+	if headers[0].Hash() != blockHash {
+		return nil, fmt.Errorf("appId %s does not match its latest checkpoint", appId)
+	}
+	block := sideChain.GetBlock(blockHash, number)
+	receipts := rawdb.ReadReceipts(api.alien.db, blockHash, number, appId)
+	if block == nil || len(receipts) != len(block.Transactions()) {
+		return nil, errUnknownBlock
+	}
+	proof, err := makeBridgeProof(headers, block.Transactions(), receipts, index)
+	if err != nil {
+		return nil, err
+	}
+	return encodeBridgeTx(ufoEventRelease, proof)
+}
+
+// GetAppMessageDeliveryData retrieves the data of the transactions delivering the
+// messages sent by the given transaction of the app chain appId to their chains,
+// in the order of the messages, which must run in the node. The block of the
+// transaction must be anchored by the latest checkpoint of a side chain, and the
+// main chain blocks of the proofs by the side chains the messages are sent to.
+func (api *API) GetAppMessageDeliveryData(hash common.Hash, appId string) ([]hexutil.Bytes, error) {
+	var checkpoint *SideCheckpoint
+	if appId != "" {
+		var err error
+		if checkpoint, err = api.alien.latestCheckpoint(appId); err != nil {
+			return nil, err
+		}
+	}
+	msgs, proofs, err := api.alien.makeMessageProofs(appId, hash, checkpoint)
+	if err != nil {
+		return nil, err
+	}
+	data := make([]hexutil.Bytes, 0, len(proofs))
+	for i, proof := range proofs {
+		if proof == nil {
+			return nil, fmt.Errorf("appId %s of message %x does not run in the node", msgs[i].AppId, msgs[i].Hash())
+		}
+		enc, err := encodeBridgeTx(ufoEventDeliver, proof)
+		if err != nil {
+			return nil, err
+		}
+		data = append(data, enc)
+	}
+	return data, nil
+}
+
+// GetAppMessageReceipt retrieves the delivery receipt of the app message with the
+// given hash.
+func (api *API) GetAppMessageReceipt(hash common.Hash) (*AppMessageReceipt, error) {
+	if receipt := readAppMessageReceipt(api.alien.db, hash); receipt != nil {
+		return receipt, nil
 	}
-	return nil, errUnknownBlock
+	return nil, fmt.Errorf("app message %x not delivered", hash)
 }
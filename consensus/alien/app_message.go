@@ -0,0 +1,375 @@
+// Copyright 2018 The giov Authors
+// This file is part of the giov library.
+//
+// The giov library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The giov library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the giov library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package alien implements the delegated-proof-of-stake consensus engine.
+
+package alien
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/CarLiveChainCo/goiov/accounts/abi"
+	"github.com/CarLiveChainCo/goiov/common"
+	"github.com/CarLiveChainCo/goiov/common/hexutil"
+	"github.com/CarLiveChainCo/goiov/consensus"
+	"github.com/CarLiveChainCo/goiov/core"
+	"github.com/CarLiveChainCo/goiov/core/rawdb"
+	"github.com/CarLiveChainCo/goiov/core/state"
+	"github.com/CarLiveChainCo/goiov/core/types"
+	"github.com/CarLiveChainCo/goiov/core/vm"
+	"github.com/CarLiveChainCo/goiov/crypto"
+	"github.com/CarLiveChainCo/goiov/ethdb"
+	"github.com/CarLiveChainCo/goiov/log"
+	"github.com/CarLiveChainCo/goiov/params"
+	"github.com/CarLiveChainCo/goiov/rlp"
+)
+
+const (
+	messageDonePrefix    = "alien-msg-done-" // messageDonePrefix + appId of the source chain + message hash -> delivery status of the message
+	messageReceiptPrefix = "alien-msg-"      // messageReceiptPrefix + message hash -> delivery receipt of the message
+
+	// appMessageReceiverABI is the function the receiver contract of an app
+	// message is called with, by params.MessageAddress.
+	appMessageReceiverABI = `[{"type":"function","name":"receiveAppMessage","inputs":[{"name":"sourceAppId","type":"string"},{"name":"sender","type":"address"},{"name":"data","type":"bytes"}],"outputs":[]}]`
+)
+
+var (
+	messageDelivered = common.BytesToHash([]byte{1}) // the receiver contract accepted the message
+	messageFailed    = common.BytesToHash([]byte{2}) // the receiver contract reverted or ran out of gas
+
+	appMessageReceiver, _ = abi.JSON(strings.NewReader(appMessageReceiverABI))
+)
+
+// messageProof :
+// message proof come from custom tx which data like "ufo:1:sc:deliver:<proof>", proof
+// is the hex RLP encoded messageProof of a message sent on another app chain.
+//
+// A contract sends a message by calling params.MessageAddress, which records it in
+// a log of the transaction (see vm.EVM.sendAppMessage). LogIndex is the index of the
+// log in the receipt of the transaction. The headers of the proof are the main chain
+// headers from the block of the transaction to a block anchored by the side chain
+// the message is delivered on, or the side chain headers from the block of the
+// transaction to the block of a checkpoint on a side chain. MainHeaders are the main
+// chain headers from the block anchoring the checkpoint to a block anchored by the
+// side chain a message of another side chain is delivered on. A message is delivered
+// once, successful or not
+type messageProof struct {
+	Proof       bridgeProof
+	LogIndex    uint64
+	MainHeaders []*types.Header `rlp:"tail"`
+}
+
+// AppMessageReceipt is the outcome of delivering an app message, stored so that
+// it can be queried through alien_getAppMessageReceipt.
+type AppMessageReceipt struct {
+	Hash        common.Hash    `json:"hash"`
+	SourceAppId string         `json:"sourceAppId"`
+	AppId       string         `json:"appId"`
+	Sender      common.Address `json:"sender"`
+	To          common.Address `json:"to"`
+	Success     bool           `json:"success"`
+	Reason      string         `json:"reason,omitempty"`
+	ReturnData  hexutil.Bytes  `json:"returnData,omitempty"`
+	BlockNumber uint64         `json:"blockNumber"`
+	TxHash      common.Hash    `json:"transactionHash"`
+}
+
+// processEventDeliver calls the receiver contract of a message sent on another
+// app chain, once the block of the message is final on its chain.
+func (a *Alien) processEventDeliver(chain consensus.ChainReader, header *types.Header, state *state.StateDB, tx *types.Transaction, deliver *deliverParams) error {
+	proof := (*messageProof)(deliver)
+	if len(proof.Proof.Headers) == 0 || proof.Proof.Headers[0] == nil || proof.Proof.Headers[0].Number == nil {
+		return errMessageProof
+	}
+	source := proof.Proof.Headers[0]
+	if source.Appid == a.config.AppId {
+		return errMessageWrongChain
+	}
+	config := rawdb.ReadChainConfig(a.db, common.Hash{}, source.Appid)
+	if config == nil || config.Alien == nil {
+		return errCheckpointUnknownChain
+	}
+	if !config.Alien.IsCrossApp(source.Number) {
+		return errCustomTxNotActive
+	}
+	if err := a.verifyMessageSource(header, state, proof); err != nil {
+		return err
+	}
+	_, receipt, err := verifyBridgeProof(&proof.Proof)
+	if err != nil {
+		return errMessageProof
+	}
+	msg, err := decodeAppMessage(receipt, proof.LogIndex)
+	if err != nil {
+		return err
+	}
+	if msg.SourceAppId != source.Appid || msg.AppId != a.config.AppId {
+		return errMessageWrongChain
+	}
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	hash := msg.Hash()
+	done := messageDoneKey(msg.SourceAppId, hash)
+	if state.GetState(params.MessageAddress, done) != (common.Hash{}) {
+		return errMessageRepeat
+	}
+	result := &AppMessageReceipt{
+		Hash:        hash,
+		SourceAppId: msg.SourceAppId,
+		AppId:       msg.AppId,
+		Sender:      msg.Sender,
+		To:          msg.To,
+		Success:     true,
+		BlockNumber: header.Number.Uint64(),
+		TxHash:      tx.Hash(),
+	}
+	ret, err := a.callAppMessageReceiver(chain, header, state, msg)
+	result.ReturnData = ret
+	status := messageDelivered
+	if err != nil {
+		status, result.Success, result.Reason = messageFailed, false, err.Error()
+	}
+	keepSystemAccount(state, params.MessageAddress)
+	state.SetState(params.MessageAddress, done, status)
+	if err := writeAppMessageReceipt(a.db, result); err != nil {
+		log.Warn("Failed to store app message receipt", "hash", hash, "err", err)
+	}
+	return nil
+}
+
+// verifyMessageSource checks that the block of a message is final on its chain.
+// A main chain block must be anchored by the side chain the message is delivered
+// on. A side chain block must be anchored by a checkpoint of the main chain, in a
+// block before the current one on the main chain, or in a main chain block
+// anchored by the side chain the message is delivered on.
+func (a *Alien) verifyMessageSource(header *types.Header, state *state.StateDB, proof *messageProof) error {
+	a.lock.RLock()
+	defer a.lock.RUnlock()
+
+	source := proof.Proof.Headers[0]
+	if source.Appid == "" {
+		if a.config.AppId == "" {
+			return errMessageWrongChain
+		}
+		return verifyMainAnchoredHeaders(state, proof.Proof.Headers)
+	}
+	if a.config.AppId == "" {
+		mainNumber, err := verifyAnchoredHeaders(state, proof.Proof.Headers)
+		if err != nil {
+			return err
+		}
+		if mainNumber >= header.Number.Uint64() {
+			return errBridgeNotFinal
+		}
+		return nil
+	}
+	if err := verifyLinkedHeaders(proof.Proof.Headers); err != nil {
+		return err
+	}
+	if err := verifyMainAnchoredHeaders(state, proof.MainHeaders); err != nil {
+		return err
+	}
+	last, mainHeader := proof.Proof.Headers[len(proof.Proof.Headers)-1], proof.MainHeaders[0]
+	if len(mainHeader.Extra) < extraVanity+extraSeal {
+		return errMessageProof
+	}
+	headerExtra := HeaderExtra{}
+	if err := rlp.DecodeBytes(mainHeader.Extra[extraVanity:len(mainHeader.Extra)-extraSeal], &headerExtra); err != nil {
+		return errMessageProof
+	}
+	for _, checkpoint := range headerExtra.CurrentBlockCheckpoints {
+		if checkpoint.AppId == last.Appid && checkpoint.Number == last.Number.Uint64() && checkpoint.Hash == last.Hash() {
+			return nil
+		}
+	}
+	return errBridgeNotFinal
+}
+
+// callAppMessageReceiver calls receiveAppMessage of the receiver contract of the
+// message with the gas limit of the message, from params.MessageAddress.
+func (a *Alien) callAppMessageReceiver(chain consensus.ChainReader, header *types.Header, state *state.StateDB, msg *types.AppMessage) ([]byte, error) {
+	input, err := appMessageReceiver.Pack("receiveAppMessage", msg.SourceAppId, msg.Sender, []byte(msg.Data))
+	if err != nil {
+		return nil, err
+	}
+	context := vm.Context{
+		CanTransfer: core.CanTransfer,
+		Transfer:    core.Transfer,
+		GetHash:     ancestorHashFn(chain, header),
+		Origin:      params.MessageAddress,
+		GasPrice:    new(big.Int),
+		Coinbase:    header.Coinbase,
+		GasLimit:    header.GasLimit,
+		BlockNumber: new(big.Int).Set(header.Number),
+		Time:        new(big.Int).Set(header.Time),
+		Difficulty:  new(big.Int).Set(header.Difficulty),
+	}
+	evm := vm.NewEVM(context, state, chain.Config(), vm.Config{})
+	ret, _, err := evm.Call(vm.AccountRef(params.MessageAddress), msg.To, input, msg.GasLimit, new(big.Int))
+	return ret, err
+}
+
+// ancestorHashFn returns a GetHashFunc which retrieves the hashes of the
+// ancestors of the header being processed.
+func ancestorHashFn(chain consensus.ChainReader, header *types.Header) vm.GetHashFunc {
+	return func(n uint64) common.Hash {
+		for parent := chain.GetHeader(header.ParentHash, header.Number.Uint64()-1); parent != nil; parent = chain.GetHeader(parent.ParentHash, parent.Number.Uint64()-1) {
+			if parent.Number.Uint64() == n {
+				return parent.Hash()
+			}
+			if parent.Number.Uint64() < n || parent.Number.Sign() == 0 {
+				break
+			}
+		}
+		return common.Hash{}
+	}
+}
+
+// decodeAppMessage returns the message recorded in the log with the given index
+// of the receipt.
+func decodeAppMessage(receipt *types.Receipt, index uint64) (*types.AppMessage, error) {
+	if index >= uint64(len(receipt.Logs)) {
+		return nil, errMessageProof
+	}
+	l := receipt.Logs[index]
+	if l.Address != params.MessageAddress || len(l.Topics) != 3 || l.Topics[0] != types.AppMessageTopic {
+		return nil, errMessageProof
+	}
+	msg := new(types.AppMessage)
+	if err := rlp.DecodeBytes(l.Data, msg); err != nil || msg.Hash() != l.Topics[2] || crypto.Keccak256Hash([]byte(msg.AppId)) != l.Topics[1] {
+		return nil, errMessageProof
+	}
+	return msg, nil
+}
+
+// makeMessageProofs returns the messages sent by the transaction with the given
+// hash on the app chain appId, with their proofs. The side chain headers of the
+// proofs lead to the given checkpoint, the main chain headers to the latest main
+// chain block anchored by the side chain the message is delivered on. The proofs
+// of the messages to chains the node does not run, or which do not accept
+// messages yet, are nil.
+func (a *Alien) makeMessageProofs(appId string, hash common.Hash, checkpoint *SideCheckpoint) ([]*types.AppMessage, []*messageProof, error) {
+	if a.eth == nil {
+		return nil, nil, errUnknownBlock
+	}
+	source, ok := a.eth.SideBlockChain(appId)
+	if !ok {
+		return nil, nil, fmt.Errorf("appId %s does not exist", appId)
+	}
+	tx, blockHash, number, index := rawdb.ReadTransaction(a.db, hash, appId)
+	if tx == nil {
+		return nil, nil, fmt.Errorf("no transaction %x", hash)
+	}
+	block := source.GetBlock(blockHash, number)
+	receipts := rawdb.ReadReceipts(a.db, blockHash, number, appId)
+	if block == nil || len(receipts) != len(block.Transactions()) {
+		return nil, nil, errUnknownBlock
+	}
+	var headers []*types.Header
+	if appId != "" {
+		var err error
+		if headers, err = anchoredHeaders(source, number, checkpoint); err != nil {
+			return nil, nil, err
+		}
+		if headers[0].Hash() != blockHash {
+			return nil, nil, errUnknownBlock
+		}
+	}
+	var (
+		msgs   []*types.AppMessage
+		proofs []*messageProof
+	)
+	for i := range receipts[index].Logs {
+		msg, err := decodeAppMessage(receipts[index], uint64(i))
+		if err != nil {
+			continue
+		}
+		msgs = append(msgs, msg)
+		if dest, ok := a.eth.SideBlockChain(msg.AppId); !ok || dest == nil || msg.AppId == appId || !dest.Config().IsCrossApp(new(big.Int).Add(dest.CurrentHeader().Number, common.Big1)) {
+			proofs = append(proofs, nil)
+			continue
+		}
+		proof := &messageProof{LogIndex: uint64(i)}
+		proofHeaders := headers
+		if appId == "" {
+			if proofHeaders, err = a.mainAnchoredHeaders(msg.AppId, number); err != nil {
+				return nil, nil, err
+			}
+			if proofHeaders[0].Hash() != blockHash {
+				return nil, nil, errUnknownBlock
+			}
+		} else if msg.AppId != "" {
+			if proof.MainHeaders, err = a.mainAnchoredHeaders(msg.AppId, checkpoint.MainNumber); err != nil {
+				return nil, nil, err
+			}
+		}
+		bridgeProof, err := makeBridgeProof(proofHeaders, block.Transactions(), receipts, index)
+		if err != nil {
+			return nil, nil, err
+		}
+		proof.Proof = *bridgeProof
+		proofs = append(proofs, proof)
+	}
+	return msgs, proofs, nil
+}
+
+// latestCheckpoint returns the latest checkpoint of the side chain appId as of
+// the head of the main chain running in the node.
+func (a *Alien) latestCheckpoint(appId string) (*SideCheckpoint, error) {
+	if a.eth == nil {
+		return nil, errUnknownBlock
+	}
+	main, ok := a.eth.SideBlockChain("")
+	if !ok || main == nil {
+		return nil, errUnknownBlock
+	}
+	return (&localMainChain{eth: a.eth, chain: main}).latestCheckpoint(appId)
+}
+
+func messageDoneKey(appId string, hash common.Hash) common.Hash {
+	return crypto.Keccak256Hash([]byte(messageDonePrefix+appId+":"), hash[:])
+}
+
+// writeAppMessageReceipt stores the delivery receipt of an app message.
+func writeAppMessageReceipt(db ethdb.Database, receipt *AppMessageReceipt) error {
+	blob, err := json.Marshal(receipt)
+	if err != nil {
+		return err
+	}
+	return db.Put(append([]byte(messageReceiptPrefix), receipt.Hash[:]...), blob)
+}
+
+// readAppMessageReceipt retrieves the delivery receipt of an app message, if it
+// was delivered by a transaction of the canonical chain of its app chain.
+func readAppMessageReceipt(db ethdb.Database, hash common.Hash) *AppMessageReceipt {
+	blob, err := db.Get(append([]byte(messageReceiptPrefix), hash[:]...))
+	if err != nil {
+		return nil
+	}
+	receipt := new(AppMessageReceipt)
+	if err := json.Unmarshal(blob, receipt); err != nil {
+		return nil
+	}
+	// The block which delivered the message may have been reorged out
+	if tx, _, number, _ := rawdb.ReadTransaction(db, receipt.TxHash, receipt.AppId); tx == nil || number != receipt.BlockNumber {
+		return nil
+	}
+	return receipt
+}
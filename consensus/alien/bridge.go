@@ -0,0 +1,457 @@
+// Copyright 2018 The giov Authors
+// This file is part of the giov library.
+//
+// The giov library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The giov library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the giov library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package alien implements the delegated-proof-of-stake consensus engine.
+
+package alien
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/CarLiveChainCo/goiov/common"
+	"github.com/CarLiveChainCo/goiov/common/hexutil"
+	"github.com/CarLiveChainCo/goiov/core"
+	"github.com/CarLiveChainCo/goiov/core/rawdb"
+	"github.com/CarLiveChainCo/goiov/core/state"
+	"github.com/CarLiveChainCo/goiov/core/types"
+	"github.com/CarLiveChainCo/goiov/core/vm"
+	"github.com/CarLiveChainCo/goiov/crypto"
+	"github.com/CarLiveChainCo/goiov/ethdb"
+	"github.com/CarLiveChainCo/goiov/params"
+	"github.com/CarLiveChainCo/goiov/rlp"
+	"github.com/CarLiveChainCo/goiov/trie"
+)
+
+const (
+	bridgeLockedPrefix = "alien-bridge-locked-" // bridgeLockedPrefix + appId -> funds locked on the main chain for the side chain
+	bridgeDonePrefix   = "alien-bridge-done-"   // bridgeDonePrefix + appId of the source chain + tx hash -> bridged transaction processed
+	mainAnchorPrefix   = "alien-anchor-"        // mainAnchorPrefix + main block number (uint64 big endian) -> hash of the main chain block anchored by a side chain
+	mainAnchorLatest   = "alien-anchor-latest"  // mainAnchorLatest -> number of the latest main chain block anchored by a side chain
+)
+
+// bridgeProof :
+// bridge proof come from custom tx which data like "ufo:1:sc:mint:<proof>" or "ufo:1:sc:release:<proof>",
+// proof is the hex RLP encoded bridgeProof of a bridge transaction on the other chain.
+//
+// A deposit "ufo:1:sc:deposit:<appId>[:<recipient>]" sent to params.BridgeAddress on the main chain
+// locks its value for the side chain appId, it is minted to the recipient on the side chain by a mint
+// transaction once the side chain anchored a main chain block from the deposit on. Headers are the main
+// chain headers from the block of the deposit to the block of a main chain anchor of the side chain.
+//
+// A burn "ufo:1:sc:burn[:<recipient>]" sent to params.BridgeAddress on a side chain burns its value,
+// it is released to the recipient on the main chain by a release transaction once the side chain
+// block is anchored by a checkpoint. Headers are the side chain headers from the block of the burn
+// to the block of a checkpoint. The recipient defaults to the sender of the deposit or burn
+type bridgeProof struct {
+	Headers      []*types.Header
+	TxIndex      uint64
+	TxProof      [][]byte
+	ReceiptProof [][]byte
+}
+
+// processEventDeposit locks the value of a deposit sent to the bridge address
+// of the main chain for the side chain of the deposit.
+func (a *Alien) processEventDeposit(state *state.StateDB, tx *types.Transaction, deposit *depositParams) error {
+	if a.config.AppId != "" {
+		return errBridgeWrongChain
+	}
+	if err := checkBridgeTx(tx); err != nil {
+		return err
+	}
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	keepSystemAccount(state, params.BridgeAddress)
+	locked := state.GetState(params.BridgeAddress, bridgeLockedKey(deposit.AppId)).Big()
+	state.SetState(params.BridgeAddress, bridgeLockedKey(deposit.AppId), common.BigToHash(locked.Add(locked, tx.Value())))
+	return nil
+}
+
+// processEventMint credits the recipient of a deposit of the main chain with
+// its value, once the side chain anchored the block of the deposit or a later
+// main chain block.
+func (a *Alien) processEventMint(state *state.StateDB, mint *mintParams) error {
+	if a.config.AppId == "" {
+		return errBridgeWrongChain
+	}
+	proof := (*bridgeProof)(mint)
+	if err := verifyLinkedHeaders(proof.Headers); err != nil {
+		return err
+	}
+	header := proof.Headers[0]
+	if header.Appid != "" {
+		return errBridgeWrongChain
+	}
+	config := rawdb.ReadChainConfig(a.db, common.Hash{})
+	if config == nil || config.Alien == nil || !config.Alien.IsBridge(header.Number) {
+		return errCustomTxNotActive
+	}
+	a.lock.RLock()
+	err := verifyMainAnchoredHeaders(state, proof.Headers)
+	a.lock.RUnlock()
+	if err != nil {
+		return err
+	}
+	tx, _, err := verifyBridgeProof(proof)
+	if err != nil {
+		return err
+	}
+	if err := checkBridgeTx(tx); err != nil {
+		return err
+	}
+	ctx, err := parseCustomTx(config.Alien, tx.Data(), header.Number)
+	if err != nil {
+		return errBridgeProof
+	}
+	deposit, ok := ctx.params.(*depositParams)
+	if !ok || tx.AppId() != "" || deposit.AppId != a.config.AppId {
+		return errBridgeWrongChain
+	}
+	recipient, err := bridgeRecipient(tx, deposit.Recipient)
+	if err != nil {
+		return err
+	}
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	done := bridgeDoneKey("", tx.Hash())
+	if state.GetState(params.BridgeAddress, done) != (common.Hash{}) {
+		return errBridgeRepeat
+	}
+	keepSystemAccount(state, params.BridgeAddress)
+	state.SetState(params.BridgeAddress, done, common.BytesToHash([]byte{1}))
+	state.AddBalance(recipient, tx.Value())
+	return nil
+}
+
+// processEventBurn burns the value of a burn sent to the bridge address of a
+// side chain.
+func (a *Alien) processEventBurn(state *state.StateDB, tx *types.Transaction) error {
+	if a.config.AppId == "" {
+		return errBridgeWrongChain
+	}
+	if err := checkBridgeTx(tx); err != nil {
+		return err
+	}
+	a.lock.Lock()
+	state.SubBalance(params.BridgeAddress, tx.Value())
+	a.lock.Unlock()
+	return nil
+}
+
+// processEventRelease pays the value of a burn of a side chain to its recipient
+// from the funds locked for the side chain, once the block of the burn is
+// anchored on the main chain by a checkpoint before the current block.
+func (a *Alien) processEventRelease(header *types.Header, state *state.StateDB, release *releaseParams) error {
+	if a.config.AppId != "" {
+		return errBridgeWrongChain
+	}
+	proof := (*bridgeProof)(release)
+	a.lock.RLock()
+	mainNumber, err := verifyAnchoredHeaders(state, proof.Headers)
+	a.lock.RUnlock()
+	if err != nil {
+		return err
+	}
+	if mainNumber >= header.Number.Uint64() {
+		return errBridgeNotFinal
+	}
+	sideHeader, appId := proof.Headers[0], proof.Headers[0].Appid
+	config := rawdb.ReadChainConfig(a.db, common.Hash{}, appId)
+	if config == nil || config.Alien == nil {
+		return errCheckpointUnknownChain
+	}
+	if !config.Alien.IsBridge(sideHeader.Number) {
+		return errCustomTxNotActive
+	}
+	tx, _, err := verifyBridgeProof(proof)
+	if err != nil {
+		return err
+	}
+	if err := checkBridgeTx(tx); err != nil {
+		return err
+	}
+	ctx, err := parseCustomTx(config.Alien, tx.Data(), sideHeader.Number)
+	if err != nil {
+		return errBridgeProof
+	}
+	burn, ok := ctx.params.(*burnParams)
+	if !ok || tx.AppId() != appId {
+		return errBridgeWrongChain
+	}
+	recipient, err := bridgeRecipient(tx, burn.Recipient)
+	if err != nil {
+		return err
+	}
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	done := bridgeDoneKey(appId, tx.Hash())
+	if state.GetState(params.BridgeAddress, done) != (common.Hash{}) {
+		return errBridgeRepeat
+	}
+	locked := state.GetState(params.BridgeAddress, bridgeLockedKey(appId)).Big()
+	if locked.Cmp(tx.Value()) < 0 {
+		return errBridgeLockedTooLow
+	}
+	keepSystemAccount(state, params.BridgeAddress)
+	state.SetState(params.BridgeAddress, done, common.BytesToHash([]byte{1}))
+	state.SetState(params.BridgeAddress, bridgeLockedKey(appId), common.BigToHash(locked.Sub(locked, tx.Value())))
+	state.SubBalance(params.BridgeAddress, tx.Value())
+	state.AddBalance(recipient, tx.Value())
+	return nil
+}
+
+// verifyAnchoredHeaders checks that the side chain headers of a proof are linked
+// from the first to the last one, and that the last one is anchored by a
+// checkpoint recorded in the state, whose main chain block number is returned.
+func verifyAnchoredHeaders(state *state.StateDB, headers []*types.Header) (uint64, error) {
+	if err := verifyLinkedHeaders(headers); err != nil {
+		return 0, err
+	}
+	last := headers[len(headers)-1]
+	if last.Appid == "" {
+		return 0, errBridgeWrongChain
+	}
+	if state.GetState(params.BridgeAddress, checkpointKey(last.Appid, last.Number.Uint64())) != last.Hash() {
+		return 0, errBridgeNotFinal
+	}
+	return state.GetState(params.BridgeAddress, checkpointMainKey(last.Appid, last.Number.Uint64())).Big().Uint64(), nil
+}
+
+// verifyMainAnchoredHeaders checks that the main chain headers of a proof are
+// linked from the first to the last one, and that the last one is anchored by
+// the side chain as of the state.
+func verifyMainAnchoredHeaders(state *state.StateDB, headers []*types.Header) error {
+	if err := verifyLinkedHeaders(headers); err != nil {
+		return err
+	}
+	last := headers[len(headers)-1]
+	if last.Appid != "" {
+		return errBridgeWrongChain
+	}
+	if state.GetState(params.BridgeAddress, mainAnchorKey(last.Number.Uint64())) != last.Hash() {
+		return errBridgeNotFinal
+	}
+	return nil
+}
+
+// verifyLinkedHeaders checks that the headers of a proof are headers of the same
+// chain, each one the parent of the next one.
+func verifyLinkedHeaders(headers []*types.Header) error {
+	if len(headers) == 0 {
+		return errBridgeProof
+	}
+	for i, header := range headers {
+		if header == nil || header.Number == nil || header.Appid != headers[0].Appid {
+			return errBridgeProof
+		}
+		if i > 0 && (header.ParentHash != headers[i-1].Hash() || header.Number.Uint64() != headers[i-1].Number.Uint64()+1) {
+			return errBridgeProof
+		}
+	}
+	return nil
+}
+
+// mainAnchoredHeaders returns the headers of the main chain from the block with
+// the given number to the latest block anchored by the side chain appId, which
+// must run in the node.
+func (a *Alien) mainAnchoredHeaders(appId string, number uint64) ([]*types.Header, error) {
+	if a.eth == nil {
+		return nil, errUnknownBlock
+	}
+	main, ok := a.eth.SideBlockChain("")
+	if !ok || main == nil {
+		return nil, errUnknownBlock
+	}
+	sideChain, ok := a.eth.SideBlockChain(appId)
+	if !ok || sideChain == nil || appId == "" {
+		return nil, fmt.Errorf("appId %s does not run in the node", appId)
+	}
+	statedb, err := sideChain.State()
+	if err != nil {
+		return nil, err
+	}
+	anchor := statedb.GetState(params.BridgeAddress, mainAnchorLatestKey()).Big().Uint64()
+	if anchor < number {
+		return nil, errBridgeNotFinal
+	}
+	var headers []*types.Header
+	for n := number; n <= anchor; n++ {
+		header := main.GetHeaderByNumber(n)
+		if header == nil {
+			return nil, errUnknownBlock
+		}
+		headers = append(headers, header)
+	}
+	if headers[len(headers)-1].Hash() != statedb.GetState(params.BridgeAddress, mainAnchorKey(anchor)) {
+		return nil, fmt.Errorf("appId %s does not match its main chain anchor", appId)
+	}
+	return headers, nil
+}
+
+// anchoredHeaders returns the headers of the side chain from the block with the
+// given number to the block of the checkpoint.
+func anchoredHeaders(sideChain *core.BlockChain, number uint64, checkpoint *SideCheckpoint) ([]*types.Header, error) {
+	if checkpoint == nil || checkpoint.Number < number {
+		return nil, fmt.Errorf("block %d not anchored yet", number)
+	}
+	var headers []*types.Header
+	for n := number; n <= checkpoint.Number; n++ {
+		header := sideChain.GetHeaderByNumber(n)
+		if header == nil {
+			return nil, errUnknownBlock
+		}
+		headers = append(headers, header)
+	}
+	if headers[len(headers)-1].Hash() != checkpoint.Hash {
+		return nil, fmt.Errorf("appId %s does not match its checkpoint", checkpoint.AppId)
+	}
+	return headers, nil
+}
+
+// checkBridgeTx checks that the deposit or burn transaction moves value to the
+// bridge address.
+func checkBridgeTx(tx *types.Transaction) error {
+	if tx.To() == nil || *tx.To() != params.BridgeAddress {
+		return errBridgeNotToBridge
+	}
+	if tx.Value().Sign() <= 0 {
+		return errBridgeNoValue
+	}
+	return nil
+}
+
+// bridgeRecipient returns the recipient of a deposit or burn, its sender if no
+// recipient was given.
+func bridgeRecipient(tx *types.Transaction, recipient common.Address) (common.Address, error) {
+	if recipient != (common.Address{}) {
+		return recipient, nil
+	}
+	sender, err := types.Sender(types.NewEIP155Signer(tx.ChainId()), tx)
+	if err != nil {
+		return common.Address{}, errBridgeProof
+	}
+	return sender, nil
+}
+
+// verifyBridgeProof returns the transaction proven to be included in the first
+// header of the proof and its receipt, if it is proven to be successful. The
+// Merkle proofs are verified by the bridge pre-compiled contract.
+func verifyBridgeProof(proof *bridgeProof) (*types.Transaction, *types.Receipt, error) {
+	header := proof.Headers[0]
+	key, _ := rlp.EncodeToBytes(uint(proof.TxIndex))
+	merkleProof := vm.PrecompiledContractsBridge[vm.MerkleProofAddress]
+
+	enc, err := merkleProof.Run(vm.EncodeMerkleProof(header.TxHash, key, proof.TxProof))
+	if err != nil || len(enc) == 0 {
+		return nil, nil, errBridgeProof
+	}
+	tx := new(types.Transaction)
+	if err := rlp.DecodeBytes(enc, tx); err != nil {
+		return nil, nil, errBridgeProof
+	}
+	enc, err = merkleProof.Run(vm.EncodeMerkleProof(header.ReceiptHash, key, proof.ReceiptProof))
+	if err != nil || len(enc) == 0 {
+		return nil, nil, errBridgeProof
+	}
+	receipt := new(types.Receipt)
+	if err := rlp.DecodeBytes(enc, receipt); err != nil || receipt.Status != types.ReceiptStatusSuccessful {
+		return nil, nil, errBridgeProof
+	}
+	return tx, receipt, nil
+}
+
+// makeBridgeProof returns the proof of the transaction with the given index in
+// the block of the first header, with the descendant headers of the block.
+func makeBridgeProof(headers []*types.Header, txs types.Transactions, receipts types.Receipts, index uint64) (*bridgeProof, error) {
+	txProof, err := proveDerivable(txs, index)
+	if err != nil {
+		return nil, err
+	}
+	receiptProof, err := proveDerivable(receipts, index)
+	if err != nil {
+		return nil, err
+	}
+	return &bridgeProof{
+		Headers:      headers,
+		TxIndex:      index,
+		TxProof:      txProof,
+		ReceiptProof: receiptProof,
+	}, nil
+}
+
+// proveDerivable returns the Merkle proof of the item with the given index in
+// the trie hashed by types.DeriveSha.
+func proveDerivable(list types.DerivableList, index uint64) ([][]byte, error) {
+	if index >= uint64(list.Len()) {
+		return nil, fmt.Errorf("index %d out of range", index)
+	}
+	tr := new(trie.Trie)
+	for i := 0; i < list.Len(); i++ {
+		key, _ := rlp.EncodeToBytes(uint(i))
+		tr.Update(key, list.GetRlp(i))
+	}
+	key, _ := rlp.EncodeToBytes(uint(index))
+	proofDb := ethdb.NewMemDatabase()
+	if err := tr.Prove(key, 0, proofDb); err != nil {
+		return nil, err
+	}
+	var proof [][]byte
+	for _, hash := range proofDb.Keys() {
+		node, _ := proofDb.Get(hash)
+		proof = append(proof, node)
+	}
+	return proof, nil
+}
+
+// encodeBridgeTx returns the data of the mint, release or deliver transaction
+// carrying the proof.
+func encodeBridgeTx(action string, proof interface{}) ([]byte, error) {
+	enc, err := rlp.EncodeToBytes(proof)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(fmt.Sprintf("%s:%s:%s:%s:%s", ufoPrefix, ufoVersion, ufoCategorySC, action, hexutil.Encode(enc))), nil
+}
+
+// keepSystemAccount keeps the system account holding engine records in its
+// storage alive, an account without nonce, balance and code is deleted when
+// empty accounts are cleared.
+func keepSystemAccount(state *state.StateDB, addr common.Address) {
+	if state.GetNonce(addr) == 0 {
+		state.SetNonce(addr, 1)
+	}
+}
+
+func bridgeLockedKey(appId string) common.Hash {
+	return crypto.Keccak256Hash([]byte(bridgeLockedPrefix + appId))
+}
+
+func mainAnchorKey(number uint64) common.Hash {
+	var enc [8]byte
+	binary.BigEndian.PutUint64(enc[:], number)
+	return crypto.Keccak256Hash([]byte(mainAnchorPrefix), enc[:])
+}
+
+func mainAnchorLatestKey() common.Hash {
+	return crypto.Keccak256Hash([]byte(mainAnchorLatest))
+}
+
+func bridgeDoneKey(appId string, hash common.Hash) common.Hash {
+	return crypto.Keccak256Hash([]byte(bridgeDonePrefix+appId+":"), hash[:])
+}
@@ -0,0 +1,243 @@
+// Copyright 2018 The giov Authors
+// This file is part of the giov library.
+//
+// The giov library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The giov library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the giov library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package alien implements the delegated-proof-of-stake consensus engine.
+
+package alien
+
+import (
+	"encoding/binary"
+	"math/big"
+	"time"
+
+	"github.com/CarLiveChainCo/goiov/common"
+	"github.com/CarLiveChainCo/goiov/consensus"
+	"github.com/CarLiveChainCo/goiov/core/rawdb"
+	"github.com/CarLiveChainCo/goiov/core/state"
+	"github.com/CarLiveChainCo/goiov/core/types"
+	"github.com/CarLiveChainCo/goiov/crypto"
+	"github.com/CarLiveChainCo/goiov/log"
+	"github.com/CarLiveChainCo/goiov/params"
+	"github.com/CarLiveChainCo/goiov/rlp"
+)
+
+const (
+	checkpointPrefix          = "alien-cp-"      // checkpointPrefix + appId + side block number (uint64 big endian) -> hash of the anchored side block
+	checkpointMainPrefix      = "alien-cp-main-" // checkpointMainPrefix + appId + side block number (uint64 big endian) -> number of the main block anchoring it
+	checkpointRefreshInterval = 10 * time.Second // Time a side chain keeps the anchored checkpoint before asking the main chain again
+)
+
+// copy creates a deep copy of the checkpoint.
+func (c *SideCheckpoint) copy() *SideCheckpoint {
+	cpy := *c
+	cpy.Signers = make([]common.Address, len(c.Signers))
+	copy(cpy.Signers, c.Signers)
+	return &cpy
+}
+
+// updateSnapshotByCheckpoints records the checkpoints anchored in the header as
+// the latest ones of their side chains.
+func (s *Snapshot) updateSnapshotByCheckpoints(checkpoints []SideCheckpoint) {
+	for i := range checkpoints {
+		s.Checkpoints[checkpoints[i].AppId] = checkpoints[i].copy()
+	}
+}
+
+// processEventCheckpoint anchors the side chain header carried by a sc confirm
+// transaction, if it is sealed by and sent from the signer set of the side chain
+// and above its latest checkpoint. The anchored hash is kept in the storage of
+// the bridge address, where proofs of the side chain blocks are checked against.
+func (a *Alien) processEventCheckpoint(chain consensus.ChainReader, header *types.Header, state *state.StateDB, headerExtra HeaderExtra, tx *types.Transaction, sender common.Address, confirm *scConfirmParams) ([]SideCheckpoint, error) {
+	checkpoints := headerExtra.CurrentBlockCheckpoints
+	sideHeader := confirm.Header
+	if sideHeader == nil {
+		return checkpoints, errCheckpointNoHeader
+	}
+	if sideHeader.Number == nil || sideHeader.Number.Uint64() != confirm.Number || sideHeader.Number.Sign() == 0 {
+		return checkpoints, errCheckpointMismatch
+	}
+	appId, config := a.sideChainByGenesis(confirm.GenesisHash)
+	if config == nil {
+		return checkpoints, errCheckpointUnknownChain
+	}
+	if sideHeader.Appid != appId {
+		return checkpoints, errCheckpointMismatch
+	}
+	snap, err := a.snapshot(chain, header.Number.Uint64()-1, header.ParentHash, nil, nil, DefaultLoopCntRecalculateSigners)
+	if err != nil {
+		log.Error(err.Error())
+		return checkpoints, errSnapshotUnavailable
+	}
+	latest := snap.Checkpoints[appId]
+	for i := range checkpoints {
+		if checkpoints[i].AppId == appId {
+			latest = &checkpoints[i]
+		}
+	}
+	signers := config.Alien.SelfVoteSigners
+	if latest != nil {
+		if confirm.Number <= latest.Number {
+			return checkpoints, errCheckpointStale
+		}
+		signers = latest.Signers
+	}
+	sealer, err := ecrecover(sideHeader, a.signatures)
+	if err != nil {
+		return checkpoints, errCheckpointMismatch
+	}
+	if !containsAddress(signers, sender) || !containsAddress(signers, sealer) {
+		return checkpoints, errCheckpointNotSigner
+	}
+	checkpoint := SideCheckpoint{
+		AppId:      appId,
+		Number:     confirm.Number,
+		Hash:       sideHeader.Hash(),
+		Root:       sideHeader.Root,
+		Signer:     sealer,
+		Signers:    checkpointSigners(sideHeader, signers),
+		MainNumber: header.Number.Uint64(),
+		TxHash:     tx.Hash(),
+	}
+	a.lock.Lock()
+	writeCheckpoint(state, &checkpoint)
+	a.lock.Unlock()
+
+	return append(checkpoints, checkpoint), nil
+}
+
+// sideChainByGenesis returns the appId and the configuration of the side chain
+// with the given genesis hash.
+func (a *Alien) sideChainByGenesis(hash common.Hash) (string, *params.ChainConfig) {
+	for appId, config := range rawdb.ReadAllChainConfig(a.db) {
+		if appId == "" || config == nil || config.Alien == nil {
+			continue
+		}
+		if rawdb.ReadCanonicalHash(a.db, 0, appId) == hash {
+			return appId, config
+		}
+	}
+	return "", nil
+}
+
+// checkpointSigners returns the signer set of a side chain from the checkpoint
+// of the header on, the distinct signers of its signer queue, or the signers
+// as of the previous checkpoint if the header has no queue.
+func checkpointSigners(header *types.Header, signers []common.Address) []common.Address {
+	if len(header.Extra) < extraVanity+extraSeal {
+		return signers
+	}
+	headerExtra := HeaderExtra{}
+	if err := rlp.DecodeBytes(header.Extra[extraVanity:len(header.Extra)-extraSeal], &headerExtra); err != nil || len(headerExtra.SignerQueue) == 0 {
+		return signers
+	}
+	var queue []common.Address
+	for _, signer := range headerExtra.SignerQueue {
+		if !containsAddress(queue, signer) {
+			queue = append(queue, signer)
+		}
+	}
+	return queue
+}
+
+func containsAddress(addresses []common.Address, address common.Address) bool {
+	for _, a := range addresses {
+		if a == address {
+			return true
+		}
+	}
+	return false
+}
+
+func checkpointKey(appId string, number uint64) common.Hash {
+	var enc [8]byte
+	binary.BigEndian.PutUint64(enc[:], number)
+	return crypto.Keccak256Hash([]byte(checkpointPrefix+appId+":"), enc[:])
+}
+
+func checkpointMainKey(appId string, number uint64) common.Hash {
+	var enc [8]byte
+	binary.BigEndian.PutUint64(enc[:], number)
+	return crypto.Keccak256Hash([]byte(checkpointMainPrefix+appId+":"), enc[:])
+}
+
+// writeCheckpoint records the checkpoint in the storage of the bridge address.
+func writeCheckpoint(state *state.StateDB, checkpoint *SideCheckpoint) {
+	keepSystemAccount(state, params.BridgeAddress)
+	state.SetState(params.BridgeAddress, checkpointKey(checkpoint.AppId, checkpoint.Number), checkpoint.Hash)
+	state.SetState(params.BridgeAddress, checkpointMainKey(checkpoint.AppId, checkpoint.Number), common.BigToHash(new(big.Int).SetUint64(checkpoint.MainNumber)))
+}
+
+// readCheckpoint retrieves the checkpoint of the given side chain block anchored
+// by the main chain as of the given state, rebuilt from the header of the main
+// chain block which anchored it.
+func readCheckpoint(chain consensus.ChainReader, state *state.StateDB, appId string, number uint64) *SideCheckpoint {
+	hash := state.GetState(params.BridgeAddress, checkpointKey(appId, number))
+	if hash == (common.Hash{}) {
+		return nil
+	}
+	mainNumber := state.GetState(params.BridgeAddress, checkpointMainKey(appId, number)).Big().Uint64()
+	header := chain.GetHeaderByNumber(mainNumber)
+	if header == nil || len(header.Extra) < extraVanity+extraSeal {
+		return nil
+	}
+	headerExtra := HeaderExtra{}
+	if err := rlp.DecodeBytes(header.Extra[extraVanity:len(header.Extra)-extraSeal], &headerExtra); err != nil {
+		return nil
+	}
+	for _, checkpoint := range headerExtra.CurrentBlockCheckpoints {
+		if checkpoint.AppId == appId && checkpoint.Number == number && checkpoint.Hash == hash {
+			return checkpoint.copy()
+		}
+	}
+	return nil
+}
+
+// anchoredNumber returns the number of the latest block of the app chain of the
+// engine anchored on the main chain, 0 on the main chain or if the local chain
+// does not contain the block. The checkpoint is cached for
+// checkpointRefreshInterval.
+func (a *Alien) anchoredNumber() uint64 {
+	if a.config.AppId == "" {
+		return 0
+	}
+	main, err := a.mainChain()
+	if err != nil {
+		return 0
+	}
+	a.checkpointLock.Lock()
+	defer a.checkpointLock.Unlock()
+
+	if time.Since(a.checkpointTime) >= checkpointRefreshInterval {
+		if checkpoint, err := main.latestCheckpoint(a.config.AppId); err != nil {
+			log.Debug("Failed to get anchored checkpoint", "appId", a.config.AppId, "err", err)
+		} else {
+			a.checkpoint = checkpoint
+		}
+		a.checkpointTime = time.Now()
+	}
+	if a.checkpoint == nil {
+		return 0
+	}
+	// Only hold the local chain once it contains the anchored block
+	if a.eth != nil {
+		if chain, ok := a.eth.SideBlockChain(a.config.AppId); ok {
+			if header := chain.GetHeaderByNumber(a.checkpoint.Number); header == nil || header.Hash() != a.checkpoint.Hash {
+				return 0
+			}
+		}
+	}
+	return a.checkpoint.Number
+}
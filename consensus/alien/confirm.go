@@ -0,0 +1,304 @@
+// Copyright 2018 The giov Authors
+// This file is part of the giov library.
+//
+// The giov library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The giov library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the giov library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package alien implements the delegated-proof-of-stake consensus engine.
+
+package alien
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+	"sort"
+
+	"github.com/CarLiveChainCo/goiov/accounts"
+	"github.com/CarLiveChainCo/goiov/common"
+	"github.com/CarLiveChainCo/goiov/consensus"
+	"github.com/CarLiveChainCo/goiov/core/types"
+	"github.com/CarLiveChainCo/goiov/crypto"
+	"github.com/CarLiveChainCo/goiov/crypto/sha3"
+	"github.com/CarLiveChainCo/goiov/event"
+	"github.com/CarLiveChainCo/goiov/log"
+	"github.com/CarLiveChainCo/goiov/rlp"
+)
+
+var (
+	// errConfirmKnown is returned if a confirmation is already in the pool.
+	errConfirmKnown = errors.New("known confirmation")
+
+	// errConfirmSignature is returned if the signature of a confirmation does not
+	// match its signer.
+	errConfirmSignature = errors.New("invalid confirmation signature")
+
+	// errConfirmByMessage is returned for a confirm custom transaction once the
+	// confirmations are exchanged as messages.
+	errConfirmByMessage = errors.New("confirmations are sent as messages")
+
+	// errInvalidConfirmation is returned if a header includes a confirmation of a
+	// block which is not its ancestor, or which is not signed by a signer of it.
+	errInvalidConfirmation = errors.New("invalid confirmation")
+
+	// errInvalidConfirmedNumber is returned if the confirmed block number of a
+	// header does not match its confirmations.
+	errInvalidConfirmedNumber = errors.New("invalid confirmed block number")
+)
+
+// SignedConfirmation :
+// confirmation of a block signed by a signer in the SignerQueue of the block.
+// From FinalityBlock on it replaces the "ufo:1:event:confirm" custom tx: the
+// signers exchange it with the eth ConfirmMsg and the next signers include it in
+// their headers.
+type SignedConfirmation struct {
+	Signer      common.Address
+	BlockNumber uint64
+	BlockHash   common.Hash
+	Signature   []byte
+}
+
+// Hash returns the hash identifying the confirmation.
+func (c *SignedConfirmation) Hash() common.Hash {
+	return crypto.Keccak256Hash(c.Signer[:], confirmSigHash(c.BlockNumber, c.BlockHash).Bytes())
+}
+
+// confirmSigHash returns the hash which is signed by the confirmation of a block.
+func confirmSigHash(number uint64, hash common.Hash) (sigHash common.Hash) {
+	hasher := sha3.NewKeccak256()
+	rlp.Encode(hasher, []interface{}{"alien-confirm", number, hash})
+	hasher.Sum(sigHash[:0])
+	return sigHash
+}
+
+// confirmations returns the confirmations of the header extra, the ones from
+// custom txs followed by the signed ones.
+func (h *HeaderExtra) confirmations() []Confirmation {
+	confirmations := make([]Confirmation, 0, len(h.CurrentBlockConfirmations)+len(h.CurrentBlockSignedConfirmations))
+	confirmations = append(confirmations, h.CurrentBlockConfirmations...)
+	for _, c := range h.CurrentBlockSignedConfirmations {
+		confirmations = append(confirmations, Confirmation{
+			Signer:      c.Signer,
+			BlockNumber: new(big.Int).SetUint64(c.BlockNumber),
+		})
+	}
+	return confirmations
+}
+
+// ConfirmedNumber implements consensus.Finality, returning the confirmed block
+// number of the header once the finality fork is active. On an app chain it is
+// at least the number of the latest checkpoint anchored on the main chain.
+func (a *Alien) ConfirmedNumber(header *types.Header) uint64 {
+	anchored := a.anchoredNumber()
+	if a.config.SideChain || !a.config.IsFinality(header.Number) || len(header.Extra) < extraVanity+extraSeal {
+		return anchored
+	}
+	headerExtra := HeaderExtra{}
+	if err := rlp.DecodeBytes(header.Extra[extraVanity:len(header.Extra)-extraSeal], &headerExtra); err != nil {
+		return anchored
+	}
+	if headerExtra.ConfirmedBlockNumber < anchored {
+		return anchored
+	}
+	return headerExtra.ConfirmedBlockNumber
+}
+
+// ConfirmBlock implements consensus.Confirmer, signing a confirmation of the
+// header with the local signer and adding it to the pool.
+func (a *Alien) ConfirmBlock(chain consensus.ChainReader, header *types.Header) (bool, error) {
+	if a.config.SideChain || !a.config.IsFinality(header.Number) {
+		return false, nil
+	}
+	a.lock.RLock()
+	signer, signFn := a.signer, a.signFn
+	a.lock.RUnlock()
+
+	if signFn == nil {
+		return true, errUnauthorized
+	}
+	sig, err := signFn(accounts.Account{Address: signer}, confirmSigHash(header.Number.Uint64(), header.Hash()).Bytes())
+	if err != nil {
+		return true, err
+	}
+	err = a.AddConfirmation(chain, &SignedConfirmation{
+		Signer:      signer,
+		BlockNumber: header.Number.Uint64(),
+		BlockHash:   header.Hash(),
+		Signature:   sig,
+	})
+	// only the signers of the block may confirm it
+	if err == errConfirmNotSigner {
+		return true, nil
+	}
+	return true, err
+}
+
+// AddConfirmation verifies a confirmation signed locally or received from a peer
+// and adds it to the pool of confirmations to include in the next headers. New
+// confirmations are sent to the subscribers for broadcasting.
+func (a *Alien) AddConfirmation(chain consensus.ChainReader, c *SignedConfirmation) error {
+	head := chain.CurrentHeader()
+	snap, err := a.snapshot(chain, head.Number.Uint64(), head.Hash(), nil, nil, DefaultLoopCntRecalculateSigners)
+	if err != nil {
+		return err
+	}
+	if c.BlockNumber > head.Number.Uint64() || head.Number.Uint64()-c.BlockNumber > snap.Params.MaxSignerCount {
+		return errConfirmOutOfRange
+	}
+	header := chain.GetHeader(c.BlockHash, c.BlockNumber)
+	if header == nil {
+		return errConfirmUnknownBlock
+	}
+	if err := verifyConfirmation(c, header); err != nil {
+		return err
+	}
+	a.confirmLock.Lock()
+	if a.confirmPool == nil {
+		a.confirmPool = make(map[common.Hash]map[common.Address]*SignedConfirmation)
+	}
+	for hash, confirmations := range a.confirmPool {
+		for _, pooled := range confirmations {
+			if pooled.BlockNumber+snap.Params.MaxSignerCount < head.Number.Uint64() {
+				delete(a.confirmPool, hash)
+			}
+			break
+		}
+	}
+	if _, ok := a.confirmPool[c.BlockHash][c.Signer]; ok {
+		a.confirmLock.Unlock()
+		return errConfirmKnown
+	}
+	if _, ok := a.confirmPool[c.BlockHash]; !ok {
+		a.confirmPool[c.BlockHash] = make(map[common.Address]*SignedConfirmation)
+	}
+	a.confirmPool[c.BlockHash][c.Signer] = c
+	a.confirmLock.Unlock()
+
+	a.confirmFeed.Send(c)
+	return nil
+}
+
+// SubscribeConfirmations registers a subscription for the confirmations added
+// to the pool.
+func (a *Alien) SubscribeConfirmations(ch chan<- *SignedConfirmation) event.Subscription {
+	return a.confirmFeed.Subscribe(ch)
+}
+
+// verifyConfirmation checks that the confirmation of the header is signed by a
+// signer in the signer queue of the header.
+func verifyConfirmation(c *SignedConfirmation, header *types.Header) error {
+	if header.Hash() != c.BlockHash || header.Number.Uint64() != c.BlockNumber {
+		return errConfirmUnknownBlock
+	}
+	pubkey, err := crypto.Ecrecover(confirmSigHash(c.BlockNumber, c.BlockHash).Bytes(), c.Signature)
+	if err != nil {
+		return errConfirmSignature
+	}
+	var signer common.Address
+	copy(signer[:], crypto.Keccak256(pubkey[1:])[12:])
+	if signer != c.Signer {
+		return errConfirmSignature
+	}
+	if len(header.Extra) < extraVanity+extraSeal {
+		return errConfirmUnknownBlock
+	}
+	headerExtra := HeaderExtra{}
+	if err := rlp.DecodeBytes(header.Extra[extraVanity:len(header.Extra)-extraSeal], &headerExtra); err != nil {
+		return errConfirmUnknownBlock
+	}
+	for _, s := range headerExtra.SignerQueue {
+		if s == c.Signer {
+			return nil
+		}
+	}
+	return errConfirmNotSigner
+}
+
+// ancestorHeader returns the ancestor of the header with the given number, which
+// must be at most maxDepth blocks below it. The parents are searched first.
+func (a *Alien) ancestorHeader(chain consensus.ChainReader, header *types.Header, parents []*types.Header, number uint64, maxDepth uint64) *types.Header {
+	if number >= header.Number.Uint64() || header.Number.Uint64()-number > maxDepth {
+		return nil
+	}
+	hash, current := header.ParentHash, header.Number.Uint64()-1
+	for {
+		var ancestor *types.Header
+		for i := len(parents) - 1; i >= 0; i-- {
+			if parents[i].Hash() == hash {
+				ancestor = parents[i]
+				break
+			}
+		}
+		if ancestor == nil {
+			ancestor = chain.GetHeader(hash, current)
+		}
+		if ancestor == nil || current == number {
+			return ancestor
+		}
+		hash, current = ancestor.ParentHash, current-1
+	}
+}
+
+// pendingConfirmations returns the pooled confirmations of the recent ancestors
+// of the header which the snapshot has not recorded yet.
+func (a *Alien) pendingConfirmations(chain consensus.ChainReader, header *types.Header, snap *Snapshot) []SignedConfirmation {
+	a.confirmLock.Lock()
+	defer a.confirmLock.Unlock()
+
+	var confirmations []SignedConfirmation
+	for number := header.Number.Uint64() - 1; number > 0 && header.Number.Uint64()-number <= snap.Params.MaxSignerCount; number-- {
+		ancestor := a.ancestorHeader(chain, header, nil, number, snap.Params.MaxSignerCount)
+		if ancestor == nil {
+			break
+		}
+		for signer, c := range a.confirmPool[ancestor.Hash()] {
+			known := false
+			for _, confirmer := range snap.Confirmations[number] {
+				if *confirmer == signer {
+					known = true
+					break
+				}
+			}
+			if !known {
+				confirmations = append(confirmations, *c)
+			}
+		}
+	}
+	sort.Slice(confirmations, func(i, j int) bool {
+		if confirmations[i].BlockNumber != confirmations[j].BlockNumber {
+			return confirmations[i].BlockNumber < confirmations[j].BlockNumber
+		}
+		return bytes.Compare(confirmations[i].Signer[:], confirmations[j].Signer[:]) < 0
+	})
+	return confirmations
+}
+
+// verifySignedConfirmations checks the signed confirmations of the header and
+// its confirmed block number.
+func (a *Alien) verifySignedConfirmations(chain consensus.ChainReader, header *types.Header, parents []*types.Header, snap *Snapshot, headerExtra HeaderExtra) error {
+	for i := range headerExtra.CurrentBlockSignedConfirmations {
+		c := &headerExtra.CurrentBlockSignedConfirmations[i]
+		ancestor := a.ancestorHeader(chain, header, parents, c.BlockNumber, snap.Params.MaxSignerCount)
+		if ancestor == nil {
+			return errInvalidConfirmation
+		}
+		if err := verifyConfirmation(c, ancestor); err != nil {
+			log.Debug("Invalid confirmation", "number", c.BlockNumber, "signer", c.Signer, "err", err)
+			return errInvalidConfirmation
+		}
+	}
+	if snap.getFinalizedBlockNumber(headerExtra.confirmations()) != headerExtra.ConfirmedBlockNumber {
+		return errInvalidConfirmedNumber
+	}
+	return nil
+}
@@ -20,17 +20,30 @@ package alien
 import (
 	"context"
 	"errors"
+	"fmt"
+	"math/big"
+	"sync"
 	"time"
 
 	"github.com/CarLiveChainCo/goiov/common"
 	"github.com/CarLiveChainCo/goiov/common/hexutil"
 	"github.com/CarLiveChainCo/goiov/consensus"
+	"github.com/CarLiveChainCo/goiov/core"
+	"github.com/CarLiveChainCo/goiov/core/state"
 	"github.com/CarLiveChainCo/goiov/core/types"
+	"github.com/CarLiveChainCo/goiov/log"
+	"github.com/CarLiveChainCo/goiov/params"
 	"github.com/CarLiveChainCo/goiov/rlp"
+	"github.com/CarLiveChainCo/goiov/rpc"
+	"github.com/hashicorp/golang-lru"
 )
 
 const (
-	mainchainRPCTimeout = 300 // Number of millisecond mainchain rpc connect timeout
+	mainchainRPCTimeout     = 300                    // Number of millisecond mainchain rpc connect timeout
+	mainchainRPCRetries     = 3                      // Number of attempts of a mainchain rpc call failing to reach the main chain
+	mainchainRPCRetryDelay  = 100 * time.Millisecond // Time to wait before attempting a failed mainchain rpc call again
+	mainchainFollowInterval = time.Second            // Time between two polls of the head of the main chain by the header follower
+	mainchainHeaderCache    = 256                    // Number of recent main chain headers to keep in memory
 )
 
 var (
@@ -39,59 +52,473 @@ var (
 
 	// errMCRPCCLientEmpty is returned if Side chain not have main chain rpc client
 	errMCRPCClientEmpty = errors.New("main chain rpc client empty")
+
+	// errMainChainSnapshot is returned if the main chain snapshot returned by the
+	// rpc client does not match the main chain header of its block.
+	errMainChainSnapshot = errors.New("main chain snapshot does not match its header")
 )
 
-func (a *Alien) getMainChainSnapshotByTime(chain consensus.ChainReader, headerTime uint64) (*Snapshot, error) {
-	if !chain.Config().Alien.SideChain {
+// mainChainReader is the access of a side chain or app chain engine to its main
+// chain, in process if the chain runs inside the node of the main chain, over
+// rpc otherwise.
+type mainChainReader interface {
+	// snapshotByTime returns the main chain snapshot of the block sealed at the
+	// given time.
+	snapshotByTime(headerTime uint64) (*Snapshot, error)
+
+	// confirmedHash returns the hash of the canonical main chain block with the
+	// given number, if the main chain confirmed it.
+	confirmedHash(number uint64) (common.Hash, error)
+
+	// latestCheckpoint returns the latest checkpoint of the app chain anchored on
+	// the main chain.
+	latestCheckpoint(appId string) (*SideCheckpoint, error)
+
+	// confirmedHeader returns the header of the latest main chain block
+	// confirmed by the main chain.
+	confirmedHeader() (*types.Header, error)
+
+	// transactionCount returns the next nonce of the account on the main chain.
+	transactionCount(account common.Address) (uint64, error)
+
+	// sendTransaction sends the signed transaction to the main chain.
+	sendTransaction(tx *types.Transaction) (common.Hash, error)
+
+	// chainId returns the chain id main chain transactions are signed for.
+	chainId() (*big.Int, error)
+
+	// gasPrice returns the gas price the main chain accepts transactions at.
+	gasPrice() (*big.Int, error)
+}
+
+// mainChain returns the access of the engine to its main chain.
+func (a *Alien) mainChain() (mainChainReader, error) {
+	if a.config.AppId != "" && a.eth != nil {
+		if main, ok := a.eth.SideBlockChain(""); ok && main != nil {
+			return &localMainChain{eth: a.eth, chain: main}, nil
+		}
+	}
+	if !a.config.SideChain && a.config.AppId == "" {
 		return nil, errNotSideChain
 	}
-	if chain.Config().Alien.MCRPCClient == nil {
+	if a.config.MCRPCClient == nil {
 		return nil, errMCRPCClientEmpty
 	}
-	ctx, cancel := context.WithTimeout(context.Background(), mainchainRPCTimeout*time.Millisecond)
-	defer cancel()
+	a.mainChainLock.Lock()
+	defer a.mainChainLock.Unlock()
+
+	// The rpc client of a side chain is set once the node started
+	if a.remoteMainChain == nil || a.remoteMainChain.client != a.config.MCRPCClient {
+		if a.remoteMainChain != nil {
+			a.remoteMainChain.stop()
+		}
+		a.remoteMainChain = newRPCMainChain(a.config.MCRPCClient)
+	}
+	return a.remoteMainChain, nil
+}
+
+// recordMainAnchors records the main chain anchors of the parent of a side chain
+// block in the storage of the bridge address, along with the latest anchored
+// main chain block.
+func (a *Alien) recordMainAnchors(state *state.StateDB, anchors []MainAnchor) {
+	if len(anchors) == 0 {
+		return
+	}
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	keepSystemAccount(state, params.BridgeAddress)
+	latest := state.GetState(params.BridgeAddress, mainAnchorLatestKey()).Big().Uint64()
+	for _, anchor := range anchors {
+		state.SetState(params.BridgeAddress, mainAnchorKey(anchor.Number), anchor.Hash)
+		if anchor.Number > latest {
+			latest = anchor.Number
+		}
+	}
+	state.SetState(params.BridgeAddress, mainAnchorLatestKey(), common.BigToHash(new(big.Int).SetUint64(latest)))
+}
+
+// newMainAnchors returns the main chain anchor of a side chain block being
+// sealed, the latest block confirmed by the main chain if it is above the latest
+// anchored one. No block is anchored if the main chain is not available.
+func (a *Alien) newMainAnchors(state *state.StateDB) []MainAnchor {
+	main, err := a.mainChain()
+	if err != nil {
+		return nil
+	}
+	confirmed, err := main.confirmedHeader()
+	if err != nil {
+		log.Debug("Failed to get confirmed main chain block", "appId", a.config.AppId, "err", err)
+		return nil
+	}
+	a.lock.RLock()
+	latest := state.GetState(params.BridgeAddress, mainAnchorLatestKey()).Big().Uint64()
+	a.lock.RUnlock()
+	if confirmed.Number.Uint64() <= latest {
+		return nil
+	}
+	return []MainAnchor{{Number: confirmed.Number.Uint64(), Hash: confirmed.Hash()}}
+}
+
+// verifyMainAnchors checks that the main chain anchor of a side chain header is
+// a block confirmed by the main chain.
+func (a *Alien) verifyMainAnchors(header *types.Header) error {
+	anchors, ok := sealedMainAnchors(header)
+	if !ok {
+		return errInvalidMainAnchor
+	}
+	if len(anchors) == 0 {
+		return nil
+	}
+	if len(anchors) > 1 || !(a.config.IsBridge(header.Number) || a.config.IsCrossApp(header.Number)) {
+		return errInvalidMainAnchor
+	}
+	main, err := a.mainChain()
+	if err != nil {
+		return err
+	}
+	hash, err := main.confirmedHash(anchors[0].Number)
+	if err != nil {
+		return err
+	}
+	if hash != anchors[0].Hash {
+		return errInvalidMainAnchor
+	}
+	return nil
+}
 
-	var ms *Snapshot
-	if err := chain.Config().Alien.MCRPCClient.CallContext(ctx, &ms, "alien_getSnapshotByHeaderTime", headerTime); err != nil {
+// sealedMainAnchors returns the main chain anchors of the header if it carries
+// the extra of a sealed block.
+func sealedMainAnchors(header *types.Header) ([]MainAnchor, bool) {
+	if len(header.Extra) < extraVanity+extraSeal {
+		return nil, false
+	}
+	headerExtra := HeaderExtra{}
+	if err := rlp.DecodeBytes(header.Extra[extraVanity:len(header.Extra)-extraSeal], &headerExtra); err != nil {
+		return nil, false
+	}
+	return headerExtra.CurrentBlockMainAnchors, true
+}
+
+// snapshotByHeaderTime returns the snapshot of the block of the chain sealed at
+// the given time.
+func (a *Alien) snapshotByHeaderTime(chain consensus.ChainReader, targetTime uint64) (*Snapshot, error) {
+	period := chain.Config().Alien.Period
+	header := chain.CurrentHeader()
+	if header == nil || targetTime > header.Time.Uint64()+period {
+		return nil, errUnknownBlock
+	}
+	minN := uint64(0)
+	maxN := header.Number.Uint64()
+	for {
+		if targetTime >= header.Time.Uint64() && targetTime < header.Time.Uint64()+period {
+			return a.snapshot(chain, header.Number.Uint64(), header.Hash(), nil, nil, DefaultLoopCntRecalculateSigners)
+		} else {
+			if maxN == minN || maxN == minN+1 {
+				break
+			}
+			// calculate next number
+			nextN := uint64(int64(header.Number.Uint64()) + (int64(targetTime)-int64(header.Time.Uint64()))/int64(period))
+			if nextN >= maxN || nextN <= minN {
+				nextN = (maxN + minN) / 2
+			}
+			// get new header
+			header = chain.GetHeaderByNumber(nextN)
+			if header == nil {
+				break
+			}
+			// update maxN & minN
+			if header.Time.Uint64() >= targetTime {
+				if header.Number.Uint64() < maxN {
+					maxN = header.Number.Uint64()
+				}
+			} else if header.Time.Uint64() <= targetTime {
+				if header.Number.Uint64() > minN {
+					minN = header.Number.Uint64()
+				}
+			}
+		}
+	}
+	return nil, errUnknownBlock
+}
+
+// localMainChain reads the main chain running in the same node.
+type localMainChain struct {
+	eth   core.Backend
+	chain *core.BlockChain
+}
+
+func (m *localMainChain) engine() (*Alien, error) {
+	engine, ok := m.chain.Engine().(*Alien)
+	if !ok {
+		return nil, errNotSideChain
+	}
+	return engine, nil
+}
+
+func (m *localMainChain) snapshotByTime(headerTime uint64) (*Snapshot, error) {
+	engine, err := m.engine()
+	if err != nil {
 		return nil, err
 	}
-	return ms, nil
+	return engine.snapshotByHeaderTime(m.chain, headerTime)
 }
 
-func (a *Alien) sendTransactionToMainChain(chain consensus.ChainReader, tx *types.Transaction) (common.Hash, error) {
-	if !chain.Config().Alien.SideChain {
-		return common.Hash{}, errNotSideChain
+func (m *localMainChain) confirmedHash(number uint64) (common.Hash, error) {
+	if m.chain.FinalizedNumber() < number {
+		return common.Hash{}, errBridgeNotFinal
 	}
-	if chain.Config().Alien.MCRPCClient == nil {
-		return common.Hash{}, errMCRPCClientEmpty
+	header := m.chain.GetHeaderByNumber(number)
+	if header == nil {
+		return common.Hash{}, errUnknownBlock
 	}
-	ctx, cancel := context.WithTimeout(context.Background(), mainchainRPCTimeout*time.Millisecond)
-	defer cancel()
+	return header.Hash(), nil
+}
 
-	data, err := rlp.EncodeToBytes(tx)
+func (m *localMainChain) confirmedHeader() (*types.Header, error) {
+	header := m.chain.GetHeaderByNumber(m.chain.FinalizedNumber())
+	if header == nil {
+		return nil, errUnknownBlock
+	}
+	return header, nil
+}
+
+func (m *localMainChain) latestCheckpoint(appId string) (*SideCheckpoint, error) {
+	engine, err := m.engine()
 	if err != nil {
+		return nil, err
+	}
+	header := m.chain.CurrentHeader()
+	if header == nil {
+		return nil, errUnknownBlock
+	}
+	snap, err := engine.snapshot(m.chain, header.Number.Uint64(), header.Hash(), nil, nil, DefaultLoopCntRecalculateSigners)
+	if err != nil {
+		return nil, err
+	}
+	if checkpoint, ok := snap.Checkpoints[appId]; ok {
+		return checkpoint.copy(), nil
+	}
+	return nil, fmt.Errorf("no checkpoint of appId %s", appId)
+}
+
+func (m *localMainChain) transactionCount(account common.Address) (uint64, error) {
+	return m.eth.TxPool().State().GetNonce(account), nil
+}
+
+func (m *localMainChain) sendTransaction(tx *types.Transaction) (common.Hash, error) {
+	if err := m.eth.TxPool().AddLocal(tx); err != nil {
 		return common.Hash{}, err
 	}
-	var hash common.Hash
-	if err := chain.Config().Alien.MCRPCClient.CallContext(ctx, &hash, "eth_sendRawTransaction", common.ToHex(data)); err != nil {
+	return tx.Hash(), nil
+}
+
+func (m *localMainChain) chainId() (*big.Int, error) {
+	return m.chain.Config().ChainId, nil
+}
+
+func (m *localMainChain) gasPrice() (*big.Int, error) {
+	return m.eth.TxPool().GasPrice(), nil
+}
+
+// rpcMainChain reads a remote main chain over rpc. Calls failing to reach the
+// main chain are attempted again, the main chain headers are followed and
+// cached to verify the snapshots returned, and the latest verified snapshot is
+// used for the headers sealed in its signer loop.
+type rpcMainChain struct {
+	client  *rpc.Client
+	headers *lru.ARCCache // Recent main chain headers by number
+
+	snap    *Snapshot // Latest verified main chain snapshot
+	chainID *big.Int  // Chain id of the main chain, once known
+	lock    sync.Mutex
+
+	quit chan struct{}
+}
+
+func newRPCMainChain(client *rpc.Client) *rpcMainChain {
+	headers, _ := lru.NewARC(mainchainHeaderCache)
+	m := &rpcMainChain{
+		client:  client,
+		headers: headers,
+		quit:    make(chan struct{}),
+	}
+	go m.follow()
+	return m
+}
+
+// call calls the rpc method of the main chain, attempting it again if the main
+// chain could not be reached. Errors returned by the main chain are final.
+func (m *rpcMainChain) call(result interface{}, method string, args ...interface{}) (err error) {
+	for i := 0; i < mainchainRPCRetries; i++ {
+		if i > 0 {
+			time.Sleep(mainchainRPCRetryDelay)
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), mainchainRPCTimeout*time.Millisecond)
+		err = m.client.CallContext(ctx, result, method, args...)
+		cancel()
+		if _, ok := err.(rpc.Error); err == nil || ok {
+			return err
+		}
+	}
+	return err
+}
+
+// follow caches the head of the main chain until the client is replaced.
+func (m *rpcMainChain) follow() {
+	ticker := time.NewTicker(mainchainFollowInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if _, err := m.header("latest"); err != nil {
+				log.Debug("Failed to follow main chain head", "err", err)
+			}
+		case <-m.quit:
+			return
+		}
+	}
+}
+
+func (m *rpcMainChain) stop() {
+	close(m.quit)
+}
+
+// header retrieves the main chain header with the given number or tag and
+// caches it.
+func (m *rpcMainChain) header(number interface{}) (*types.Header, error) {
+	var header *types.Header
+	if err := m.call(&header, "eth_getBlockByNumber", number, false); err != nil {
+		return nil, err
+	}
+	if header == nil {
+		return nil, errUnknownBlock
+	}
+	m.headers.Add(header.Number.Uint64(), header)
+	return header, nil
+}
+
+// headerByNumber returns the main chain header with the given number from the
+// cache, or from the main chain if it is not cached or refresh is set.
+func (m *rpcMainChain) headerByNumber(number uint64, refresh bool) (*types.Header, error) {
+	if cached, ok := m.headers.Get(number); ok && !refresh {
+		return cached.(*types.Header), nil
+	}
+	return m.header(hexutil.Uint64(number))
+}
+
+// covers returns whether the signer loop of the snapshot contains the time.
+func covers(snap *Snapshot, headerTime uint64) bool {
+	return snap != nil && len(snap.Signers) > 0 && headerTime >= snap.LoopStartTime &&
+		headerTime < snap.LoopStartTime+snap.Period*uint64(len(snap.Signers))
+}
+
+func (m *rpcMainChain) snapshotByTime(headerTime uint64) (*Snapshot, error) {
+	m.lock.Lock()
+	snap := m.snap
+	m.lock.Unlock()
+
+	if covers(snap, headerTime) {
+		return snap, nil
+	}
+	if err := m.call(&snap, "alien_getSnapshotByHeaderTime", headerTime); err != nil {
+		return nil, err
+	}
+	if snap == nil {
+		return nil, errUnknownBlock
+	}
+	// The snapshot must be the one of a main chain block, refresh a reorged header
+	header, err := m.headerByNumber(snap.Number, false)
+	if err == nil && header.Hash() != snap.Hash {
+		header, err = m.headerByNumber(snap.Number, true)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if header.Hash() != snap.Hash || header.Time.Uint64() > headerTime {
+		return nil, errMainChainSnapshot
+	}
+	m.lock.Lock()
+	if m.snap == nil || snap.Number > m.snap.Number {
+		m.snap = snap
+	}
+	m.lock.Unlock()
+	return snap, nil
+}
+
+func (m *rpcMainChain) confirmedHash(number uint64) (common.Hash, error) {
+	finalized, err := m.header("finalized")
+	if err != nil {
 		return common.Hash{}, err
 	}
-	return hash, nil
+	if finalized.Number.Uint64() < number {
+		return common.Hash{}, errBridgeNotFinal
+	}
+	if finalized.Number.Uint64() == number {
+		return finalized.Hash(), nil
+	}
+	// The cached header may have been reorged out before it was confirmed
+	header, err := m.headerByNumber(number, true)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return header.Hash(), nil
 }
 
-func (a *Alien) getTransactionCountFromMainChain(chain consensus.ChainReader, account common.Address) (uint64, error) {
-	if !chain.Config().Alien.SideChain {
-		return 0, errNotSideChain
+func (m *rpcMainChain) confirmedHeader() (*types.Header, error) {
+	return m.header("finalized")
+}
+
+func (m *rpcMainChain) latestCheckpoint(appId string) (*SideCheckpoint, error) {
+	var checkpoint *SideCheckpoint
+	if err := m.call(&checkpoint, "alien_getSideChainCheckpoint", appId, "latest"); err != nil {
+		return nil, err
 	}
-	if chain.Config().Alien.MCRPCClient == nil {
-		return 0, errMCRPCClientEmpty
+	if checkpoint == nil {
+		return nil, fmt.Errorf("no checkpoint of appId %s", appId)
 	}
-	ctx, cancel := context.WithTimeout(context.Background(), mainchainRPCTimeout*time.Millisecond)
-	defer cancel()
+	return checkpoint, nil
+}
 
+func (m *rpcMainChain) transactionCount(account common.Address) (uint64, error) {
 	var result hexutil.Uint64
-	if err := chain.Config().Alien.MCRPCClient.CallContext(ctx, &result, "eth_getTransactionCount", account.Hex(), "latest"); err != nil {
+	if err := m.call(&result, "eth_getTransactionCount", account, "pending"); err != nil {
 		return 0, err
 	}
 	return uint64(result), nil
 }
+
+func (m *rpcMainChain) sendTransaction(tx *types.Transaction) (common.Hash, error) {
+	data, err := rlp.EncodeToBytes(tx)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	var hash common.Hash
+	if err := m.call(&hash, "eth_sendRawTransaction", hexutil.Bytes(data)); err != nil {
+		return common.Hash{}, err
+	}
+	return hash, nil
+}
+
+func (m *rpcMainChain) chainId() (*big.Int, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if m.chainID == nil {
+		var result hexutil.Big
+		if err := m.call(&result, "eth_chainId"); err != nil {
+			return nil, err
+		}
+		m.chainID = (*big.Int)(&result)
+	}
+	return m.chainID, nil
+}
+
+func (m *rpcMainChain) gasPrice() (*big.Int, error) {
+	var result hexutil.Big
+	if err := m.call(&result, "eth_gasPrice"); err != nil {
+		return nil, err
+	}
+	return (*big.Int)(&result), nil
+}
@@ -19,31 +19,56 @@
 package alien
 
 import (
-	"github.com/CarLiveChainCo/goiov/core"
+	"encoding/json"
+	"errors"
+	"io"
 	"math/big"
+	"reflect"
 	"strconv"
 	"strings"
 
 	"github.com/CarLiveChainCo/goiov/common"
+	"github.com/CarLiveChainCo/goiov/common/hexutil"
 	"github.com/CarLiveChainCo/goiov/consensus"
+	"github.com/CarLiveChainCo/goiov/core"
+	"github.com/CarLiveChainCo/goiov/core/rawdb"
 	"github.com/CarLiveChainCo/goiov/core/state"
 	"github.com/CarLiveChainCo/goiov/core/types"
+	"github.com/CarLiveChainCo/goiov/ethdb"
 	"github.com/CarLiveChainCo/goiov/log"
+	"github.com/CarLiveChainCo/goiov/params"
 	"github.com/CarLiveChainCo/goiov/rlp"
 )
 
 const (
 	/*
 	 *  ufo:version:category:action/data
+	 *
+	 *  version 1 : ufo:1:category:action:param1:param2...
+	 *  version 2 : ufo:2:<rlp(customTxV2{category, action, rlp(params)})>
 	 */
 	ufoPrefix             = "ufo"
 	ufoVersion            = "1"
+	ufoVersionV2          = "2"
 	ufoCategoryEvent      = "event"
 	ufoCategoryLog        = "oplog"
 	ufoCategorySC         = "sc"
 	ufoEventVote          = "vote"
 	ufoEventConfirm       = "confirm"
 	ufoEventCancel        = "cancel"
+	ufoEventAddStake      = "addstake"
+	ufoEventUnstake       = "unstake"
+	ufoEventRedelegate    = "redelegate"
+	ufoEventSlash         = "slash"
+	ufoEventProposal      = "proposal"
+	ufoEventDeclare       = "declare"
+	ufoEventDeposit       = "deposit"
+	ufoEventMint          = "mint"
+	ufoEventBurn          = "burn"
+	ufoEventRelease       = "release"
+	ufoEventDeliver       = "deliver"
+	ufoDeclareYes         = "yes"
+	ufoDeclareNo          = "no"
 	ufoMinSplitLen        = 3
 	posPrefix             = 0
 	posVersion            = 1
@@ -53,6 +78,77 @@ const (
 	posEventCancel        = 3
 	posEventVoteValue     = 4
 	posEventConfirmNumber = 4
+	posEventStakeValue    = 4
+	posEventProposalParam = 4
+	posEventProposalValue = 5
+	posEventDeclareHash   = 4
+	posEventDeclareResult = 5
+	posSCConfirmHash      = 4
+	posSCConfirmNumber    = 5
+	posSCConfirmHeader    = 6
+	posSCDepositAppId     = 4
+	posSCDepositRecipient = 5
+	posSCBurnRecipient    = 4
+	posSCBridgeProof      = 4
+	posSCMessageProof     = 4
+
+	customTxStatusPrefix = "alien-ctx-" // customTxStatusPrefix + tx hash + block hash -> custom tx status
+
+	slashReasonDoubleSign = uint8(1) // the signer sealed two blocks at the same height
+	slashReasonMissing    = uint8(2) // the signer kept missing its turn to seal
+)
+
+// Reasons for a custom transaction being rejected by the engine. They are
+// recorded in the custom tx status so wallets can tell why a vote was dropped.
+var (
+	errCustomTxMalformed      = errors.New("malformed custom transaction")
+	errCustomTxUnknown        = errors.New("unknown custom transaction")
+	errCustomTxNotActive      = errors.New("custom transaction version not active")
+	errCustomTxNoRecipient    = errors.New("custom transaction without recipient")
+	errVoteRepeat             = errors.New("repeat vote")
+	errVoteValueTooLow        = errors.New("vote value less than MinVoteValue")
+	errSelfVoteValueTooLow    = errors.New("vote value less than SelfVoteValue")
+	errVoteTargetNotCandidate = errors.New("vote target is not a candidate")
+	errVoteBalanceNotEnough   = errors.New("not enough balance for vote")
+	errCancelRepeat           = errors.New("repeat cancel")
+	errConfirmOutOfRange      = errors.New("confirmed block number out of range")
+	errConfirmUnknownBlock    = errors.New("confirmed block unknown")
+	errConfirmNotSigner       = errors.New("confirmer not in signer queue of confirmed block")
+	errSnapshotUnavailable    = errors.New("snapshot unavailable")
+	errStakeNoVote            = errors.New("sender has no vote")
+	errStakeVoteCanceled      = errors.New("vote of sender is canceled")
+	errStakeChangeRepeat      = errors.New("repeat stake change")
+	errStakeAmountInvalid     = errors.New("invalid stake amount")
+	errUnstakeTooMuch         = errors.New("remaining stake less than minimum vote value")
+	errRedelegateSelfVote     = errors.New("candidate can not redelegate its self vote")
+	errRedelegateSameTarget   = errors.New("redelegate target is the current candidate")
+	errRedelegateTooEarly     = errors.New("redelegate cooldown not passed")
+	errSlashEvidenceInvalid   = errors.New("invalid double sign evidence")
+	errSlashRepeat            = errors.New("repeat slash")
+	errSlashNoStake           = errors.New("no stake to slash")
+	errProposalParameter      = errors.New("unknown proposal parameter")
+	errProposalValue          = errors.New("invalid proposal value")
+	errProposalRepeat         = errors.New("open proposal for the parameter exists")
+	errProposerNotCandidate   = errors.New("proposer is not a candidate")
+	errDeclareUnknown         = errors.New("unknown proposal")
+	errDeclareClosed          = errors.New("proposal closed for declares")
+	errDeclareRepeat          = errors.New("repeat declare")
+	errDeclarerNotCandidate   = errors.New("declarer is not a candidate")
+	errCheckpointNoHeader     = errors.New("side chain header missing")
+	errCheckpointUnknownChain = errors.New("unknown side chain")
+	errCheckpointMismatch     = errors.New("side chain header does not match the checkpoint")
+	errCheckpointNotSigner    = errors.New("sender or sealer not in side chain signer set")
+	errCheckpointStale        = errors.New("checkpoint not above the latest one")
+	errBridgeWrongChain       = errors.New("bridge transaction for another chain")
+	errBridgeNotToBridge      = errors.New("bridge transaction not sent to the bridge address")
+	errBridgeNoValue          = errors.New("bridge transaction without value")
+	errBridgeProof            = errors.New("invalid bridge proof")
+	errBridgeNotFinal         = errors.New("bridged transaction not confirmed on its chain")
+	errBridgeRepeat           = errors.New("bridged transaction already processed")
+	errBridgeLockedTooLow     = errors.New("not enough funds locked for the side chain")
+	errMessageWrongChain      = errors.New("app message for another chain")
+	errMessageProof           = errors.New("invalid app message proof")
+	errMessageRepeat          = errors.New("app message already delivered")
 )
 
 // Vote :
@@ -83,201 +179,989 @@ type Confirmation struct {
 	BlockNumber *big.Int
 }
 
+// StakeChange :
+// stake change come from custom tx which data like "ufo:1:event:addstake:amount"
+// or "ufo:1:event:unstake:amount"
+// Sender of tx is Voter, the stake of its existing vote is raised or lowered by Amount
+type StakeChange struct {
+	Voter  common.Address
+	Amount *big.Int
+	Hash   common.Hash
+}
+
+// Redelegation :
+// redelegation come from custom tx which data like "ufo:1:event:redelegate"
+// Sender of tx is Voter, the tx.to is the new Candidate of its existing vote
+type Redelegation struct {
+	Voter     common.Address
+	Candidate common.Address
+	Hash      common.Hash
+}
+
+// Slash :
+// slash for double signing come from custom tx which data like "ufo:2:<rlp(event, slash, headers)>",
+// the two headers are the evidence and Number is their height.
+// slash for missing is added by the signer of the block once a missing signer's punished credit
+// reaches MissingSlashThreshold.
+// Reporter is the sender of the evidence or the signer of the block, it receives the slashed
+// stake if SlashRedistribute is set, otherwise the stake is burned
+type Slash struct {
+	Signer   common.Address
+	Reason   uint8
+	Reporter common.Address
+	Number   uint64
+	Hash     common.Hash
+}
+
+// Proposal :
+// proposal come from custom tx which data like "ufo:1:event:proposal:freeze:604800"
+// Sender of tx is Proposer, it must be a candidate. Parameter is one of the
+// governed parameters and Value is its proposed value
+type Proposal struct {
+	Hash      common.Hash
+	Proposer  common.Address
+	Parameter string
+	Value     *big.Int
+}
+
+// Declare :
+// declare come from custom tx which data like "ufo:1:event:declare:0x1234...:yes"
+// Sender of tx is Declarer, it must be a candidate. ProposalHash is the hash of
+// the proposal tx and Decision is true for "yes"
+type Declare struct {
+	ProposalHash common.Hash
+	Declarer     common.Address
+	Decision     bool
+	Hash         common.Hash
+}
 
+// SideCheckpoint :
+// side chain checkpoint come from custom tx which data like "ufo:1:sc:confirm:<genesisHash>:<number>:<header>"
+// sent to the main chain by the signers of the side chain, header is the hex RLP encoded side chain header.
+// Sender of tx and Signer, the sealer of the header, must be in the signer set of the side chain as of
+// its previous checkpoint. Signers is the signer set from this checkpoint on, taken from the signer queue of the header
+type SideCheckpoint struct {
+	AppId      string           `json:"appId"`
+	Number     uint64           `json:"number"`
+	Hash       common.Hash      `json:"hash"`
+	Root       common.Hash      `json:"root"`
+	Signer     common.Address   `json:"signer"`
+	Signers    []common.Address `json:"signers"`
+	MainNumber uint64           `json:"mainNumber"`
+	TxHash     common.Hash      `json:"txHash"`
+}
+
+// MainAnchor :
+// main chain anchor recorded in the header of a side chain block by its sealer, a block confirmed by the
+// main chain above the latest anchored one. It is checked against the main chain with the header, and
+// recorded in the side chain state by the next block. Main chain headers in the proofs of deposits and
+// messages are verified against the anchored blocks
+type MainAnchor struct {
+	Number uint64      `json:"number"`
+	Hash   common.Hash `json:"hash"`
+}
 
 // HeaderExtra is the struct of info in header.Extra[extraVanity:len(header.extra)-extraSeal]
+//
+// Fields after ConfirmedBlockNumber were added after genesis. They are optional
+// in the RLP encoding: trailing empty ones are omitted when encoding and missing
+// ones are left empty when decoding, so headers sealed before they existed keep
+// their encoding.
 type HeaderExtra struct {
-	CurrentBlockConfirmations []Confirmation
-	CurrentBlockVotes         []Vote
-	CurrentBlockCancels       []Cancel
-	LoopStartTime             uint64
-	SignerQueue               []common.Address
-	SignerMissing             []common.Address
-	ConfirmedBlockNumber      uint64
-	backup1					  []byte
-	backup2                   []byte
+	CurrentBlockConfirmations       []Confirmation
+	CurrentBlockVotes               []Vote
+	CurrentBlockCancels             []Cancel
+	LoopStartTime                   uint64
+	SignerQueue                     []common.Address
+	SignerMissing                   []common.Address
+	ConfirmedBlockNumber            uint64
+	CurrentBlockAddStakes           []StakeChange
+	CurrentBlockUnstakes            []StakeChange
+	CurrentBlockRedelegations       []Redelegation
+	CurrentBlockSlashes             []Slash
+	CurrentBlockSignedConfirmations []SignedConfirmation
+	CurrentBlockProposals           []Proposal
+	CurrentBlockDeclares            []Declare
+	CurrentBlockCheckpoints         []SideCheckpoint
+	CurrentBlockMainAnchors         []MainAnchor
+	backup1                         []byte
+	backup2                         []byte
+}
+
+// headerExtraFields returns pointers to the mandatory and the optional fields of
+// the header extra, in encoding order.
+func (h *HeaderExtra) headerExtraFields() ([]interface{}, []interface{}) {
+	return []interface{}{
+		&h.CurrentBlockConfirmations,
+		&h.CurrentBlockVotes,
+		&h.CurrentBlockCancels,
+		&h.LoopStartTime,
+		&h.SignerQueue,
+		&h.SignerMissing,
+		&h.ConfirmedBlockNumber,
+	}, []interface{}{
+		&h.CurrentBlockAddStakes,
+		&h.CurrentBlockUnstakes,
+		&h.CurrentBlockRedelegations,
+		&h.CurrentBlockSlashes,
+		&h.CurrentBlockSignedConfirmations,
+		&h.CurrentBlockProposals,
+		&h.CurrentBlockDeclares,
+		&h.CurrentBlockCheckpoints,
+		&h.CurrentBlockMainAnchors,
+	}
+}
+
+// EncodeRLP implements rlp.Encoder, omitting the trailing empty optional fields.
+func (h HeaderExtra) EncodeRLP(w io.Writer) error {
+	fields, optional := h.headerExtraFields()
+	n := len(optional)
+	for n > 0 && reflect.ValueOf(optional[n-1]).Elem().Len() == 0 {
+		n--
+	}
+	return rlp.Encode(w, append(fields, optional[:n]...))
+}
+
+// DecodeRLP implements rlp.Decoder, accepting encodings without the trailing
+// optional fields.
+func (h *HeaderExtra) DecodeRLP(s *rlp.Stream) error {
+	if _, err := s.List(); err != nil {
+		return err
+	}
+	fields, optional := h.headerExtraFields()
+	for _, field := range fields {
+		if err := s.Decode(field); err != nil {
+			return err
+		}
+	}
+	for _, field := range optional {
+		if err := s.Decode(field); err == rlp.EOL {
+			break
+		} else if err != nil {
+			return err
+		}
+	}
+	return s.ListEnd()
+}
+
+// customTxV2 is the RLP envelope of a "ufo:2:" custom transaction. Params is
+// the RLP encoding of the typed parameters of the action, e.g. voteParams.
+type customTxV2 struct {
+	Category string
+	Action   string
+	Params   rlp.RawValue
+}
+
+// Typed parameters of the custom transaction actions, shared by both versions.
+type voteParams struct {
+	Stake *big.Int
+}
+
+type cancelParams struct{}
+
+type stakeParams struct {
+	Amount *big.Int
+}
+
+type redelegateParams struct{}
+
+type slashParams struct {
+	First  *types.Header
+	Second *types.Header
+}
+
+type proposalParams struct {
+	Parameter string
+	Value     *big.Int
+}
+
+type declareParams struct {
+	Proposal common.Hash
+	Decision bool
+}
+
+type confirmParams struct {
+	Number uint64
 }
 
-// Calculate Votes from transaction in this block, write into header.Extra
-func (a *Alien) processCustomTx(headerExtra HeaderExtra, chain consensus.ChainReader, header *types.Header, state *state.StateDB, txs []*types.Transaction) (HeaderExtra, error) {
+type scConfirmParams struct {
+	GenesisHash common.Hash
+	Number      uint64
+	Header      *types.Header
+}
+
+type depositParams struct {
+	AppId     string
+	Recipient common.Address
+}
+
+type burnParams struct {
+	Recipient common.Address
+}
+
+type mintParams bridgeProof
+
+type releaseParams bridgeProof
+
+type deliverParams messageProof
+
+// customTx is a decoded custom transaction, independent of the version of the
+// encoding it was carried in.
+type customTx struct {
+	version  string
+	category string
+	action   string
+	params   interface{}
+}
+
+// CustomTxStatus is the outcome of processing a custom transaction, stored so
+// that it can be queried through alien_getCustomTxStatus.
+type CustomTxStatus struct {
+	Hash        common.Hash `json:"hash"`
+	BlockNumber uint64      `json:"blockNumber"`
+	Version     string      `json:"version"`
+	Category    string      `json:"category"`
+	Action      string      `json:"action"`
+	Accepted    bool        `json:"accepted"`
+	Reason      string      `json:"reason,omitempty"`
+}
+
+// EncodeCustomTxV2 returns the transaction data of a "ufo:2:" custom transaction
+// with the given category, action and typed parameters.
+func EncodeCustomTxV2(category string, action string, params interface{}) ([]byte, error) {
+	enc, err := rlp.EncodeToBytes(params)
+	if err != nil {
+		return nil, err
+	}
+	envelope, err := rlp.EncodeToBytes(customTxV2{Category: category, Action: action, Params: enc})
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(ufoPrefix+":"+ufoVersionV2+":"), envelope...), nil
+}
+
+// isCustomTx reports whether the transaction data carries the ufo prefix.
+func isCustomTx(data []byte) bool {
+	return strings.HasPrefix(string(data), ufoPrefix+":")
+}
+
+// parseCustomTx decodes the data of a custom transaction of any supported version,
+// sent in the given block of the chain with the given engine configuration.
+func parseCustomTx(config *params.AlienConfig, data []byte, number *big.Int) (*customTx, error) {
+	txData := string(data)
+	txDataInfo := strings.SplitN(txData, ":", ufoMinSplitLen)
+	if len(txDataInfo) < ufoMinSplitLen || txDataInfo[posPrefix] != ufoPrefix {
+		return nil, errCustomTxMalformed
+	}
+	switch txDataInfo[posVersion] {
+	case ufoVersion:
+		return parseCustomTxV1(strings.Split(txData, ":"))
+	case ufoVersionV2:
+		if !config.IsCustomTxV2(number) {
+			return nil, errCustomTxNotActive
+		}
+		return parseCustomTxV2(data[len(ufoPrefix)+len(ufoVersionV2)+2:])
+	}
+	return nil, errCustomTxUnknown
+}
+
+// parseCustomTxV1 decodes the colon separated "ufo:1:" format.
+func parseCustomTxV1(txDataInfo []string) (*customTx, error) {
+	if len(txDataInfo) <= ufoMinSplitLen {
+		return nil, errCustomTxMalformed
+	}
+	ctx := &customTx{
+		version:  ufoVersion,
+		category: txDataInfo[posCategory],
+		action:   txDataInfo[ufoMinSplitLen],
+	}
+	switch ctx.category {
+	case ufoCategoryEvent:
+		switch ctx.action {
+		case ufoEventVote:
+			if len(txDataInfo) <= posEventVoteValue {
+				return nil, errCustomTxMalformed
+			}
+			value, ok := new(big.Int).SetString(txDataInfo[posEventVoteValue], 10)
+			if !ok {
+				return nil, errCustomTxMalformed
+			}
+			ctx.params = &voteParams{Stake: value}
+		case ufoEventCancel:
+			ctx.params = &cancelParams{}
+		case ufoEventRedelegate:
+			ctx.params = &redelegateParams{}
+		case ufoEventAddStake, ufoEventUnstake:
+			if len(txDataInfo) <= posEventStakeValue {
+				return nil, errCustomTxMalformed
+			}
+			value, ok := new(big.Int).SetString(txDataInfo[posEventStakeValue], 10)
+			if !ok {
+				return nil, errCustomTxMalformed
+			}
+			ctx.params = &stakeParams{Amount: value}
+		case ufoEventProposal:
+			if len(txDataInfo) <= posEventProposalValue {
+				return nil, errCustomTxMalformed
+			}
+			value, ok := new(big.Int).SetString(txDataInfo[posEventProposalValue], 10)
+			if !ok {
+				return nil, errCustomTxMalformed
+			}
+			ctx.params = &proposalParams{Parameter: txDataInfo[posEventProposalParam], Value: value}
+		case ufoEventDeclare:
+			if len(txDataInfo) <= posEventDeclareResult {
+				return nil, errCustomTxMalformed
+			}
+			var decision bool
+			switch txDataInfo[posEventDeclareResult] {
+			case ufoDeclareYes:
+				decision = true
+			case ufoDeclareNo:
+				decision = false
+			default:
+				return nil, errCustomTxMalformed
+			}
+			ctx.params = &declareParams{Proposal: common.HexToHash(txDataInfo[posEventDeclareHash]), Decision: decision}
+		case ufoEventConfirm:
+			if len(txDataInfo) <= posEventConfirmNumber {
+				return nil, errCustomTxMalformed
+			}
+			confirmedBlockNumber, err := strconv.ParseUint(txDataInfo[posEventConfirmNumber], 10, 64)
+			if err != nil {
+				return nil, errCustomTxMalformed
+			}
+			ctx.params = &confirmParams{Number: confirmedBlockNumber}
+		default:
+			return nil, errCustomTxUnknown
+		}
+	case ufoCategorySC:
+		switch ctx.action {
+		case ufoEventConfirm:
+			if len(txDataInfo) <= posSCConfirmNumber {
+				return nil, errCustomTxMalformed
+			}
+			number, err := strconv.ParseUint(txDataInfo[posSCConfirmNumber], 10, 64)
+			if err != nil {
+				return nil, errCustomTxMalformed
+			}
+			params := &scConfirmParams{GenesisHash: common.HexToHash(txDataInfo[posSCConfirmHash]), Number: number}
+			if len(txDataInfo) > posSCConfirmHeader {
+				enc, err := hexutil.Decode(txDataInfo[posSCConfirmHeader])
+				if err != nil {
+					return nil, errCustomTxMalformed
+				}
+				params.Header = new(types.Header)
+				if err := rlp.DecodeBytes(enc, params.Header); err != nil {
+					return nil, errCustomTxMalformed
+				}
+			}
+			ctx.params = params
+		case ufoEventDeposit:
+			if len(txDataInfo) <= posSCDepositAppId || txDataInfo[posSCDepositAppId] == "" {
+				return nil, errCustomTxMalformed
+			}
+			params := &depositParams{AppId: txDataInfo[posSCDepositAppId]}
+			if len(txDataInfo) > posSCDepositRecipient {
+				if !common.IsHexAddress(txDataInfo[posSCDepositRecipient]) {
+					return nil, errCustomTxMalformed
+				}
+				params.Recipient = common.HexToAddress(txDataInfo[posSCDepositRecipient])
+			}
+			ctx.params = params
+		case ufoEventBurn:
+			params := &burnParams{}
+			if len(txDataInfo) > posSCBurnRecipient {
+				if !common.IsHexAddress(txDataInfo[posSCBurnRecipient]) {
+					return nil, errCustomTxMalformed
+				}
+				params.Recipient = common.HexToAddress(txDataInfo[posSCBurnRecipient])
+			}
+			ctx.params = params
+		case ufoEventMint, ufoEventRelease:
+			if len(txDataInfo) <= posSCBridgeProof {
+				return nil, errCustomTxMalformed
+			}
+			enc, err := hexutil.Decode(txDataInfo[posSCBridgeProof])
+			if err != nil {
+				return nil, errCustomTxMalformed
+			}
+			proof := new(bridgeProof)
+			if err := rlp.DecodeBytes(enc, proof); err != nil {
+				return nil, errCustomTxMalformed
+			}
+			if ctx.action == ufoEventMint {
+				ctx.params = (*mintParams)(proof)
+			} else {
+				ctx.params = (*releaseParams)(proof)
+			}
+		case ufoEventDeliver:
+			if len(txDataInfo) <= posSCMessageProof {
+				return nil, errCustomTxMalformed
+			}
+			enc, err := hexutil.Decode(txDataInfo[posSCMessageProof])
+			if err != nil {
+				return nil, errCustomTxMalformed
+			}
+			params := new(deliverParams)
+			if err := rlp.DecodeBytes(enc, params); err != nil {
+				return nil, errCustomTxMalformed
+			}
+			ctx.params = params
+		default:
+			return nil, errCustomTxUnknown
+		}
+	default:
+		return nil, errCustomTxUnknown
+	}
+	return ctx, nil
+}
+
+// parseCustomTxV2 decodes the RLP envelope of the "ufo:2:" format.
+func parseCustomTxV2(data []byte) (*customTx, error) {
+	var envelope customTxV2
+	if err := rlp.DecodeBytes(data, &envelope); err != nil {
+		return nil, errCustomTxMalformed
+	}
+	ctx := &customTx{
+		version:  ufoVersionV2,
+		category: envelope.Category,
+		action:   envelope.Action,
+	}
+	switch {
+	case ctx.category == ufoCategoryEvent && ctx.action == ufoEventVote:
+		ctx.params = new(voteParams)
+	case ctx.category == ufoCategoryEvent && ctx.action == ufoEventCancel:
+		ctx.params = new(cancelParams)
+	case ctx.category == ufoCategoryEvent && ctx.action == ufoEventRedelegate:
+		ctx.params = new(redelegateParams)
+	case ctx.category == ufoCategoryEvent && ctx.action == ufoEventSlash:
+		ctx.params = new(slashParams)
+	case ctx.category == ufoCategoryEvent && ctx.action == ufoEventProposal:
+		ctx.params = new(proposalParams)
+	case ctx.category == ufoCategoryEvent && ctx.action == ufoEventDeclare:
+		ctx.params = new(declareParams)
+	case ctx.category == ufoCategoryEvent && ctx.action == ufoEventConfirm:
+		ctx.params = new(confirmParams)
+	case ctx.category == ufoCategoryEvent && (ctx.action == ufoEventAddStake || ctx.action == ufoEventUnstake):
+		ctx.params = new(stakeParams)
+	case ctx.category == ufoCategorySC && ctx.action == ufoEventConfirm:
+		ctx.params = new(scConfirmParams)
+	case ctx.category == ufoCategorySC && ctx.action == ufoEventDeposit:
+		ctx.params = new(depositParams)
+	case ctx.category == ufoCategorySC && ctx.action == ufoEventBurn:
+		ctx.params = new(burnParams)
+	case ctx.category == ufoCategorySC && ctx.action == ufoEventMint:
+		ctx.params = new(mintParams)
+	case ctx.category == ufoCategorySC && ctx.action == ufoEventRelease:
+		ctx.params = new(releaseParams)
+	case ctx.category == ufoCategorySC && ctx.action == ufoEventDeliver:
+		ctx.params = new(deliverParams)
+	default:
+		return nil, errCustomTxUnknown
+	}
+	if err := rlp.DecodeBytes(envelope.Params, ctx.params); err != nil {
+		return nil, errCustomTxMalformed
+	}
+	if vote, ok := ctx.params.(*voteParams); ok && vote.Stake == nil {
+		return nil, errCustomTxMalformed
+	}
+	if stake, ok := ctx.params.(*stakeParams); ok && stake.Amount == nil {
+		return nil, errCustomTxMalformed
+	}
+	if slash, ok := ctx.params.(*slashParams); ok && (slash.First == nil || slash.Second == nil) {
+		return nil, errCustomTxMalformed
+	}
+	if proposal, ok := ctx.params.(*proposalParams); ok && proposal.Value == nil {
+		return nil, errCustomTxMalformed
+	}
+	if deposit, ok := ctx.params.(*depositParams); ok && deposit.AppId == "" {
+		return nil, errCustomTxMalformed
+	}
+	return ctx, nil
+}
+
+// Calculate Votes from transaction in this block, write into header.Extra, and
+// return the processing outcome of every custom transaction of the block
+func (a *Alien) processCustomTx(headerExtra HeaderExtra, chain consensus.ChainReader, header *types.Header, state *state.StateDB, txs []*types.Transaction) (HeaderExtra, []*CustomTxStatus, error) {
 
 	// if predecessor voter make transaction and vote in this block,
 	// just process as vote, do it in snapshot.apply
 	var (
-		number uint64
+		number   uint64
+		statuses []*CustomTxStatus
 	)
 	number = header.Number.Uint64()
 	for _, tx := range txs {
+		if !isCustomTx(tx.Data()) {
+			continue
+		}
 		txSender, err := types.Sender(types.NewEIP155Signer(tx.ChainId()), tx)
 		if err != nil {
 			continue
 		}
-		if len(string(tx.Data())) >= len(ufoPrefix) {
-			txData := string(tx.Data())
-			txDataInfo := strings.Split(txData, ":")
-			if len(txDataInfo) >= ufoMinSplitLen {
-				if txDataInfo[posPrefix] == ufoPrefix {
-					if txDataInfo[posVersion] == ufoVersion {
-						// process vote event
-						if txDataInfo[posCategory] == ufoCategoryEvent {
-							if len(txDataInfo) > ufoMinSplitLen {
-								// check is vote or not
-								if txDataInfo[posEventVote] == ufoEventVote {
-									headerExtra.CurrentBlockVotes = a.processEventVote(chain , headerExtra.CurrentBlockVotes, state, tx, txSender, txDataInfo)
-								} else if txDataInfo[posEventCancel] == ufoEventCancel {
-									headerExtra.CurrentBlockCancels = a.processEventCancel(headerExtra.CurrentBlockCancels, state, tx, txSender, txDataInfo)
-								} else if txDataInfo[posEventConfirm] == ufoEventConfirm {
-									headerExtra.CurrentBlockConfirmations = a.processEventConfirm(headerExtra.CurrentBlockConfirmations, chain, txDataInfo, number, tx, txSender)
-								}
-
-								// if value is not zero, this vote may influence the balance of tx.To()
-								if tx.Value().Cmp(big.NewInt(0)) == 0 {
-									continue
-								}
-
-							} else {
-								// todo : something wrong, leave this transaction to process as normal transaction
-							}
-						} else if txDataInfo[posCategory] == ufoCategoryLog {
-							// todo :
-						} else if txDataInfo[posCategory] == ufoCategorySC {
-							if len(txDataInfo) > ufoMinSplitLen+2 {
-								if txDataInfo[posEventConfirm] == ufoEventConfirm {
-									// log.Info("Side chain confirm info", "hash", txDataInfo[ufoMinSplitLen+1])
-									// log.Info("Side chain confirm info", "number", txDataInfo[ufoMinSplitLen+2])
-								}
-							}
-						}
-					}
+		status := &CustomTxStatus{Hash: tx.Hash(), BlockNumber: number}
+		ctx, err := parseCustomTx(a.config, tx.Data(), header.Number)
+		if err == nil {
+			status.Version, status.Category, status.Action = ctx.version, ctx.category, ctx.action
+			switch params := ctx.params.(type) {
+			case *voteParams:
+				headerExtra.CurrentBlockVotes, err = a.processEventVote(chain, headerExtra.CurrentBlockVotes, state, tx, txSender, params)
+			case *cancelParams:
+				headerExtra.CurrentBlockCancels, err = a.processEventCancel(headerExtra.CurrentBlockCancels, state, tx, txSender, params)
+			case *stakeParams:
+				if !a.config.IsStakeChange(header.Number) {
+					err = errCustomTxNotActive
+				} else if ctx.action == ufoEventAddStake {
+					headerExtra.CurrentBlockAddStakes, err = a.processEventAddStake(chain, header, headerExtra, state, tx, txSender, params)
+				} else {
+					headerExtra.CurrentBlockUnstakes, err = a.processEventUnstake(chain, header, headerExtra, tx, txSender, params)
+				}
+			case *redelegateParams:
+				if !a.config.IsRedelegate(header.Number) {
+					err = errCustomTxNotActive
+				} else {
+					headerExtra.CurrentBlockRedelegations, err = a.processEventRedelegate(chain, header, headerExtra, tx, txSender, params)
+				}
+			case *slashParams:
+				if !a.config.IsSlash(header.Number) {
+					err = errCustomTxNotActive
+				} else {
+					headerExtra.CurrentBlockSlashes, err = a.processEventSlash(chain, header, headerExtra, tx, txSender, params)
+				}
+			case *proposalParams:
+				if !a.config.IsProposal(header.Number) {
+					err = errCustomTxNotActive
+				} else {
+					headerExtra.CurrentBlockProposals, err = a.processEventProposal(chain, header, headerExtra, tx, txSender, params)
+				}
+			case *declareParams:
+				if !a.config.IsProposal(header.Number) {
+					err = errCustomTxNotActive
+				} else {
+					headerExtra.CurrentBlockDeclares, err = a.processEventDeclare(chain, header, headerExtra, tx, txSender, params)
+				}
+			case *confirmParams:
+				if a.config.IsFinality(header.Number) {
+					err = errConfirmByMessage
+					break
+				}
+				headerExtra.CurrentBlockConfirmations, err = a.processEventConfirm(headerExtra.CurrentBlockConfirmations, chain, params, header, tx, txSender)
+			case *scConfirmParams:
+				if !a.config.IsCheckpoint(header.Number) {
+					err = errCustomTxNotActive
+				} else {
+					headerExtra.CurrentBlockCheckpoints, err = a.processEventCheckpoint(chain, header, state, headerExtra, tx, txSender, params)
+				}
+			case *depositParams:
+				if !a.config.IsBridge(header.Number) {
+					err = errCustomTxNotActive
+				} else {
+					err = a.processEventDeposit(state, tx, params)
+				}
+			case *mintParams:
+				if !a.config.IsBridge(header.Number) {
+					err = errCustomTxNotActive
+				} else {
+					err = a.processEventMint(state, params)
+				}
+			case *burnParams:
+				if !a.config.IsBridge(header.Number) {
+					err = errCustomTxNotActive
+				} else {
+					err = a.processEventBurn(state, tx)
+				}
+			case *releaseParams:
+				if !a.config.IsBridge(header.Number) {
+					err = errCustomTxNotActive
+				} else {
+					err = a.processEventRelease(header, state, params)
+				}
+			case *deliverParams:
+				if !a.config.IsCrossApp(header.Number) {
+					err = errCustomTxNotActive
+				} else {
+					err = a.processEventDeliver(chain, header, state, tx, params)
 				}
 			}
 		}
-
+		if err != nil {
+			log.Debug("Custom transaction rejected", "hash", tx.Hash(), "err", err)
+			status.Reason = err.Error()
+		} else {
+			status.Accepted = true
+		}
+		statuses = append(statuses, status)
 	}
 
-	return headerExtra, nil
+	return headerExtra, statuses, nil
 }
 
-func (a *Alien) processEventVote(chain consensus.ChainReader,currentBlockVotes []Vote, state *state.StateDB, tx *types.Transaction, voter common.Address, txDataInfo []string) []Vote {
-	if len(txDataInfo) >= posEventVoteValue {
-		value, ok := big.NewInt(0).SetString(txDataInfo[posEventVoteValue], 10)
-		if !ok {
-			log.Warn("invalid vote value")
-			return currentBlockVotes
-		}
+func (a *Alien) processEventVote(chain consensus.ChainReader, currentBlockVotes []Vote, state *state.StateDB, tx *types.Transaction, voter common.Address, params *voteParams) ([]Vote, error) {
+	value := params.Stake
 
-		bc, ok := chain.(*core.BlockChain)
-		if !ok {
-			log.Error("blockchain == nil when convert")
-			return currentBlockVotes
-		}
-		header := bc.CurrentBlock().Header()
+	// the candidate voted for is the recipient, a contract creation has none
+	if tx.To() == nil {
+		return currentBlockVotes, errCustomTxNoRecipient
+	}
+	bc, ok := chain.(*core.BlockChain)
+	if !ok {
+		log.Error("blockchain == nil when convert")
+		return currentBlockVotes, errSnapshotUnavailable
+	}
+	header := bc.CurrentBlock().Header()
 
-		snap , err:= a.snapshot(chain, header.Number.Uint64(), header.Hash(), nil, nil, DefaultLoopCntRecalculateSigners)
-		if err != nil {
-			log.Error(err.Error())
-			return currentBlockVotes
+	snap, err := a.snapshot(chain, header.Number.Uint64(), header.Hash(), nil, nil, DefaultLoopCntRecalculateSigners)
+	if err != nil {
+		log.Error(err.Error())
+		return currentBlockVotes, errSnapshotUnavailable
+	}
+
+	if _, ok := snap.Votes[voter]; ok {
+		return currentBlockVotes, errVoteRepeat
+	}
+	if voter != *tx.To() {
+		if value.Cmp(snap.Params.MinVoteValue) < 0 {
+			return currentBlockVotes, errVoteValueTooLow
 		}
+		if !snap.isCandidate(*tx.To()) {
+			return currentBlockVotes, errVoteTargetNotCandidate
+		}
+	} else {
+		if value.Cmp(snap.Params.SelfVoteValue) < 0 {
+			return currentBlockVotes, errSelfVoteValueTooLow
+		}
+	}
 
-		if _, ok := snap.Votes[voter]; ok {
-			log.Warn("Repeat vote in snap.Votes")
-			log.Info("processEventVote...vote failed")
-			return currentBlockVotes
+	if state.GetBalance(voter).Cmp(value) <= 0 {
+		return currentBlockVotes, errVoteBalanceNotEnough
+	}
+	for _, vote := range currentBlockVotes {
+		if vote.Voter == voter {
+			return currentBlockVotes, errVoteRepeat
 		}
-		if voter != *tx.To() {
-			if value.Cmp(a.config.MinVoteValue) < 0 {
-				log.Warn("Vote value less than MinVoteValue")
-				return currentBlockVotes
-			}
-			if !snap.isCandidate(*tx.To()) {
-				log.Warn("Vote target is not a candidate")
-				return currentBlockVotes
-			}
-		} else {
-			if value.Cmp(a.config.SelfVoteValue) < 0 {
-				log.Warn("Vote value less than SelfVoteValue")
-				return currentBlockVotes
-			}
+	}
+	a.lock.Lock()
+	state.SubBalance(voter, value)
+	a.lock.Unlock()
+	currentBlockVotes = append(currentBlockVotes, Vote{
+		Voter:     voter,
+		Candidate: *tx.To(),
+		Stake:     value,
+		Hash:      tx.Hash(),
+	})
+	return currentBlockVotes, nil
+}
+
+func (a *Alien) processEventCancel(currentBlockCancels []Cancel, state *state.StateDB, tx *types.Transaction, canceler common.Address, params *cancelParams) ([]Cancel, error) {
+	for _, cancel := range currentBlockCancels {
+		if cancel.Canceler == canceler {
+			return currentBlockCancels, errCancelRepeat
 		}
+	}
 
-		if state.GetBalance(voter).Cmp(value) > 0 {
-			a.lock.RLock()
-			stake := value
-			a.lock.RUnlock()
-			for _, vote := range currentBlockVotes {
-				if vote.Voter == voter {
-					log.Warn("Repeat vote in currentBlockVotes")
-					return currentBlockVotes
-				}
-			}
-			a.lock.Lock()
-			state.SubBalance(voter, value)
-			a.lock.Unlock()
-			currentBlockVotes = append(currentBlockVotes, Vote{
-				Voter:     voter,
-				Candidate: *tx.To(),
-				Stake:     stake,
-				Hash:      tx.Hash(),
-			})
-		} else {
-			log.Warn("Not enough balance for vote")
-			return currentBlockVotes
+	currentBlockCancels = append(currentBlockCancels, Cancel{
+		Canceler: canceler,
+		Passive:  false,
+	})
+	return currentBlockCancels, nil
+}
+
+// stakeChangeSnapshot returns the parent snapshot and the existing vote of the
+// voter if it may change its stake in the current block.
+func (a *Alien) stakeChangeSnapshot(chain consensus.ChainReader, header *types.Header, headerExtra HeaderExtra, voter common.Address, amount *big.Int) (*Snapshot, *Vote, error) {
+	if amount.Sign() <= 0 {
+		return nil, nil, errStakeAmountInvalid
+	}
+	for _, change := range append(headerExtra.CurrentBlockAddStakes, headerExtra.CurrentBlockUnstakes...) {
+		if change.Voter == voter {
+			return nil, nil, errStakeChangeRepeat
 		}
 	}
-	return currentBlockVotes
+	snap, err := a.snapshot(chain, header.Number.Uint64()-1, header.ParentHash, nil, nil, DefaultLoopCntRecalculateSigners)
+	if err != nil {
+		log.Error(err.Error())
+		return nil, nil, errSnapshotUnavailable
+	}
+	vote, ok := snap.Votes[voter]
+	if !ok {
+		return nil, nil, errStakeNoVote
+	}
+	if _, ok := snap.Cancels[voter]; ok {
+		return nil, nil, errStakeVoteCanceled
+	}
+	return snap, vote, nil
 }
 
-func (a *Alien) processEventCancel(currentBlockCancels []Cancel, state *state.StateDB, tx *types.Transaction, canceler common.Address, txDataInfo []string) []Cancel {
-	if len(txDataInfo) >= posEventCancel {
+func (a *Alien) processEventAddStake(chain consensus.ChainReader, header *types.Header, headerExtra HeaderExtra, state *state.StateDB, tx *types.Transaction, voter common.Address, params *stakeParams) ([]StakeChange, error) {
+	if _, _, err := a.stakeChangeSnapshot(chain, header, headerExtra, voter, params.Amount); err != nil {
+		return headerExtra.CurrentBlockAddStakes, err
+	}
+	if state.GetBalance(voter).Cmp(params.Amount) < 0 {
+		return headerExtra.CurrentBlockAddStakes, errVoteBalanceNotEnough
+	}
+	a.lock.Lock()
+	state.SubBalance(voter, params.Amount)
+	a.lock.Unlock()
+	return append(headerExtra.CurrentBlockAddStakes, StakeChange{
+		Voter:  voter,
+		Amount: params.Amount,
+		Hash:   tx.Hash(),
+	}), nil
+}
 
-		for _, cancel := range currentBlockCancels {
-			if cancel.Canceler == canceler {
-				log.Error("Repeat cancel")
-				return currentBlockCancels
-			}
+func (a *Alien) processEventUnstake(chain consensus.ChainReader, header *types.Header, headerExtra HeaderExtra, tx *types.Transaction, voter common.Address, params *stakeParams) ([]StakeChange, error) {
+	snap, vote, err := a.stakeChangeSnapshot(chain, header, headerExtra, voter, params.Amount)
+	if err != nil {
+		return headerExtra.CurrentBlockUnstakes, err
+	}
+	minStake := snap.Params.MinVoteValue
+	if vote.Voter == vote.Candidate {
+		minStake = snap.Params.SelfVoteValue
+	}
+	if new(big.Int).Sub(vote.Stake, params.Amount).Cmp(minStake) < 0 {
+		return headerExtra.CurrentBlockUnstakes, errUnstakeTooMuch
+	}
+	return append(headerExtra.CurrentBlockUnstakes, StakeChange{
+		Voter:  voter,
+		Amount: params.Amount,
+		Hash:   tx.Hash(),
+	}), nil
+}
+
+func (a *Alien) processEventRedelegate(chain consensus.ChainReader, header *types.Header, headerExtra HeaderExtra, tx *types.Transaction, voter common.Address, params *redelegateParams) ([]Redelegation, error) {
+	// the new candidate is the recipient, a contract creation has none
+	if tx.To() == nil {
+		return headerExtra.CurrentBlockRedelegations, errCustomTxNoRecipient
+	}
+	for _, redelegation := range headerExtra.CurrentBlockRedelegations {
+		if redelegation.Voter == voter {
+			return headerExtra.CurrentBlockRedelegations, errStakeChangeRepeat
 		}
+	}
+	snap, err := a.snapshot(chain, header.Number.Uint64()-1, header.ParentHash, nil, nil, DefaultLoopCntRecalculateSigners)
+	if err != nil {
+		log.Error(err.Error())
+		return headerExtra.CurrentBlockRedelegations, errSnapshotUnavailable
+	}
+	if err := snap.checkRedelegation(voter, *tx.To(), header.Number); err != nil {
+		return headerExtra.CurrentBlockRedelegations, err
+	}
+	return append(headerExtra.CurrentBlockRedelegations, Redelegation{
+		Voter:     voter,
+		Candidate: *tx.To(),
+		Hash:      tx.Hash(),
+	}), nil
+}
 
-		currentBlockCancels = append(currentBlockCancels, Cancel{
-			Canceler: canceler,
-			Passive:  false,
-		})
+func (a *Alien) processEventSlash(chain consensus.ChainReader, header *types.Header, headerExtra HeaderExtra, tx *types.Transaction, reporter common.Address, params *slashParams) ([]Slash, error) {
+	signer, err := a.verifyDoubleSign(params.First, params.Second, header.Number.Uint64())
+	if err != nil {
+		return headerExtra.CurrentBlockSlashes, err
+	}
+	if hasSlash(headerExtra.CurrentBlockSlashes, signer) {
+		return headerExtra.CurrentBlockSlashes, errSlashRepeat
+	}
+	snap, err := a.snapshot(chain, header.Number.Uint64()-1, header.ParentHash, nil, nil, DefaultLoopCntRecalculateSigners)
+	if err != nil {
+		log.Error(err.Error())
+		return headerExtra.CurrentBlockSlashes, errSnapshotUnavailable
+	}
+	if number, ok := snap.DoubleSigned[signer]; ok && number >= params.First.Number.Uint64() {
+		return headerExtra.CurrentBlockSlashes, errSlashRepeat
 	}
-	return currentBlockCancels
+	if snap.slashAmount(signer, slashReasonDoubleSign).Sign() == 0 {
+		return headerExtra.CurrentBlockSlashes, errSlashNoStake
+	}
+	return append(headerExtra.CurrentBlockSlashes, Slash{
+		Signer:   signer,
+		Reason:   slashReasonDoubleSign,
+		Reporter: reporter,
+		Number:   params.First.Number.Uint64(),
+		Hash:     tx.Hash(),
+	}), nil
 }
 
-func (a *Alien) processEventConfirm(currentBlockConfirmations []Confirmation, chain consensus.ChainReader, txDataInfo []string, number uint64, tx *types.Transaction, confirmer common.Address) []Confirmation {
-	if len(txDataInfo) >= posEventConfirmNumber {
-		confirmedBlockNumber, err := strconv.Atoi(txDataInfo[posEventConfirmNumber])
-		if err != nil || number-uint64(confirmedBlockNumber) > a.config.MaxSignerCount || number-uint64(confirmedBlockNumber) < 0 {
-			return currentBlockConfirmations
+func (a *Alien) processEventProposal(chain consensus.ChainReader, header *types.Header, headerExtra HeaderExtra, tx *types.Transaction, proposer common.Address, params *proposalParams) ([]Proposal, error) {
+	for _, proposal := range headerExtra.CurrentBlockProposals {
+		if proposal.Parameter == params.Parameter {
+			return headerExtra.CurrentBlockProposals, errProposalRepeat
 		}
-		// check if the voter is in block
-		confirmedHeader := chain.GetHeaderByNumber(uint64(confirmedBlockNumber))
-		if confirmedHeader == nil {
-			log.Info("Fail to get confirmedHeader")
-			return currentBlockConfirmations
+	}
+	snap, err := a.snapshot(chain, header.Number.Uint64()-1, header.ParentHash, nil, nil, DefaultLoopCntRecalculateSigners)
+	if err != nil {
+		log.Error(err.Error())
+		return headerExtra.CurrentBlockProposals, errSnapshotUnavailable
+	}
+	if err := snap.checkProposal(proposer, params.Parameter, params.Value); err != nil {
+		return headerExtra.CurrentBlockProposals, err
+	}
+	return append(headerExtra.CurrentBlockProposals, Proposal{
+		Hash:      tx.Hash(),
+		Proposer:  proposer,
+		Parameter: params.Parameter,
+		Value:     params.Value,
+	}), nil
+}
+
+func (a *Alien) processEventDeclare(chain consensus.ChainReader, header *types.Header, headerExtra HeaderExtra, tx *types.Transaction, declarer common.Address, params *declareParams) ([]Declare, error) {
+	for _, declare := range headerExtra.CurrentBlockDeclares {
+		if declare.Declarer == declarer && declare.ProposalHash == params.Proposal {
+			return headerExtra.CurrentBlockDeclares, errDeclareRepeat
 		}
-		confirmedHeaderExtra := HeaderExtra{}
-		if extraVanity+extraSeal > len(confirmedHeader.Extra) {
-			return currentBlockConfirmations
+	}
+	snap, err := a.snapshot(chain, header.Number.Uint64()-1, header.ParentHash, nil, nil, DefaultLoopCntRecalculateSigners)
+	if err != nil {
+		log.Error(err.Error())
+		return headerExtra.CurrentBlockDeclares, errSnapshotUnavailable
+	}
+	if err := snap.checkDeclare(declarer, params.Proposal, header.Number.Uint64()); err != nil {
+		return headerExtra.CurrentBlockDeclares, err
+	}
+	return append(headerExtra.CurrentBlockDeclares, Declare{
+		ProposalHash: params.Proposal,
+		Declarer:     declarer,
+		Decision:     params.Decision,
+		Hash:         tx.Hash(),
+	}), nil
+}
+
+// verifyDoubleSign checks that the two headers are distinct blocks sealed by the
+// same signer at the same height below number, and returns that signer.
+func (a *Alien) verifyDoubleSign(first *types.Header, second *types.Header, number uint64) (common.Address, error) {
+	if first.Number == nil || second.Number == nil || first.Number.Cmp(second.Number) != 0 || first.Number.Uint64() >= number {
+		return common.Address{}, errSlashEvidenceInvalid
+	}
+	if len(first.Extra) < extraVanity+extraSeal || len(second.Extra) < extraVanity+extraSeal {
+		return common.Address{}, errSlashEvidenceInvalid
+	}
+	if sigHash(first) == sigHash(second) {
+		return common.Address{}, errSlashEvidenceInvalid
+	}
+	firstSigner, err := ecrecover(first, a.signatures)
+	if err != nil {
+		return common.Address{}, errSlashEvidenceInvalid
+	}
+	secondSigner, err := ecrecover(second, a.signatures)
+	if err != nil {
+		return common.Address{}, errSlashEvidenceInvalid
+	}
+	if firstSigner != secondSigner || firstSigner != first.Coinbase || secondSigner != second.Coinbase {
+		return common.Address{}, errSlashEvidenceInvalid
+	}
+	return firstSigner, nil
+}
+
+func (a *Alien) processEventConfirm(currentBlockConfirmations []Confirmation, chain consensus.ChainReader, params *confirmParams, header *types.Header, tx *types.Transaction, confirmer common.Address) ([]Confirmation, error) {
+	number := header.Number.Uint64()
+	snap, err := a.snapshot(chain, number-1, header.ParentHash, nil, nil, DefaultLoopCntRecalculateSigners)
+	if err != nil {
+		log.Error(err.Error())
+		return currentBlockConfirmations, errSnapshotUnavailable
+	}
+	confirmedBlockNumber := params.Number
+	if confirmedBlockNumber > number || number-confirmedBlockNumber > snap.Params.MaxSignerCount {
+		return currentBlockConfirmations, errConfirmOutOfRange
+	}
+	// check if the voter is in block
+	confirmedHeader := chain.GetHeaderByNumber(confirmedBlockNumber)
+	if confirmedHeader == nil {
+		return currentBlockConfirmations, errConfirmUnknownBlock
+	}
+	confirmedHeaderExtra := HeaderExtra{}
+	if extraVanity+extraSeal > len(confirmedHeader.Extra) {
+		return currentBlockConfirmations, errConfirmUnknownBlock
+	}
+	err = rlp.DecodeBytes(confirmedHeader.Extra[extraVanity:len(confirmedHeader.Extra)-extraSeal], &confirmedHeaderExtra)
+	if err != nil {
+		log.Info("Fail to decode parent header", "err", err)
+		return currentBlockConfirmations, errConfirmUnknownBlock
+	}
+	for _, s := range confirmedHeaderExtra.SignerQueue {
+		if s == confirmer {
+			currentBlockConfirmations = append(currentBlockConfirmations, Confirmation{
+				Signer:      confirmer,
+				BlockNumber: new(big.Int).SetUint64(confirmedBlockNumber),
+			})
+			return currentBlockConfirmations, nil
 		}
-		err = rlp.DecodeBytes(confirmedHeader.Extra[extraVanity:len(confirmedHeader.Extra)-extraSeal], &confirmedHeaderExtra)
+	}
+	return currentBlockConfirmations, errConfirmNotSigner
+}
+
+// customTxStatusKey = customTxStatusPrefix + tx hash + block hash
+func customTxStatusKey(hash common.Hash, blockHash common.Hash) []byte {
+	return append(append([]byte(customTxStatusPrefix), hash[:]...), blockHash[:]...)
+}
+
+// writeCustomTxStatuses stores the processing outcome of the custom transactions
+// of the block with the given hash. The statuses are keyed by block, as the
+// blocks of every fork are imported.
+func writeCustomTxStatuses(db ethdb.Database, blockHash common.Hash, statuses []*CustomTxStatus) error {
+	batch := db.NewBatch()
+	for _, status := range statuses {
+		blob, err := json.Marshal(status)
 		if err != nil {
-			log.Info("Fail to decode parent header", "err", err)
-			return currentBlockConfirmations
-		}
-		for _, s := range confirmedHeaderExtra.SignerQueue {
-			if s == confirmer {
-				currentBlockConfirmations = append(currentBlockConfirmations, Confirmation{
-					Signer:      confirmer,
-					BlockNumber: big.NewInt(int64(confirmedBlockNumber)),
-				})
-				break
-			}
+			return err
 		}
+		batch.Put(customTxStatusKey(status.Hash, blockHash), blob)
 	}
+	return batch.Write()
+}
 
-	return currentBlockConfirmations
+// readCustomTxStatus retrieves the processing outcome of a custom transaction
+// in the block with the given hash.
+func readCustomTxStatus(db ethdb.Database, hash common.Hash, blockHash common.Hash) (*CustomTxStatus, error) {
+	blob, err := db.Get(customTxStatusKey(hash, blockHash))
+	if err != nil {
+		return nil, err
+	}
+	status := new(CustomTxStatus)
+	if err := json.Unmarshal(blob, status); err != nil {
+		return nil, err
+	}
+	return status, nil
+}
+
+// deleteCustomTxStatuses removes the processing outcome of the transactions of
+// the given block.
+func deleteCustomTxStatuses(db ethdb.Database, block *types.Block) error {
+	batch := db.NewBatch()
+	for _, tx := range block.Transactions() {
+		batch.Delete(customTxStatusKey(tx.Hash(), block.Hash()))
+	}
+	return batch.Write()
+}
+
+// WriteBlockRecords implements consensus.Recorder, storing the custom tx
+// statuses of an imported block. Finalize keeps them by seal hash, as the
+// blocks mined are finalized before they are sealed, and not every block
+// finalized is imported.
+func (a *Alien) WriteBlockRecords(block *types.Block) error {
+	statuses, ok := a.txStatuses.Get(sigHash(block.Header()))
+	if !ok {
+		return nil
+	}
+	return writeCustomTxStatuses(a.store, block.Hash(), statuses.([]*CustomTxStatus))
+}
+
+// DeleteBlockRecords implements consensus.Recorder, removing the custom tx
+// statuses of a block dropped from the canonical chain.
+func (a *Alien) DeleteBlockRecords(block *types.Block) error {
+	return deleteCustomTxStatuses(a.store, block)
+}
+
+// customTxStatus retrieves the processing outcome of a custom transaction in the
+// canonical block of the chain holding it.
+func (a *Alien) customTxStatus(chain consensus.ChainReader, hash common.Hash) (*CustomTxStatus, error) {
+	tx, blockHash, number, _ := rawdb.ReadTransaction(a.db, hash, a.config.AppId)
+	if tx == nil {
+		return nil, errUnknownTransaction
+	}
+	if chain.GetHeader(blockHash, number) == nil {
+		return nil, errUnknownBlock
+	}
+	return readCustomTxStatus(a.store, hash, blockHash)
 }
 
 func (a *Alien) processPredecessorVoter(modifyPredecessorVotes []Vote, state *state.StateDB, tx *types.Transaction, voter common.Address, snap *Snapshot) []Vote {
@@ -294,7 +1178,7 @@ func (a *Alien) processPredecessorVoter(modifyPredecessorVotes []Vote, state *st
 				Hash:      tx.Hash(),
 			})
 		}
-		if snap.isVoter(*tx.To()) {
+		if tx.To() != nil && snap.isVoter(*tx.To()) {
 			a.lock.RLock()
 			stake := state.GetBalance(*tx.To())
 			a.lock.RUnlock()
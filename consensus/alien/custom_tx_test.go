@@ -0,0 +1,203 @@
+// Copyright 2018 The giov Authors
+// This file is part of the giov library.
+//
+// The giov library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The giov library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the giov library. If not, see <http://www.gnu.org/licenses/>.
+
+package alien
+
+import (
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/CarLiveChainCo/goiov/common"
+	"github.com/CarLiveChainCo/goiov/core/rawdb"
+	"github.com/CarLiveChainCo/goiov/core/state"
+	"github.com/CarLiveChainCo/goiov/core/types"
+	"github.com/CarLiveChainCo/goiov/crypto"
+	"github.com/CarLiveChainCo/goiov/ethdb"
+	"github.com/CarLiveChainCo/goiov/params"
+	"github.com/CarLiveChainCo/goiov/rlp"
+	"github.com/hashicorp/golang-lru"
+)
+
+// Tests that the custom transactions of both formats are decoded into their
+// typed parameters, and that malformed ones are rejected.
+func TestParseCustomTx(t *testing.T) {
+	config := &params.AlienConfig{CustomTxV2Block: big.NewInt(10)}
+
+	encode := func(category, action string, params interface{}) string {
+		data, err := EncodeCustomTxV2(category, action, params)
+		if err != nil {
+			t.Fatalf("failed to encode %s:%s: %v", category, action, err)
+		}
+		return string(data)
+	}
+	tests := []struct {
+		data   string
+		number int64
+		params interface{}
+		err    error
+	}{
+		// Version 1, the colon separated format
+		{data: "ufo:1:event:vote:100", params: &voteParams{Stake: big.NewInt(100)}},
+		{data: "ufo:1:event:cancel", params: &cancelParams{}},
+		{data: "ufo:1:event:redelegate", params: &redelegateParams{}},
+		{data: "ufo:1:event:addstake:20", params: &stakeParams{Amount: big.NewInt(20)}},
+		{data: "ufo:1:event:unstake:30", params: &stakeParams{Amount: big.NewInt(30)}},
+		{data: "ufo:1:event:proposal:period:5", params: &proposalParams{Parameter: "period", Value: big.NewInt(5)}},
+		{data: "ufo:1:event:declare:0x01:yes", params: &declareParams{Proposal: common.HexToHash("0x01"), Decision: true}},
+		{data: "ufo:1:event:declare:0x01:no", params: &declareParams{Proposal: common.HexToHash("0x01")}},
+		{data: "ufo:1:event:confirm:12", params: &confirmParams{Number: 12}},
+		{data: "ufo:1:sc:deposit:7", params: &depositParams{AppId: "7"}},
+		{data: "ufo:1:sc:burn", params: &burnParams{}},
+
+		// Version 1, malformed
+		{data: "ufo:1", err: errCustomTxMalformed},
+		{data: "ufo:1:event", err: errCustomTxMalformed},
+		{data: "ufo:1:event:vote", err: errCustomTxMalformed},
+		{data: "ufo:1:event:vote:lots", err: errCustomTxMalformed},
+		{data: "ufo:1:event:unstake", err: errCustomTxMalformed},
+		{data: "ufo:1:event:proposal:period", err: errCustomTxMalformed},
+		{data: "ufo:1:event:declare:0x01:maybe", err: errCustomTxMalformed},
+		{data: "ufo:1:event:confirm:-1", err: errCustomTxMalformed},
+		{data: "ufo:1:sc:deposit:", err: errCustomTxMalformed},
+		{data: "ufo:1:sc:deposit:7:nothex", err: errCustomTxMalformed},
+		{data: "ufo:1:sc:mint:0xzz", err: errCustomTxMalformed},
+		{data: "ufo:1:event:unknown", err: errCustomTxUnknown},
+		{data: "ufo:1:unknown:vote:100", err: errCustomTxUnknown},
+		{data: "ufo:3:event:vote:100", err: errCustomTxUnknown},
+		{data: "ufx:1:event:vote:100", err: errCustomTxMalformed},
+
+		// Version 2, the RLP envelope format
+		{data: encode(ufoCategoryEvent, ufoEventVote, &voteParams{Stake: big.NewInt(100)}), number: 10, params: &voteParams{Stake: big.NewInt(100)}},
+		{data: encode(ufoCategoryEvent, ufoEventUnstake, &stakeParams{Amount: big.NewInt(30)}), number: 11, params: &stakeParams{Amount: big.NewInt(30)}},
+		{data: encode(ufoCategoryEvent, ufoEventProposal, &proposalParams{Parameter: "freeze", Value: big.NewInt(60)}), number: 11, params: &proposalParams{Parameter: "freeze", Value: big.NewInt(60)}},
+		{data: encode(ufoCategoryEvent, ufoEventDeclare, &declareParams{Proposal: common.HexToHash("0x02"), Decision: true}), number: 11, params: &declareParams{Proposal: common.HexToHash("0x02"), Decision: true}},
+		{data: encode(ufoCategorySC, ufoEventDeposit, &depositParams{AppId: "7", Recipient: common.HexToAddress("0x03")}), number: 11, params: &depositParams{AppId: "7", Recipient: common.HexToAddress("0x03")}},
+
+		// Version 2, malformed or not active yet
+		{data: encode(ufoCategoryEvent, ufoEventVote, &voteParams{Stake: big.NewInt(100)}), number: 9, err: errCustomTxNotActive},
+		{data: encode(ufoCategoryEvent, ufoEventVote, []uint{1, 2}), number: 10, err: errCustomTxMalformed},
+		{data: encode(ufoCategoryEvent, ufoEventProposal, "freeze"), number: 10, err: errCustomTxMalformed},
+		{data: encode(ufoCategorySC, ufoEventDeposit, &depositParams{}), number: 10, err: errCustomTxMalformed},
+		{data: encode(ufoCategoryEvent, "unknown", &cancelParams{}), number: 10, err: errCustomTxUnknown},
+		{data: "ufo:2:" + string([]byte{0xc5, 0x01}), number: 10, err: errCustomTxMalformed},
+	}
+	for i, tt := range tests {
+		ctx, err := parseCustomTx(config, []byte(tt.data), big.NewInt(tt.number))
+		if err != tt.err {
+			t.Errorf("test %d: error mismatch: have %v, want %v", i, err, tt.err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if !reflect.DeepEqual(ctx.params, tt.params) {
+			t.Errorf("test %d: params mismatch: have %+v, want %+v", i, ctx.params, tt.params)
+		}
+	}
+}
+
+// Tests that the custom tx statuses of a block are only stored once the block is
+// imported, and removed when it is dropped from the canonical chain.
+func TestCustomTxStatusRecords(t *testing.T) {
+	db := ethdb.NewMemDatabase()
+	txStatuses, _ := lru.NewARC(inMemoryTxStatuses)
+	engine := &Alien{store: db, txStatuses: txStatuses}
+
+	tx := types.NewTransaction(0, common.HexToAddress("0x01"), big.NewInt(0), 0, big.NewInt(0), []byte("ufo:1:event:cancel"))
+	header := &types.Header{Number: big.NewInt(1), Extra: make([]byte, extraVanity+extraSeal)}
+	status := &CustomTxStatus{Hash: tx.Hash(), BlockNumber: 1, Version: ufoVersion, Category: ufoCategoryEvent, Action: ufoEventCancel, Reason: errVoteTargetNotCandidate.Error()}
+
+	// A finalized block is kept by seal hash, the imported block is sealed
+	finalized := types.NewBlock(header, []*types.Transaction{tx}, nil, nil)
+	engine.txStatuses.Add(sigHash(finalized.Header()), []*CustomTxStatus{status})
+
+	sealed := finalized.Header()
+	sealed.Extra[len(sealed.Extra)-1] = 1
+	block := finalized.WithSeal(sealed)
+
+	if _, err := readCustomTxStatus(db, tx.Hash(), block.Hash()); err == nil {
+		t.Fatalf("status stored before the block was imported")
+	}
+	if err := engine.WriteBlockRecords(block); err != nil {
+		t.Fatalf("failed to write block records: %v", err)
+	}
+	stored, err := readCustomTxStatus(db, tx.Hash(), block.Hash())
+	if err != nil {
+		t.Fatalf("failed to read status: %v", err)
+	}
+	if !reflect.DeepEqual(stored, status) {
+		t.Errorf("status mismatch: have %+v, want %+v", stored, status)
+	}
+	if err := engine.DeleteBlockRecords(block); err != nil {
+		t.Fatalf("failed to delete block records: %v", err)
+	}
+	if _, err := readCustomTxStatus(db, tx.Hash(), block.Hash()); err == nil {
+		t.Errorf("status left after the block was dropped")
+	}
+}
+
+// Tests that a mint is accepted with the main chain headers from the block of the
+// deposit to a main chain block anchored by the side chain, and only once.
+func TestProcessEventMint(t *testing.T) {
+	db := ethdb.NewMemDatabase()
+	rawdb.WriteChainConfig(db, common.Hash{}, &params.ChainConfig{ChainId: big.NewInt(1), Alien: &params.AlienConfig{BridgeBlock: big.NewInt(0)}})
+	engine := &Alien{config: &params.AlienConfig{AppId: "1", BridgeBlock: big.NewInt(0)}, db: db}
+
+	key, _ := crypto.GenerateKey()
+	recipient := common.HexToAddress("0x03")
+	tx, err := types.SignTx(types.NewTransaction(0, params.BridgeAddress, big.NewInt(100), 50000, big.NewInt(1), []byte("ufo:1:sc:deposit:1:"+recipient.Hex())), types.NewEIP155Signer(big.NewInt(1)), key)
+	if err != nil {
+		t.Fatalf("failed to sign deposit: %v", err)
+	}
+	txs, receipts := types.Transactions{tx}, types.Receipts{types.NewReceipt(nil, false, 50000)}
+	headers := []*types.Header{{Number: big.NewInt(1), TxHash: types.DeriveSha(txs), ReceiptHash: types.DeriveSha(receipts)}}
+	headers = append(headers, &types.Header{Number: big.NewInt(2), ParentHash: headers[0].Hash()})
+
+	proof, err := makeBridgeProof(headers, txs, receipts, 0)
+	if err != nil {
+		t.Fatalf("failed to prove deposit: %v", err)
+	}
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(ethdb.NewMemDatabase()))
+	if err := engine.processEventMint(statedb, (*mintParams)(proof)); err != errBridgeNotFinal {
+		t.Fatalf("unanchored deposit: error mismatch: have %v, want %v", err, errBridgeNotFinal)
+	}
+	// The side chain block anchoring the main chain block is sealed, its anchor
+	// recorded by the next block
+	extra, err := rlp.EncodeToBytes(HeaderExtra{CurrentBlockMainAnchors: []MainAnchor{{Number: 2, Hash: headers[1].Hash()}}})
+	if err != nil {
+		t.Fatalf("failed to encode header extra: %v", err)
+	}
+	anchors, ok := sealedMainAnchors(&types.Header{Extra: append(append(make([]byte, extraVanity), extra...), make([]byte, extraSeal)...)})
+	if !ok || len(anchors) != 1 {
+		t.Fatalf("anchors mismatch: have %v, want 1", anchors)
+	}
+	engine.recordMainAnchors(statedb, anchors)
+
+	unlinked := &bridgeProof{Headers: []*types.Header{headers[0], {Number: big.NewInt(2)}}, TxIndex: proof.TxIndex, TxProof: proof.TxProof, ReceiptProof: proof.ReceiptProof}
+	if err := engine.processEventMint(statedb, (*mintParams)(unlinked)); err != errBridgeProof {
+		t.Errorf("unlinked headers: error mismatch: have %v, want %v", err, errBridgeProof)
+	}
+	if err := engine.processEventMint(statedb, (*mintParams)(proof)); err != nil {
+		t.Fatalf("anchored deposit rejected: %v", err)
+	}
+	if balance := statedb.GetBalance(recipient); balance.Cmp(big.NewInt(100)) != 0 {
+		t.Errorf("recipient balance %v, want 100", balance)
+	}
+	if err := engine.processEventMint(statedb, (*mintParams)(proof)); err != errBridgeRepeat {
+		t.Errorf("repeat mint: error mismatch: have %v, want %v", err, errBridgeRepeat)
+	}
+}
@@ -0,0 +1,373 @@
+// Copyright 2018 The giov Authors
+// This file is part of the giov library.
+//
+// The giov library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The giov library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the giov library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package alien implements the delegated-proof-of-stake consensus engine.
+
+package alien
+
+import (
+	"bytes"
+	"math/big"
+	"sort"
+
+	"github.com/CarLiveChainCo/goiov/common"
+	"github.com/CarLiveChainCo/goiov/log"
+	"github.com/CarLiveChainCo/goiov/params"
+)
+
+// Parameters of the engine the candidates may change by proposals.
+const (
+	proposalParamPeriod                 = "period"
+	proposalParamMaxSignerCount         = "maxSignerCount"
+	proposalParamMinVoteValue           = "minVoteValue"
+	proposalParamSelfVoteValue          = "selfVoteValue"
+	proposalParamFreeze                 = "freeze"
+	proposalParamSignerBlockReward      = "signerBlockReward"
+	proposalParamMinerRewardPerThousand = "minerRewardPerThousand"
+)
+
+const (
+	proposalStatusPending  = "pending"  // the candidates may declare for the proposal
+	proposalStatusPassed   = "passed"   // waiting for the loop boundary it is activated in
+	proposalStatusRejected = "rejected" // not enough stake declared yes, or the value became invalid
+	proposalStatusActive   = "active"   // the proposed value is in effect
+
+	minFreezeBlocks        = 3   // the stake of a cancel is returned 2 blocks before the freeze window ends
+	maxProposalSignerCount = 100 // Max signer count a proposal may set
+)
+
+// GovernedParams are the engine parameters which the candidates may change by
+// proposals. A new snapshot takes them from the AlienConfig; the block period is
+// kept in Snapshot.Period.
+type GovernedParams struct {
+	MaxSignerCount         uint64   `json:"maxSignerCount"`         // Max count of signers
+	MinVoteValue           *big.Int `json:"minVoteValue"`           // Min vote value to valid this vote
+	SelfVoteValue          *big.Int `json:"selfVoteValue"`          // Min value of a self vote
+	Freeze                 uint64   `json:"freeze"`                 // Number of seconds the stake is frozen after a cancel or unstake
+	SignerBlockReward      *big.Int `json:"signerBlockReward"`      // Block reward in wei for the first year
+	MinerRewardPerThousand uint64   `json:"minerRewardPerThousand"` // Per mille of the block reward for the miner
+}
+
+// newGovernedParams returns the governed parameters set in the config.
+func newGovernedParams(config *params.AlienConfig) *GovernedParams {
+	return &GovernedParams{
+		MaxSignerCount:         config.MaxSignerCount,
+		MinVoteValue:           new(big.Int).Set(config.MinVoteValue),
+		SelfVoteValue:          new(big.Int).Set(config.SelfVoteValue),
+		Freeze:                 config.Freeze,
+		SignerBlockReward:      new(big.Int).Set(SignerBlockReward),
+		MinerRewardPerThousand: MinerRewardPerThousand,
+	}
+}
+
+func (p *GovernedParams) copy() *GovernedParams {
+	return &GovernedParams{
+		MaxSignerCount:         p.MaxSignerCount,
+		MinVoteValue:           new(big.Int).Set(p.MinVoteValue),
+		SelfVoteValue:          new(big.Int).Set(p.SelfVoteValue),
+		Freeze:                 p.Freeze,
+		SignerBlockReward:      new(big.Int).Set(p.SignerBlockReward),
+		MinerRewardPerThousand: p.MinerRewardPerThousand,
+	}
+}
+
+// ProposalRecord is a proposal with the declares of the candidates for it. The
+// stake of the declares is counted when the deadline is reached; a passed
+// proposal is activated in the first loop boundary from ActivationNumber on.
+type ProposalRecord struct {
+	Hash             common.Hash             `json:"hash"`             // Hash of the proposal tx
+	Proposer         common.Address          `json:"proposer"`         // Candidate which made the proposal
+	Parameter        string                  `json:"parameter"`        // Governed parameter to change
+	Value            *big.Int                `json:"value"`            // Proposed value of the parameter
+	Number           uint64                  `json:"number"`           // Block number the proposal was made in
+	Deadline         uint64                  `json:"deadline"`         // Last block number the candidates may declare in
+	Declares         map[common.Address]bool `json:"declares"`         // Decision of each declared candidate
+	YesStake         *big.Int                `json:"yesStake"`         // Tally of the candidates declared yes
+	NoStake          *big.Int                `json:"noStake"`          // Tally of the candidates declared no
+	TotalStake       *big.Int                `json:"totalStake"`       // Tally of all candidates
+	Status           string                  `json:"status"`           // Status of the proposal
+	ActivationNumber uint64                  `json:"activationNumber"` // Block number the value is activated in, or the earliest one if passed
+}
+
+func (p *ProposalRecord) copy() *ProposalRecord {
+	cpy := *p
+	cpy.Declares = make(map[common.Address]bool, len(p.Declares))
+	for declarer, decision := range p.Declares {
+		cpy.Declares[declarer] = decision
+	}
+	return &cpy
+}
+
+// freezeBlocks returns the number of blocks the stake of a canceled vote is frozen for.
+func (s *Snapshot) freezeBlocks() uint64 {
+	return s.Params.Freeze / s.Period
+}
+
+// isActiveCandidate reports whether the address is a candidate with a tally
+// which has not canceled its self vote.
+func (s *Snapshot) isActiveCandidate(address common.Address) bool {
+	if _, ok := s.Tally[address]; !ok || !s.isCandidate(address) {
+		return false
+	}
+	_, canceled := s.Cancels[address]
+	return !canceled
+}
+
+// checkProposalValue checks the value of a governed parameter against the
+// current values of the others.
+func (s *Snapshot) checkProposalValue(parameter string, value *big.Int) error {
+	if value == nil || value.Sign() < 0 {
+		return errProposalValue
+	}
+	switch parameter {
+	case proposalParamPeriod:
+		if !value.IsUint64() || value.Uint64() == 0 || s.Params.Freeze/value.Uint64() < minFreezeBlocks {
+			return errProposalValue
+		}
+	case proposalParamMaxSignerCount:
+		if !value.IsUint64() || value.Uint64() == 0 || value.Uint64() > maxProposalSignerCount {
+			return errProposalValue
+		}
+	case proposalParamMinVoteValue, proposalParamSelfVoteValue:
+		if value.Sign() == 0 {
+			return errProposalValue
+		}
+	case proposalParamFreeze:
+		if !value.IsUint64() || value.Uint64()/s.Period < minFreezeBlocks {
+			return errProposalValue
+		}
+	case proposalParamSignerBlockReward:
+		// the reward of a chain with a reward schedule is fixed by it
+		if len(s.config.RewardSchedule) > 0 {
+			return errProposalParameter
+		}
+	case proposalParamMinerRewardPerThousand:
+		if len(s.config.RewardSchedule) > 0 {
+			return errProposalParameter
+		}
+		if !value.IsUint64() || value.Uint64() > 1000 {
+			return errProposalValue
+		}
+	default:
+		return errProposalParameter
+	}
+	return nil
+}
+
+// checkProposal checks whether the proposer may propose the value of the parameter.
+func (s *Snapshot) checkProposal(proposer common.Address, parameter string, value *big.Int) error {
+	if !s.isActiveCandidate(proposer) {
+		return errProposerNotCandidate
+	}
+	if err := s.checkProposalValue(parameter, value); err != nil {
+		return err
+	}
+	for _, proposal := range s.Proposals {
+		if proposal.Parameter == parameter && (proposal.Status == proposalStatusPending || proposal.Status == proposalStatusPassed) {
+			return errProposalRepeat
+		}
+	}
+	return nil
+}
+
+// checkDeclare checks whether the declarer may declare for the proposal in the
+// block with the given number.
+func (s *Snapshot) checkDeclare(declarer common.Address, hash common.Hash, number uint64) error {
+	proposal, ok := s.Proposals[hash]
+	if !ok {
+		return errDeclareUnknown
+	}
+	if proposal.Status != proposalStatusPending || number > proposal.Deadline {
+		return errDeclareClosed
+	}
+	if !s.isActiveCandidate(declarer) {
+		return errDeclarerNotCandidate
+	}
+	if _, ok := proposal.Declares[declarer]; ok {
+		return errDeclareRepeat
+	}
+	return nil
+}
+
+func (s *Snapshot) updateSnapshotByProposals(proposals []Proposal, headerNumber *big.Int) {
+	for _, proposal := range proposals {
+		if err := s.checkProposal(proposal.Proposer, proposal.Parameter, proposal.Value); err != nil {
+			log.Warn("Invalid proposal", "hash", proposal.Hash, "err", err)
+			continue
+		}
+		s.Proposals[proposal.Hash] = &ProposalRecord{
+			Hash:      proposal.Hash,
+			Proposer:  proposal.Proposer,
+			Parameter: proposal.Parameter,
+			Value:     new(big.Int).Set(proposal.Value),
+			Number:    headerNumber.Uint64(),
+			Deadline:  headerNumber.Uint64() + s.config.ProposalDeadline/s.Period,
+			Declares:  make(map[common.Address]bool),
+			Status:    proposalStatusPending,
+		}
+	}
+}
+
+func (s *Snapshot) updateSnapshotByDeclares(declares []Declare, headerNumber *big.Int) {
+	for _, declare := range declares {
+		if err := s.checkDeclare(declare.Declarer, declare.ProposalHash, headerNumber.Uint64()); err != nil {
+			log.Warn("Invalid declare", "proposal", declare.ProposalHash, "declarer", declare.Declarer, "err", err)
+			continue
+		}
+		s.Proposals[declare.ProposalHash].Declares[declare.Declarer] = declare.Decision
+	}
+}
+
+// updateSnapshotForProposals counts the declares of the proposals whose deadline
+// is reached in the block, and activates the passed proposals if the block is a
+// loop boundary. A change of the max signer count is only activated in a block
+// which is a loop boundary for both counts, so the loops stay aligned.
+func (s *Snapshot) updateSnapshotForProposals(headerNumber *big.Int) {
+	number := headerNumber.Uint64()
+	maxSignerCount := s.Params.MaxSignerCount
+	for _, proposal := range s.sortedProposals() {
+		switch proposal.Status {
+		case proposalStatusPending:
+			if number >= proposal.Deadline {
+				s.decideProposal(proposal, number)
+			}
+		case proposalStatusPassed:
+			if number < proposal.ActivationNumber || number%maxSignerCount != 0 {
+				continue
+			}
+			if proposal.Parameter == proposalParamMaxSignerCount && number%proposal.Value.Uint64() != 0 {
+				continue
+			}
+			s.activateProposal(proposal, number)
+		}
+	}
+}
+
+// proposalTally returns the tally of the candidates which declared yes and no
+// for the proposal, and the tally of all candidates.
+func (s *Snapshot) proposalTally(proposal *ProposalRecord) (*big.Int, *big.Int, *big.Int) {
+	yes, no, total := new(big.Int), new(big.Int), new(big.Int)
+	for candidate, tally := range s.Tally {
+		if !s.isActiveCandidate(candidate) {
+			continue
+		}
+		total.Add(total, tally)
+		if decision, ok := proposal.Declares[candidate]; ok {
+			if decision {
+				yes.Add(yes, tally)
+			} else {
+				no.Add(no, tally)
+			}
+		}
+	}
+	return yes, no, total
+}
+
+// decideProposal passes the proposal if the candidates which declared yes hold
+// more than two thirds of the tally, otherwise it is rejected.
+func (s *Snapshot) decideProposal(proposal *ProposalRecord, number uint64) {
+	proposal.YesStake, proposal.NoStake, proposal.TotalStake = s.proposalTally(proposal)
+
+	required := new(big.Int).Mul(proposal.TotalStake, big.NewInt(2))
+	if new(big.Int).Mul(proposal.YesStake, big.NewInt(3)).Cmp(required) <= 0 || s.checkProposalValue(proposal.Parameter, proposal.Value) != nil {
+		proposal.Status = proposalStatusRejected
+		return
+	}
+	step := s.Params.MaxSignerCount
+	if proposal.Parameter == proposalParamMaxSignerCount {
+		step = lcm(step, proposal.Value.Uint64())
+	}
+	proposal.Status = proposalStatusPassed
+	proposal.ActivationNumber = (number/step + 1) * step
+}
+
+// activateProposal sets the proposed value from the next block on. The frozen
+// stake is returned in the block recorded when it was frozen, whatever the new
+// freeze window in blocks.
+func (s *Snapshot) activateProposal(proposal *ProposalRecord, number uint64) {
+	if err := s.checkProposalValue(proposal.Parameter, proposal.Value); err != nil {
+		log.Warn("Proposal value became invalid", "hash", proposal.Hash, "err", err)
+		proposal.Status = proposalStatusRejected
+		return
+	}
+	switch proposal.Parameter {
+	case proposalParamPeriod:
+		s.Period = proposal.Value.Uint64()
+	case proposalParamMaxSignerCount:
+		s.Params.MaxSignerCount = proposal.Value.Uint64()
+	case proposalParamMinVoteValue:
+		s.Params.MinVoteValue = new(big.Int).Set(proposal.Value)
+	case proposalParamSelfVoteValue:
+		s.Params.SelfVoteValue = new(big.Int).Set(proposal.Value)
+	case proposalParamFreeze:
+		s.Params.Freeze = proposal.Value.Uint64()
+	case proposalParamSignerBlockReward:
+		s.Params.SignerBlockReward = new(big.Int).Set(proposal.Value)
+	case proposalParamMinerRewardPerThousand:
+		s.Params.MinerRewardPerThousand = proposal.Value.Uint64()
+	}
+	proposal.Status = proposalStatusActive
+	proposal.ActivationNumber = number
+	log.Info("Proposal activated", "parameter", proposal.Parameter, "value", proposal.Value, "number", number)
+}
+
+// sortedProposals returns the proposals in the order they were made.
+func (s *Snapshot) sortedProposals() []*ProposalRecord {
+	proposals := make([]*ProposalRecord, 0, len(s.Proposals))
+	for _, proposal := range s.Proposals {
+		proposals = append(proposals, proposal)
+	}
+	sort.Slice(proposals, func(i, j int) bool {
+		if proposals[i].Number != proposals[j].Number {
+			return proposals[i].Number < proposals[j].Number
+		}
+		return bytes.Compare(proposals[i].Hash[:], proposals[j].Hash[:]) < 0
+	})
+	return proposals
+}
+
+// proposal returns a copy of the proposal, counting the tally of the declares of
+// a pending one as of the snapshot.
+func (s *Snapshot) proposal(hash common.Hash) (*ProposalRecord, bool) {
+	proposal, ok := s.Proposals[hash]
+	if !ok {
+		return nil, false
+	}
+	cpy := proposal.copy()
+	if cpy.Status == proposalStatusPending {
+		cpy.YesStake, cpy.NoStake, cpy.TotalStake = s.proposalTally(cpy)
+	}
+	return cpy, true
+}
+
+// proposalList returns copies of the proposals in the order they were made.
+func (s *Snapshot) proposalList() []*ProposalRecord {
+	var proposals []*ProposalRecord
+	for _, proposal := range s.sortedProposals() {
+		cpy, _ := s.proposal(proposal.Hash)
+		proposals = append(proposals, cpy)
+	}
+	return proposals
+}
+
+// lcm returns the least common multiple of a and b.
+func lcm(a, b uint64) uint64 {
+	x, y := a, b
+	for y != 0 {
+		x, y = y, x%y
+	}
+	return a / x * b
+}
@@ -0,0 +1,186 @@
+// Copyright 2018 The giov Authors
+// This file is part of the giov library.
+//
+// The giov library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The giov library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the giov library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package alien implements the delegated-proof-of-stake consensus engine.
+
+package alien
+
+import (
+	"encoding/binary"
+	"math/big"
+
+	"github.com/CarLiveChainCo/goiov/accounts"
+	"github.com/CarLiveChainCo/goiov/common"
+	"github.com/CarLiveChainCo/goiov/core"
+	"github.com/CarLiveChainCo/goiov/core/rawdb"
+	"github.com/CarLiveChainCo/goiov/core/types"
+	"github.com/CarLiveChainCo/goiov/ethdb"
+	"github.com/CarLiveChainCo/goiov/log"
+	"github.com/CarLiveChainCo/goiov/params"
+)
+
+const (
+	relayProgressPrefix = "alien-relay-" // relayProgressPrefix + appId -> number of the last block the messages of the app chain were relayed from
+	relayBlocksPerRound = 128            // Maximum number of blocks of an app chain scanned for messages on a new main chain head
+)
+
+// relayMessages is the message relayer of a signing node, run by the main chain
+// engine. On every new main chain head it delivers the messages sent from the
+// final blocks of the app chains of the node to their destination chains, if
+// the node runs them. A message is relayed once, a message the relayer missed
+// is delivered with the data from alien_getAppMessageDeliveryData.
+func (a *Alien) relayMessages() {
+	main, ok := a.eth.SideBlockChain("")
+	if !ok || main == nil {
+		return
+	}
+	headCh := make(chan core.ChainHeadEvent, 16)
+	sub := main.SubscribeChainHeadEvent(headCh)
+	defer sub.Unsubscribe()
+
+	for {
+		select {
+		case <-headCh:
+			for appId := range rawdb.ReadAllChainConfig(a.db) {
+				a.relayChainMessages(main, appId)
+			}
+		case <-sub.Err():
+			return
+		}
+	}
+}
+
+// relayChainMessages relays the messages of the final blocks of the app chain
+// appId above the last block relayed.
+func (a *Alien) relayChainMessages(main *core.BlockChain, appId string) {
+	source, ok := a.eth.SideBlockChain(appId)
+	if !ok || source == nil {
+		return
+	}
+	var (
+		checkpoint *SideCheckpoint
+		final      = main.FinalizedNumber()
+	)
+	if appId != "" {
+		var err error
+		if checkpoint, err = a.latestCheckpoint(appId); err != nil || checkpoint.MainNumber > final {
+			return
+		}
+		final = checkpoint.Number
+	}
+	from, ok := readRelayProgress(a.db, appId)
+	if !ok {
+		// Only relay the messages sent from now on, the older ones are delivered by hand
+		writeRelayProgress(a.db, appId, final)
+		return
+	}
+	if final > from+relayBlocksPerRound {
+		final = from + relayBlocksPerRound
+	}
+	for number := from + 1; number <= final; number++ {
+		block := source.GetBlockByNumber(number)
+		if block == nil {
+			return
+		}
+		receipts := rawdb.ReadReceipts(a.db, block.Hash(), number, appId)
+		if len(receipts) != len(block.Transactions()) {
+			return
+		}
+		for i, receipt := range receipts {
+			for _, l := range receipt.Logs {
+				if l.Address == params.MessageAddress {
+					// Retry on a later head once the destination chains anchored the block
+					if err := a.relayTxMessages(appId, block.Transactions()[i].Hash(), checkpoint); err == errBridgeNotFinal {
+						return
+					}
+					break
+				}
+			}
+		}
+		writeRelayProgress(a.db, appId, number)
+	}
+}
+
+// relayTxMessages sends the transactions delivering the messages of the given
+// transaction to the pools of their destination chains.
+func (a *Alien) relayTxMessages(appId string, hash common.Hash, checkpoint *SideCheckpoint) error {
+	a.lock.RLock()
+	signer, signTxFn := a.signer, a.signTxFn
+	a.lock.RUnlock()
+
+	if signer == (common.Address{}) || signTxFn == nil {
+		return nil
+	}
+	msgs, proofs, err := a.makeMessageProofs(appId, hash, checkpoint)
+	if err != nil {
+		log.Debug("Failed to prove app messages", "appId", appId, "tx", hash, "err", err)
+		return err
+	}
+	for i, msg := range msgs {
+		dest, ok := a.eth.SideBlockChain(msg.AppId)
+		pool := a.eth.SideTxPool(msg.AppId)
+		if !ok || dest == nil || pool == nil || proofs[i] == nil {
+			continue
+		}
+		if state, err := dest.State(); err != nil || state.GetState(params.MessageAddress, messageDoneKey(appId, msg.Hash())) != (common.Hash{}) {
+			continue
+		}
+		data, err := encodeBridgeTx(ufoEventDeliver, proofs[i])
+		if err != nil {
+			continue
+		}
+		gas, err := core.IntrinsicGas(data, false, true)
+		if err != nil {
+			continue
+		}
+		tx := types.NewTransaction(pool.State().GetNonce(signer), signer, new(big.Int), gas, pool.GasPrice(), data, msg.AppId)
+		signedTx, err := signTxFn(accounts.Account{Address: signer}, tx, dest.Config().ChainId)
+		if err != nil {
+			log.Info("deliver tx sign fail", "err", err)
+			return nil
+		}
+		if err := pool.AddLocal(signedTx); err != nil {
+			log.Info("deliver tx send fail", "appId", msg.AppId, "message", msg.Hash(), "err", err)
+		} else {
+			log.Info("deliver tx result", "appId", msg.AppId, "message", msg.Hash(), "hash", signedTx.Hash())
+		}
+	}
+	return nil
+}
+
+func relayProgressKey(appId string) []byte {
+	return []byte(relayProgressPrefix + appId)
+}
+
+// readRelayProgress retrieves the number of the last block of the app chain the
+// messages were relayed from.
+func readRelayProgress(db ethdb.Database, appId string) (uint64, bool) {
+	blob, err := db.Get(relayProgressKey(appId))
+	if err != nil || len(blob) != 8 {
+		return 0, false
+	}
+	return binary.BigEndian.Uint64(blob), true
+}
+
+// writeRelayProgress stores the number of the last block of the app chain the
+// messages were relayed from.
+func writeRelayProgress(db ethdb.Database, appId string, number uint64) {
+	blob := make([]byte, 8)
+	binary.BigEndian.PutUint64(blob, number)
+	if err := db.Put(relayProgressKey(appId), blob); err != nil {
+		log.Warn("Failed to store relay progress", "appId", appId, "err", err)
+	}
+}
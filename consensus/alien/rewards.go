@@ -0,0 +1,414 @@
+// Copyright 2018 The giov Authors
+// This file is part of the giov library.
+//
+// The giov library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The giov library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the giov library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package alien implements the delegated-proof-of-stake consensus engine.
+
+package alien
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math/big"
+	"sort"
+
+	"github.com/CarLiveChainCo/goiov/common"
+	"github.com/CarLiveChainCo/goiov/consensus"
+	"github.com/CarLiveChainCo/goiov/core/types"
+	"github.com/CarLiveChainCo/goiov/ethdb"
+	"github.com/CarLiveChainCo/goiov/log"
+	"github.com/CarLiveChainCo/goiov/rlp"
+)
+
+const (
+	rewardPrefix          = "alien-reward-" // rewardPrefix + "b" + appId + number -> RewardRecord
+	rewardDayPrefix       = "d"             // rewardPrefix + "d" + appId + address + day -> rewardBucket
+	rewardBlockPrefix     = "b"
+	secondsPerDay         = 24 * 3600
+	maxRewardHistoryRange = 100000 // Max number of blocks scanned by one reward history query
+)
+
+var (
+	// errRewardIndexDisabled is returned if the reward history is requested from
+	// a node which does not keep the reward index.
+	errRewardIndexDisabled = errors.New("reward index disabled")
+
+	// errInvalidRewardRange is returned if a reward history query has an invalid block range.
+	errInvalidRewardRange = errors.New("invalid reward block range")
+
+	// errUnknownRewardPeriod is returned if a reward summary is requested for an unknown period.
+	errUnknownRewardPeriod = errors.New("unknown reward period")
+
+	// rewardPeriods are the periods accepted by the reward summary, in seconds.
+	rewardPeriods = map[string]uint64{
+		"day":   secondsPerDay,
+		"week":  7 * secondsPerDay,
+		"month": 30 * secondsPerDay,
+		"year":  365 * secondsPerDay,
+	}
+)
+
+// BlockReward is the split of the reward of a block between the treasury, the
+// miner and the voters of the miner.
+type BlockReward struct {
+	Total          *big.Int                    // Reward of the block
+	Treasury       common.Address              // Treasury of the chain, if any
+	TreasuryReward *big.Int                    // Part of the reward paid to the treasury
+	Miner          common.Address              // Coinbase of the block
+	MinerReward    *big.Int                    // Part of the reward paid to the miner
+	VoterRewards   map[common.Address]*big.Int // Part of the reward paid to each voter of the miner
+}
+
+// blockReward returns the reward of the block with the given number on top of
+// the snapshot, and the per mille of it for the miner. Without a reward
+// schedule the signer block reward halves every year, and a side chain pays
+// all of it to the miner. The reward is cut to what is left below the supply cap.
+func (s *Snapshot) blockReward(number uint64) (*big.Int, uint64) {
+	var (
+		reward     *big.Int
+		minerShare uint64
+	)
+	if len(s.config.RewardSchedule) > 0 {
+		reward = new(big.Int)
+		if step, ok := s.config.RewardStepAt(number); ok && step.Reward != nil {
+			reward.Set(step.Reward)
+			minerShare = step.MinerShare
+		}
+	} else {
+		blockNumPerYear := SecondsPerYear / s.Period
+		yearCount := number / blockNumPerYear
+		reward = new(big.Int).Rsh(s.Params.SignerBlockReward, uint(yearCount))
+		minerShare = s.Params.MinerRewardPerThousand
+		if s.config.SideChain {
+			minerShare = 1000
+		}
+	}
+	if minerShare > 1000 {
+		minerShare = 1000
+	}
+	if supplyCap := s.config.RewardSupplyCap; supplyCap != nil {
+		left := new(big.Int).Sub(supplyCap, s.Minted)
+		if left.Sign() < 0 {
+			left.SetUint64(0)
+		}
+		if reward.Cmp(left) > 0 {
+			reward = left
+		}
+	}
+	return reward, minerShare
+}
+
+// BlockReward returns the split of the reward of the header, on top of the
+// snapshot of its parent. The engine credits the block reward from it and the
+// block rewards RPC reports it, so they never disagree.
+func (s *Snapshot) BlockReward(header *types.Header) *BlockReward {
+	total, minerShare := s.blockReward(header.Number.Uint64())
+	split := &BlockReward{
+		Total:          total,
+		TreasuryReward: new(big.Int),
+		Miner:          header.Coinbase,
+		VoterRewards:   make(map[common.Address]*big.Int),
+	}
+	reward := new(big.Int).Set(total)
+	if s.config.Treasury != nil && s.config.TreasuryShare > 0 {
+		share := s.config.TreasuryShare
+		if share > 1000 {
+			share = 1000
+		}
+		split.Treasury = *s.config.Treasury
+		split.TreasuryReward.Mul(reward, new(big.Int).SetUint64(share))
+		split.TreasuryReward.Div(split.TreasuryReward, big.NewInt(1000))
+		reward.Sub(reward, split.TreasuryReward)
+	}
+	split.MinerReward = new(big.Int).Mul(reward, new(big.Int).SetUint64(minerShare))
+	split.MinerReward.Div(split.MinerReward, big.NewInt(1000)) // cause the reward is calculate by cnt per thousand
+
+	votersReward := reward.Sub(reward, split.MinerReward)
+	if votersReward.Sign() > 0 {
+		split.VoterRewards = s.calculateReward(header.Coinbase, votersReward)
+		// with a reward schedule the share of a miner without voters is not burned
+		if len(split.VoterRewards) == 0 && len(s.config.RewardSchedule) > 0 {
+			split.MinerReward.Add(split.MinerReward, votersReward)
+		}
+	}
+	return split
+}
+
+// VoterReward is the reward credited to one voter in a block.
+type VoterReward struct {
+	Voter  common.Address
+	Reward *big.Int
+}
+
+// RewardRecord is the reward split of one block as credited by accumulateRewards.
+type RewardRecord struct {
+	Number       uint64
+	Time         uint64
+	ParentHash   common.Hash
+	Miner        common.Address
+	MinerReward  *big.Int
+	VoterRewards []VoterReward
+}
+
+// rewards returns the miner and voter reward of the address in the record.
+func (r *RewardRecord) rewards(address common.Address) (*big.Int, *big.Int) {
+	minerReward, voterReward := new(big.Int), new(big.Int)
+	if r.Miner == address {
+		minerReward.Set(r.MinerReward)
+	}
+	for _, reward := range r.VoterRewards {
+		if reward.Voter == address {
+			voterReward.Set(reward.Reward)
+		}
+	}
+	return minerReward, voterReward
+}
+
+// addresses returns every address rewarded in the record.
+func (r *RewardRecord) addresses() []common.Address {
+	addresses := []common.Address{r.Miner}
+	for _, reward := range r.VoterRewards {
+		if reward.Voter != r.Miner {
+			addresses = append(addresses, reward.Voter)
+		}
+	}
+	return addresses
+}
+
+// rewardBucket is the reward credited to an address during one day.
+type rewardBucket struct {
+	Blocks      uint64
+	MinerReward *big.Int
+	VoterReward *big.Int
+}
+
+// RewardEntry is the reward of an address in one block.
+type RewardEntry struct {
+	BlockNumber uint64   `json:"blockNumber"`
+	Time        uint64   `json:"time"`
+	MinerReward *big.Int `json:"minerReward"`
+	VoterReward *big.Int `json:"voterReward"`
+}
+
+// RewardSummary is the reward of an address accumulated over a period.
+type RewardSummary struct {
+	Address     common.Address `json:"address"`
+	Period      string         `json:"period"`
+	FromTime    uint64         `json:"fromTime"`
+	ToTime      uint64         `json:"toTime"`
+	Blocks      uint64         `json:"blocks"`
+	MinerReward *big.Int       `json:"minerReward"`
+	VoterReward *big.Int       `json:"voterReward"`
+	Total       *big.Int       `json:"total"`
+}
+
+// EnableRewardIndex makes the engine record the reward split of every block it
+// finalizes. Records older than retain blocks are pruned, 0 keeps all of them.
+func (a *Alien) EnableRewardIndex(retain uint64) {
+	a.rewardLock.Lock()
+	defer a.rewardLock.Unlock()
+
+	a.rewardIndex = true
+	a.rewardRetain = retain
+}
+
+func rewardRecordKey(appId string, number uint64) []byte {
+	enc := make([]byte, 8)
+	binary.BigEndian.PutUint64(enc, number)
+	return append(append([]byte(rewardPrefix+rewardBlockPrefix), []byte(appId)...), enc...)
+}
+
+func rewardBucketKey(appId string, address common.Address, day uint64) []byte {
+	enc := make([]byte, 8)
+	binary.BigEndian.PutUint64(enc, day)
+	key := append([]byte(rewardPrefix+rewardDayPrefix), []byte(appId)...)
+	return append(append(key, address[:]...), enc...)
+}
+
+// readRewardRecord retrieves the reward record of the block with the given number.
+func readRewardRecord(db ethdb.Database, appId string, number uint64) *RewardRecord {
+	blob, err := db.Get(rewardRecordKey(appId, number))
+	if err != nil {
+		return nil
+	}
+	record := new(RewardRecord)
+	if err := rlp.DecodeBytes(blob, record); err != nil {
+		log.Error("Invalid reward record RLP", "number", number, "err", err)
+		return nil
+	}
+	return record
+}
+
+// readRewardBucket retrieves the reward of the address during the given day.
+func readRewardBucket(db ethdb.Database, appId string, address common.Address, day uint64) *rewardBucket {
+	bucket := &rewardBucket{MinerReward: new(big.Int), VoterReward: new(big.Int)}
+	blob, err := db.Get(rewardBucketKey(appId, address, day))
+	if err != nil {
+		return bucket
+	}
+	if err := rlp.DecodeBytes(blob, bucket); err != nil {
+		log.Error("Invalid reward bucket RLP", "address", address, "day", day, "err", err)
+		return &rewardBucket{MinerReward: new(big.Int), VoterReward: new(big.Int)}
+	}
+	return bucket
+}
+
+// updateRewardBuckets adds (or with sign -1 removes) the rewards of the record
+// to the daily buckets of the rewarded addresses.
+func updateRewardBuckets(db ethdb.Database, batch ethdb.Batch, appId string, record *RewardRecord, sign int) error {
+	day := record.Time / secondsPerDay
+	for _, address := range record.addresses() {
+		bucket := readRewardBucket(db, appId, address, day)
+		minerReward, voterReward := record.rewards(address)
+		if sign < 0 {
+			if bucket.Blocks > 0 {
+				bucket.Blocks--
+			}
+			bucket.MinerReward.Sub(bucket.MinerReward, minerReward)
+			bucket.VoterReward.Sub(bucket.VoterReward, voterReward)
+		} else {
+			bucket.Blocks++
+			bucket.MinerReward.Add(bucket.MinerReward, minerReward)
+			bucket.VoterReward.Add(bucket.VoterReward, voterReward)
+		}
+		if bucket.MinerReward.Sign() < 0 || bucket.VoterReward.Sign() < 0 {
+			bucket.MinerReward, bucket.VoterReward = new(big.Int), new(big.Int)
+		}
+		blob, err := rlp.EncodeToBytes(bucket)
+		if err != nil {
+			return err
+		}
+		if err := batch.Put(rewardBucketKey(appId, address, day), blob); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// storeRewardRecord writes the reward record of a block into the reward index,
+// replacing the record of a block previously finalized at the same height.
+func (a *Alien) storeRewardRecord(record *RewardRecord) {
+	a.rewardLock.Lock()
+	defer a.rewardLock.Unlock()
+
+	if !a.rewardIndex {
+		return
+	}
+	sort.Slice(record.VoterRewards, func(i, j int) bool {
+		return bytes.Compare(record.VoterRewards[i].Voter[:], record.VoterRewards[j].Voter[:]) < 0
+	})
+	blob, err := rlp.EncodeToBytes(record)
+	if err != nil {
+		log.Error("Failed to encode reward record", "number", record.Number, "err", err)
+		return
+	}
+	batch := a.store.NewBatch()
+	if old := readRewardRecord(a.store, a.config.AppId, record.Number); old != nil {
+		if err := updateRewardBuckets(a.store, batch, a.config.AppId, old, -1); err != nil {
+			log.Error("Failed to update reward buckets", "number", record.Number, "err", err)
+			return
+		}
+		if err := batch.Write(); err != nil {
+			log.Error("Failed to store reward record", "number", record.Number, "err", err)
+			return
+		}
+		batch.Reset()
+	}
+	if err := updateRewardBuckets(a.store, batch, a.config.AppId, record, 1); err != nil {
+		log.Error("Failed to update reward buckets", "number", record.Number, "err", err)
+		return
+	}
+	batch.Put(rewardRecordKey(a.config.AppId, record.Number), blob)
+	if err := batch.Write(); err != nil {
+		log.Error("Failed to store reward record", "number", record.Number, "err", err)
+		return
+	}
+	// prune the records beyond the retained range, the daily buckets are kept
+	if a.rewardRetain > 0 && record.Number > a.rewardRetain {
+		a.store.Delete(rewardRecordKey(a.config.AppId, record.Number-a.rewardRetain))
+	}
+}
+
+// rewardHistory returns the rewards of the address in the canonical blocks of
+// the given range which are still kept in the reward index.
+func (a *Alien) rewardHistory(chain consensus.ChainReader, address common.Address, fromBlock uint64, toBlock uint64) ([]*RewardEntry, error) {
+	if !a.rewardIndex {
+		return nil, errRewardIndexDisabled
+	}
+	if toBlock < fromBlock || toBlock-fromBlock >= maxRewardHistoryRange {
+		return nil, errInvalidRewardRange
+	}
+	entries := []*RewardEntry{}
+	for number := fromBlock; number <= toBlock; number++ {
+		record := readRewardRecord(a.store, a.config.AppId, number)
+		if record == nil {
+			continue
+		}
+		// skip records of blocks which were finalized but are not canonical
+		header := chain.GetHeaderByNumber(number)
+		if header == nil || header.ParentHash != record.ParentHash || header.Coinbase != record.Miner {
+			continue
+		}
+		minerReward, voterReward := record.rewards(address)
+		if minerReward.Sign() == 0 && voterReward.Sign() == 0 {
+			continue
+		}
+		entries = append(entries, &RewardEntry{
+			BlockNumber: number,
+			Time:        record.Time,
+			MinerReward: minerReward,
+			VoterReward: voterReward,
+		})
+	}
+	return entries, nil
+}
+
+// rewardSummary returns the rewards of the address accumulated over the period
+// ending at the current head.
+func (a *Alien) rewardSummary(chain consensus.ChainReader, address common.Address, period string) (*RewardSummary, error) {
+	if !a.rewardIndex {
+		return nil, errRewardIndexDisabled
+	}
+	seconds, ok := rewardPeriods[period]
+	if !ok {
+		return nil, errUnknownRewardPeriod
+	}
+	header := chain.CurrentHeader()
+	if header == nil {
+		return nil, errUnknownBlock
+	}
+	toDay := header.Time.Uint64() / secondsPerDay
+	fromDay := toDay + 1 - seconds/secondsPerDay
+	if seconds/secondsPerDay > toDay {
+		fromDay = 0
+	}
+	summary := &RewardSummary{
+		Address:     address,
+		Period:      period,
+		FromTime:    fromDay * secondsPerDay,
+		ToTime:      header.Time.Uint64(),
+		MinerReward: new(big.Int),
+		VoterReward: new(big.Int),
+	}
+	for day := fromDay; day <= toDay; day++ {
+		bucket := readRewardBucket(a.store, a.config.AppId, address, day)
+		summary.Blocks += bucket.Blocks
+		summary.MinerReward.Add(summary.MinerReward, bucket.MinerReward)
+		summary.VoterReward.Add(summary.VoterReward, bucket.VoterReward)
+	}
+	summary.Total = new(big.Int).Add(summary.MinerReward, summary.VoterReward)
+	return summary, nil
+}
@@ -0,0 +1,181 @@
+// Copyright 2018 The giov Authors
+// This file is part of the giov library.
+//
+// The giov library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The giov library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the giov library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package alien implements the delegated-proof-of-stake consensus engine.
+
+package alien
+
+import (
+	"errors"
+	"time"
+
+	"github.com/CarLiveChainCo/goiov/common"
+	"github.com/CarLiveChainCo/goiov/consensus"
+	"github.com/CarLiveChainCo/goiov/rlp"
+)
+
+const (
+	maxSignerScheduleCount = 1024  // Max number of slots forecast by one signer schedule query
+	maxSignerStatsRange    = 10000 // Max number of blocks scanned by one signer stats query
+)
+
+var (
+	// errInvalidScheduleCount is returned if a signer schedule is requested for
+	// no slots or too many of them.
+	errInvalidScheduleCount = errors.New("invalid signer schedule slot count")
+
+	// errInvalidStatsRange is returned if a signer stats query has an invalid block range.
+	errInvalidStatsRange = errors.New("invalid signer stats block range")
+
+	// errNoSignerQueue is returned if the signer schedule is requested while the
+	// snapshot has no signer queue to forecast it from.
+	errNoSignerQueue = errors.New("no signer queue")
+)
+
+// SignerSlot is a slot of the signer queue, the time a block is due and the
+// signer expected to seal it.
+type SignerSlot struct {
+	Time   uint64         `json:"time"`   // Time the block of the slot is due
+	Index  uint64         `json:"index"`  // Index of the slot in the signer queue
+	Signer common.Address `json:"signer"` // Signer in turn in the slot
+	Final  bool           `json:"final"`  // Whether the slot is in the current loop, later loops may get a new signer queue
+}
+
+// SignerStats is the activity of a signer over a range of blocks.
+type SignerStats struct {
+	Address   common.Address  `json:"address"`
+	FromBlock uint64          `json:"fromBlock"`
+	ToBlock   uint64          `json:"toBlock"`
+	Blocks    uint64          `json:"blocks"`    // Blocks in the range
+	Produced  uint64          `json:"produced"`  // Blocks sealed by the signer
+	Missed    uint64          `json:"missed"`    // Slots of the signer missed, as recorded by the headers
+	Confirmed uint64          `json:"confirmed"` // Confirmations of the signer included in the blocks
+	Credits   []*SignerCredit `json:"credits"`   // Punishment credit of the signer, whenever it changed
+}
+
+// SignerCredit is the punishment credit of a signer as of a block, the higher
+// the credit the lower the weight of the signer in the next signer queue.
+type SignerCredit struct {
+	BlockNumber uint64 `json:"blockNumber"`
+	Punished    uint64 `json:"punished"`
+}
+
+// signerSchedule forecasts the count slots from fromTime on with the signers in
+// turn in them, now if fromTime is 0. The signers of an app chain follow its
+// signer queue, the ones of a side chain the queue of the main chain.
+func (a *Alien) signerSchedule(chain consensus.ChainReader, fromTime uint64, count uint64) ([]*SignerSlot, error) {
+	if count == 0 || count > maxSignerScheduleCount {
+		return nil, errInvalidScheduleCount
+	}
+	if fromTime == 0 {
+		fromTime = uint64(time.Now().Unix())
+	}
+	var snap *Snapshot
+	if chain.Config().Alien.SideChain {
+		main, err := a.mainChain()
+		if err != nil {
+			return nil, err
+		}
+		if snap, err = main.snapshotByTime(fromTime); err != nil {
+			return nil, err
+		}
+	} else {
+		header := chain.CurrentHeader()
+		if header == nil {
+			return nil, errUnknownBlock
+		}
+		var err error
+		if snap, err = a.snapshot(chain, header.Number.Uint64(), header.Hash(), nil, nil, DefaultLoopCntRecalculateSigners); err != nil {
+			return nil, err
+		}
+	}
+	if len(snap.Signers) == 0 || snap.Period == 0 {
+		return nil, errNoSignerQueue
+	}
+	if fromTime < snap.LoopStartTime {
+		fromTime = snap.LoopStartTime
+	}
+	var (
+		signers = uint64(len(snap.Signers))
+		loopEnd = snap.LoopStartTime + signers*snap.Period
+		slot    = (fromTime - snap.LoopStartTime + snap.Period - 1) / snap.Period
+		slots   = make([]*SignerSlot, 0, count)
+	)
+	for ; uint64(len(slots)) < count; slot++ {
+		at := snap.LoopStartTime + slot*snap.Period
+		slots = append(slots, &SignerSlot{
+			Time:   at,
+			Index:  slot % signers,
+			Signer: *snap.Signers[slot%signers],
+			Final:  at < loopEnd,
+		})
+	}
+	return slots, nil
+}
+
+// signerStats counts the blocks sealed, the slots missed and the confirmations
+// of the signer in the canonical blocks of the range, and follows its
+// punishment credit through them.
+func (a *Alien) signerStats(chain consensus.ChainReader, address common.Address, fromBlock uint64, toBlock uint64) (*SignerStats, error) {
+	if toBlock < fromBlock || toBlock-fromBlock >= maxSignerStatsRange {
+		return nil, errInvalidStatsRange
+	}
+	if head := chain.CurrentHeader(); head == nil || toBlock > head.Number.Uint64() {
+		return nil, errUnknownBlock
+	}
+	stats := &SignerStats{
+		Address:   address,
+		FromBlock: fromBlock,
+		ToBlock:   toBlock,
+		Credits:   []*SignerCredit{},
+	}
+	var credit *uint64
+	for number := fromBlock; number <= toBlock; number++ {
+		header := chain.GetHeaderByNumber(number)
+		if header == nil {
+			return nil, errUnknownBlock
+		}
+		stats.Blocks++
+		if header.Coinbase == address {
+			stats.Produced++
+		}
+		if number > 0 && len(header.Extra) >= extraVanity+extraSeal {
+			headerExtra := HeaderExtra{}
+			if err := rlp.DecodeBytes(header.Extra[extraVanity:len(header.Extra)-extraSeal], &headerExtra); err != nil {
+				return nil, err
+			}
+			for _, missing := range headerExtra.SignerMissing {
+				if missing == address {
+					stats.Missed++
+				}
+			}
+			for _, confirmation := range headerExtra.confirmations() {
+				if confirmation.Signer == address {
+					stats.Confirmed++
+				}
+			}
+		}
+		snap, err := a.snapshot(chain, number, header.Hash(), nil, nil, DefaultLoopCntRecalculateSigners)
+		if err != nil {
+			return nil, err
+		}
+		if punished := snap.Punished[address]; credit == nil || *credit != punished {
+			credit = &punished
+			stats.Credits = append(stats.Credits, &SignerCredit{BlockNumber: number, Punished: punished})
+		}
+	}
+	return stats, nil
+}
@@ -63,7 +63,7 @@ func (s SignerSlice) Less(i, j int) bool {
 // verify the SignerQueue base on block hash
 func (s *Snapshot) verifySignerQueue(signerQueue []common.Address, eth core.Backend) error {
 
-	if len(signerQueue) > int(s.config.MaxSignerCount) {
+	if len(signerQueue) > int(s.Params.MaxSignerCount) {
 		return errInvalidSignerQueue
 	}
 	sq, err := s.createSignerQueue(eth)
@@ -106,21 +106,21 @@ func (s *Snapshot) buildTallySlice() TallySlice {
 
 func (s *Snapshot) createSignerQueue(eth core.Backend) ([]common.Address, error) {
 
-	if (s.Number+1)%s.config.MaxSignerCount != 0 || s.Hash != s.HistoryHash[len(s.HistoryHash)-1] {
+	if (s.Number+1)%s.Params.MaxSignerCount != 0 || s.Hash != s.HistoryHash[len(s.HistoryHash)-1] {
 		return nil, errCreateSignerQueueNotAllowed
 	}
 
 	var signerSlice SignerSlice
 	var topStakeAddress []common.Address
 
-	if (s.Number+1)%(s.config.MaxSignerCount*s.LCRS) == 0 {
+	if (s.Number+1)%(s.Params.MaxSignerCount*s.LCRS) == 0 {
 		// before recalculate the signers, clear the candidate is not in snap.Candidates
 
 		// only recalculate signers from to tally per 10 loop,
 		// other loop end just reset the order of signers by block hash (nearly random)
 		tallySlice := s.buildTallySlice()
 		sort.Sort(TallySlice(tallySlice))
-		queueLength := int(s.config.MaxSignerCount)
+		queueLength := int(s.Params.MaxSignerCount)
 		if queueLength > len(tallySlice) {
 			queueLength = len(tallySlice)
 		}
@@ -142,7 +142,7 @@ func (s *Snapshot) createSignerQueue(eth core.Backend) ([]common.Address, error)
 			return nil, errSignerQueueEmpty
 		}
 	}
-	for i := 0; i < int(s.config.MaxSignerCount); i++ {
+	for i := 0; i < int(s.Params.MaxSignerCount); i++ {
 		topStakeAddress = append(topStakeAddress, signerSlice[i%len(signerSlice)].addr)
 	}
 	return topStakeAddress, nil
@@ -165,7 +165,6 @@ func TestQueue(t *testing.T) {
 
 	// Run through the scenarios and test them
 	for i, tt := range tests {
-		candidateNeedPD = false
 		// Create the account pool and generate the initial set of all address in addrNames
 		accounts := newTesterAccountPool()
 		addrNames := make([]common.Address, len(tt.addrNames))
@@ -182,6 +181,7 @@ func TestQueue(t *testing.T) {
 
 		snap := &Snapshot{
 			config:   &params.AlienConfig{MaxSignerCount: tt.maxSignerCount},
+			Params:   &GovernedParams{MaxSignerCount: tt.maxSignerCount},
 			Number:   tt.number,
 			LCRS:     1,
 			Tally:    make(map[common.Address]*big.Int),
@@ -203,7 +203,7 @@ func TestQueue(t *testing.T) {
 			snap.Punished[accounts.address(signer)] = punish
 		}
 
-		signerQueue, err := snap.createSignerQueue()
+		signerQueue, err := snap.createSignerQueue(nil)
 		if err != nil {
 			t.Errorf("test %d: create signer queue fail , err = %s", i, err)
 			continue
@@ -70,15 +70,39 @@ type Snapshot struct {
 	Voters          map[common.Address]*big.Int  `json:"voters"`          // Block number for each voter address
 	Cancels         map[common.Address]*Cancel   `json:"cancels"`         // All cancels
 	Cancelers       map[common.Address]*big.Int  `json:"cancelers"`       // Block number for each canceler address
+	CancelUnlocks   map[common.Address]uint64     `json:"cancelUnlocks"`  // Block number the stake of each canceler is returned in
 	Candidates      map[common.Address][]*Vote   `json:"candidates"`      		  // all votes for candidates, used for private
 	Punished        map[common.Address]uint64    `json:"punished"`        // The signer be punished count cause of missing seal
 	Confirmations   map[uint64][]*common.Address `json:"confirms"`        // The signer confirm given block number
 	HeaderTime      uint64                       `json:"headerTime"`      // Time of the current header
 	LoopStartTime   uint64                       `json:"loopStartTime"`   // Start Time of the current loop
+	Unstakes        map[common.Address][]*Unstake `json:"unstakes"`       // Partially withdrawn stake waiting to be returned to each voter
+	DoubleSigned    map[common.Address]uint64     `json:"doubleSigned"`   // Height of the last double sign each signer was slashed for
+	Params          *GovernedParams               `json:"params"`         // Engine parameters in effect, changed by proposals
+	Proposals       map[common.Hash]*ProposalRecord `json:"proposals"`    // Proposals to change the engine parameters
+	Minted          *big.Int                      `json:"minted"`         // Total of the block rewards paid up to the snapshot
+	Checkpoints     map[string]*SideCheckpoint    `json:"checkpoints"`    // Latest checkpoint of each side chain anchored on the main chain
 	Backup1         []byte
 	Backup2         []byte
 }
 
+// Unstake is a part of the stake of a vote which has been withdrawn and is
+// frozen until it is returned to the voter.
+type Unstake struct {
+	Amount       *big.Int `json:"amount"`       // Withdrawn stake
+	Number       *big.Int `json:"number"`       // Block number the unstake was requested in
+	UnlockNumber uint64   `json:"unlockNumber"` // Block number the stake is returned in
+}
+
+// UnlockItem is a frozen stake scheduled to be returned to a voter.
+type UnlockItem struct {
+	Amount        *big.Int `json:"amount"`        // Stake to be returned
+	UnlockNumber  uint64   `json:"unlockNumber"`  // Block number the stake is returned in
+	RemainingTime uint64   `json:"remainingTime"` // Seconds left until the stake is returned
+	Passive       bool     `json:"passive"`       // Whether the candidate of the vote canceled
+	Full          bool     `json:"full"`          // Whether the whole vote was canceled
+}
+
 // newSnapshot creates a new snapshot with the specified startup parameters. only ever use if for
 // the genesis block.
 func newSnapshot(config *params.AlienConfig, sigcache *lru.ARCCache, hash common.Hash, votes []*Vote, lcrs uint64) *Snapshot {
@@ -98,11 +122,18 @@ func newSnapshot(config *params.AlienConfig, sigcache *lru.ARCCache, hash common
 		Voters:          make(map[common.Address]*big.Int),
 		Cancels:         make(map[common.Address]*Cancel),
 		Cancelers:       make(map[common.Address]*big.Int),
+		CancelUnlocks:   make(map[common.Address]uint64),
 		Punished:        make(map[common.Address]uint64),
 		Candidates:      make(map[common.Address][]*Vote),
 		Confirmations:   make(map[uint64][]*common.Address),
 		HeaderTime:      uint64(time.Now().Unix()) - 1,
 		LoopStartTime:   config.GenesisTimestamp,
+		Unstakes:        make(map[common.Address][]*Unstake),
+		DoubleSigned:    make(map[common.Address]uint64),
+		Params:          newGovernedParams(config),
+		Proposals:       make(map[common.Hash]*ProposalRecord),
+		Minted:          new(big.Int),
+		Checkpoints:     make(map[string]*SideCheckpoint),
 		Backup1: 		 []byte{},
 		Backup2: 		 []byte{},
 	}
@@ -142,6 +173,42 @@ func loadSnapshot(config *params.AlienConfig, sigcache *lru.ARCCache, db ethdb.D
 	}
 	snap.config = config
 	snap.sigcache = sigcache
+	if snap.Unstakes == nil {
+		snap.Unstakes = make(map[common.Address][]*Unstake)
+	}
+	for _, unstakes := range snap.Unstakes {
+		for _, unstake := range unstakes {
+			if unstake.UnlockNumber == 0 {
+				unstake.UnlockNumber = snap.unstakeUnlockNumber(unstake.Number)
+			}
+		}
+	}
+	if snap.CancelUnlocks == nil {
+		snap.CancelUnlocks = make(map[common.Address]uint64)
+	}
+	for canceler, cancel := range snap.Cancels {
+		if _, ok := snap.CancelUnlocks[canceler]; !ok {
+			snap.CancelUnlocks[canceler] = snap.cancelUnlockNumber(snap.Cancelers[canceler], cancel.Passive)
+		}
+	}
+	if snap.DoubleSigned == nil {
+		snap.DoubleSigned = make(map[common.Address]uint64)
+	}
+	if snap.Period == 0 {
+		snap.Period = config.Period
+	}
+	if snap.Params == nil {
+		snap.Params = newGovernedParams(config)
+	}
+	if snap.Proposals == nil {
+		snap.Proposals = make(map[common.Hash]*ProposalRecord)
+	}
+	if snap.Minted == nil {
+		snap.Minted = new(big.Int)
+	}
+	if snap.Checkpoints == nil {
+		snap.Checkpoints = make(map[string]*SideCheckpoint)
+	}
 	return snap, nil
 }
 
@@ -172,9 +239,16 @@ func (s *Snapshot) copy() *Snapshot {
 		Voters:        make(map[common.Address]*big.Int),
 		Cancels:       make(map[common.Address]*Cancel),
 		Cancelers:     make(map[common.Address]*big.Int),
+		CancelUnlocks: make(map[common.Address]uint64),
 		Candidates:    make(map[common.Address][]*Vote),
 		Punished:      make(map[common.Address]uint64),
 		Confirmations: make(map[uint64][]*common.Address),
+		Unstakes:      make(map[common.Address][]*Unstake),
+		DoubleSigned:  make(map[common.Address]uint64),
+		Params:        s.Params.copy(),
+		Proposals:     make(map[common.Hash]*ProposalRecord),
+		Minted:        new(big.Int).Set(s.Minted),
+		Checkpoints:   make(map[string]*SideCheckpoint),
 
 		HeaderTime:    s.HeaderTime,
 		LoopStartTime: s.LoopStartTime,
@@ -210,6 +284,9 @@ func (s *Snapshot) copy() *Snapshot {
 	for canceler, number := range s.Cancelers {
 		cpy.Cancelers[canceler] = new(big.Int).Set(number)
 	}
+	for canceler, number := range s.CancelUnlocks {
+		cpy.CancelUnlocks[canceler] = number
+	}
 	for candidate, state := range s.Candidates {
 		cpy.Candidates[candidate] = state
 	}
@@ -220,6 +297,19 @@ func (s *Snapshot) copy() *Snapshot {
 		cpy.Confirmations[blockNumber] = make([]*common.Address, len(confirmers))
 		copy(cpy.Confirmations[blockNumber], confirmers)
 	}
+	for voter, unstakes := range s.Unstakes {
+		cpy.Unstakes[voter] = make([]*Unstake, len(unstakes))
+		copy(cpy.Unstakes[voter], unstakes)
+	}
+	for signer, number := range s.DoubleSigned {
+		cpy.DoubleSigned[signer] = number
+	}
+	for hash, proposal := range s.Proposals {
+		cpy.Proposals[hash] = proposal.copy()
+	}
+	for appId, checkpoint := range s.Checkpoints {
+		cpy.Checkpoints[appId] = checkpoint.copy()
+	}
 
 	return cpy
 }
@@ -259,6 +349,10 @@ func (s *Snapshot) apply(headers []*types.Header) (*Snapshot, error) {
 		if err != nil {
 			return nil, err
 		}
+		// count the reward of the block before any parameter of it changes
+		reward, _ := snap.blockReward(header.Number.Uint64())
+		snap.Minted.Add(snap.Minted, reward)
+
 		snap.HeaderTime = header.Time.Uint64()
 		snap.LoopStartTime = headerExtra.LoopStartTime
 		snap.Signers = nil
@@ -268,17 +362,29 @@ func (s *Snapshot) apply(headers []*types.Header) (*Snapshot, error) {
 
 		snap.ConfirmedNumber = headerExtra.ConfirmedBlockNumber
 
-		if len(snap.HistoryHash) >= int(s.config.MaxSignerCount)*2 {
-			snap.HistoryHash = snap.HistoryHash[1 : int(s.config.MaxSignerCount)*2]
+		if historyLen := int(snap.Params.MaxSignerCount) * 2; len(snap.HistoryHash) >= historyLen {
+			snap.HistoryHash = snap.HistoryHash[len(snap.HistoryHash)-historyLen+1:]
 		}
 		snap.HistoryHash = append(snap.HistoryHash, header.Hash())
 
 		// deal the new confirmation in this block
-		snap.updateSnapshotByConfirmations(headerExtra.CurrentBlockConfirmations)
+		snap.updateSnapshotByConfirmations(headerExtra.confirmations())
+
+		// deal the slashes before any other stake change, so the slashed amount
+		// is the one Finalize paid out from the parent snapshot
+		snap.updateSnapshotBySlashes(headerExtra.CurrentBlockSlashes)
 
 		// deal the new vote from voter
 		snap.updateSnapshotByVotes(headerExtra.CurrentBlockVotes, header.Number)
 
+		// deal the stake added to or withdrawn from existing votes, before the
+		// cancels so that a cancel in the same block returns the changed stake
+		snap.updateSnapshotByAddStakes(headerExtra.CurrentBlockAddStakes)
+		snap.updateSnapshotByUnstakes(headerExtra.CurrentBlockUnstakes, header.Number)
+
+		// deal the votes moved to another candidate
+		snap.updateSnapshotByRedelegations(headerExtra.CurrentBlockRedelegations, header.Number)
+
 		// deal the new cancel from canceler
 		snap.updateSnapshotByCancels(headerExtra.CurrentBlockCancels, header.Number)
 
@@ -288,12 +394,24 @@ func (s *Snapshot) apply(headers []*types.Header) (*Snapshot, error) {
 		// deal the snap related with punished
 		snap.updateSnapshotForPunish(headerExtra.SignerMissing, header.Number, header.Coinbase)
 
+		// deal the proposals and declares, then decide and activate the proposals
+		if s.config.IsProposal(header.Number) {
+			snap.updateSnapshotByProposals(headerExtra.CurrentBlockProposals, header.Number)
+			snap.updateSnapshotByDeclares(headerExtra.CurrentBlockDeclares, header.Number)
+			snap.updateSnapshotForProposals(header.Number)
+		}
+
+		// deal the checkpoints anchored by the side chains
+		snap.updateSnapshotByCheckpoints(headerExtra.CurrentBlockCheckpoints)
+
 		// check the len of candidate if not candidateNeedPD
 		//if (snap.Number+1)%(snap.config.MaxSignerCount*snap.LCRS) == 0 {
 		//	snap.removeZeroTallyCandidate()
 		//}
 
-		snap.removeExtraVotesAndCancel()
+		snap.removeExtraVotesAndCancel(header.Number.Uint64())
+
+		snap.removeReturnedUnstakes(header.Number.Uint64())
 
 	}
 	snap.Number += uint64(len(headers))
@@ -310,10 +428,11 @@ func (s *Snapshot) apply(headers []*types.Header) (*Snapshot, error) {
 
 
 
-func (s *Snapshot) removeExtraVotesAndCancel() {
-	for canceler, cancel := range s.Cancels {
-		if (cancel.Passive && (s.Number > s.Cancelers[canceler].Uint64() + 1)) ||
-			!cancel.Passive && (s.Number + 1 - s.Cancelers[cancel.Canceler].Uint64() >= s.config.Freeze/s.config.Period) {
+// removeExtraVotesAndCancel drops the canceled votes whose stake was returned
+// to the voters up to the given block.
+func (s *Snapshot) removeExtraVotesAndCancel(number uint64) {
+	for canceler := range s.Cancels {
+		if s.CancelUnlocks[canceler] <= number {
 			// delete s.Candidates
 			if s.isCandidate(canceler) {
 				delete(s.Punished, canceler)
@@ -332,6 +451,7 @@ func (s *Snapshot) removeExtraVotesAndCancel() {
 			delete(s.Voters, canceler)
 			delete(s.Cancels, canceler)
 			delete(s.Cancelers, canceler)
+			delete(s.CancelUnlocks, canceler)
 		}
 	}
 }
@@ -413,7 +533,7 @@ func (s *Snapshot) updateSnapshotForExpired() {
 
 	// deal the expired confirmation
 	for blockNumber := range s.Confirmations {
-		if s.Number-blockNumber > s.config.MaxSignerCount {
+		if s.Number-blockNumber > s.Params.MaxSignerCount {
 			delete(s.Confirmations, blockNumber)
 		}
 	}
@@ -489,6 +609,7 @@ func (s *Snapshot) updateSnapshotByCancels(cancels []Cancel, headerNumber *big.I
 				s.Tally[vote.Candidate].Sub(s.Tally[vote.Candidate], vote.Stake)
 				s.Cancels[cancels[i].Canceler] = &Cancel{cancels[i].Canceler, cancels[i].Passive}
 				s.Cancelers[cancels[i].Canceler] = headerNumber
+				s.CancelUnlocks[cancels[i].Canceler] = s.cancelUnlockNumber(headerNumber, cancels[i].Passive)
 			} else {
 				log.Error("No vote for the candidate")
 			}
@@ -498,6 +619,212 @@ func (s *Snapshot) updateSnapshotByCancels(cancels []Cancel, headerNumber *big.I
 	}
 }
 
+func (s *Snapshot) updateSnapshotByAddStakes(addStakes []StakeChange) {
+	for _, addStake := range addStakes {
+		vote, ok := s.stakeChangeVote(addStake)
+		if !ok {
+			continue
+		}
+		vote.Stake.Add(vote.Stake, addStake.Amount)
+		s.Tally[vote.Candidate].Add(s.Tally[vote.Candidate], addStake.Amount)
+		s.updateCandidateVote(vote)
+	}
+}
+
+func (s *Snapshot) updateSnapshotByUnstakes(unstakes []StakeChange, headerNumber *big.Int) {
+	for _, unstake := range unstakes {
+		vote, ok := s.stakeChangeVote(unstake)
+		if !ok {
+			continue
+		}
+		if vote.Stake.Cmp(unstake.Amount) <= 0 {
+			log.Warn("Unstake more than vote stake")
+			continue
+		}
+		vote.Stake.Sub(vote.Stake, unstake.Amount)
+		s.Tally[vote.Candidate].Sub(s.Tally[vote.Candidate], unstake.Amount)
+		s.updateCandidateVote(vote)
+		s.Unstakes[unstake.Voter] = append(s.Unstakes[unstake.Voter], &Unstake{
+			Amount:       new(big.Int).Set(unstake.Amount),
+			Number:       new(big.Int).Set(headerNumber),
+			UnlockNumber: s.unstakeUnlockNumber(headerNumber),
+		})
+	}
+}
+
+func (s *Snapshot) updateSnapshotByRedelegations(redelegations []Redelegation, headerNumber *big.Int) {
+	for _, redelegation := range redelegations {
+		if err := s.checkRedelegation(redelegation.Voter, redelegation.Candidate, headerNumber); err != nil {
+			log.Warn("Invalid redelegation", "voter", redelegation.Voter, "err", err)
+			continue
+		}
+		vote := s.Votes[redelegation.Voter]
+		s.Tally[vote.Candidate].Sub(s.Tally[vote.Candidate], vote.Stake)
+		s.Tally[redelegation.Candidate].Add(s.Tally[redelegation.Candidate], vote.Stake)
+
+		// move the vote from the list of the old candidate to the new one
+		var votes []*Vote
+		for _, candidateVote := range s.Candidates[vote.Candidate] {
+			if candidateVote.Voter != vote.Voter {
+				votes = append(votes, candidateVote)
+			}
+		}
+		s.Candidates[vote.Candidate] = votes
+		vote.Candidate = redelegation.Candidate
+		vote.Hash = redelegation.Hash
+		votes = append([]*Vote{}, s.Candidates[vote.Candidate]...)
+		s.Candidates[vote.Candidate] = append(votes, &Vote{vote.Voter, vote.Candidate, new(big.Int).Set(vote.Stake), vote.Hash})
+		s.Voters[vote.Voter] = new(big.Int).Set(headerNumber)
+	}
+}
+
+// checkRedelegation checks whether the vote of voter may be moved to candidate
+// in the block with the given number.
+func (s *Snapshot) checkRedelegation(voter common.Address, candidate common.Address, number *big.Int) error {
+	vote, ok := s.Votes[voter]
+	if !ok {
+		return errStakeNoVote
+	}
+	if _, ok := s.Cancels[voter]; ok {
+		return errStakeVoteCanceled
+	}
+	if vote.Voter == vote.Candidate {
+		return errRedelegateSelfVote
+	}
+	if vote.Candidate == candidate {
+		return errRedelegateSameTarget
+	}
+	if !s.isCandidate(candidate) || candidate == voter {
+		return errVoteTargetNotCandidate
+	}
+	if _, ok := s.Cancels[candidate]; ok {
+		return errVoteTargetNotCandidate
+	}
+	if _, ok := s.Tally[candidate]; !ok {
+		return errVoteTargetNotCandidate
+	}
+	if s.remainingRedelegateTime(voter, number.Uint64()) > 0 {
+		return errRedelegateTooEarly
+	}
+	return nil
+}
+
+// remainingRedelegateTime returns the number of seconds left, as of the block
+// with the given number, before the vote of voter may be moved again.
+func (s *Snapshot) remainingRedelegateTime(voter common.Address, number uint64) uint64 {
+	voted, ok := s.Voters[voter]
+	if !ok {
+		return 0
+	}
+	cooldown := s.config.RedelegateCooldown / s.Period
+	if number >= voted.Uint64()+cooldown {
+		return 0
+	}
+	return (voted.Uint64() + cooldown - number) * s.Period
+}
+
+// stakeChangeVote returns the vote whose stake the given change applies to.
+func (s *Snapshot) stakeChangeVote(change StakeChange) (*Vote, bool) {
+	vote, ok := s.Votes[change.Voter]
+	if !ok {
+		log.Warn("No vote for the stake change")
+		return nil, false
+	}
+	if _, ok := s.Cancels[change.Voter]; ok {
+		log.Warn("Stake change for canceled vote")
+		return nil, false
+	}
+	if _, ok := s.Tally[vote.Candidate]; !ok {
+		log.Warn("No tally for the stake change")
+		return nil, false
+	}
+	return vote, true
+}
+
+// updateCandidateVote replaces the copy of the vote in s.Candidates. The vote
+// slices are shared with older snapshots, so they are rebuilt instead of
+// modified in place.
+func (s *Snapshot) updateCandidateVote(vote *Vote) {
+	votes := make([]*Vote, len(s.Candidates[vote.Candidate]))
+	for i, candidateVote := range s.Candidates[vote.Candidate] {
+		if candidateVote.Voter == vote.Voter {
+			votes[i] = &Vote{vote.Voter, vote.Candidate, new(big.Int).Set(vote.Stake), vote.Hash}
+		} else {
+			votes[i] = candidateVote
+		}
+	}
+	s.Candidates[vote.Candidate] = votes
+}
+
+// unstakeUnlockNumber returns the block number in which stake withdrawn in the
+// given block is returned to the voter, under the current freeze window.
+func (s *Snapshot) unstakeUnlockNumber(number *big.Int) uint64 {
+	freeze := s.freezeBlocks()
+	if freeze == 0 {
+		freeze = 1
+	}
+	return number.Uint64() + freeze
+}
+
+// cancelUnlockNumber returns the block number in which the stake of a vote
+// canceled in the given block is returned to the voter, under the current
+// freeze window. The stake of a passive cancel is returned in the next block.
+func (s *Snapshot) cancelUnlockNumber(number *big.Int, passive bool) uint64 {
+	if freeze := s.freezeBlocks(); !passive && freeze > 3 {
+		return number.Uint64() + freeze - 2
+	}
+	return number.Uint64() + 1
+}
+
+// removeReturnedUnstakes drops the unstakes returned to voters up to the given block.
+func (s *Snapshot) removeReturnedUnstakes(number uint64) {
+	for voter, unstakes := range s.Unstakes {
+		var pending []*Unstake
+		for _, unstake := range unstakes {
+			if unstake.UnlockNumber > number {
+				pending = append(pending, unstake)
+			}
+		}
+		if len(pending) == 0 {
+			delete(s.Unstakes, voter)
+		} else {
+			s.Unstakes[voter] = pending
+		}
+	}
+}
+
+// unlockSchedule returns the frozen stake of the address which is still to be
+// returned, both from partial unstakes and from a cancel of the whole vote.
+func (s *Snapshot) unlockSchedule(address common.Address) []*UnlockItem {
+	var schedule []*UnlockItem
+	remaining := func(unlockNumber uint64) uint64 {
+		if unlockNumber <= s.Number {
+			return 0
+		}
+		return (unlockNumber - s.Number) * s.Period
+	}
+	for _, unstake := range s.Unstakes[address] {
+		schedule = append(schedule, &UnlockItem{
+			Amount:        new(big.Int).Set(unstake.Amount),
+			UnlockNumber:  unstake.UnlockNumber,
+			RemainingTime: remaining(unstake.UnlockNumber),
+		})
+	}
+	if cancel, ok := s.Cancels[address]; ok {
+		if vote, ok := s.Votes[address]; ok {
+			unlockNumber := s.CancelUnlocks[address]
+			schedule = append(schedule, &UnlockItem{
+				Amount:        new(big.Int).Set(vote.Stake),
+				UnlockNumber:  unlockNumber,
+				RemainingTime: remaining(unlockNumber),
+				Passive:       cancel.Passive,
+				Full:          true,
+			})
+		}
+	}
+	return schedule
+}
+
 func (s *Snapshot) updateSnapshotByMPVotes(votes []Vote) {
 	for _, txVote := range votes {
 
@@ -510,6 +837,100 @@ func (s *Snapshot) updateSnapshotByMPVotes(votes []Vote) {
 	}
 }
 
+// slashRate returns the per mille of the stake slashed for the reason.
+func (s *Snapshot) slashRate(reason uint8) uint64 {
+	rate := s.config.MissingSlashRate
+	if reason == slashReasonDoubleSign {
+		rate = s.config.DoubleSignSlashRate
+	}
+	if rate > 1000 {
+		rate = 1000
+	}
+	return rate
+}
+
+// slashAmount returns the stake a slash of the signer for the reason takes from
+// the votes for the signer.
+func (s *Snapshot) slashAmount(signer common.Address, reason uint8) *big.Int {
+	rate := new(big.Int).SetUint64(s.slashRate(reason))
+	amount := new(big.Int)
+	for _, vote := range s.Votes {
+		if vote.Candidate == signer {
+			cut := new(big.Int).Mul(vote.Stake, rate)
+			amount.Add(amount, cut.Div(cut, big.NewInt(1000)))
+		}
+	}
+	return amount
+}
+
+func (s *Snapshot) updateSnapshotBySlashes(slashes []Slash) {
+	for _, slash := range slashes {
+		rate := new(big.Int).SetUint64(s.slashRate(slash.Reason))
+		for _, vote := range s.Votes {
+			if vote.Candidate != slash.Signer {
+				continue
+			}
+			cut := new(big.Int).Mul(vote.Stake, rate)
+			if cut.Div(cut, big.NewInt(1000)).Sign() == 0 {
+				continue
+			}
+			vote.Stake.Sub(vote.Stake, cut)
+			// the stake of canceled votes is already out of the tally
+			if _, ok := s.Cancels[vote.Voter]; !ok {
+				if tally, ok := s.Tally[vote.Candidate]; ok {
+					tally.Sub(tally, cut)
+				}
+			}
+			s.updateCandidateVote(vote)
+		}
+		if slash.Reason == slashReasonDoubleSign {
+			s.DoubleSigned[slash.Signer] = slash.Number
+		}
+	}
+}
+
+// missingSlashes returns the slashes of the missing signers whose punished
+// credit reaches MissingSlashThreshold in the block, skipping the signers which
+// are already slashed in it.
+func (s *Snapshot) missingSlashes(signerMissing []common.Address, coinbase common.Address, slashed []Slash) []Slash {
+	var slashes []Slash
+	punished := make(map[common.Address]uint64)
+	for _, signer := range signerMissing {
+		credit, ok := punished[signer]
+		if !ok {
+			credit = s.Punished[signer]
+		}
+		// same rule as updateSnapshotForPunish
+		next := credit
+		if credit <= 10*defaultFullCredit {
+			next += missingPublishCredit
+		}
+		punished[signer] = next
+		if credit >= s.config.MissingSlashThreshold || next < s.config.MissingSlashThreshold {
+			continue
+		}
+		if hasSlash(slashed, signer) || hasSlash(slashes, signer) || s.slashAmount(signer, slashReasonMissing).Sign() == 0 {
+			continue
+		}
+		slashes = append(slashes, Slash{
+			Signer:   signer,
+			Reason:   slashReasonMissing,
+			Reporter: coinbase,
+		})
+	}
+	return slashes
+}
+
+// hasSlash reports whether the signer is slashed in the list.
+func hasSlash(slashes []Slash, signer common.Address) bool {
+	for _, slash := range slashes {
+		if slash.Signer == signer {
+			return true
+		}
+	}
+	return false
+}
+
 func (s *Snapshot) updateSnapshotForPunish(signerMissing []common.Address, headerNumber *big.Int, coinbase common.Address) {
 	// set punished count to half of origin in Epoch
 	/*
@@ -558,7 +979,7 @@ func (s *Snapshot) inturn(signer common.Address, header *types.Header) bool {
 		return false
 	}
 	if signersCount := len(s.Signers); signersCount > 0 {
-		if loopIndex := ((header.Time.Uint64() - s.LoopStartTime) / s.config.Period) % uint64(signersCount); *s.Signers[loopIndex] == signer {
+		if loopIndex := ((header.Time.Uint64() - s.LoopStartTime) / s.Period) % uint64(signersCount); *s.Signers[loopIndex] == signer {
 			return true
 		}
 	}
@@ -581,6 +1002,41 @@ func (s *Snapshot) isCandidate(address common.Address) bool {
 	return false
 }
 
+// getFinalizedBlockNumber returns the highest block confirmed by more than two
+// thirds of the signers, counting the given confirmations of the next block.
+// From the finality fork on it never goes below the confirmed number of the
+// snapshot.
+func (s *Snapshot) getFinalizedBlockNumber(confirmations []Confirmation) uint64 {
+	finalized := uint64(0)
+	if s.config.IsFinality(new(big.Int).SetUint64(s.Number)) {
+		finalized = s.ConfirmedNumber
+	}
+	confirmers := make(map[uint64]map[common.Address]struct{})
+	for number, addresses := range s.Confirmations {
+		confirmers[number] = make(map[common.Address]struct{})
+		for _, address := range addresses {
+			confirmers[number][*address] = struct{}{}
+		}
+	}
+	for _, confirmation := range confirmations {
+		number := confirmation.BlockNumber.Uint64()
+		if _, ok := confirmers[number]; !ok {
+			confirmers[number] = make(map[common.Address]struct{})
+		}
+		confirmers[number][confirmation.Signer] = struct{}{}
+	}
+	signers := make(map[common.Address]struct{})
+	for _, signer := range s.Signers {
+		signers[*signer] = struct{}{}
+	}
+	for number, addresses := range confirmers {
+		if number > finalized && number <= s.Number && len(addresses) > len(signers)*2/3 {
+			finalized = number
+		}
+	}
+	return finalized
+}
+
 // get last block number meet the confirm condition
 func (s *Snapshot) getLastConfirmedBlockNumber(confirmations []Confirmation) *big.Int {
 
@@ -610,9 +1066,9 @@ func (s *Snapshot) getLastConfirmedBlockNumber(confirmations []Confirmation) *bi
 	}
 
 	i := s.Number
-	for ; i > s.Number-s.config.MaxSignerCount*2/3+1; i-- {
+	for ; i > s.Number-s.Params.MaxSignerCount*2/3+1; i-- {
 		if confirmers, ok := cpyConfirmations[i]; ok {
-			if len(confirmers) > int(s.config.MaxSignerCount*2/3) {
+			if len(confirmers) > int(s.Params.MaxSignerCount*2/3) {
 				return big.NewInt(int64(i))
 			}
 		}
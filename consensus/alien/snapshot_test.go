@@ -21,30 +21,37 @@ package alien
 import (
 	"bytes"
 	"crypto/ecdsa"
+	"errors"
 	"math/big"
+	"reflect"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/CarLiveChainCo/goiov/common"
 	"github.com/CarLiveChainCo/goiov/core"
 	"github.com/CarLiveChainCo/goiov/core/rawdb"
+	"github.com/CarLiveChainCo/goiov/core/state"
 	"github.com/CarLiveChainCo/goiov/core/types"
 	"github.com/CarLiveChainCo/goiov/crypto"
 	"github.com/CarLiveChainCo/goiov/ethdb"
 	"github.com/CarLiveChainCo/goiov/params"
 	"github.com/CarLiveChainCo/goiov/rlp"
+	"github.com/CarLiveChainCo/goiov/rpc"
+	"github.com/hashicorp/golang-lru"
 )
 
 type testerTransaction struct {
-	from         string // name of from address
-	to           string // name of to address
-	balance      int    // balance address in snap.voter
-	isVote       bool   // "ufo:1:event:vote"
-	isProposal   bool   // "ufo:1:event:proposal:..."
-	proposalType uint64 // proposalTypeCandidateAdd or proposalTypeCandidateRemove
-	isDeclare    bool   // "ufo:1:event:declare:..."
-	candidate    string // name of candidate in proposal
-	txHash       string // hash of tx
-	decision     bool   // decision of declare
+	from       string // name of from address
+	to         string // name of to address
+	balance    int    // balance address in snap.voter
+	isVote     bool   // "ufo:1:event:vote"
+	isProposal bool   // "ufo:1:event:proposal:..."
+	parameter  string // governed parameter changed by the proposal
+	value      int    // proposed value of the parameter
+	isDeclare  bool   // "ufo:1:event:declare:..."
+	txHash     string // hash of tx
+	decision   bool   // decision of declare
 }
 
 type testerSingleHeader struct {
@@ -133,7 +140,6 @@ func TestVoting(t *testing.T) {
 	// Define the various voting scenarios to test
 	tests := []struct {
 		addrNames        []string             // accounts used in this case
-		candidateNeedPD  bool                 // candidate from POA
 		period           uint64               // default 3
 		epoch            uint64               // default 30000
 		maxSignerCount   uint64               // default 5 for test
@@ -143,7 +149,6 @@ func TestVoting(t *testing.T) {
 		selfVoters       []testerSelfVoter    //
 		txHeaders        []testerSingleHeader //
 		result           testerSnapshot       // the result of current snapshot
-		vlCnt            uint64
 	}{
 		{
 			/* 	Case 0:
@@ -179,7 +184,7 @@ func TestVoting(t *testing.T) {
 		{
 			/*	Case 1:
 			*	Two self vote address A B in  genesis
-			* 	C vote D to be signer in block 3
+			* 	D vote D to be candidate and C vote D to be signer in block 3
 			* 	But current loop do not finish, so D is not signer,
 			* 	the vote info already in Tally, Voters and Votes
 			 */
@@ -194,17 +199,18 @@ func TestVoting(t *testing.T) {
 			txHeaders: []testerSingleHeader{
 				{[]testerTransaction{}},
 				{[]testerTransaction{}},
-				{[]testerTransaction{{from: "C", to: "D", balance: 200, isVote: true}}},
+				{[]testerTransaction{{from: "D", to: "D", balance: 50, isVote: true}, {from: "C", to: "D", balance: 200, isVote: true}}},
 				{[]testerTransaction{}},
 			},
 			result: testerSnapshot{
 				Signers: []string{"A", "B"},
-				Tally:   map[string]int{"A": 100, "B": 200, "D": 200},
-				Voters:  map[string]int{"A": 0, "B": 0, "C": 3},
+				Tally:   map[string]int{"A": 100, "B": 200, "D": 250},
+				Voters:  map[string]int{"A": 0, "B": 0, "C": 3, "D": 3},
 				Votes: map[string]*testerVote{
 					"A": {"A", "A", 100},
 					"B": {"B", "B", 200},
 					"C": {"C", "D", 200},
+					"D": {"D", "D", 50},
 				},
 			},
 		},
@@ -245,7 +251,7 @@ func TestVoting(t *testing.T) {
 		{
 			/*	Case 3:
 			*	Two self vote address A B in  genesis
-			* 	C vote D to be signer in block 3
+			* 	D vote D to be candidate and C vote D to be signer in block 3
 			* 	balance of C is higher than minVoterBalance
 			* 	D is signer in next loop
 			 */
@@ -260,7 +266,7 @@ func TestVoting(t *testing.T) {
 			txHeaders: []testerSingleHeader{
 				{[]testerTransaction{}},
 				{[]testerTransaction{}},
-				{[]testerTransaction{{from: "C", to: "D", balance: 200, isVote: true}}},
+				{[]testerTransaction{{from: "D", to: "D", balance: 50, isVote: true}, {from: "C", to: "D", balance: 200, isVote: true}}},
 				{[]testerTransaction{}},
 				{[]testerTransaction{}},
 				{[]testerTransaction{}},
@@ -268,12 +274,13 @@ func TestVoting(t *testing.T) {
 			},
 			result: testerSnapshot{
 				Signers: []string{"A", "B", "D"},
-				Tally:   map[string]int{"A": 100, "B": 200, "D": 200},
-				Voters:  map[string]int{"A": 0, "B": 0, "C": 3},
+				Tally:   map[string]int{"A": 100, "B": 200, "D": 250},
+				Voters:  map[string]int{"A": 0, "B": 0, "C": 3, "D": 3},
 				Votes: map[string]*testerVote{
 					"A": {"A", "A", 100},
 					"B": {"B", "B", 200},
 					"C": {"C", "D", 200},
+					"D": {"D", "D", 50},
 				},
 			},
 		},
@@ -317,9 +324,11 @@ func TestVoting(t *testing.T) {
 		{
 			/*	Case 5:
 			*	Two self vote address A B in  genesis
-			* 	C vote D to be signer in block 2
-			*  	C transaction to E 20 in block 3
-			*	In Voters, the vote block number of C is still 2, not 4
+			* 	D vote D to be candidate and C vote D to be signer in block 2
+			*  	C transaction to E in block 4 leaves C 20, the unstake of 80 is
+			*	dropped as the vote would be less than minVoterBalance
+			*  	C transaction to E in block 5 leaves C 60, so C unstakes 40
+			*	In Voters, the vote block number of C is still 2, not 5
 			 */
 			addrNames:        []string{"A", "B", "C", "D", "E"},
 			period:           uint64(3),
@@ -331,28 +340,30 @@ func TestVoting(t *testing.T) {
 			selfVoters:       []testerSelfVoter{{"A", 100}, {"B", 200}},
 			txHeaders: []testerSingleHeader{
 				{[]testerTransaction{}},
-				{[]testerTransaction{{from: "C", to: "D", balance: 100, isVote: true}}},
+				{[]testerTransaction{{from: "D", to: "D", balance: 50, isVote: true}, {from: "C", to: "D", balance: 100, isVote: true}}},
 				{[]testerTransaction{}},
 				{[]testerTransaction{{from: "C", to: "E", balance: 20, isVote: false}}}, // when C transaction to E, the balance of C is 20
-				{[]testerTransaction{}},
+				{[]testerTransaction{{from: "C", to: "E", balance: 60, isVote: false}}}, // when C transaction to E, the balance of C is 60
 				{[]testerTransaction{}},
 			},
 			result: testerSnapshot{
 				Signers: []string{"A", "B", "D"},
-				Tally:   map[string]int{"A": 100, "B": 200, "D": 20},
-				Voters:  map[string]int{"A": 0, "B": 0, "C": 2},
+				Tally:   map[string]int{"A": 100, "B": 200, "D": 110},
+				Voters:  map[string]int{"A": 0, "B": 0, "C": 2, "D": 2},
 				Votes: map[string]*testerVote{
 					"A": {"A", "A", 100},
 					"B": {"B", "B", 200},
-					"C": {"C", "D", 20},
+					"C": {"C", "D", 60},
+					"D": {"D", "D", 50},
 				},
 			},
 		},
 		{
 			/*	Case 6:
 			*	Two self vote address A B in  genesis
+			* 	D, K, I vote themselves to be candidates in block 2
 			* 	C vote D , J vote K, H vote I  to be signer in block 2
-			*   E vote F in block 3
+			*   F vote F and E vote F in block 3
 			* 	The signers in the next loop is A,B,D,F,I but not K
 			*	K is not top 5(maxsigercount) in Tally
 			 */
@@ -363,11 +374,11 @@ func TestVoting(t *testing.T) {
 			minVoterBalance:  50,
 			lcrs:             1,
 			genesisTimestamp: uint64(0),
-			selfVoters:       []testerSelfVoter{{"A", 100}, {"B", 200}},
+			selfVoters:       []testerSelfVoter{{"A", 150}, {"B", 200}},
 			txHeaders: []testerSingleHeader{
 				{[]testerTransaction{}},
-				{[]testerTransaction{{from: "C", to: "D", balance: 110, isVote: true}, {from: "J", to: "K", balance: 80, isVote: true}, {from: "H", to: "I", balance: 160, isVote: true}}},
-				{[]testerTransaction{{from: "E", to: "F", balance: 130, isVote: true}}},
+				{[]testerTransaction{{from: "D", to: "D", balance: 50, isVote: true}, {from: "K", to: "K", balance: 50, isVote: true}, {from: "I", to: "I", balance: 50, isVote: true}, {from: "C", to: "D", balance: 110, isVote: true}, {from: "J", to: "K", balance: 80, isVote: true}, {from: "H", to: "I", balance: 160, isVote: true}}},
+				{[]testerTransaction{{from: "F", to: "F", balance: 50, isVote: true}, {from: "E", to: "F", balance: 130, isVote: true}}},
 				{[]testerTransaction{}},
 				{[]testerTransaction{}},
 				{[]testerTransaction{}},
@@ -375,27 +386,32 @@ func TestVoting(t *testing.T) {
 			},
 			result: testerSnapshot{
 				Signers: []string{"A", "B", "D", "F", "I"},
-				Tally:   map[string]int{"A": 100, "B": 200, "D": 110, "I": 160, "F": 130, "K": 80},
-				Voters:  map[string]int{"A": 0, "B": 0, "C": 2, "H": 2, "J": 2, "E": 3},
+				Tally:   map[string]int{"A": 150, "B": 200, "D": 160, "I": 210, "F": 180, "K": 130},
+				Voters:  map[string]int{"A": 0, "B": 0, "C": 2, "H": 2, "J": 2, "E": 3, "D": 2, "K": 2, "I": 2, "F": 3},
 				Votes: map[string]*testerVote{
-					"A": {"A", "A", 100},
+					"A": {"A", "A", 150},
 					"B": {"B", "B", 200},
 					"C": {"C", "D", 110},
 					"J": {"J", "K", 80},
 					"H": {"H", "I", 160},
 					"E": {"E", "F", 130},
+					"D": {"D", "D", 50},
+					"K": {"K", "K", 50},
+					"I": {"I", "I", 50},
+					"F": {"F", "F", 50},
 				},
 			},
 		},
 		{
 			/*	Case 7:
 			*	one self vote address A in  genesis
-			* 	C vote D , J vote K, H vote I  to be signer in block 3
-			*   E vote F in block 4
-			* 	B vote B in block 5
-			* 	The signers in the next loop is A, B, D,F,I,K
-			*	current number - The block number of vote for A > epoch expired
-			*
+			* 	D, K, I vote themselves to be candidates in block 12
+			* 	C vote D , J vote K, H vote I  to be signer in block 12
+			*   F vote F and E vote F in block 13
+			* 	B vote B in block 14
+			* 	The signers in the next loop is B,D,F,I,K
+			*	The votes do not expire any more, the vote of A is kept
+			*	but A is not top 5(maxsigercount) in Tally
 			 */
 			addrNames:        []string{"A", "B", "C", "D", "E", "F", "H", "I", "J", "K"},
 			period:           uint64(3),
@@ -417,22 +433,27 @@ func TestVoting(t *testing.T) {
 				{[]testerTransaction{}},
 				{[]testerTransaction{}},
 				{[]testerTransaction{}},
-				{[]testerTransaction{{from: "C", to: "D", balance: 110, isVote: true}, {from: "J", to: "K", balance: 80, isVote: true}, {from: "H", to: "I", balance: 160, isVote: true}}},
-				{[]testerTransaction{{from: "E", to: "F", balance: 130, isVote: true}}},
+				{[]testerTransaction{{from: "D", to: "D", balance: 50, isVote: true}, {from: "K", to: "K", balance: 50, isVote: true}, {from: "I", to: "I", balance: 50, isVote: true}, {from: "C", to: "D", balance: 110, isVote: true}, {from: "J", to: "K", balance: 80, isVote: true}, {from: "H", to: "I", balance: 160, isVote: true}}},
+				{[]testerTransaction{{from: "F", to: "F", balance: 50, isVote: true}, {from: "E", to: "F", balance: 130, isVote: true}}},
 				{[]testerTransaction{{from: "B", to: "B", balance: 200, isVote: true}}},
 				{[]testerTransaction{}},
 				{[]testerTransaction{}},
 			},
 			result: testerSnapshot{
 				Signers: []string{"B", "D", "F", "I", "K"},
-				Tally:   map[string]int{"B": 200, "D": 110, "I": 160, "F": 130, "K": 80},
-				Voters:  map[string]int{"B": 14, "C": 12, "H": 12, "J": 12, "E": 13},
+				Tally:   map[string]int{"A": 100, "B": 200, "D": 160, "I": 210, "F": 180, "K": 130},
+				Voters:  map[string]int{"A": 0, "B": 14, "C": 12, "H": 12, "J": 12, "E": 13, "D": 12, "K": 12, "I": 12, "F": 13},
 				Votes: map[string]*testerVote{
+					"A": {"A", "A", 100},
 					"B": {"B", "B", 200},
 					"C": {"C", "D", 110},
 					"J": {"J", "K", 80},
 					"H": {"H", "I", 160},
 					"E": {"E", "F", 130},
+					"D": {"D", "D", 50},
+					"K": {"K", "K", 50},
+					"I": {"I", "I", 50},
+					"F": {"F", "F", 50},
 				},
 			},
 		},
@@ -440,6 +461,8 @@ func TestVoting(t *testing.T) {
 			/*	Case 8:
 			*	Two self vote address A,B in  genesis
 			* 	C vote D , D vote C to be signer in block 3
+			*	Neither C nor D is a candidate, so both votes are dropped
+			*	and the transaction of C to E has no vote to change
 			 */
 			addrNames:        []string{"A", "B", "C", "D", "E"},
 			period:           uint64(3),
@@ -458,21 +481,19 @@ func TestVoting(t *testing.T) {
 				{[]testerTransaction{}},
 			},
 			result: testerSnapshot{
-				Signers: []string{"B", "A", "C", "D"},
-				Tally:   map[string]int{"B": 200, "D": 110, "A": 100, "C": 80},
-				Voters:  map[string]int{"B": 0, "C": 3, "D": 3, "A": 0},
+				Signers: []string{"B", "A"},
+				Tally:   map[string]int{"B": 200, "A": 100},
+				Voters:  map[string]int{"B": 0, "A": 0},
 				Votes: map[string]*testerVote{
 					"B": {"B", "B", 200},
 					"A": {"A", "A", 100},
-					"C": {"C", "D", 110},
-					"D": {"D", "C", 80},
 				},
 			},
 		},
 		{
 			/*	Case 9:
 			*	Two self vote address A B in  genesis
-			* 	C vote D to be signer in block 3
+			* 	D vote D to be candidate and C vote D to be signer in block 3
 			* 	lcrs  is 2, so the signers will recalculate after 5 *2 block
 			* 	D is still not signer
 			 */
@@ -487,7 +508,7 @@ func TestVoting(t *testing.T) {
 			txHeaders: []testerSingleHeader{
 				{[]testerTransaction{}},
 				{[]testerTransaction{}},
-				{[]testerTransaction{{from: "C", to: "D", balance: 200, isVote: true}}},
+				{[]testerTransaction{{from: "D", to: "D", balance: 50, isVote: true}, {from: "C", to: "D", balance: 200, isVote: true}}},
 				{[]testerTransaction{}},
 				{[]testerTransaction{}},
 				{[]testerTransaction{}},
@@ -495,19 +516,20 @@ func TestVoting(t *testing.T) {
 			},
 			result: testerSnapshot{
 				Signers: []string{"A", "B"},
-				Tally:   map[string]int{"A": 100, "B": 200, "D": 200},
-				Voters:  map[string]int{"A": 0, "B": 0, "C": 3},
+				Tally:   map[string]int{"A": 100, "B": 200, "D": 250},
+				Voters:  map[string]int{"A": 0, "B": 0, "C": 3, "D": 3},
 				Votes: map[string]*testerVote{
 					"A": {"A", "A", 100},
 					"B": {"B", "B", 200},
 					"C": {"C", "D", 200},
+					"D": {"D", "D", 50},
 				},
 			},
 		},
 		{
 			/*	Case 10:
 			*	Two self vote address A B in  genesis
-			* 	C vote D to be signer in block 3
+			* 	D vote D to be candidate and C vote D to be signer in block 3
 			* 	lcrs  is 2, so the signers will recalculate after 5 *2 block
 			* 	D is signer
 			 */
@@ -522,7 +544,7 @@ func TestVoting(t *testing.T) {
 			txHeaders: []testerSingleHeader{
 				{[]testerTransaction{}},
 				{[]testerTransaction{}},
-				{[]testerTransaction{{from: "C", to: "D", balance: 200, isVote: true}}},
+				{[]testerTransaction{{from: "D", to: "D", balance: 50, isVote: true}, {from: "C", to: "D", balance: 200, isVote: true}}},
 				{[]testerTransaction{}},
 				{[]testerTransaction{}},
 				{[]testerTransaction{}},
@@ -535,12 +557,13 @@ func TestVoting(t *testing.T) {
 			},
 			result: testerSnapshot{
 				Signers: []string{"A", "B", "D"},
-				Tally:   map[string]int{"A": 100, "B": 200, "D": 200},
-				Voters:  map[string]int{"A": 0, "B": 0, "C": 3},
+				Tally:   map[string]int{"A": 100, "B": 200, "D": 250},
+				Voters:  map[string]int{"A": 0, "B": 0, "C": 3, "D": 3},
 				Votes: map[string]*testerVote{
 					"A": {"A", "A", 100},
 					"B": {"B", "B", 200},
 					"C": {"C", "D", 200},
+					"D": {"D", "D", 50},
 				},
 			},
 		},
@@ -548,7 +571,7 @@ func TestVoting(t *testing.T) {
 			/*	Case 11:
 			*	All self vote in  genesis
 			* 	lcrs  is 1, so the signers will recalculate after 5 block
-			*   official 21 node test case
+			*   official 21 node test case, the signers are the top 21 in Tally
 			 */
 			addrNames: []string{"A1", "A2", "A3", "A4", "A5", "A6", "A7", "A8", "A9", "A10",
 				"A11", "A12", "A13", "A14", "A15", "A16", "A17", "A18", "A19", "A20",
@@ -641,12 +664,10 @@ func TestVoting(t *testing.T) {
 		},
 		{
 			/*	Case 12:
-			*   Candidate from Poa is enable
 			*	Two self vote address A B in  genesis
 			* 	C vote D to be signer in block 3, but D is not in candidates ,so this vote not valid
 			 */
 			addrNames:        []string{"A", "B", "C", "D"},
-			candidateNeedPD:  true,
 			period:           uint64(3),
 			epoch:            uint64(31),
 			maxSignerCount:   uint64(5),
@@ -675,13 +696,11 @@ func TestVoting(t *testing.T) {
 		},
 		{
 			/*	Case 13:
-			*   Candidate from Poa is enable
 			*	Two self vote address A B in  genesis
-			*   A proposal D to candidates, B declare agree to this proposal ,but not pass 2/3 * all stake, so fail
-			* 	C vote D to be signer in block 3, but D is not in candidates ,so this vote not valid
+			*   A proposal minVoteValue 300, B declare agree to this proposal ,but not pass 2/3 * all stake, so fail
+			* 	D vote D and C vote D to be signer in block 5, the vote of C is valid
 			 */
 			addrNames:        []string{"A", "B", "C", "D"},
-			candidateNeedPD:  true,
 			period:           uint64(3),
 			epoch:            uint64(31),
 			maxSignerCount:   uint64(5),
@@ -691,10 +710,10 @@ func TestVoting(t *testing.T) {
 			selfVoters:       []testerSelfVoter{{"A", 100}, {"B", 200}},
 			txHeaders: []testerSingleHeader{
 				{[]testerTransaction{}},
-				{[]testerTransaction{{from: "A", to: "A", isProposal: true, candidate: "D", txHash: "a", proposalType: proposalTypeCandidateAdd}}},
+				{[]testerTransaction{{from: "A", to: "A", isProposal: true, parameter: proposalParamMinVoteValue, value: 300, txHash: "a"}}},
 				{[]testerTransaction{{from: "B", to: "B", isDeclare: true, txHash: "a", decision: true}}},
 				{[]testerTransaction{}},
-				{[]testerTransaction{{from: "C", to: "D", balance: 250, isVote: true}}},
+				{[]testerTransaction{{from: "D", to: "D", balance: 50, isVote: true}, {from: "C", to: "D", balance: 250, isVote: true}}},
 				{[]testerTransaction{}},
 				{[]testerTransaction{}},
 				{[]testerTransaction{}},
@@ -703,24 +722,24 @@ func TestVoting(t *testing.T) {
 				{[]testerTransaction{}},
 			},
 			result: testerSnapshot{
-				Signers: []string{"A", "B"},
-				Tally:   map[string]int{"A": 100, "B": 200},
-				Voters:  map[string]int{"A": 0, "B": 0},
+				Signers: []string{"A", "B", "D"},
+				Tally:   map[string]int{"A": 100, "B": 200, "D": 300},
+				Voters:  map[string]int{"A": 0, "B": 0, "C": 5, "D": 5},
 				Votes: map[string]*testerVote{
 					"A": {"A", "A", 100},
 					"B": {"B", "B", 200},
+					"C": {"C", "D", 250},
+					"D": {"D", "D", 50},
 				},
 			},
 		},
 		{
 			/*	Case 14:
-			*   Candidate from Poa is enable
 			*	Two self vote address A B in  genesis
-			*   A proposal D to candidates, and A,B declare agree to this proposal, so D is in candidates
-			* 	C vote D to be signer in block 5
+			*   A proposal minVoteValue 300, and A,B declare agree to this proposal, so it is active from block 6
+			* 	D vote D and C vote D to be signer in block 11, the vote of C is less than minVoteValue
 			 */
 			addrNames:        []string{"A", "B", "C", "D"},
-			candidateNeedPD:  true,
 			period:           uint64(3),
 			epoch:            uint64(31),
 			maxSignerCount:   uint64(5),
@@ -730,7 +749,7 @@ func TestVoting(t *testing.T) {
 			selfVoters:       []testerSelfVoter{{"A", 100}, {"B", 200}},
 			txHeaders: []testerSingleHeader{
 				{[]testerTransaction{}},
-				{[]testerTransaction{{from: "A", to: "A", isProposal: true, candidate: "D", txHash: "a", proposalType: proposalTypeCandidateAdd}}},
+				{[]testerTransaction{{from: "A", to: "A", isProposal: true, parameter: proposalParamMinVoteValue, value: 300, txHash: "a"}}},
 				{[]testerTransaction{{from: "A", to: "A", isDeclare: true, txHash: "a", decision: true}, {from: "B", to: "B", isDeclare: true, txHash: "a", decision: true}}},
 				{[]testerTransaction{}},
 				{[]testerTransaction{}},
@@ -739,7 +758,7 @@ func TestVoting(t *testing.T) {
 				{[]testerTransaction{}},
 				{[]testerTransaction{}},
 				{[]testerTransaction{}},
-				{[]testerTransaction{{from: "C", to: "D", balance: 250, isVote: true}}},
+				{[]testerTransaction{{from: "D", to: "D", balance: 50, isVote: true}, {from: "C", to: "D", balance: 250, isVote: true}}},
 				{[]testerTransaction{}},
 				{[]testerTransaction{}},
 				{[]testerTransaction{}},
@@ -749,25 +768,23 @@ func TestVoting(t *testing.T) {
 			},
 			result: testerSnapshot{
 				Signers: []string{"A", "B", "D"},
-				Tally:   map[string]int{"A": 100, "B": 200, "D": 250},
-				Voters:  map[string]int{"A": 0, "B": 0, "C": 11},
+				Tally:   map[string]int{"A": 100, "B": 200, "D": 50},
+				Voters:  map[string]int{"A": 0, "B": 0, "D": 11},
 				Votes: map[string]*testerVote{
 					"A": {"A", "A", 100},
 					"B": {"B", "B", 200},
-					"C": {"C", "D", 250},
+					"D": {"D", "D", 50},
 				},
 			},
 		},
 		{
 			/*	Case 15:
-			*   Candidate from Poa is enable
 			*	Two self vote address A B E F in  genesis
-			*   A proposal D to candidates, and A,B,F declare agree to this proposal,
-			*   but the sum stake of A B F is less than 2/3 of all stake, so D is not in candidates
-			* 	C vote D to be signer in block 5
+			*   A proposal minVoteValue 300, and A,B,F declare agree to this proposal,
+			*   but the sum stake of A B F is less than 2/3 of all stake, so it fails
+			* 	D vote D and C vote D to be signer in block 5, the vote of C is valid
 			 */
 			addrNames:        []string{"A", "B", "C", "D", "E", "F"},
-			candidateNeedPD:  true,
 			period:           uint64(3),
 			epoch:            uint64(31),
 			maxSignerCount:   uint64(5),
@@ -777,10 +794,10 @@ func TestVoting(t *testing.T) {
 			selfVoters:       []testerSelfVoter{{"A", 100}, {"B", 200}, {"E", 2000}, {"F", 200}},
 			txHeaders: []testerSingleHeader{
 				{[]testerTransaction{}},
-				{[]testerTransaction{{from: "A", to: "A", isProposal: true, candidate: "D", txHash: "a", proposalType: proposalTypeCandidateAdd}}},
+				{[]testerTransaction{{from: "A", to: "A", isProposal: true, parameter: proposalParamMinVoteValue, value: 300, txHash: "a"}}},
 				{[]testerTransaction{{from: "A", to: "A", isDeclare: true, txHash: "a", decision: true}, {from: "B", to: "B", isDeclare: true, txHash: "a", decision: true}, {from: "F", to: "F", isDeclare: true, txHash: "a", decision: true}}},
 				{[]testerTransaction{}},
-				{[]testerTransaction{{from: "C", to: "D", balance: 250, isVote: true}}},
+				{[]testerTransaction{{from: "D", to: "D", balance: 50, isVote: true}, {from: "C", to: "D", balance: 250, isVote: true}}},
 				{[]testerTransaction{}},
 				{[]testerTransaction{}},
 				{[]testerTransaction{}},
@@ -789,12 +806,14 @@ func TestVoting(t *testing.T) {
 				{[]testerTransaction{}},
 			},
 			result: testerSnapshot{
-				Signers: []string{"A", "B", "E", "F"},
-				Tally:   map[string]int{"A": 100, "B": 200, "E": 2000, "F": 200},
-				Voters:  map[string]int{"A": 0, "B": 0, "E": 0, "F": 0},
+				Signers: []string{"A", "B", "D", "E", "F"},
+				Tally:   map[string]int{"A": 100, "B": 200, "D": 300, "E": 2000, "F": 200},
+				Voters:  map[string]int{"A": 0, "B": 0, "C": 5, "D": 5, "E": 0, "F": 0},
 				Votes: map[string]*testerVote{
 					"A": {"A", "A", 100},
 					"B": {"B", "B", 200},
+					"C": {"C", "D", 250},
+					"D": {"D", "D", 50},
 					"E": {"E", "E", 2000},
 					"F": {"F", "F", 200},
 				},
@@ -802,14 +821,13 @@ func TestVoting(t *testing.T) {
 		},
 		{
 			/*	Case 16:
-			*   Candidate from Poa is enable
 			*	Two self vote address A B E F in  genesis
-			*   A proposal B remove from candidates, and A, E ,F declare agree to this proposal,
-			*   the sum stake of A E F is more than 2/3 of all stake, so B is not in candidates
-			*   Now do not change the vote automatically,
+			*   A proposal selfVoteValue 150, and A, E ,F declare agree to this proposal,
+			*   the sum stake of A E F is more than 2/3 of all stake, so it is active from block 6
+			*   Now do not change the vote automatically, A keeps its self vote of 100,
+			* 	but the self vote of D in block 7 is less than selfVoteValue
 			 */
 			addrNames:        []string{"A", "B", "C", "D", "E", "F"},
-			candidateNeedPD:  true,
 			period:           uint64(3),
 			epoch:            uint64(31),
 			maxSignerCount:   uint64(5),
@@ -819,18 +837,18 @@ func TestVoting(t *testing.T) {
 			selfVoters:       []testerSelfVoter{{"A", 100}, {"B", 200}, {"E", 2000}, {"F", 200}},
 			txHeaders: []testerSingleHeader{
 				{[]testerTransaction{}},
-				{[]testerTransaction{{from: "A", to: "A", isProposal: true, candidate: "B", txHash: "a", proposalType: proposalTypeCandidateRemove}}},
+				{[]testerTransaction{{from: "A", to: "A", isProposal: true, parameter: proposalParamSelfVoteValue, value: 150, txHash: "a"}}},
 				{[]testerTransaction{{from: "A", to: "A", isDeclare: true, txHash: "a", decision: true}, {from: "E", to: "E", isDeclare: true, txHash: "a", decision: true}, {from: "F", to: "F", isDeclare: true, txHash: "a", decision: true}}},
 				{[]testerTransaction{}},
 				{[]testerTransaction{}},
 				{[]testerTransaction{}},
-				{[]testerTransaction{}},
+				{[]testerTransaction{{from: "D", to: "D", balance: 100, isVote: true}}},
 				{[]testerTransaction{}},
 				{[]testerTransaction{}},
 				{[]testerTransaction{}},
 			},
 			result: testerSnapshot{
-				Signers: []string{"A", "E", "F"},
+				Signers: []string{"A", "B", "E", "F"},
 				Tally:   map[string]int{"A": 100, "B": 200, "E": 2000, "F": 200},
 				Voters:  map[string]int{"A": 0, "B": 0, "E": 0, "F": 0},
 				Votes: map[string]*testerVote{
@@ -845,10 +863,6 @@ func TestVoting(t *testing.T) {
 
 	// Run through the scenarios and test them
 	for i, tt := range tests {
-		candidateNeedPD = tt.candidateNeedPD
-		if tt.vlCnt == 0 {
-			tt.vlCnt = 1
-		}
 		// Create the account pool and generate the initial set of all address in addrNames
 		accounts := newTesterAccountPool()
 		addrNames := make([]common.Address, len(tt.addrNames))
@@ -882,6 +896,7 @@ func TestVoting(t *testing.T) {
 
 		// extend length of extra, so address of CoinBase can keep signature .
 		genesis := &core.Genesis{
+			Config:    params.AllAlienProtocolChanges,
 			ExtraData: make([]byte, extraVanity+extraSeal),
 		}
 
@@ -891,11 +906,14 @@ func TestVoting(t *testing.T) {
 
 		// Create new alien
 		alien := New(&params.AlienConfig{
-			Period:          tt.period,
-			MinVoteValue:    big.NewInt(int64(tt.minVoterBalance)),
-			MaxSignerCount:  tt.maxSignerCount,
-			SelfVoteSigners: selfVoteSigners,
-		}, db)
+			Period:           tt.period,
+			MinVoteValue:     big.NewInt(int64(tt.minVoterBalance)),
+			SelfVoteValue:    big.NewInt(int64(tt.minVoterBalance)),
+			MaxSignerCount:   tt.maxSignerCount,
+			SelfVoteSigners:  selfVoteSigners,
+			ProposalBlock:    big.NewInt(0),
+			ProposalDeadline: 2 * tt.period,
+		}, db, false)
 
 		// Assemble a chain of headers from the cast votes
 		headers := make([]*types.Header, len(tt.txHeaders))
@@ -904,49 +922,63 @@ func TestVoting(t *testing.T) {
 			var currentBlockVotes []Vote
 			var currentBlockProposals []Proposal
 			var currentBlockDeclares []Declare
-			var modifyPredecessorVotes []Vote
+			var currentBlockAddStakes []StakeChange
+			var currentBlockUnstakes []StakeChange
 			for _, trans := range header.txs {
 				if trans.isVote {
-					if trans.balance >= tt.minVoterBalance && (!candidateNeedPD || snap.isCandidate(accounts.address(trans.to))) {
-						// vote event
-						currentBlockVotes = append(currentBlockVotes, Vote{
-							Voter:     accounts.address(trans.from),
-							Candidate: accounts.address(trans.to),
-							Stake:     big.NewInt(int64(trans.balance)),
-						})
+					// the vote values are governed, the first block has no snapshot yet
+					minVoteValue, selfVoteValue := big.NewInt(int64(tt.minVoterBalance)), big.NewInt(int64(tt.minVoterBalance))
+					if snap != nil {
+						minVoteValue, selfVoteValue = snap.Params.MinVoteValue, snap.Params.SelfVoteValue
 					}
-				} else if trans.isProposal {
-					if snap.isCandidate(accounts.address(trans.from)) {
-						currentBlockProposals = append(currentBlockProposals, Proposal{
-							Hash:                   common.HexToHash(trans.txHash),
-							ValidationLoopCnt:      tt.vlCnt,
-							ImplementNumber:        big.NewInt(1),
-							ProposalType:           trans.proposalType,
-							Proposer:               accounts.address(trans.from),
-							Candidate:              accounts.address(trans.candidate),
-							MinerRewardPerThousand: MinerRewardPerThousand,
-							Declares:               []*Declare{},
-							ReceivedNumber:         big.NewInt(int64(j)),
-						})
+					if trans.from == trans.to && big.NewInt(int64(trans.balance)).Cmp(selfVoteValue) < 0 {
+						continue
 					}
-				} else if trans.isDeclare {
-					if snap.isCandidate(accounts.address(trans.from)) {
-
-						currentBlockDeclares = append(currentBlockDeclares, Declare{
-							ProposalHash: common.HexToHash(trans.txHash),
-							Declarer:     accounts.address(trans.from),
-							Decision:     trans.decision,
-						})
-
+					if trans.from != trans.to && big.NewInt(int64(trans.balance)).Cmp(minVoteValue) < 0 {
+						continue
 					}
-				} else {
+					// vote event
+					currentBlockVotes = append(currentBlockVotes, Vote{
+						Voter:     accounts.address(trans.from),
+						Candidate: accounts.address(trans.to),
+						Stake:     big.NewInt(int64(trans.balance)),
+					})
+				} else if trans.isProposal {
+					currentBlockProposals = append(currentBlockProposals, Proposal{
+						Hash:      common.HexToHash(trans.txHash),
+						Proposer:  accounts.address(trans.from),
+						Parameter: trans.parameter,
+						Value:     big.NewInt(int64(trans.value)),
+					})
+				} else if trans.isDeclare {
+					currentBlockDeclares = append(currentBlockDeclares, Declare{
+						ProposalHash: common.HexToHash(trans.txHash),
+						Declarer:     accounts.address(trans.from),
+						Decision:     trans.decision,
+					})
+				} else if snap != nil {
 					// modify balance
 					// modifyPredecessorVotes
-					// only consider the voter
-					modifyPredecessorVotes = append(modifyPredecessorVotes, Vote{
-						Voter: accounts.address(trans.from),
-						Stake: big.NewInt(int64(trans.balance)),
-					})
+					// only consider the voter, the stake of its vote follows the
+					// balance by adding stake or unstaking, as long as the vote
+					// keeps minVoterBalance
+					vote, ok := snap.Votes[accounts.address(trans.from)]
+					if !ok {
+						continue
+					}
+					change := StakeChange{
+						Voter:  vote.Voter,
+						Amount: new(big.Int).Sub(big.NewInt(int64(trans.balance)), vote.Stake),
+					}
+					switch change.Amount.Sign() {
+					case 1:
+						currentBlockAddStakes = append(currentBlockAddStakes, change)
+					case -1:
+						if trans.balance >= tt.minVoterBalance {
+							change.Amount.Neg(change.Amount)
+							currentBlockUnstakes = append(currentBlockUnstakes, change)
+						}
+					}
 				}
 			}
 			currentHeaderExtra := HeaderExtra{}
@@ -972,7 +1004,7 @@ func TestVoting(t *testing.T) {
 						continue
 					}
 					currentHeaderExtra.SignerQueue = []common.Address{}
-					newSignerQueue, err := snap.createSignerQueue()
+					newSignerQueue, err := snap.createSignerQueue(nil)
 					if err != nil {
 						t.Errorf("test %d: failed to create signer queue: %v", i, err)
 					}
@@ -985,7 +1017,10 @@ func TestVoting(t *testing.T) {
 			}
 
 			currentHeaderExtra.CurrentBlockVotes = currentBlockVotes
-			currentHeaderExtra.ModifyPredecessorVotes = modifyPredecessorVotes
+			currentHeaderExtra.CurrentBlockProposals = currentBlockProposals
+			currentHeaderExtra.CurrentBlockDeclares = currentBlockDeclares
+			currentHeaderExtra.CurrentBlockAddStakes = currentBlockAddStakes
+			currentHeaderExtra.CurrentBlockUnstakes = currentBlockUnstakes
 			currentHeaderExtraEnc, err := rlp.EncodeToBytes(currentHeaderExtra)
 			if err != nil {
 				t.Errorf("test %d: failed to rlp encode to bytes: %v", i, err)
@@ -1080,7 +1115,8 @@ func TestVoting(t *testing.T) {
 					l4 += 1
 				}
 			}
-			if l1 != 10 || l2 != 6 || l3 != 4 || l4 != 1 {
+			// the signers are the top of the tally
+			if l1 != 10 || l2 != 10 || l3 != 1 || l4 != 0 {
 				t.Errorf("test %d: signer not select right count from different level l1 = %d, l2 = %d, l3 = %d, l4 = %d", i, l1, l2, l3, l4)
 			}
 
@@ -1091,7 +1127,7 @@ func TestVoting(t *testing.T) {
 			t.Errorf("test %d: tally length result %d, snap %d dismatch", i, len(tt.result.Tally), len(snap.Tally))
 		}
 		for name, tally := range tt.result.Tally {
-			if big.NewInt(int64(tally)).Cmp(snap.Tally[accounts.address(name)]) != 0 {
+			if snapTally, ok := snap.Tally[accounts.address(name)]; !ok || big.NewInt(int64(tally)).Cmp(snapTally) != 0 {
 				t.Errorf("test %d: tally %v address: %v, tally:%v ,result: %v", i, name, accounts.address(name), snap.Tally[accounts.address(name)], big.NewInt(int64(tally)))
 				continue
 			}
@@ -1101,7 +1137,7 @@ func TestVoting(t *testing.T) {
 			t.Errorf("test %d: voter length result %d, snap %d dismatch", i, len(tt.result.Voters), len(snap.Voters))
 		}
 		for name, number := range tt.result.Voters {
-			if snap.Voters[accounts.address(name)].Cmp(big.NewInt(int64(number))) != 0 {
+			if snapNumber, ok := snap.Voters[accounts.address(name)]; !ok || snapNumber.Cmp(big.NewInt(int64(number))) != 0 {
 				t.Errorf("test %d: voter %v address: %v, number:%v ,result: %v", i, name, accounts.address(name), snap.Voters[accounts.address(name)], big.NewInt(int64(number)))
 				continue
 			}
@@ -1115,7 +1151,7 @@ func TestVoting(t *testing.T) {
 			snapVote, ok := snap.Votes[accounts.address(name)]
 			if !ok {
 				t.Errorf("test %d: votes %v address: %v can not found", i, name, accounts.address(name))
-
+				continue
 			}
 			if snapVote.Voter != accounts.address(vote.voter) {
 				t.Errorf("test %d: votes voter dismatch %v address: %v  , show in snap is %v address: %v", i, vote.voter, accounts.address(vote.voter), accounts.name(snapVote.Voter), snapVote.Voter)
@@ -1130,3 +1166,805 @@ func TestVoting(t *testing.T) {
 
 	}
 }
+
+// testerSnapshotChain applies blocks sealed in turn by the genesis self voters
+// to a snapshot, to test how the events in the header extra change it.
+type testerSnapshotChain struct {
+	accounts *testerAccountPool
+	snap     *Snapshot
+}
+
+func newTesterSnapshotChain(config *params.AlienConfig, selfVoters []testerSelfVoter) *testerSnapshotChain {
+	accounts := newTesterAccountPool()
+
+	var votes []*Vote
+	for _, voter := range selfVoters {
+		votes = append(votes, &Vote{
+			Voter:     accounts.address(voter.voter),
+			Candidate: accounts.address(voter.voter),
+			Stake:     big.NewInt(int64(voter.balance)),
+		})
+		config.SelfVoteSigners = append(config.SelfVoteSigners, accounts.address(voter.voter))
+	}
+	if config.Period == 0 {
+		config.Period = 3
+	}
+	if config.MaxSignerCount == 0 {
+		config.MaxSignerCount = 3
+	}
+	config.MinVoteValue = big.NewInt(50)
+	config.SelfVoteValue = big.NewInt(50)
+	if config.Freeze == 0 {
+		config.Freeze = 9
+	}
+	if config.RedelegateCooldown == 0 {
+		config.RedelegateCooldown = config.Freeze
+	}
+	if config.DoubleSignSlashRate == 0 {
+		config.DoubleSignSlashRate = defaultDoubleSignSlashRate
+	}
+	if config.MissingSlashRate == 0 {
+		config.MissingSlashRate = defaultMissingSlashRate
+	}
+	if config.MissingSlashThreshold == 0 {
+		config.MissingSlashThreshold = defaultMissingSlashThreshold
+	}
+	if config.ProposalDeadline == 0 {
+		config.ProposalDeadline = 2 * config.Period
+	}
+	sigcache, _ := lru.NewARC(inMemorySignatures)
+	return &testerSnapshotChain{
+		accounts: accounts,
+		snap:     newSnapshot(config, sigcache, common.Hash{}, votes, 1),
+	}
+}
+
+// apply seals the next block with the header extra and applies it.
+func (c *testerSnapshotChain) apply(t *testing.T, headerExtra HeaderExtra) {
+	number := c.snap.Number + 1
+	signer := *c.snap.Signers[number%uint64(len(c.snap.Signers))]
+	for _, signer := range c.snap.Signers {
+		headerExtra.SignerQueue = append(headerExtra.SignerQueue, *signer)
+	}
+	headerExtraEnc, err := rlp.EncodeToBytes(headerExtra)
+	if err != nil {
+		t.Fatalf("block %d: failed to rlp encode to bytes: %v", number, err)
+	}
+	extra := make([]byte, extraVanity+len(headerExtraEnc)+extraSeal)
+	copy(extra[extraVanity:], headerExtraEnc)
+
+	header := &types.Header{
+		ParentHash: c.snap.Hash,
+		Number:     new(big.Int).SetUint64(number),
+		Time:       new(big.Int).SetUint64(number * c.snap.Period),
+		Coinbase:   signer,
+		Extra:      extra,
+	}
+	c.accounts.sign(header, c.accounts.name(signer))
+
+	snap, err := c.snap.apply([]*types.Header{header})
+	if err != nil {
+		t.Fatalf("block %d: failed to apply header: %v", number, err)
+	}
+	c.snap = snap
+}
+
+// applyEmpty applies n blocks without events.
+func (c *testerSnapshotChain) applyEmpty(t *testing.T, n int) {
+	for i := 0; i < n; i++ {
+		c.apply(t, HeaderExtra{})
+	}
+}
+
+// checkTally checks the tally of the candidates in the snapshot.
+func (c *testerSnapshotChain) checkTally(t *testing.T, tally map[string]int64) {
+	if len(c.snap.Tally) != len(tally) {
+		t.Errorf("block %d: tally length %d, want %d", c.snap.Number, len(c.snap.Tally), len(tally))
+	}
+	for name, want := range tally {
+		if have, ok := c.snap.Tally[c.accounts.address(name)]; !ok || have.Cmp(big.NewInt(want)) != 0 {
+			t.Errorf("block %d: tally of %s %v, want %d", c.snap.Number, name, have, want)
+		}
+	}
+}
+
+// checkVote checks the stake and the candidate of the vote of the voter, both in
+// the votes and in the votes of the candidate.
+func (c *testerSnapshotChain) checkVote(t *testing.T, voter string, candidate string, stake int64) {
+	vote, ok := c.snap.Votes[c.accounts.address(voter)]
+	if !ok {
+		t.Errorf("block %d: vote of %s not found", c.snap.Number, voter)
+		return
+	}
+	if vote.Candidate != c.accounts.address(candidate) || vote.Stake.Cmp(big.NewInt(stake)) != 0 {
+		t.Errorf("block %d: vote of %s for %s with %v, want %s with %d", c.snap.Number, voter, c.accounts.name(vote.Candidate), vote.Stake, candidate, stake)
+	}
+	found := false
+	for _, candidateVote := range c.snap.Candidates[c.accounts.address(candidate)] {
+		if candidateVote.Voter == vote.Voter {
+			found = true
+			if candidateVote.Stake.Cmp(big.NewInt(stake)) != 0 {
+				t.Errorf("block %d: vote of %s in candidate %s with %v, want %d", c.snap.Number, voter, candidate, candidateVote.Stake, stake)
+			}
+		}
+	}
+	if !found {
+		t.Errorf("block %d: vote of %s not in the votes of candidate %s", c.snap.Number, voter, candidate)
+	}
+}
+
+// Tests that the stake added to and withdrawn from a vote changes the vote and
+// the tally, and that the withdrawn stake is frozen until it is returned.
+func TestSnapshotStakeChanges(t *testing.T) {
+	chain := newTesterSnapshotChain(&params.AlienConfig{Freeze: 9}, []testerSelfVoter{{"A", 100}, {"B", 200}})
+	accounts := chain.accounts
+
+	chain.apply(t, HeaderExtra{CurrentBlockVotes: []Vote{{Voter: accounts.address("C"), Candidate: accounts.address("A"), Stake: big.NewInt(100)}}})
+	chain.apply(t, HeaderExtra{CurrentBlockAddStakes: []StakeChange{
+		{Voter: accounts.address("C"), Amount: big.NewInt(50)},
+		{Voter: accounts.address("D"), Amount: big.NewInt(50)}, // no vote, dropped
+	}})
+	chain.checkVote(t, "C", "A", 150)
+	chain.checkTally(t, map[string]int64{"A": 250, "B": 200})
+
+	chain.apply(t, HeaderExtra{CurrentBlockUnstakes: []StakeChange{
+		{Voter: accounts.address("C"), Amount: big.NewInt(30)},
+		{Voter: accounts.address("B"), Amount: big.NewInt(200)}, // the whole stake, dropped
+	}})
+	chain.checkVote(t, "C", "A", 120)
+	chain.checkVote(t, "B", "B", 200)
+	chain.checkTally(t, map[string]int64{"A": 220, "B": 200})
+
+	unstakes := chain.snap.Unstakes[accounts.address("C")]
+	if len(unstakes) != 1 || unstakes[0].Amount.Cmp(big.NewInt(30)) != 0 || unstakes[0].Number.Uint64() != 3 {
+		t.Fatalf("unstakes of C %v, want 30 in block 3", unstakes)
+	}
+	if len(chain.snap.Unstakes[accounts.address("B")]) != 0 {
+		t.Errorf("unstakes of B %v, want none", chain.snap.Unstakes[accounts.address("B")])
+	}
+	// The stake is frozen for 9s / 3s = 3 blocks
+	chain.applyEmpty(t, 2)
+	if len(chain.snap.Unstakes[accounts.address("C")]) != 1 {
+		t.Errorf("block %d: unstake of C returned before block 6", chain.snap.Number)
+	}
+	chain.applyEmpty(t, 1)
+	if len(chain.snap.Unstakes[accounts.address("C")]) != 0 {
+		t.Errorf("block %d: unstake of C not returned", chain.snap.Number)
+	}
+}
+
+// Tests that the frozen stake is returned in the block recorded when it was
+// frozen, even if a proposal changes the freeze window meanwhile.
+func TestSnapshotFreezeChange(t *testing.T) {
+	chain := newTesterSnapshotChain(&params.AlienConfig{Freeze: 30, ProposalBlock: big.NewInt(0)}, []testerSelfVoter{{"A", 100}, {"B", 200}})
+	accounts := chain.accounts
+
+	freeze := Proposal{Hash: common.HexToHash("0x01"), Proposer: accounts.address("A"), Parameter: proposalParamFreeze, Value: big.NewInt(9)}
+	chain.apply(t, HeaderExtra{
+		CurrentBlockVotes: []Vote{
+			{Voter: accounts.address("C"), Candidate: accounts.address("A"), Stake: big.NewInt(100)},
+			{Voter: accounts.address("D"), Candidate: accounts.address("B"), Stake: big.NewInt(100)},
+		},
+		CurrentBlockProposals: []Proposal{freeze},
+	})
+	chain.apply(t, HeaderExtra{
+		CurrentBlockUnstakes: []StakeChange{{Voter: accounts.address("C"), Amount: big.NewInt(30)}},
+		CurrentBlockCancels:  []Cancel{{Canceler: accounts.address("D")}},
+		CurrentBlockDeclares: []Declare{
+			{ProposalHash: freeze.Hash, Declarer: accounts.address("A"), Decision: true},
+			{ProposalHash: freeze.Hash, Declarer: accounts.address("B"), Decision: true},
+		},
+	})
+	// The stake is frozen for 30s / 3s = 10 blocks, the cancel returns it 2 blocks early
+	if unstakes := chain.snap.Unstakes[accounts.address("C")]; len(unstakes) != 1 || unstakes[0].UnlockNumber != 12 {
+		t.Fatalf("unstakes of C %v, want one returned in block 12", unstakes)
+	}
+	if number := chain.snap.CancelUnlocks[accounts.address("D")]; number != 10 {
+		t.Fatalf("cancel of D returned in block %d, want 10", number)
+	}
+	// The freeze window shrinks to 9s / 3s = 3 blocks while the stake is frozen
+	for chain.snap.Params.Freeze != 9 {
+		if chain.snap.Number >= 9 {
+			t.Fatalf("block %d: freeze %d, want 9", chain.snap.Number, chain.snap.Params.Freeze)
+		}
+		chain.applyEmpty(t, 1)
+	}
+	for _, item := range chain.snap.unlockSchedule(accounts.address("C")) {
+		if item.UnlockNumber != 12 {
+			t.Errorf("block %d: unstake of C returned in block %d, want 12", chain.snap.Number, item.UnlockNumber)
+		}
+	}
+	chain.applyEmpty(t, int(9-chain.snap.Number))
+	if _, ok := chain.snap.Cancels[accounts.address("D")]; !ok {
+		t.Errorf("block %d: cancel of D removed before block 10", chain.snap.Number)
+	}
+	chain.applyEmpty(t, 1)
+	if _, ok := chain.snap.Votes[accounts.address("D")]; ok {
+		t.Errorf("block %d: canceled vote of D not removed", chain.snap.Number)
+	}
+	chain.applyEmpty(t, 1)
+	if len(chain.snap.Unstakes[accounts.address("C")]) != 1 {
+		t.Errorf("block %d: unstake of C returned before block 12", chain.snap.Number)
+	}
+	chain.applyEmpty(t, 1)
+	if len(chain.snap.Unstakes[accounts.address("C")]) != 0 {
+		t.Errorf("block %d: unstake of C not returned", chain.snap.Number)
+	}
+}
+
+// Tests that a vote is moved to another candidate once the cooldown since the
+// vote passed, and that self votes can't be moved.
+func TestSnapshotRedelegations(t *testing.T) {
+	chain := newTesterSnapshotChain(&params.AlienConfig{RedelegateCooldown: 6}, []testerSelfVoter{{"A", 100}, {"B", 200}})
+	accounts := chain.accounts
+
+	chain.apply(t, HeaderExtra{CurrentBlockVotes: []Vote{{Voter: accounts.address("C"), Candidate: accounts.address("A"), Stake: big.NewInt(100)}}})
+
+	// The cooldown of 6s / 3s = 2 blocks since the vote in block 1 isn't over
+	redelegation := Redelegation{Voter: accounts.address("C"), Candidate: accounts.address("B")}
+	chain.apply(t, HeaderExtra{CurrentBlockRedelegations: []Redelegation{redelegation}})
+	chain.checkVote(t, "C", "A", 100)
+	chain.checkTally(t, map[string]int64{"A": 200, "B": 200})
+
+	chain.apply(t, HeaderExtra{CurrentBlockRedelegations: []Redelegation{
+		redelegation,
+		{Voter: accounts.address("A"), Candidate: accounts.address("B")}, // self vote, dropped
+		{Voter: accounts.address("D"), Candidate: accounts.address("B")}, // no vote, dropped
+	}})
+	chain.checkVote(t, "C", "B", 100)
+	chain.checkVote(t, "A", "A", 100)
+	chain.checkTally(t, map[string]int64{"A": 100, "B": 300})
+
+	for _, vote := range chain.snap.Candidates[accounts.address("A")] {
+		if vote.Voter == accounts.address("C") {
+			t.Errorf("vote of C still in the votes of candidate A")
+		}
+	}
+	if voted := chain.snap.Voters[accounts.address("C")]; voted.Uint64() != 3 {
+		t.Errorf("vote number of C %v, want 3", voted)
+	}
+	// The cooldown starts again from the redelegation
+	chain.apply(t, HeaderExtra{CurrentBlockRedelegations: []Redelegation{{Voter: accounts.address("C"), Candidate: accounts.address("A")}}})
+	chain.checkVote(t, "C", "B", 100)
+}
+
+// Tests that a slash takes its rate from every vote for the signer, and that a
+// double sign slash is recorded.
+func TestSnapshotSlashes(t *testing.T) {
+	chain := newTesterSnapshotChain(&params.AlienConfig{DoubleSignSlashRate: 100, MissingSlashRate: 50}, []testerSelfVoter{{"A", 100}, {"B", 200}})
+	accounts := chain.accounts
+
+	chain.apply(t, HeaderExtra{CurrentBlockVotes: []Vote{{Voter: accounts.address("C"), Candidate: accounts.address("A"), Stake: big.NewInt(200)}}})
+	chain.apply(t, HeaderExtra{CurrentBlockSlashes: []Slash{{Signer: accounts.address("A"), Reason: slashReasonDoubleSign, Reporter: accounts.address("B"), Number: 1}}})
+	chain.checkVote(t, "A", "A", 90)
+	chain.checkVote(t, "C", "A", 180)
+	chain.checkTally(t, map[string]int64{"A": 270, "B": 200})
+	if number, ok := chain.snap.DoubleSigned[accounts.address("A")]; !ok || number != 1 {
+		t.Errorf("double sign of A in block %d, want 1", number)
+	}
+
+	chain.apply(t, HeaderExtra{CurrentBlockSlashes: []Slash{{Signer: accounts.address("B"), Reason: slashReasonMissing, Reporter: accounts.address("A")}}})
+	chain.checkVote(t, "B", "B", 190)
+	chain.checkTally(t, map[string]int64{"A": 270, "B": 190})
+	if _, ok := chain.snap.DoubleSigned[accounts.address("B")]; ok {
+		t.Errorf("missing slash of B recorded as double sign")
+	}
+}
+
+// Tests that the confirmations of both the transactions and the p2p messages are
+// counted once for each signer, and dropped once they expire.
+func TestSnapshotConfirmations(t *testing.T) {
+	chain := newTesterSnapshotChain(&params.AlienConfig{}, []testerSelfVoter{{"A", 100}, {"B", 200}})
+	accounts := chain.accounts
+
+	chain.applyEmpty(t, 1)
+	chain.apply(t, HeaderExtra{
+		CurrentBlockConfirmations: []Confirmation{
+			{Signer: accounts.address("A"), BlockNumber: big.NewInt(1)},
+			{Signer: accounts.address("A"), BlockNumber: big.NewInt(1)},
+		},
+		CurrentBlockSignedConfirmations: []SignedConfirmation{
+			{Signer: accounts.address("B"), BlockNumber: 1},
+			{Signer: accounts.address("A"), BlockNumber: 2},
+		},
+	})
+	if confirmers := chain.snap.Confirmations[1]; len(confirmers) != 2 {
+		t.Errorf("confirmer count of block 1 %d, want 2", len(confirmers))
+	}
+	if confirmers := chain.snap.Confirmations[2]; len(confirmers) != 1 || *confirmers[0] != accounts.address("A") {
+		t.Errorf("confirmers of block 2 %v, want A", confirmers)
+	}
+	// The confirmations are kept for max signer count blocks
+	chain.applyEmpty(t, 2)
+	if _, ok := chain.snap.Confirmations[1]; !ok {
+		t.Errorf("block %d: confirmations of block 1 dropped", chain.snap.Number)
+	}
+	chain.applyEmpty(t, 1)
+	if _, ok := chain.snap.Confirmations[1]; ok {
+		t.Errorf("block %d: confirmations of block 1 not dropped", chain.snap.Number)
+	}
+}
+
+// Tests that a proposal declared yes by more than two thirds of the tally is
+// activated at the next loop boundary, and that the others are rejected.
+func TestSnapshotProposals(t *testing.T) {
+	chain := newTesterSnapshotChain(&params.AlienConfig{ProposalBlock: big.NewInt(0)}, []testerSelfVoter{{"A", 100}, {"B", 200}})
+	accounts := chain.accounts
+
+	freeze := Proposal{Hash: common.HexToHash("0x01"), Proposer: accounts.address("A"), Parameter: proposalParamFreeze, Value: big.NewInt(30)}
+	minVote := Proposal{Hash: common.HexToHash("0x02"), Proposer: accounts.address("B"), Parameter: proposalParamMinVoteValue, Value: big.NewInt(80)}
+	chain.apply(t, HeaderExtra{CurrentBlockProposals: []Proposal{
+		freeze,
+		minVote,
+		{Hash: common.HexToHash("0x03"), Proposer: accounts.address("C"), Parameter: proposalParamPeriod, Value: big.NewInt(1)}, // not a candidate, dropped
+	}})
+	if len(chain.snap.Proposals) != 2 {
+		t.Fatalf("proposal count %d, want 2", len(chain.snap.Proposals))
+	}
+	chain.apply(t, HeaderExtra{CurrentBlockDeclares: []Declare{
+		{ProposalHash: freeze.Hash, Declarer: accounts.address("A"), Decision: true},
+		{ProposalHash: freeze.Hash, Declarer: accounts.address("B"), Decision: true},
+		{ProposalHash: minVote.Hash, Declarer: accounts.address("A"), Decision: true},
+		{ProposalHash: minVote.Hash, Declarer: accounts.address("C"), Decision: true}, // not a candidate, dropped
+	}})
+	// The deadline of 6s / 3s = 2 blocks since block 1 is reached
+	chain.applyEmpty(t, 1)
+	if status := chain.snap.Proposals[freeze.Hash].Status; status != proposalStatusPassed {
+		t.Fatalf("freeze proposal %s, want %s", status, proposalStatusPassed)
+	}
+	if status := chain.snap.Proposals[minVote.Hash].Status; status != proposalStatusRejected {
+		t.Errorf("min vote proposal %s, want %s", status, proposalStatusRejected)
+	}
+	if number := chain.snap.Proposals[freeze.Hash].ActivationNumber; number != 6 {
+		t.Errorf("freeze proposal activation number %d, want 6", number)
+	}
+	chain.applyEmpty(t, 2)
+	if chain.snap.Params.Freeze != 9 {
+		t.Errorf("block %d: freeze %d, want 9", chain.snap.Number, chain.snap.Params.Freeze)
+	}
+	chain.applyEmpty(t, 1)
+	if chain.snap.Params.Freeze != 30 {
+		t.Errorf("block %d: freeze %d, want 30", chain.snap.Number, chain.snap.Params.Freeze)
+	}
+	if status := chain.snap.Proposals[freeze.Hash].Status; status != proposalStatusActive {
+		t.Errorf("freeze proposal %s, want %s", status, proposalStatusActive)
+	}
+	if chain.snap.Params.MinVoteValue.Cmp(big.NewInt(50)) != 0 {
+		t.Errorf("min vote value %v, want 50", chain.snap.Params.MinVoteValue)
+	}
+}
+
+// Tests that the checkpoint anchored last is kept for each side chain, without
+// changing the snapshots it was copied from.
+func TestSnapshotCheckpoints(t *testing.T) {
+	chain := newTesterSnapshotChain(&params.AlienConfig{}, []testerSelfVoter{{"A", 100}, {"B", 200}})
+	accounts := chain.accounts
+
+	chain.apply(t, HeaderExtra{CurrentBlockCheckpoints: []SideCheckpoint{
+		{AppId: "1", Number: 10, Signers: []common.Address{accounts.address("C")}},
+		{AppId: "2", Number: 20},
+	}})
+	parent := chain.snap
+	chain.apply(t, HeaderExtra{CurrentBlockCheckpoints: []SideCheckpoint{{AppId: "1", Number: 30, Signers: []common.Address{accounts.address("D")}}}})
+
+	if len(chain.snap.Checkpoints) != 2 {
+		t.Fatalf("checkpoint count %d, want 2", len(chain.snap.Checkpoints))
+	}
+	if checkpoint := chain.snap.Checkpoints["1"]; checkpoint.Number != 30 || checkpoint.Signers[0] != accounts.address("D") {
+		t.Errorf("checkpoint of 1 at %d signed by %s, want 30 signed by D", checkpoint.Number, accounts.name(checkpoint.Signers[0]))
+	}
+	if checkpoint := chain.snap.Checkpoints["2"]; checkpoint.Number != 20 {
+		t.Errorf("checkpoint of 2 at %d, want 20", checkpoint.Number)
+	}
+	if checkpoint := parent.Checkpoints["1"]; checkpoint.Number != 10 || checkpoint.Signers[0] != accounts.address("C") {
+		t.Errorf("parent checkpoint of 1 at %d signed by %s, want 10 signed by C", checkpoint.Number, accounts.name(checkpoint.Signers[0]))
+	}
+}
+
+// Tests that proofs of side chain blocks are only accepted up to a checkpoint
+// recorded in the state, and that the checkpoint is rebuilt from the header of
+// the main chain block which anchored it.
+func TestAnchoredCheckpoints(t *testing.T) {
+	sideHeaders := make([]*types.Header, 3)
+	for i := range sideHeaders {
+		sideHeaders[i] = &types.Header{Number: big.NewInt(int64(i + 1)), Appid: "1"}
+		if i > 0 {
+			sideHeaders[i].ParentHash = sideHeaders[i-1].Hash()
+		}
+	}
+	checkpoint := SideCheckpoint{AppId: "1", Number: 3, Hash: sideHeaders[2].Hash(), MainNumber: 5}
+	extra, err := rlp.EncodeToBytes(HeaderExtra{CurrentBlockCheckpoints: []SideCheckpoint{checkpoint}})
+	if err != nil {
+		t.Fatalf("failed to encode header extra: %v", err)
+	}
+	mainHeader := &types.Header{Number: big.NewInt(5), Extra: append(append(make([]byte, extraVanity), extra...), make([]byte, extraSeal)...)}
+	chain := newTesterHeaderChain(mainHeader)
+
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(ethdb.NewMemDatabase()))
+	if _, err := verifyAnchoredHeaders(statedb, sideHeaders); err != errBridgeNotFinal {
+		t.Fatalf("unanchored headers: error mismatch: have %v, want %v", err, errBridgeNotFinal)
+	}
+	writeCheckpoint(statedb, &checkpoint)
+
+	if mainNumber, err := verifyAnchoredHeaders(statedb, sideHeaders); err != nil || mainNumber != 5 {
+		t.Errorf("anchored headers: have main block %d, %v, want 5", mainNumber, err)
+	}
+	if _, err := verifyAnchoredHeaders(statedb, sideHeaders[:2]); err != errBridgeNotFinal {
+		t.Errorf("headers to an unanchored block: error mismatch: have %v, want %v", err, errBridgeNotFinal)
+	}
+	unlinked := []*types.Header{sideHeaders[0], sideHeaders[2]}
+	if _, err := verifyAnchoredHeaders(statedb, unlinked); err != errBridgeProof {
+		t.Errorf("unlinked headers: error mismatch: have %v, want %v", err, errBridgeProof)
+	}
+	if stored := readCheckpoint(chain, statedb, "1", 3); stored == nil || stored.Hash != checkpoint.Hash || stored.MainNumber != 5 {
+		t.Errorf("checkpoint mismatch: have %+v, want %+v", stored, checkpoint)
+	}
+	if stored := readCheckpoint(chain, statedb, "1", 2); stored != nil {
+		t.Errorf("checkpoint of an unanchored block: have %+v", stored)
+	}
+}
+
+// testerHeaderChain implements consensus.ChainReader over a canonical chain of
+// headers kept by number.
+type testerHeaderChain struct {
+	headers map[uint64]*types.Header
+	head    uint64
+}
+
+func newTesterHeaderChain(headers ...*types.Header) *testerHeaderChain {
+	chain := &testerHeaderChain{headers: make(map[uint64]*types.Header)}
+	for _, header := range headers {
+		chain.headers[header.Number.Uint64()] = header
+		if header.Number.Uint64() > chain.head {
+			chain.head = header.Number.Uint64()
+		}
+	}
+	return chain
+}
+
+func (c *testerHeaderChain) Config() *params.ChainConfig  { return params.AllAlienProtocolChanges }
+func (c *testerHeaderChain) CurrentHeader() *types.Header { return c.headers[c.head] }
+func (c *testerHeaderChain) GetHeaderByNumber(number uint64) *types.Header {
+	return c.headers[number]
+}
+func (c *testerHeaderChain) GetHeader(hash common.Hash, number uint64) *types.Header {
+	if header := c.headers[number]; header != nil && header.Hash() == hash {
+		return header
+	}
+	return nil
+}
+func (c *testerHeaderChain) GetHeaderByHash(hash common.Hash) *types.Header {
+	for _, header := range c.headers {
+		if header.Hash() == hash {
+			return header
+		}
+	}
+	return nil
+}
+func (c *testerHeaderChain) GetBlock(common.Hash, uint64) *types.Block { return nil }
+
+// Tests that the signer block reward halves every year of blocks sealed at the
+// governed period.
+func TestBlockRewardHalving(t *testing.T) {
+	chain := newTesterSnapshotChain(&params.AlienConfig{Period: 3}, []testerSelfVoter{{"A", 100}})
+	chain.snap.Period = 6
+
+	yearBlocks := uint64(SecondsPerYear / 6)
+	if reward, _ := chain.snap.blockReward(yearBlocks - 1); reward.Cmp(SignerBlockReward) != 0 {
+		t.Errorf("block %d: reward %v, want %v", yearBlocks-1, reward, SignerBlockReward)
+	}
+	half := new(big.Int).Rsh(SignerBlockReward, 1)
+	if reward, _ := chain.snap.blockReward(yearBlocks); reward.Cmp(half) != 0 {
+		t.Errorf("block %d: reward %v, want %v", yearBlocks, reward, half)
+	}
+}
+
+// Tests that the reward index keeps the rewards of the canonical blocks, replaces
+// the record of a block finalized again at the same height and prunes the
+// records beyond the retained range, while the daily buckets keep the totals.
+func TestRewardIndex(t *testing.T) {
+	accounts := newTesterAccountPool()
+	miner, other, voter := accounts.address("A"), accounts.address("B"), accounts.address("C")
+
+	engine := &Alien{config: &params.AlienConfig{}, store: ethdb.NewMemDatabase()}
+	if _, err := engine.rewardHistory(newTesterHeaderChain(), miner, 1, 1); err != errRewardIndexDisabled {
+		t.Fatalf("reward history error mismatch: have %v, want %v", err, errRewardIndexDisabled)
+	}
+	engine.EnableRewardIndex(2)
+
+	headers := make([]*types.Header, 4)
+	for i := range headers {
+		headers[i] = &types.Header{Number: big.NewInt(int64(i)), Time: big.NewInt(int64(i) * 3), Coinbase: miner}
+		if i > 0 {
+			headers[i].ParentHash = headers[i-1].Hash()
+		}
+	}
+	headers[3].Time = big.NewInt(secondsPerDay)
+	record := func(number uint64, miner common.Address, minerReward int64, voterReward int64) *RewardRecord {
+		return &RewardRecord{
+			Number:       number,
+			Time:         headers[number].Time.Uint64(),
+			ParentHash:   headers[number].ParentHash,
+			Miner:        miner,
+			MinerReward:  big.NewInt(minerReward),
+			VoterRewards: []VoterReward{{Voter: voter, Reward: big.NewInt(voterReward)}},
+		}
+	}
+	engine.storeRewardRecord(record(1, miner, 10, 5))
+	engine.storeRewardRecord(record(2, other, 20, 6)) // a fork block mined by another signer
+	engine.storeRewardRecord(record(2, miner, 30, 7))
+	engine.storeRewardRecord(record(3, miner, 40, 8))
+
+	chain := newTesterHeaderChain(headers...)
+	entries, err := engine.rewardHistory(chain, miner, 1, 3)
+	if err != nil {
+		t.Fatalf("failed to get reward history: %v", err)
+	}
+	// the record of block 1 is pruned, retaining the last two
+	if len(entries) != 2 || entries[0].BlockNumber != 2 || entries[1].BlockNumber != 3 {
+		t.Fatalf("reward history mismatch: have %d entries, want blocks 2 and 3", len(entries))
+	}
+	if entries[0].MinerReward.Int64() != 30 || entries[1].MinerReward.Int64() != 40 {
+		t.Errorf("miner rewards mismatch: have %v and %v, want 30 and 40", entries[0].MinerReward, entries[1].MinerReward)
+	}
+	if entries, _ := engine.rewardHistory(chain, other, 1, 3); len(entries) != 0 {
+		t.Errorf("rewards of the replaced fork block left: %d entries", len(entries))
+	}
+	if _, err := engine.rewardHistory(chain, miner, 3, 2); err != errInvalidRewardRange {
+		t.Errorf("reward range error mismatch: have %v, want %v", err, errInvalidRewardRange)
+	}
+	// a record whose block is not canonical any more is skipped
+	fork := newTesterHeaderChain(headers[0], headers[1], &types.Header{Number: big.NewInt(2), ParentHash: headers[1].Hash(), Coinbase: other}, headers[3])
+	if entries, _ := engine.rewardHistory(fork, miner, 2, 2); len(entries) != 0 {
+		t.Errorf("rewards of a non canonical block reported: %d entries", len(entries))
+	}
+
+	// the daily buckets keep the pruned block, and drop the replaced one
+	summary, err := engine.rewardSummary(chain, miner, "week")
+	if err != nil {
+		t.Fatalf("failed to get reward summary: %v", err)
+	}
+	if summary.Blocks != 3 || summary.MinerReward.Int64() != 80 || summary.VoterReward.Int64() != 0 {
+		t.Errorf("miner summary mismatch: have %d blocks, %v miner and %v voter reward, want 3 blocks, 80 and 0", summary.Blocks, summary.MinerReward, summary.VoterReward)
+	}
+	summary, err = engine.rewardSummary(chain, voter, "day")
+	if err != nil {
+		t.Fatalf("failed to get reward summary: %v", err)
+	}
+	if summary.Blocks != 1 || summary.VoterReward.Int64() != 8 || summary.Total.Int64() != 8 {
+		t.Errorf("voter summary mismatch: have %d blocks, %v total, want 1 block, 8 total", summary.Blocks, summary.Total)
+	}
+	if _, err := engine.rewardSummary(chain, voter, "hour"); err != errUnknownRewardPeriod {
+		t.Errorf("reward period error mismatch: have %v, want %v", err, errUnknownRewardPeriod)
+	}
+}
+
+// FakeMainChainEth is an eth namespace backend serving the headers of a main
+// chain, stalling the first calls to simulate an unreachable main chain.
+type FakeMainChainEth struct {
+	headers   map[uint64]*types.Header
+	finalized uint64
+	stalls    int // Number of calls left to stall
+	calls     int // Number of calls served
+	lock      sync.Mutex
+}
+
+func (s *FakeMainChainEth) GetBlockByNumber(number rpc.BlockNumber, full bool) (*types.Header, error) {
+	s.lock.Lock()
+	s.calls++
+	stall := s.stalls > 0
+	if stall {
+		s.stalls--
+	}
+	switch number {
+	case rpc.FinalizedBlockNumber:
+		number = rpc.BlockNumber(s.finalized)
+	case rpc.LatestBlockNumber:
+		number = rpc.BlockNumber(len(s.headers) - 1)
+	}
+	header, ok := s.headers[uint64(number)]
+	s.lock.Unlock()
+
+	if stall {
+		time.Sleep(2 * mainchainRPCTimeout * time.Millisecond)
+	}
+	if !ok {
+		return nil, errors.New("unknown block")
+	}
+	return header, nil
+}
+
+// FakeMainChainAlien is an alien namespace backend serving a single main chain
+// snapshot.
+type FakeMainChainAlien struct {
+	snap  *Snapshot
+	calls int
+}
+
+func (s *FakeMainChainAlien) GetSnapshotByHeaderTime(targetTime uint64) *Snapshot {
+	s.calls++
+	return s.snap
+}
+
+// newTestRPCMainChain creates an rpc main chain connected in process to a main
+// chain of the given number of blocks, of which the first finalized are.
+func newTestRPCMainChain(t *testing.T, blocks int, finalized uint64) (*rpcMainChain, *FakeMainChainEth, *FakeMainChainAlien) {
+	ethApi := &FakeMainChainEth{headers: make(map[uint64]*types.Header), finalized: finalized}
+	for i := 0; i < blocks; i++ {
+		ethApi.headers[uint64(i)] = &types.Header{Number: big.NewInt(int64(i)), Time: big.NewInt(int64(10 * i)), Difficulty: common.Big1}
+	}
+	alienApi := &FakeMainChainAlien{}
+
+	server := rpc.NewServer()
+	if err := server.RegisterName("eth", ethApi); err != nil {
+		t.Fatalf("failed to register eth service: %v", err)
+	}
+	if err := server.RegisterName("alien", alienApi); err != nil {
+		t.Fatalf("failed to register alien service: %v", err)
+	}
+	return newRPCMainChain(rpc.DialInProc(server)), ethApi, alienApi
+}
+
+// Tests that the rpc main chain attempts the calls failing to reach the main
+// chain again, and only confirms the blocks the main chain finalized.
+func TestRPCMainChainConfirmedHash(t *testing.T) {
+	main, ethApi, _ := newTestRPCMainChain(t, 4, 2)
+	defer main.stop()
+
+	// A stalled call is attempted again
+	ethApi.lock.Lock()
+	ethApi.stalls = 1
+	ethApi.lock.Unlock()
+
+	hash, err := main.confirmedHash(1)
+	if err != nil {
+		t.Fatalf("failed to retrieve confirmed hash: %v", err)
+	}
+	if want := ethApi.headers[1].Hash(); hash != want {
+		t.Errorf("confirmed hash mismatch: have %x, want %x", hash, want)
+	}
+	// Blocks above the finalized one are not confirmed
+	if _, err := main.confirmedHash(3); err != errBridgeNotFinal {
+		t.Errorf("unfinalized block error mismatch: have %v, want %v", err, errBridgeNotFinal)
+	}
+	// Errors of the main chain are not attempted again
+	ethApi.lock.Lock()
+	delete(ethApi.headers, 1)
+	calls := ethApi.calls
+	ethApi.lock.Unlock()
+
+	if _, err := main.confirmedHash(1); err == nil {
+		t.Errorf("unknown block confirmed")
+	}
+	ethApi.lock.Lock()
+	defer ethApi.lock.Unlock()
+	if ethApi.calls-calls != 2 {
+		t.Errorf("failed call attempts mismatch: have %d, want 2", ethApi.calls-calls)
+	}
+}
+
+// Tests that the rpc main chain only accepts the snapshots of main chain blocks
+// and reuses them for the headers sealed in their signer loop.
+func TestRPCMainChainSnapshot(t *testing.T) {
+	main, ethApi, alienApi := newTestRPCMainChain(t, 4, 2)
+	defer main.stop()
+
+	signer := common.Address{1}
+	alienApi.snap = &Snapshot{Number: 2, Hash: common.Hash{2}, Period: 10, LoopStartTime: 20, Signers: []*common.Address{&signer}}
+	if _, err := main.snapshotByTime(25); err != errMainChainSnapshot {
+		t.Fatalf("forged snapshot error mismatch: have %v, want %v", err, errMainChainSnapshot)
+	}
+	alienApi.snap.Hash = ethApi.headers[2].Hash()
+	snap, err := main.snapshotByTime(25)
+	if err != nil {
+		t.Fatalf("failed to retrieve snapshot: %v", err)
+	}
+	if snap.Hash != alienApi.snap.Hash {
+		t.Errorf("snapshot hash mismatch: have %x, want %x", snap.Hash, alienApi.snap.Hash)
+	}
+	calls := alienApi.calls
+	if _, err := main.snapshotByTime(29); err != nil {
+		t.Fatalf("failed to retrieve snapshot: %v", err)
+	}
+	if alienApi.calls != calls {
+		t.Errorf("snapshot of the signer loop retrieved again")
+	}
+}
+
+// newTesterScheduleChain creates an engine and a chain of headers sealed by the
+// given coinbases with the given extras, the snapshot of every header built by
+// the given function and cached by the engine.
+func newTesterScheduleChain(t *testing.T, extras []HeaderExtra, coinbases []common.Address, snapshot func(number int) *Snapshot) (*Alien, *testerHeaderChain) {
+	engine := New(&params.AlienConfig{Period: 10}, ethdb.NewMemDatabase(), true)
+
+	headers := make([]*types.Header, len(extras))
+	for i, headerExtra := range extras {
+		extra, err := rlp.EncodeToBytes(headerExtra)
+		if err != nil {
+			t.Fatalf("failed to encode header extra: %v", err)
+		}
+		headers[i] = &types.Header{
+			Number:   big.NewInt(int64(i)),
+			Coinbase: coinbases[i],
+			Extra:    append(append(make([]byte, extraVanity), extra...), make([]byte, extraSeal)...),
+		}
+		if i > 0 {
+			headers[i].ParentHash = headers[i-1].Hash()
+		}
+		snap := snapshot(i)
+		snap.Number, snap.Hash = uint64(i), headers[i].Hash()
+		engine.recents.Add(snap.Hash, snap)
+	}
+	return engine, newTesterHeaderChain(headers...)
+}
+
+// Tests that the signer schedule follows the signer queue of the latest snapshot,
+// and only the slots of the current loop are final.
+func TestSignerSchedule(t *testing.T) {
+	signers := []common.Address{{1}, {2}, {3}}
+	engine, chain := newTesterScheduleChain(t, []HeaderExtra{{}}, []common.Address{{}}, func(int) *Snapshot {
+		return &Snapshot{Period: 10, LoopStartTime: 100, Signers: []*common.Address{&signers[0], &signers[1], &signers[2]}}
+	})
+	if _, err := engine.signerSchedule(chain, 100, 0); err != errInvalidScheduleCount {
+		t.Errorf("empty schedule error mismatch: have %v, want %v", err, errInvalidScheduleCount)
+	}
+	slots, err := engine.signerSchedule(chain, 105, 4)
+	if err != nil {
+		t.Fatalf("failed to forecast schedule: %v", err)
+	}
+	want := []*SignerSlot{
+		{Time: 110, Index: 1, Signer: signers[1], Final: true},
+		{Time: 120, Index: 2, Signer: signers[2], Final: true},
+		{Time: 130, Index: 0, Signer: signers[0], Final: false},
+		{Time: 140, Index: 1, Signer: signers[1], Final: false},
+	}
+	if !reflect.DeepEqual(slots, want) {
+		t.Errorf("schedule mismatch: have %v, want %v", slots, want)
+	}
+	// Times before the loop are forecast from its start
+	if slots, err := engine.signerSchedule(chain, 50, 1); err != nil || slots[0].Time != 100 || slots[0].Signer != signers[0] {
+		t.Errorf("schedule before the loop mismatch: have %v, %v", slots, err)
+	}
+}
+
+// Tests that the signer stats count the blocks sealed, the slots missed and the
+// confirmations of the signer, and follow the changes of its punishment credit.
+func TestSignerStats(t *testing.T) {
+	var (
+		a, b     = common.Address{1}, common.Address{2}
+		punished = []uint64{0, 0, 10, 10}
+	)
+	engine, chain := newTesterScheduleChain(t,
+		[]HeaderExtra{
+			{},
+			{SignerMissing: []common.Address{b}},
+			{CurrentBlockConfirmations: []Confirmation{{Signer: a, BlockNumber: big.NewInt(1)}}},
+			{SignerMissing: []common.Address{b}, CurrentBlockConfirmations: []Confirmation{{Signer: a, BlockNumber: big.NewInt(2)}}},
+		},
+		[]common.Address{{}, a, b, a},
+		func(number int) *Snapshot {
+			return &Snapshot{Punished: map[common.Address]uint64{b: punished[number]}}
+		},
+	)
+	tests := []struct {
+		address   common.Address
+		from, to  uint64
+		want      *SignerStats
+		wantError error
+	}{
+		{a, 0, 3, &SignerStats{Address: a, FromBlock: 0, ToBlock: 3, Blocks: 4, Produced: 2, Confirmed: 2, Credits: []*SignerCredit{{0, 0}}}, nil},
+		{b, 1, 3, &SignerStats{Address: b, FromBlock: 1, ToBlock: 3, Blocks: 3, Produced: 1, Missed: 2, Credits: []*SignerCredit{{1, 0}, {2, 10}}}, nil},
+		{a, 2, 1, nil, errInvalidStatsRange},
+		{a, 0, maxSignerStatsRange, nil, errInvalidStatsRange},
+		{a, 0, 4, nil, errUnknownBlock},
+	}
+	for i, tt := range tests {
+		stats, err := engine.signerStats(chain, tt.address, tt.from, tt.to)
+		if err != tt.wantError {
+			t.Errorf("test %d: error mismatch: have %v, want %v", i, err, tt.wantError)
+		}
+		if !reflect.DeepEqual(stats, tt.want) {
+			t.Errorf("test %d: stats mismatch: have %+v, want %+v", i, stats, tt.want)
+		}
+	}
+}
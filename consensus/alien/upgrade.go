@@ -0,0 +1,155 @@
+// Copyright 2018 The giov Authors
+// This file is part of the giov library.
+//
+// The giov library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The giov library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the giov library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package alien implements the delegated-proof-of-stake consensus engine.
+
+package alien
+
+import (
+	"encoding/json"
+
+	"github.com/CarLiveChainCo/goiov/common"
+	"github.com/CarLiveChainCo/goiov/core/rawdb"
+	"github.com/CarLiveChainCo/goiov/ethdb"
+	"github.com/CarLiveChainCo/goiov/log"
+)
+
+// UpgradeAppChain moves the records the engine of the app chain appId stored in
+// the shared database, before the app chains had their tables, into the table
+// of the app chain: the snapshots of its canonical checkpoints, the statuses of
+// its custom transactions and its reward index. It is run once the chain data
+// of the app chain was moved by core.UpgradeAppChainDatabase.
+func UpgradeAppChain(db ethdb.Database, appId string) error {
+	if appId == "" {
+		return nil
+	}
+	number := rawdb.ReadHeaderNumber(db, rawdb.ReadHeadBlockHash(db, appId), appId)
+	if number == nil {
+		return nil
+	}
+	var (
+		table = rawdb.AppChainDatabase(db, appId)
+		moved int
+	)
+	move := func(key []byte, to []byte) error {
+		blob, err := db.Get(key)
+		if err != nil {
+			return nil
+		}
+		if err := table.Put(to, blob); err != nil {
+			return err
+		}
+		moved++
+		return db.Delete(key)
+	}
+	for n := uint64(0); n <= *number; n++ {
+		hash := rawdb.ReadCanonicalHash(db, n, appId)
+		if n%checkpointInterval == 0 {
+			key := append([]byte("alien-"), hash[:]...)
+			if err := move(key, key); err != nil {
+				return err
+			}
+		}
+		header, body := rawdb.ReadHeader(db, hash, n, appId), rawdb.ReadBody(db, hash, n, appId)
+		if header == nil || body == nil {
+			continue
+		}
+		// The statuses were keyed by the transaction alone
+		for _, tx := range body.Transactions {
+			if err := move(append([]byte(customTxStatusPrefix), tx.Hash().Bytes()...), customTxStatusKey(tx.Hash(), hash)); err != nil {
+				return err
+			}
+		}
+	}
+	// The reward keys hold the appId followed by fixed size fields, the keys of
+	// the app chains their appIds start with appId are longer
+	if it, ok := db.(ethdb.Iteratee); ok {
+		for prefix, size := range map[string]int{
+			rewardPrefix + rewardBlockPrefix + appId: 8,
+			rewardPrefix + rewardDayPrefix + appId:   common.AddressLength + 8,
+		} {
+			iter := it.NewIteratorWithPrefix([]byte(prefix))
+			for iter.Next() {
+				if len(iter.Key()) != len(prefix)+size {
+					continue
+				}
+				if err := move(common.CopyBytes(iter.Key()), common.CopyBytes(iter.Key())); err != nil {
+					iter.Release()
+					return err
+				}
+			}
+			iter.Release()
+		}
+	}
+	log.Info("Upgraded app chain engine records", "appId", appId, "records", moved)
+	return nil
+}
+
+// DeleteAppChain deletes the records the engine of the main chain stored in the
+// shared database for the app chain appId: the progress of the relayer and the
+// receipts of the messages delivered to it. The checkpoints anchored for it are
+// part of the main chain state and kept. The records of the engine of the app
+// chain are in its table, deleted along with its chain data by
+// core.DeleteAppChain.
+func DeleteAppChain(db ethdb.Database, appId string) error {
+	if appId == "" {
+		return nil
+	}
+	it, ok := db.(ethdb.Iteratee)
+	if !ok {
+		return nil
+	}
+	var (
+		batch   = db.NewBatch()
+		deleted int
+	)
+	del := func(key []byte) error {
+		if err := batch.Delete(key); err != nil {
+			return err
+		}
+		deleted++
+		if batch.ValueSize() >= ethdb.IdealBatchSize {
+			if err := batch.Write(); err != nil {
+				return err
+			}
+			batch.Reset()
+		}
+		return nil
+	}
+	iter := it.NewIteratorWithPrefix([]byte(messageReceiptPrefix))
+	for iter.Next() {
+		receipt := new(AppMessageReceipt)
+		if err := json.Unmarshal(iter.Value(), receipt); err != nil || receipt.AppId != appId {
+			continue
+		}
+		if err := del(common.CopyBytes(iter.Key())); err != nil {
+			iter.Release()
+			return err
+		}
+	}
+	iter.Release()
+
+	if ok, _ := db.Has(relayProgressKey(appId)); ok {
+		if err := del(relayProgressKey(appId)); err != nil {
+			return err
+		}
+	}
+	if err := batch.Write(); err != nil {
+		return err
+	}
+	log.Info("Deleted app chain engine records", "appId", appId, "records", deleted)
+	return nil
+}
@@ -99,6 +99,41 @@ type Engine interface {
 	APIs(chain ChainReader) []rpc.API
 }
 
+// Finality is a consensus engine which confirms blocks, below which the chain
+// must never be reorganised.
+type Finality interface {
+	Engine
+
+	// ConfirmedNumber returns the number of the last block confirmed as of the
+	// given header, 0 if none is.
+	ConfirmedNumber(header *types.Header) uint64
+}
+
+// Confirmer is a consensus engine whose signers confirm blocks with signed
+// messages exchanged between the nodes.
+type Confirmer interface {
+	Engine
+
+	// ConfirmBlock signs a confirmation of the header with the local signing key
+	// and hands it out for broadcasting. It returns false if blocks at the height
+	// of the header are not confirmed by messages.
+	ConfirmBlock(chain ChainReader, header *types.Header) (bool, error)
+}
+
+// Recorder is a consensus engine which keeps records of the blocks it
+// finalizes, stored only once the blocks are imported into the chain.
+type Recorder interface {
+	Engine
+
+	// WriteBlockRecords stores the records kept when finalizing the block, once
+	// the block is written into the chain.
+	WriteBlockRecords(block *types.Block) error
+
+	// DeleteBlockRecords removes the records of a block dropped from the
+	// canonical chain by a reorg.
+	DeleteBlockRecords(block *types.Block) error
+}
+
 // PoW is a consensus engine based on proof-of-work.
 type PoW interface {
 	Engine
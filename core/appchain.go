@@ -0,0 +1,161 @@
+// Copyright 2018 The giov Authors
+// This file is part of the giov library.
+//
+// The giov library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The giov library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the giov library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"sort"
+
+	"github.com/CarLiveChainCo/goiov/common"
+	"github.com/CarLiveChainCo/goiov/core/rawdb"
+	"github.com/CarLiveChainCo/goiov/core/state"
+	"github.com/CarLiveChainCo/goiov/ethdb"
+	"github.com/CarLiveChainCo/goiov/log"
+)
+
+// UpgradeAppChainDatabase moves the app chains of a database of the legacy
+// layout, where all chain configs shared one blob and the keys of an app chain
+// were the keys of the main chain prefixed with its appId, into their tables.
+// The state of an app chain is copied into its table as of its most recent
+// block with a state, the older states stay behind in the shared trie nodes.
+// It returns the appIds of the app chains moved, none on a database of the
+// current layout.
+func UpgradeAppChainDatabase(db ethdb.Database) ([]string, error) {
+	configs := rawdb.ReadLegacyChainConfigs(db)
+	if configs == nil {
+		return nil, nil
+	}
+	if config, ok := configs[""]; ok && rawdb.ReadChainConfig(db, common.Hash{}) == nil {
+		rawdb.WriteChainConfig(db, rawdb.ReadCanonicalHash(db, 0), config)
+	}
+	delete(configs, "")
+	appIds := make([]string, 0, len(configs))
+	for appId := range configs {
+		appIds = append(appIds, appId)
+	}
+	sort.Strings(appIds)
+
+	for _, appId := range appIds {
+		moved, err := rawdb.MigrateLegacyAppChain(db, appId, appIds)
+		if err != nil {
+			return nil, err
+		}
+		rawdb.WriteChainConfig(db, rawdb.ReadCanonicalHash(db, 0, appId), configs[appId], appId)
+
+		nodes, err := copyAppChainState(db, appId)
+		if err != nil {
+			return nil, err
+		}
+		log.Info("Upgraded app chain database", "appId", appId, "keys", moved, "nodes", nodes)
+	}
+	rawdb.WriteAppChains(db, appIds)
+	rawdb.DeleteLegacyChainConfigs(db)
+	return appIds, nil
+}
+
+// copyAppChainState copies the state of the most recent block of the app chain
+// with a state in the shared trie nodes into the table of the app chain.
+func copyAppChainState(db ethdb.Database, appId string) (int, error) {
+	hash := rawdb.ReadHeadBlockHash(db, appId)
+	number := rawdb.ReadHeaderNumber(db, hash, appId)
+	if number == nil {
+		return 0, nil
+	}
+	var (
+		table  = rawdb.AppChainDatabase(db, appId)
+		shared = state.NewDatabase(db)
+		header = rawdb.ReadHeader(db, hash, *number, appId)
+	)
+	for ; header != nil; header = rawdb.ReadHeader(db, header.ParentHash, header.Number.Uint64()-1, appId) {
+		statedb, err := state.New(header.Root, shared)
+		if err != nil {
+			if header.Number.Sign() == 0 {
+				return 0, nil
+			}
+			continue
+		}
+		var (
+			batch = table.NewBatch()
+			it    = state.NewNodeIterator(statedb)
+			nodes int
+		)
+		for it.Next() {
+			if it.Hash == (common.Hash{}) {
+				continue
+			}
+			blob, err := db.Get(it.Hash[:])
+			if err != nil {
+				return nodes, err
+			}
+			batch.Put(it.Hash[:], blob)
+			nodes++
+
+			if batch.ValueSize() >= ethdb.IdealBatchSize {
+				if err := batch.Write(); err != nil {
+					return nodes, err
+				}
+				batch.Reset()
+			}
+		}
+		if it.Error != nil {
+			return nodes, it.Error
+		}
+		return nodes, batch.Write()
+	}
+	return 0, nil
+}
+
+// DeleteAppChain removes all the data of the app chain appId from the database,
+// its table and its transaction lookup entries, and forgets the app chain. The
+// app chain must not run while it is deleted.
+func DeleteAppChain(db ethdb.Database, appId string) error {
+	if appId == "" {
+		return nil
+	}
+	// The transaction lookups of all chains share one index
+	hash := rawdb.ReadHeadBlockHash(db, appId)
+	if number := rawdb.ReadHeaderNumber(db, hash, appId); number != nil {
+		for n := uint64(0); n <= *number; n++ {
+			body := rawdb.ReadBody(db, rawdb.ReadCanonicalHash(db, n, appId), n, appId)
+			if body == nil {
+				continue
+			}
+			for _, tx := range body.Transactions {
+				rawdb.DeleteTxLookupEntry(db, tx.Hash(), appId)
+			}
+		}
+	}
+	deleted, err := rawdb.DeleteAppChainTable(db, appId)
+	if err != nil {
+		return err
+	}
+	rawdb.WriteAppChains(db, removeAppId(rawdb.ReadAppChains(db), appId))
+	rawdb.WriteAppId(db, removeAppId(rawdb.ReadAppId(db), appId))
+
+	log.Info("Deleted app chain", "appId", appId, "keys", deleted)
+	return nil
+}
+
+// removeAppId returns the appIds without appId.
+func removeAppId(appIds []string, appId string) []string {
+	kept := appIds[:0]
+	for _, id := range appIds {
+		if id != appId {
+			kept = append(kept, id)
+		}
+	}
+	return kept
+}
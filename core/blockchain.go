@@ -151,7 +151,7 @@ func NewBlockChain(db ethdb.Database, cacheConfig *CacheConfig, chainConfig *par
 		cacheConfig:  cacheConfig,
 		db:           db,
 		triegc:       prque.New(),
-		stateCache:   state.NewDatabase(db),
+		stateCache:   state.NewDatabase(rawdb.AppChainDatabase(db, chainConfig.AppId)),
 		quit:         make(chan struct{}),
 		bodyCache:    bodyCache,
 		bodyRLPCache: bodyRLPCache,
@@ -987,6 +987,11 @@ func (bc *BlockChain) WriteBlockWithState(block *types.Block, receipts []*types.
 	if err := batch.Write(); err != nil {
 		return NonStatTy, err
 	}
+	if recorder, ok := bc.engine.(consensus.Recorder); ok {
+		if err := recorder.WriteBlockRecords(block); err != nil {
+			log.Warn("Failed to store consensus block records", "number", block.Number(), "hash", block.Hash(), "err", err)
+		}
+	}
 
 	// Set new head.
 	if status == CanonStatTy {
@@ -1009,14 +1014,25 @@ func (bc *BlockChain) getGenesisFromTxs(txs types.Transactions, num *big.Int, ha
 	for _, transaction := range txs {
 		if transaction.To() == nil && transaction.AppId() != "" {
 			from, _ := types.Sender(types.MakeSigner(bc.Config(), num), transaction)
+			var spec *SideGenesisSpec
+			if data := transaction.GenesisSpec(); data != nil {
+				var err error
+				// Every node skips the same invalid specs, creating no side chain for them
+				if spec, err = DecodeSideGenesisSpec(data, from); err != nil {
+					log.Warn("Invalid side genesis spec", "appId", transaction.AppId(), "tx", transaction.Hash(), "err", err)
+					continue
+				}
+			}
 			newGenesis := &SideGenesis{
 				Genesis:      nil,
 				AppId:        transaction.AppId(),
 				ContractAddr: crypto.CreateAddress(from, transaction.Nonce(), bc.Config().AppId),
 				Author:       from,
-				Alloc:        transaction.Alloc(),
+				Spec:         spec,
 				TxHash:       transaction.Hash(),
 				BlockHash:    hash,
+				BlockNumber:  new(big.Int).Set(num),
+				MainConfig:   bc.chainConfig.Alien,
 			}
 			newGeneses = append(newGeneses, newGenesis)
 		}
@@ -1242,6 +1258,11 @@ func (bc *BlockChain) newGeneses(newGeneses *SideGeneses) error {
 		if genesis.AppId == "" {
 			return fmt.Errorf("no appid")
 		}
+		if genesis.Spec != nil {
+			if err := genesis.Spec.verify(genesis.Author); err != nil {
+				return err
+			}
+		}
 		// 初始化genesis
 		genesis.Genesis = MakeGenesis(genesis)
 		// 写入数据库
@@ -1375,6 +1396,12 @@ func (bc *BlockChain) reorg(oldBlock, newBlock *types.Block) error {
 			return fmt.Errorf("Invalid new chain")
 		}
 	}
+	// Never drop a block confirmed by the consensus engine
+	if finalized := bc.FinalizedNumber(); len(oldChain) > 0 && commonBlock.NumberU64() < finalized {
+		log.Warn("Rejected reorg below finalized block", "number", commonBlock.Number(), "hash", commonBlock.Hash(),
+			"finalized", finalized, "drop", len(oldChain), "add", len(newChain))
+		return ErrReorgFinalized
+	}
 	// Ensure the user sees large reorgs
 	if len(oldChain) > 0 && len(newChain) > 0 {
 		logFn := log.Debug
@@ -1395,6 +1422,19 @@ func (bc *BlockChain) reorg(oldBlock, newBlock *types.Block) error {
 		rawdb.WriteTxLookupEntries(bc.db, newChain[i], bc.chainConfig.AppId)
 		addedTxs = append(addedTxs, newChain[i].Transactions()...)
 	}
+	// Swap the records the consensus engine keeps of the blocks of the chains
+	if recorder, ok := bc.engine.(consensus.Recorder); ok {
+		for _, block := range oldChain {
+			if err := recorder.DeleteBlockRecords(block); err != nil {
+				log.Warn("Failed to delete consensus block records", "number", block.Number(), "hash", block.Hash(), "err", err)
+			}
+		}
+		for _, block := range newChain {
+			if err := recorder.WriteBlockRecords(block); err != nil {
+				log.Warn("Failed to store consensus block records", "number", block.Number(), "hash", block.Hash(), "err", err)
+			}
+		}
+	}
 	// calculate the difference between deleted and added transactions
 	diff := types.TxDifference(deletedTxs, addedTxs)
 	// When transactions get deleted from the database that means the
@@ -1605,6 +1645,15 @@ func (bc *BlockChain) Config() *params.ChainConfig { return bc.chainConfig }
 // Engine retrieves the blockchain's consensus engine.
 func (bc *BlockChain) Engine() consensus.Engine { return bc.engine }
 
+// FinalizedNumber retrieves the number of the last block confirmed as of the
+// current head, below which the chain is never reorganised.
+func (bc *BlockChain) FinalizedNumber() uint64 {
+	if finality, ok := bc.engine.(consensus.Finality); ok {
+		return finality.ConfirmedNumber(bc.CurrentHeader())
+	}
+	return 0
+}
+
 // SubscribeRemovedLogsEvent registers a subscription of RemovedLogsEvent.
 func (bc *BlockChain) SubscribeRemovedLogsEvent(ch chan<- RemovedLogsEvent) event.Subscription {
 	return bc.scope.Track(bc.rmLogsFeed.Subscribe(ch))
@@ -32,4 +32,8 @@ var (
 	// ErrNonceTooHigh is returned if the nonce of a transaction is higher than the
 	// next one expected based on the local chain.
 	ErrNonceTooHigh = errors.New("nonce too high")
+
+	// ErrReorgFinalized is returned if a chain reorganisation would drop a block
+	// confirmed by the consensus engine.
+	ErrReorgFinalized = errors.New("reorg below finalized block")
 )
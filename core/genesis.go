@@ -46,6 +46,26 @@ var errGenesisNoConfig = errors.New("genesis has no chain configuration")
 var errNoSideGenesis = errors.New("no side genesis")
 var errSideChainIdexists = errors.New("the appId already exists")
 
+var (
+	errSideGenesisPeriod      = errors.New("side genesis period out of range")
+	errSideGenesisFreeze      = errors.New("side genesis freeze shorter than three blocks")
+	errSideGenesisSignerCount = errors.New("side genesis max signer count out of range")
+	errSideGenesisVoteValue   = errors.New("side genesis vote values invalid")
+	errSideGenesisGasLimit    = errors.New("side genesis gas limit out of range")
+	errSideGenesisAlloc       = errors.New("side genesis alloc invalid")
+	errSideGenesisSigners     = errors.New("side genesis signers invalid")
+	errSideGenesisUnfunded    = errors.New("side genesis signer cannot pay its self vote")
+	errSideGenesisReward      = errors.New("side genesis reward schedule invalid")
+	errSideGenesisTreasury    = errors.New("side genesis treasury invalid")
+)
+
+const (
+	maxSidePeriod             = 3600 // Max seconds between blocks of a side chain
+	minSideFreezeBlocks       = 3    // Min blocks the stake of a cancelled vote stays frozen, as in the alien engine
+	maxSideSignerCount        = 100  // Max signer count of a side chain
+	defaultSideMaxSignerCount = 21   // Max signer count the alien engine defaults to
+)
+
 var extraData = "0x00000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000"
 
 type SideGenesis struct {
@@ -54,13 +74,38 @@ type SideGenesis struct {
 	ContractAddr common.Address
 	Author       common.Address
 	Code         []byte
-	Alloc        map[common.Address]*big.Int
+	Spec         *SideGenesisSpec
 	TxHash       common.Hash
 	BlockHash    common.Hash
+	BlockNumber  *big.Int            // Number of the main chain block creating the side chain
+	MainConfig   *params.AlienConfig // Engine config of the main chain
 }
 
 type SideGeneses []*SideGenesis
 
+// SideGenesisSpec is the genesis of a side chain carried RLP encoded by the
+// transaction creating it. Zero values keep the defaults of MakeGenesis.
+type SideGenesisSpec struct {
+	Period          uint64               `json:"period"`
+	Freeze          uint64               `json:"freeze"`
+	MaxSignerCount  uint64               `json:"maxSignersCount"`
+	MinVoteValue    *big.Int             `json:"minVoteValue"`
+	SelfVoteValue   *big.Int             `json:"selfVoteValue"`
+	GasLimit        uint64               `json:"gasLimit"`
+	Alloc           []SideGenesisAccount `json:"alloc"`
+	Signers         []common.Address     `json:"signers"`
+	RewardSchedule  []params.RewardStep  `json:"rewardSchedule"`
+	RewardSupplyCap *big.Int             `json:"rewardSupplyCap"`
+	Treasury        common.Address       `json:"treasury"`
+	TreasuryShare   uint64               `json:"treasuryShare"`
+}
+
+// SideGenesisAccount is an account of the initial allocation of a side chain.
+type SideGenesisAccount struct {
+	Address common.Address `json:"address"`
+	Balance *big.Int       `json:"balance"`
+}
+
 // Genesis specifies the header fields, state of a genesis block. It also defines hard
 // fork switch-over blocks through the chain configuration.
 type Genesis struct {
@@ -219,9 +264,7 @@ func SetupGenesisBlock(db ethdb.Database, genesis *Genesis, testFlag ...bool) (*
 	storedcfg := rawdb.ReadChainConfig(db, stored, appid)
 	if storedcfg == nil {
 		log.Warn("Found genesis block without chain config")
-		rMap := rawdb.ReadAllChainConfig(db)
-		rMap[appid] = newcfg
-		rawdb.WriteChainConfig(db, stored, rMap, appid)
+		writeChainConfig(db, stored, newcfg, appid)
 		return newcfg, stored, nil
 	}
 	// Special case: don't change the existing config of a non-mainnet chain if no new
@@ -245,9 +288,7 @@ func SetupGenesisBlock(db ethdb.Database, genesis *Genesis, testFlag ...bool) (*
 	if compatErr != nil && *height != 0 && compatErr.RewindTo != 0 {
 		return newcfg, stored, compatErr
 	}
-	rMap := rawdb.ReadAllChainConfig(db)
-	rMap[appid] = newcfg
-	rawdb.WriteChainConfig(db, stored, rMap, appid)
+	writeChainConfig(db, stored, newcfg, appid)
 	return newcfg, stored, nil
 }
 
@@ -314,8 +355,8 @@ func (g *Genesis) ToBlock(db ethdb.Database) *types.Block {
 // Commit writes the block and state of a genesis specification to the database.
 // The block is committed as the canonical head block.
 func (g *Genesis) Commit(db ethdb.Database) (*types.Block, error) {
-
-	block := g.ToBlock(db)
+	// The state of an app chain is kept in its table
+	block := g.ToBlock(rawdb.AppChainDatabase(db, g.Config.AppId))
 
 	if block.Number().Sign() != 0 {
 		return nil, fmt.Errorf("can't commit genesis block with number > 0")
@@ -331,12 +372,26 @@ func (g *Genesis) Commit(db ethdb.Database) (*types.Block, error) {
 	if config == nil {
 		config = params.AllEthashProtocolChanges
 	}
-	rMap := rawdb.ReadAllChainConfig(db)
-	rMap[g.Config.AppId] = config
-	rawdb.WriteChainConfig(db, block.Hash(), rMap, g.Config.AppId)
+	writeChainConfig(db, block.Hash(), config, g.Config.AppId)
 	return block, nil
 }
 
+// writeChainConfig writes the config of the chain appId, adding an app chain to
+// the index of the app chains.
+func writeChainConfig(db ethdb.Database, hash common.Hash, config *params.ChainConfig, appId string) {
+	rawdb.WriteChainConfig(db, hash, config, appId)
+	if appId == "" {
+		return
+	}
+	appIds := rawdb.ReadAppChains(db)
+	for _, id := range appIds {
+		if id == appId {
+			return
+		}
+	}
+	rawdb.WriteAppChains(db, append(appIds, appId))
+}
+
 // MustCommit writes the genesis block and state to db, panicking on error.
 // The block is committed as the canonical head block.
 func (g *Genesis) MustCommit(db ethdb.Database) *types.Block {
@@ -556,12 +611,61 @@ func MakeGenesis(sideGenesis *SideGenesis) (genesis *Genesis) {
 	genesis.Config.Alien = new(params.AlienConfig)
 	genesis.Config.Alien.SideChain = false
 
-	genesis.Config.Alien.SelfVoteSigners = append(genesis.Config.Alien.SelfVoteSigners, sideGenesis.Author)
-	genesis.Config.Alien.Period = uint64(2)
+	genesis.Config.Alien.Period = params.SidePeriod
 	genesis.Config.Alien.AppId = sideGenesis.AppId
-	genesis.Config.Alien.Freeze = 20
-	genesis.Config.Alien.MinVoteValue = new(big.Int).Mul(big.NewInt(1), big.NewInt(1e+18))
-	genesis.Config.Alien.SelfVoteValue = new(big.Int).Mul(big.NewInt(10), big.NewInt(1e+18))
+	genesis.Config.Alien.Freeze = params.SideFreeze
+	genesis.Config.Alien.MinVoteValue = new(big.Int).Set(params.SideMinVoteValue)
+	genesis.Config.Alien.SelfVoteValue = new(big.Int).Set(params.DefaultSelfVoteValue)
+	genesis.Config.Alien.SelfVoteSigners = []common.Address{sideGenesis.Author}
+	// new side chains can mint and burn bridged funds and exchange app messages
+	// from their genesis on
+	genesis.Config.Alien.BridgeBlock = new(big.Int)
+	genesis.Config.Alien.CrossAppBlock = new(big.Int)
+	inheritAlienForks(genesis.Config.Alien, sideGenesis.MainConfig, sideGenesis.BlockNumber)
+	genesis.Alloc = GenesisAlloc{sideGenesis.Author: {Balance: params.SideDefaultBalance, Nonce: 1}}
+
+	if spec := sideGenesis.Spec; spec != nil {
+		if spec.Period != 0 {
+			genesis.Config.Alien.Period = spec.Period
+		}
+		if spec.Freeze != 0 {
+			genesis.Config.Alien.Freeze = spec.Freeze
+		}
+		genesis.Config.Alien.MaxSignerCount = spec.MaxSignerCount
+		if spec.MinVoteValue != nil && spec.MinVoteValue.Sign() > 0 {
+			genesis.Config.Alien.MinVoteValue = new(big.Int).Set(spec.MinVoteValue)
+		}
+		if spec.SelfVoteValue != nil && spec.SelfVoteValue.Sign() > 0 {
+			genesis.Config.Alien.SelfVoteValue = new(big.Int).Set(spec.SelfVoteValue)
+		}
+		if len(spec.Signers) > 0 {
+			genesis.Config.Alien.SelfVoteSigners = append([]common.Address{}, spec.Signers...)
+		}
+		if len(spec.Alloc) > 0 {
+			genesis.Alloc = make(GenesisAlloc, len(spec.Alloc))
+			for _, account := range spec.Alloc {
+				genesis.Alloc[account.Address] = GenesisAccount{Balance: new(big.Int).Set(account.Balance), Nonce: 1}
+			}
+		}
+		if spec.GasLimit != 0 {
+			genesis.GasLimit = spec.GasLimit
+		}
+		for _, step := range spec.RewardSchedule {
+			genesis.Config.Alien.RewardSchedule = append(genesis.Config.Alien.RewardSchedule, params.RewardStep{
+				FromBlock:  step.FromBlock,
+				Reward:     new(big.Int).Set(step.Reward),
+				MinerShare: step.MinerShare,
+			})
+		}
+		if spec.RewardSupplyCap != nil && spec.RewardSupplyCap.Sign() > 0 {
+			genesis.Config.Alien.RewardSupplyCap = new(big.Int).Set(spec.RewardSupplyCap)
+		}
+		if spec.TreasuryShare > 0 {
+			treasury := spec.Treasury
+			genesis.Config.Alien.Treasury = &treasury
+			genesis.Config.Alien.TreasuryShare = spec.TreasuryShare
+		}
+	}
 
 	if genesis.Nonce == 0 {
 		genesis.Nonce = params.SideNonce
@@ -575,6 +679,124 @@ func MakeGenesis(sideGenesis *SideGenesis) (genesis *Genesis) {
 	if genesis.Difficulty == nil {
 		genesis.Difficulty = params.SideDifficulty
 	}
-	genesis.Alloc = GenesisAlloc{sideGenesis.Author: {Balance: params.SideDefaultBalance,Nonce:1}}
 	return genesis
 }
+
+// inheritAlienForks enables on a new side chain from its genesis on the engine
+// features active on the main chain at the block creating the side chain. The
+// finality confirmations are only exchanged between the signers of the main
+// chain, so the side chains keep confirming by transactions.
+func inheritAlienForks(config *params.AlienConfig, main *params.AlienConfig, number *big.Int) {
+	if main == nil || number == nil {
+		return
+	}
+	for _, fork := range []struct {
+		active bool
+		block  **big.Int
+	}{
+		{main.IsCustomTxV2(number), &config.CustomTxV2Block},
+		{main.IsStakeChange(number), &config.StakeChangeBlock},
+		{main.IsRedelegate(number), &config.RedelegateBlock},
+		{main.IsSlash(number), &config.SlashBlock},
+		{main.IsProposal(number), &config.ProposalBlock},
+		{main.IsCheckpoint(number), &config.CheckpointBlock},
+	} {
+		if fork.active {
+			*fork.block = new(big.Int)
+		}
+	}
+}
+
+// DecodeSideGenesisSpec decodes the genesis spec carried by the transaction of
+// author creating a side chain and checks the side chain it describes.
+func DecodeSideGenesisSpec(data []byte, author common.Address) (*SideGenesisSpec, error) {
+	spec := new(SideGenesisSpec)
+	if err := rlp.DecodeBytes(data, spec); err != nil {
+		return nil, err
+	}
+	if err := spec.verify(author); err != nil {
+		return nil, err
+	}
+	return spec, nil
+}
+
+// verify checks the parameters of the side chain of author described by the
+// spec, applying the defaults of MakeGenesis to the zero values.
+func (spec *SideGenesisSpec) verify(author common.Address) error {
+	period, freeze := params.SidePeriod, params.SideFreeze
+	if spec.Period != 0 {
+		period = spec.Period
+	}
+	if spec.Freeze != 0 {
+		freeze = spec.Freeze
+	}
+	if period > maxSidePeriod {
+		return errSideGenesisPeriod
+	}
+	if freeze/period < minSideFreezeBlocks {
+		return errSideGenesisFreeze
+	}
+	signerCount := uint64(defaultSideMaxSignerCount)
+	if spec.MaxSignerCount != 0 {
+		signerCount = spec.MaxSignerCount
+	}
+	if signerCount > maxSideSignerCount {
+		return errSideGenesisSignerCount
+	}
+	minVoteValue, selfVoteValue := params.SideMinVoteValue, params.DefaultSelfVoteValue
+	if spec.MinVoteValue != nil && spec.MinVoteValue.Sign() > 0 {
+		minVoteValue = spec.MinVoteValue
+	}
+	if spec.SelfVoteValue != nil && spec.SelfVoteValue.Sign() > 0 {
+		selfVoteValue = spec.SelfVoteValue
+	}
+	if selfVoteValue.Cmp(minVoteValue) < 0 {
+		return errSideGenesisVoteValue
+	}
+	if spec.GasLimit != 0 && spec.GasLimit < params.MinGasLimit {
+		return errSideGenesisGasLimit
+	}
+	// The steps of the reward schedule are ordered by their first block, the
+	// engine pays the step of the last one reached
+	for i, step := range spec.RewardSchedule {
+		if step.Reward == nil || step.MinerShare > 1000 {
+			return errSideGenesisReward
+		}
+		if i > 0 && step.FromBlock <= spec.RewardSchedule[i-1].FromBlock {
+			return errSideGenesisReward
+		}
+	}
+	if spec.TreasuryShare > 1000 || (spec.TreasuryShare > 0 && spec.Treasury == (common.Address{})) {
+		return errSideGenesisTreasury
+	}
+
+	alloc := map[common.Address]*big.Int{author: params.SideDefaultBalance}
+	if len(spec.Alloc) > 0 {
+		alloc = make(map[common.Address]*big.Int, len(spec.Alloc))
+		for _, account := range spec.Alloc {
+			if _, ok := alloc[account.Address]; ok || account.Balance == nil {
+				return errSideGenesisAlloc
+			}
+			alloc[account.Address] = account.Balance
+		}
+	}
+	signers := spec.Signers
+	if len(signers) == 0 {
+		signers = []common.Address{author}
+	}
+	if uint64(len(signers)) > signerCount {
+		return errSideGenesisSigners
+	}
+	seen := make(map[common.Address]struct{}, len(signers))
+	for _, signer := range signers {
+		if _, ok := seen[signer]; ok || signer == (common.Address{}) {
+			return errSideGenesisSigners
+		}
+		seen[signer] = struct{}{}
+		// A signer unable to pay its self vote has no vote and never seals
+		if balance, ok := alloc[signer]; !ok || balance.Cmp(selfVoteValue) < 0 {
+			return errSideGenesisUnfunded
+		}
+	}
+	return nil
+}
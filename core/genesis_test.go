@@ -27,6 +27,7 @@ import (
 	"github.com/CarLiveChainCo/goiov/core/vm"
 	"github.com/CarLiveChainCo/goiov/ethdb"
 	"github.com/CarLiveChainCo/goiov/params"
+	"github.com/CarLiveChainCo/goiov/rlp"
 	"github.com/davecgh/go-spew/spew"
 )
 
@@ -162,3 +163,117 @@ func TestSetupGenesis(t *testing.T) {
 		}
 	}
 }
+
+// Tests that the genesis spec carried by a side chain creating transaction is
+// only accepted if the side chain it describes can seal blocks.
+func TestDecodeSideGenesisSpec(t *testing.T) {
+	var (
+		author  = common.Address{1}
+		signer  = common.Address{2}
+		balance = new(big.Int).Set(params.DefaultSelfVoteValue)
+	)
+	tests := []struct {
+		spec SideGenesisSpec
+		err  error
+	}{
+		// the defaults of MakeGenesis
+		{SideGenesisSpec{}, nil},
+		{SideGenesisSpec{Period: 5, Freeze: 15, MaxSignerCount: 3, GasLimit: params.MinGasLimit}, nil},
+		{SideGenesisSpec{Alloc: []SideGenesisAccount{{signer, balance}}, Signers: []common.Address{signer}}, nil},
+		{SideGenesisSpec{RewardSchedule: []params.RewardStep{{FromBlock: 0, Reward: big.NewInt(1)}, {FromBlock: 10, Reward: big.NewInt(0), MinerShare: 1000}}}, nil},
+		{SideGenesisSpec{Treasury: signer, TreasuryShare: 100}, nil},
+
+		{SideGenesisSpec{Period: maxSidePeriod + 1}, errSideGenesisPeriod},
+		{SideGenesisSpec{Period: 5, Freeze: 14}, errSideGenesisFreeze},
+		{SideGenesisSpec{MaxSignerCount: maxSideSignerCount + 1}, errSideGenesisSignerCount},
+		{SideGenesisSpec{SelfVoteValue: big.NewInt(1)}, errSideGenesisVoteValue},
+		{SideGenesisSpec{GasLimit: params.MinGasLimit - 1}, errSideGenesisGasLimit},
+		{SideGenesisSpec{RewardSchedule: []params.RewardStep{{FromBlock: 0, Reward: big.NewInt(1), MinerShare: 1001}}}, errSideGenesisReward},
+		{SideGenesisSpec{RewardSchedule: []params.RewardStep{{FromBlock: 10, Reward: big.NewInt(1)}, {FromBlock: 10, Reward: big.NewInt(1)}}}, errSideGenesisReward},
+		{SideGenesisSpec{TreasuryShare: 100}, errSideGenesisTreasury},
+		{SideGenesisSpec{Treasury: signer, TreasuryShare: 1001}, errSideGenesisTreasury},
+		{SideGenesisSpec{Alloc: []SideGenesisAccount{{author, balance}, {author, balance}}}, errSideGenesisAlloc},
+		{SideGenesisSpec{Signers: []common.Address{author, author}}, errSideGenesisSigners},
+		{SideGenesisSpec{Signers: []common.Address{{}}}, errSideGenesisSigners},
+		{SideGenesisSpec{MaxSignerCount: 1, Alloc: []SideGenesisAccount{{author, balance}, {signer, balance}}, Signers: []common.Address{author, signer}}, errSideGenesisSigners},
+		// the signer has no balance, or not enough for its self vote
+		{SideGenesisSpec{Signers: []common.Address{signer}}, errSideGenesisUnfunded},
+		{SideGenesisSpec{Alloc: []SideGenesisAccount{{author, big.NewInt(1)}}}, errSideGenesisUnfunded},
+	}
+	for i, test := range tests {
+		data, err := rlp.EncodeToBytes(&test.spec)
+		if err != nil {
+			t.Fatalf("test %d: failed to encode spec: %v", i, err)
+		}
+		spec, err := DecodeSideGenesisSpec(data, author)
+		if err != test.err {
+			t.Errorf("test %d: error mismatch: have %v, want %v", i, err, test.err)
+			continue
+		}
+		if err == nil && spec.Period != test.spec.Period {
+			t.Errorf("test %d: period mismatch: have %d, want %d", i, spec.Period, test.spec.Period)
+		}
+	}
+	if _, err := DecodeSideGenesisSpec([]byte{0x01}, author); err == nil {
+		t.Errorf("malformed spec accepted")
+	}
+}
+
+// Tests that the genesis of a side chain takes the parameters of its spec and
+// the engine forks active on the main chain when it was created.
+func TestMakeSideGenesis(t *testing.T) {
+	var (
+		author = common.Address{1}
+		signer = common.Address{2}
+		main   = &params.AlienConfig{CustomTxV2Block: big.NewInt(5), ProposalBlock: big.NewInt(20)}
+	)
+	genesis := MakeGenesis(&SideGenesis{AppId: "7", Author: author, BlockNumber: big.NewInt(10), MainConfig: main})
+	if alien := genesis.Config.Alien; alien.Period != params.SidePeriod || alien.Freeze != params.SideFreeze ||
+		!reflect.DeepEqual(alien.SelfVoteSigners, []common.Address{author}) {
+		t.Errorf("default engine config mismatch: %+v", alien)
+	}
+	if genesis.Config.ChainId.Cmp(big.NewInt(7)) != 0 || genesis.GasLimit != params.SideGasLimit {
+		t.Errorf("default chain id %v and gas limit %d", genesis.Config.ChainId, genesis.GasLimit)
+	}
+	if account, ok := genesis.Alloc[author]; !ok || account.Balance.Cmp(params.SideDefaultBalance) != 0 || len(genesis.Alloc) != 1 {
+		t.Errorf("default alloc mismatch: %v", genesis.Alloc)
+	}
+	if alien := genesis.Config.Alien; !alien.IsCustomTxV2(common.Big0) || alien.IsProposal(big.NewInt(1000)) {
+		t.Errorf("forks of the main chain at block 10 not inherited: custom tx v2 %v, proposal %v", alien.CustomTxV2Block, alien.ProposalBlock)
+	}
+
+	spec := &SideGenesisSpec{
+		Period:         4,
+		Freeze:         40,
+		MaxSignerCount: 5,
+		MinVoteValue:   big.NewInt(100),
+		SelfVoteValue:  big.NewInt(200),
+		GasLimit:       params.MinGasLimit,
+		Alloc:          []SideGenesisAccount{{author, big.NewInt(300)}, {signer, big.NewInt(400)}},
+		Signers:        []common.Address{author, signer},
+		Treasury:       signer,
+		TreasuryShare:  50,
+	}
+	genesis = MakeGenesis(&SideGenesis{AppId: "8", Author: author, Spec: spec})
+	alien := genesis.Config.Alien
+	if alien.Period != 4 || alien.Freeze != 40 || alien.MaxSignerCount != 5 || alien.MinVoteValue.Cmp(big.NewInt(100)) != 0 || alien.SelfVoteValue.Cmp(big.NewInt(200)) != 0 {
+		t.Errorf("engine config mismatch: %+v", alien)
+	}
+	if !reflect.DeepEqual(alien.SelfVoteSigners, spec.Signers) {
+		t.Errorf("signers mismatch: have %v, want %v", alien.SelfVoteSigners, spec.Signers)
+	}
+	if alien.Treasury == nil || *alien.Treasury != signer || alien.TreasuryShare != 50 {
+		t.Errorf("treasury mismatch: %v with %d", alien.Treasury, alien.TreasuryShare)
+	}
+	if genesis.GasLimit != params.MinGasLimit {
+		t.Errorf("gas limit mismatch: have %d, want %d", genesis.GasLimit, params.MinGasLimit)
+	}
+	for _, account := range spec.Alloc {
+		if genesis.Alloc[account.Address].Balance.Cmp(account.Balance) != 0 {
+			t.Errorf("balance of %x mismatch: have %v, want %v", account.Address, genesis.Alloc[account.Address].Balance, account.Balance)
+		}
+	}
+	if len(genesis.Alloc) != len(spec.Alloc) {
+		t.Errorf("alloc length mismatch: have %d, want %d", len(genesis.Alloc), len(spec.Alloc))
+	}
+}
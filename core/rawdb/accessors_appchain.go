@@ -0,0 +1,151 @@
+// Copyright 2018 The giov Authors
+// This file is part of the giov library.
+//
+// The giov library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The giov library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the giov library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"bytes"
+	"errors"
+
+	"github.com/CarLiveChainCo/goiov/common"
+	"github.com/CarLiveChainCo/goiov/ethdb"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+// errNotIterable is returned if the data of an app chain is moved or deleted
+// in a database its keys can't be iterated over in.
+var errNotIterable = errors.New("database not iterable")
+
+// legacyAppChainKey reports whether the remainder of a key following the appId
+// of an app chain in the legacy layout, where the appId was prepended to the
+// keys of the main chain, has the shape of a key of the chain data. The shapes
+// are checked with their lengths, as the trie nodes of all chains share the
+// database unprefixed and start with arbitrary bytes.
+func legacyAppChainKey(key []byte) bool {
+	hashLen, numLen := common.HashLength, 8
+	switch {
+	case bytes.Equal(key, headHeaderKey), bytes.Equal(key, headBlockKey), bytes.Equal(key, headFastBlockKey), bytes.Equal(key, fastTrieProgressKey):
+		return true
+	case bytes.HasPrefix(key, BloomBitsIndexPrefix), bytes.HasPrefix(key, preimagePrefix) && len(key) == len(preimagePrefix)+hashLen:
+		return true
+	case len(key) == 0:
+		return false
+	}
+	switch key[0] {
+	case headerPrefix[0]:
+		// Headers, total difficulties and canonical hashes
+		return len(key) == 1+numLen+hashLen ||
+			len(key) == 1+numLen+hashLen+1 && key[len(key)-1] == headerTDSuffix[0] ||
+			len(key) == 1+numLen+1 && key[len(key)-1] == headerHashSuffix[0]
+	case headerNumberPrefix[0]:
+		return len(key) == 1+hashLen
+	case blockBodyPrefix[0], blockReceiptsPrefix[0]:
+		return len(key) == 1+numLen+hashLen
+	case bloomBitsPrefix[0]:
+		return len(key) == 1+2+numLen+hashLen
+	}
+	return false
+}
+
+// MigrateLegacyAppChain moves the chain data of the app chain appId from the
+// legacy layout, its appId prepended to the keys, into the table of the app
+// chain. The keys of the other app chains their appIds start with appId are
+// left alone. It returns the number of keys moved.
+func MigrateLegacyAppChain(db ethdb.Database, appId string, appIds []string) (int, error) {
+	it, ok := db.(ethdb.Iteratee)
+	if !ok {
+		return 0, errNotIterable
+	}
+	if appId == "" {
+		return 0, nil
+	}
+	iter := it.NewIteratorWithPrefix([]byte(appId))
+	defer iter.Release()
+
+	var (
+		batch = db.NewBatch()
+		moved int
+	)
+next:
+	for iter.Next() {
+		key := iter.Key()
+		for _, other := range appIds {
+			if len(other) > len(appId) && bytes.HasPrefix(key, []byte(other)) && legacyAppChainKey(key[len(other):]) {
+				continue next
+			}
+		}
+		if !legacyAppChainKey(key[len(appId):]) {
+			continue
+		}
+		batch.Put(appKey(common.CopyBytes(key[len(appId):]), []string{appId}), common.CopyBytes(iter.Value()))
+		batch.Delete(common.CopyBytes(key))
+		moved++
+
+		if batch.ValueSize() >= ethdb.IdealBatchSize {
+			if err := batch.Write(); err != nil {
+				return moved, err
+			}
+			batch.Reset()
+		}
+	}
+	if err := iter.Error(); err != nil {
+		return moved, err
+	}
+	return moved, batch.Write()
+}
+
+// DeleteAppChainTable removes the table of the app chain appId with all its
+// data from the database and compacts its key range to reclaim the disk space.
+// It returns the number of keys deleted.
+func DeleteAppChainTable(db ethdb.Database, appId string) (int, error) {
+	it, ok := db.(ethdb.Iteratee)
+	if !ok {
+		return 0, errNotIterable
+	}
+	if appId == "" {
+		return 0, nil
+	}
+	prefix := AppChainPrefix(appId)
+	iter := it.NewIteratorWithPrefix(prefix)
+	defer iter.Release()
+
+	var (
+		batch   = db.NewBatch()
+		deleted int
+	)
+	for iter.Next() {
+		batch.Delete(common.CopyBytes(iter.Key()))
+		deleted++
+
+		if batch.ValueSize() >= ethdb.IdealBatchSize {
+			if err := batch.Write(); err != nil {
+				return deleted, err
+			}
+			batch.Reset()
+		}
+	}
+	if err := iter.Error(); err != nil {
+		return deleted, err
+	}
+	if err := batch.Write(); err != nil {
+		return deleted, err
+	}
+	if compacter, ok := db.(ethdb.Compacter); ok {
+		r := util.BytesPrefix(prefix)
+		return deleted, compacter.Compact(r.Start, r.Limit)
+	}
+	return deleted, nil
+}
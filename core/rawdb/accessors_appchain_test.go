@@ -0,0 +1,72 @@
+// Copyright 2018 The giov Authors
+// This file is part of the giov library.
+//
+// The giov library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The giov library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the giov library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/CarLiveChainCo/goiov/common"
+	"github.com/CarLiveChainCo/goiov/core/types"
+	"github.com/CarLiveChainCo/goiov/ethdb"
+	"github.com/CarLiveChainCo/goiov/rlp"
+)
+
+// Tests that the chain data of an app chain of the legacy layout is moved into
+// its table, leaving the app chains with longer appIds and the trie nodes alone,
+// and that deleting the table removes only its data.
+func TestAppChainTable(t *testing.T) {
+	db := ethdb.NewMemDatabase()
+
+	header := &types.Header{Number: big.NewInt(1), Extra: []byte("app chain header")}
+	enc, _ := rlp.EncodeToBytes(header)
+	legacy := func(appId string) []byte {
+		return append([]byte(appId), append(append(headerPrefix, encodeBlockNumber(1)...), header.Hash().Bytes()...)...)
+	}
+	node := append([]byte("1"), make([]byte, common.HashLength-1)...)
+
+	db.Put(legacy("1"), enc)
+	db.Put(legacy("12"), enc)
+	db.Put(node, []byte("trie node"))
+
+	if moved, err := MigrateLegacyAppChain(db, "1", []string{"1", "12"}); err != nil || moved != 1 {
+		t.Fatalf("migration mismatch: have %d keys, %v, want 1 key", moved, err)
+	}
+	if entry := ReadHeader(db, header.Hash(), 1, "1"); entry == nil || entry.Hash() != header.Hash() {
+		t.Fatalf("migrated header mismatch: have %v, want %v", entry, header)
+	}
+	if has, _ := db.Has(legacy("1")); has {
+		t.Errorf("legacy header of the app chain kept")
+	}
+	if has, _ := db.Has(legacy("12")); !has {
+		t.Errorf("legacy header of the longer appId moved")
+	}
+	if has, _ := db.Has(node); !has {
+		t.Errorf("trie node moved")
+	}
+	WriteHeader(db, header, "12")
+
+	if deleted, err := DeleteAppChainTable(db, "1"); err != nil || deleted != 1 {
+		t.Fatalf("deletion mismatch: have %d keys, %v, want 1 key", deleted, err)
+	}
+	if entry := ReadHeader(db, header.Hash(), 1, "1"); entry != nil {
+		t.Errorf("deleted header returned: %v", entry)
+	}
+	if entry := ReadHeader(db, header.Hash(), 1, "12"); entry == nil {
+		t.Errorf("header of another app chain deleted")
+	}
+}
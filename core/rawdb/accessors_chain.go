@@ -31,9 +31,7 @@ import (
 // ReadCanonicalHash retrieves the hash assigned to a canonical block number.
 func ReadCanonicalHash(db DatabaseReader, number uint64, appid ...string) common.Hash {
 	var key = append(append(headerPrefix, encodeBlockNumber(number)...), headerHashSuffix...)
-	if len(appid) == 1 && appid[0] != "" {
-		key = append([]byte(appid[0]), key...)
-	}
+	key = appKey(key, appid)
 
 	data, _ := db.Get(key)
 	if len(data) == 0 {
@@ -45,9 +43,7 @@ func ReadCanonicalHash(db DatabaseReader, number uint64, appid ...string) common
 // WriteCanonicalHash stores the hash assigned to a canonical block number.
 func WriteCanonicalHash(db DatabaseWriter, hash common.Hash, number uint64, appid ...string) {
 	key := append(append(headerPrefix, encodeBlockNumber(number)...), headerHashSuffix...)
-	if len(appid) == 1 && appid[0] != "" {
-		key = append([]byte(appid[0]), key...)
-	}
+	key = appKey(key, appid)
 	
 	if err := db.Put(key, hash.Bytes()); err != nil {
 		log.Crit("Failed to store number to hash mapping", "err", err)
@@ -57,9 +53,7 @@ func WriteCanonicalHash(db DatabaseWriter, hash common.Hash, number uint64, appi
 // DeleteCanonicalHash removes the number to hash canonical mapping.
 func DeleteCanonicalHash(db DatabaseDeleter, number uint64, appid ...string) {
 	var key = append(append(headerPrefix, encodeBlockNumber(number)...), headerHashSuffix...)
-	if len(appid) == 1 && appid[0] != "" {
-		key = append([]byte(appid[0]), key...)
-	}
+	key = appKey(key, appid)
 	if err := db.Delete(key); err != nil {
 		log.Crit("Failed to delete number to hash mapping", "err", err)
 	}
@@ -68,9 +62,7 @@ func DeleteCanonicalHash(db DatabaseDeleter, number uint64, appid ...string) {
 // ReadHeaderNumber returns the header number assigned to a hash.
 func ReadHeaderNumber(db DatabaseReader, hash common.Hash, appid ...string) *uint64 {
 	var key = append(headerNumberPrefix, hash.Bytes()...)
-	if len(appid) == 1 && appid[0] != "" {
-		key = append([]byte(appid[0]), key...)
-	}
+	key = appKey(key, appid)
 	data, _ := db.Get(key)
 	if len(data) != 8 {
 		return nil
@@ -82,9 +74,7 @@ func ReadHeaderNumber(db DatabaseReader, hash common.Hash, appid ...string) *uin
 // ReadHeadHeaderHash retrieves the hash of the current canonical head header.
 func ReadHeadHeaderHash(db DatabaseReader, appid ...string) common.Hash {
 	var key = headHeaderKey
-	if len(appid) == 1 && appid[0] != "" {
-		key = append([]byte(appid[0]), key...)
-	}
+	key = appKey(key, appid)
 	data, _ := db.Get(key)
 	if len(data) == 0 {
 		return common.Hash{}
@@ -95,9 +85,7 @@ func ReadHeadHeaderHash(db DatabaseReader, appid ...string) common.Hash {
 // WriteHeadHeaderHash stores the hash of the current canonical head header.
 func WriteHeadHeaderHash(db DatabaseWriter, hash common.Hash, appid ...string) {
 	var key = headHeaderKey
-	if len(appid) == 1 && appid[0] != "" {
-		key = append([]byte(appid[0]), key...)
-	}
+	key = appKey(key, appid)
 	if err := db.Put(key, hash.Bytes()); err != nil {
 		log.Crit("Failed to store last header's hash", "err", err)
 	}
@@ -106,9 +94,7 @@ func WriteHeadHeaderHash(db DatabaseWriter, hash common.Hash, appid ...string) {
 // ReadHeadBlockHash retrieves the hash of the current canonical head block.
 func ReadHeadBlockHash(db DatabaseReader, appid ...string) common.Hash {
 	var key = headBlockKey
-	if len(appid) == 1 && appid[0] != "" {
-		key = append([]byte(appid[0]), key...)
-	}
+	key = appKey(key, appid)
 	data, _ := db.Get(key)
 	if len(data) == 0 {
 		return common.Hash{}
@@ -119,9 +105,7 @@ func ReadHeadBlockHash(db DatabaseReader, appid ...string) common.Hash {
 // WriteHeadBlockHash stores the head block's hash.
 func WriteHeadBlockHash(db DatabaseWriter, hash common.Hash, appid ...string) {
 	var key = headBlockKey
-	if len(appid) == 1 && appid[0] != "" {
-		key = append([]byte(appid[0]), key...)
-	}
+	key = appKey(key, appid)
 	if err := db.Put(key, hash.Bytes()); err != nil {
 		log.Crit("Failed to store last block's hash", "err", err)
 	}
@@ -130,9 +114,7 @@ func WriteHeadBlockHash(db DatabaseWriter, hash common.Hash, appid ...string) {
 // ReadHeadFastBlockHash retrieves the hash of the current fast-sync head block.
 func ReadHeadFastBlockHash(db DatabaseReader, appid ...string) common.Hash {
 	var key = headFastBlockKey
-	if len(appid) == 1 && appid[0] != "" {
-		key = append([]byte(appid[0]), key...)
-	}
+	key = appKey(key, appid)
 	data, _ := db.Get(key)
 	if len(data) == 0 {
 		return common.Hash{}
@@ -143,9 +125,7 @@ func ReadHeadFastBlockHash(db DatabaseReader, appid ...string) common.Hash {
 // WriteHeadFastBlockHash stores the hash of the current fast-sync head block.
 func WriteHeadFastBlockHash(db DatabaseWriter, hash common.Hash, appid ...string) {
 	var key = headFastBlockKey
-	if len(appid) == 1 && appid[0] != "" {
-		key = append([]byte(appid[0]), key...)
-	}
+	key = appKey(key, appid)
 	if err := db.Put(key, hash.Bytes()); err != nil {
 		log.Crit("Failed to store last fast block's hash", "err", err)
 	}
@@ -155,9 +135,7 @@ func WriteHeadFastBlockHash(db DatabaseWriter, hash common.Hash, appid ...string
 // reporting correct numbers across restarts.
 func ReadFastTrieProgress(db DatabaseReader, appid ...string) uint64 {
 	var key = fastTrieProgressKey
-	if len(appid) == 1 && appid[0] != "" {
-		key = append([]byte(appid[0]), key...)
-	}
+	key = appKey(key, appid)
 	data, _ := db.Get(key)
 	if len(data) == 0 {
 		return 0
@@ -169,9 +147,7 @@ func ReadFastTrieProgress(db DatabaseReader, appid ...string) uint64 {
 // retrieving it across restarts.
 func WriteFastTrieProgress(db DatabaseWriter, count uint64, appid ...string) {
 	var key = fastTrieProgressKey
-	if len(appid) == 1 && appid[0] != "" {
-		key = append([]byte(appid[0]), key...)
-	}
+	key = appKey(key, appid)
 	if err := db.Put(key, new(big.Int).SetUint64(count).Bytes()); err != nil {
 		log.Crit("Failed to store fast sync trie progress", "err", err)
 	}
@@ -180,19 +156,15 @@ func WriteFastTrieProgress(db DatabaseWriter, count uint64, appid ...string) {
 // ReadHeaderRLP retrieves a block header in its raw RLP database encoding.
 func ReadHeaderRLP(db DatabaseReader, hash common.Hash, number uint64, appid ...string) rlp.RawValue {
 	var key = append(append(headerPrefix, encodeBlockNumber(number)...), hash.Bytes()...)
-	if len(appid) == 1 && appid[0] != "" {
-		key = append([]byte(appid[0]), key...)
-	}
+	key = appKey(key, appid)
 	data, _ := db.Get(key)
 	return data
 }
 
 // HasHeader verifies the existence of a block header corresponding to the hash.
 func HasHeader(db DatabaseReader, hash common.Hash, number uint64, appid ...string) bool {
-	var key = append(append(append(headerPrefix, encodeBlockNumber(number)...), hash.Bytes()...))
-	if len(appid) == 1 && appid[0] != "" {
-		key = append([]byte(appid[0]), key...)
-	}
+	var key = append(append(headerPrefix, encodeBlockNumber(number)...), hash.Bytes()...)
+	key = appKey(key, appid)
 	if has, err := db.Has(key); !has || err != nil {
 		return false
 	}
@@ -227,9 +199,7 @@ func WriteHeader(db DatabaseWriter, header *types.Header, appid ...string) {
 		encoded = encodeBlockNumber(number)
 	)
 	key := append(headerNumberPrefix, hash...)
-	if len(appid) == 1 && appid[0] != "" {
-		key = append([]byte(appid[0]), key...)
-	}
+	key = appKey(key, appid)
 	if err := db.Put(key, encoded); err != nil {
 		log.Crit("Failed to store hash to number mapping", "err", err)
 	}
@@ -239,9 +209,7 @@ func WriteHeader(db DatabaseWriter, header *types.Header, appid ...string) {
 		log.Crit("Failed to RLP encode header", "err", err)
 	}
 	key = append(append(headerPrefix, encoded...), hash...)
-	if len(appid) == 1 && appid[0] != "" {
-		key = append([]byte(appid[0]), key...)
-	}
+	key = appKey(key, appid)
 	if err := db.Put(key, data); err != nil {
 		log.Crit("Failed to store header", "err", err)
 	}
@@ -250,17 +218,13 @@ func WriteHeader(db DatabaseWriter, header *types.Header, appid ...string) {
 // DeleteHeader removes all block header data associated with a hash.
 func DeleteHeader(db DatabaseDeleter, hash common.Hash, number uint64, appid ...string) {
 	var key = append(append(headerPrefix, encodeBlockNumber(number)...), hash.Bytes()...)
-	if len(appid) == 1 && appid[0] != "" {
-		key = append([]byte(appid[0]), key...)
-	}
+	key = appKey(key, appid)
 	if err := db.Delete(key); err != nil {
 		log.Crit("Failed to delete header", "err", err)
 	}
 
 	key = append(headerNumberPrefix, hash.Bytes()...)
-	if len(appid) == 1 && appid[0] != "" {
-		key = append([]byte(appid[0]), key...)
-	}
+	key = appKey(key, appid)
 	if err := db.Delete(key); err != nil {
 		log.Crit("Failed to delete hash to number mapping", "err", err)
 	}
@@ -284,9 +248,7 @@ func WriteBodyRLP(db DatabaseWriter, hash common.Hash, number uint64, rlp rlp.Ra
 // HasBody verifies the existence of a block body corresponding to the hash.
 func HasBody(db DatabaseReader, hash common.Hash, number uint64, appid ...string) bool {
 	key := append(append(blockBodyPrefix, encodeBlockNumber(number)...), hash.Bytes()...)
-	if len(appid) == 1 && appid[0] != "" {
-		key = append([]byte(appid[0]), key...)
-	}
+	key = appKey(key, appid)
 	if has, err := db.Has(key); !has || err != nil {
 		return false
 	}
@@ -319,9 +281,7 @@ func WriteBody(db DatabaseWriter, hash common.Hash, number uint64, body *types.B
 // DeleteBody removes all block body data associated with a hash.
 func DeleteBody(db DatabaseDeleter, hash common.Hash, number uint64, appid ...string) {
 	key := append(append(blockBodyPrefix, encodeBlockNumber(number)...), hash.Bytes()...)
-	if len(appid) == 1 && appid[0] != "" {
-		key = append([]byte(appid[0]), key...)
-	}
+	key = appKey(key, appid)
 	if err := db.Delete(key); err != nil {
 		log.Crit("Failed to delete block body", "err", err)
 	}
@@ -330,9 +290,7 @@ func DeleteBody(db DatabaseDeleter, hash common.Hash, number uint64, appid ...st
 // ReadTd retrieves a block's total difficulty corresponding to the hash.
 func ReadTd(db DatabaseReader, hash common.Hash, number uint64, appid ...string) *big.Int {
 	key := append(append(append(headerPrefix, encodeBlockNumber(number)...), hash[:]...), headerTDSuffix...)
-	if len(appid) == 1 && appid[0] != "" {
-		key = append([]byte(appid[0]), key...)
-	}
+	key = appKey(key, appid)
 	data, _ := db.Get(key)
 	if len(data) == 0 {
 		return nil
@@ -352,9 +310,7 @@ func WriteTd(db DatabaseWriter, hash common.Hash, number uint64, td *big.Int, ap
 		log.Crit("Failed to RLP encode block total difficulty", "err", err)
 	}
 	key := append(append(append(headerPrefix, encodeBlockNumber(number)...), hash.Bytes()...), headerTDSuffix...)
-	if len(appid) == 1 && appid[0] != "" {
-		key = append([]byte(appid[0]), key...)
-	}
+	key = appKey(key, appid)
 	if err := db.Put(key, data); err != nil {
 		log.Crit("Failed to store block total difficulty", "err", err)
 	}
@@ -363,9 +319,7 @@ func WriteTd(db DatabaseWriter, hash common.Hash, number uint64, td *big.Int, ap
 // DeleteTd removes all block total difficulty data associated with a hash.
 func DeleteTd(db DatabaseDeleter, hash common.Hash, number uint64, appid ...string) {
 	key := append(append(append(headerPrefix, encodeBlockNumber(number)...), hash.Bytes()...), headerTDSuffix...)
-	if len(appid) == 1 && appid[0] != "" {
-		key = append([]byte(appid[0]), key...)
-	}
+	key = appKey(key, appid)
 	if err := db.Delete(key); err != nil {
 		log.Crit("Failed to delete block total difficulty", "err", err)
 	}
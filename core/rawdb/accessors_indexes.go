@@ -26,74 +26,116 @@ import (
 )
 
 // ReadTxLookupEntry retrieves the positional metadata associated with a transaction
-// hash to allow retrieving the transaction or receipt by hash.
-func ReadTxLookupEntry(db DatabaseReader, hash common.Hash , appid ...string) (common.Hash, uint64, uint64) {
+// hash to allow retrieving the transaction or receipt by hash. If an appId is
+// given, transactions of other chains are not returned.
+func ReadTxLookupEntry(db DatabaseReader, hash common.Hash, appid ...string) (common.Hash, uint64, uint64) {
+	blockHash, blockNumber, txIndex, appId := ReadTxLookupEntryAppId(db, hash)
+	if len(appid) == 1 && appId != "" && appId != appid[0] {
+		return common.Hash{}, 0, 0
+	}
+	return blockHash, blockNumber, txIndex
+}
+
+// ReadTxLookupEntryAppId retrieves the positional metadata associated with a
+// transaction hash along with the appId of the chain holding the transaction,
+// empty for the main chain and for the entries written before the lookups
+// recorded their chain.
+func ReadTxLookupEntryAppId(db DatabaseReader, hash common.Hash) (common.Hash, uint64, uint64, string) {
 	var key = append(txLookupPrefix, hash.Bytes()...)
 	data, _ := db.Get(key)
 	if len(data) == 0 {
-		return common.Hash{}, 0, 0
+		return common.Hash{}, 0, 0, ""
 	}
-	var entry TxLookupEntry
+	var entry txLookupEntry
 	if err := rlp.DecodeBytes(data, &entry); err != nil {
 		log.Error("Invalid transaction lookup entry RLP", "hash", hash, "err", err)
-		return common.Hash{}, 0, 0
+		return common.Hash{}, 0, 0, ""
 	}
-	return entry.BlockHash, entry.BlockIndex, entry.Index
+	var appId string
+	if len(entry.AppId) > 0 {
+		appId = entry.AppId[0]
+	}
+	return entry.BlockHash, entry.BlockIndex, entry.Index, appId
 }
 
 // WriteTxLookupEntries stores a positional metadata for every transaction from
-// a block, enabling hash based transaction and receipt lookups.
-func WriteTxLookupEntries(db DatabaseWriter, block *types.Block , appid ...string) {
+// a block, enabling hash based transaction and receipt lookups. The entries of
+// all chains share one index, the ones of an app chain record its appId.
+func WriteTxLookupEntries(db DatabaseWriter, block *types.Block, appid ...string) {
 	for i, tx := range block.Transactions() {
-		entry := TxLookupEntry{
+		entry := txLookupEntry{
 			BlockHash:  block.Hash(),
 			BlockIndex: block.NumberU64(),
 			Index:      uint64(i),
 		}
+		if len(appid) == 1 && appid[0] != "" {
+			entry.AppId = []string{appid[0]}
+		}
 		data, err := rlp.EncodeToBytes(entry)
 		if err != nil {
 			log.Crit("Failed to encode transaction lookup entry", "err", err)
 		}
 		var key = append(txLookupPrefix, tx.Hash().Bytes()...)
-		if err := db.Put(key , data); err != nil {
+		if err := db.Put(key, data); err != nil {
 			log.Crit("Failed to store transaction lookup entry", "err", err)
 		}
 	}
 }
 
-// DeleteTxLookupEntry removes all transaction data associated with a hash.
-func DeleteTxLookupEntry(db DatabaseDeleter, hash common.Hash , appid ...string) {
+// DeleteTxLookupEntry removes all transaction data associated with a hash. If
+// an appId is given, the entry is kept if it records another chain.
+func DeleteTxLookupEntry(db DatabaseDeleter, hash common.Hash, appid ...string) {
 	var key = append(txLookupPrefix, hash.Bytes()...)
+	if reader, ok := db.(DatabaseReader); ok && len(appid) == 1 {
+		if _, _, _, appId := ReadTxLookupEntryAppId(reader, hash); appId != "" && appId != appid[0] {
+			return
+		}
+	}
 	db.Delete(key)
 }
 
 // ReadTransaction retrieves a specific transaction from the database, along with
-// its added positional metadata.
-func ReadTransaction(db DatabaseReader, hash common.Hash , appid ...string) (*types.Transaction, common.Hash, uint64, uint64) {
-	blockHash, blockNumber, txIndex := ReadTxLookupEntry(db, hash)
+// its added positional metadata. The transaction is looked up in the chain its
+// lookup entry records, or in the chain of the appId if one is given.
+func ReadTransaction(db DatabaseReader, hash common.Hash, appid ...string) (*types.Transaction, common.Hash, uint64, uint64) {
+	tx, blockHash, blockNumber, txIndex, _ := ReadTransactionAppId(db, hash, appid...)
+	return tx, blockHash, blockNumber, txIndex
+}
+
+// ReadTransactionAppId retrieves a specific transaction from the database, along
+// with its added positional metadata and the appId of the chain holding it.
+func ReadTransactionAppId(db DatabaseReader, hash common.Hash, appid ...string) (*types.Transaction, common.Hash, uint64, uint64, string) {
+	blockHash, blockNumber, txIndex, appId := ReadTxLookupEntryAppId(db, hash)
 	if blockHash == (common.Hash{}) {
-		return nil, common.Hash{}, 0, 0
+		return nil, common.Hash{}, 0, 0, ""
+	}
+	if len(appid) == 1 {
+		if appId != "" && appId != appid[0] {
+			return nil, common.Hash{}, 0, 0, ""
+		}
+		appId = appid[0]
+	}
+	// The entries written before the lookups recorded their chain look like the
+	// ones of the main chain, only the header of the block tells its chain
+	if number := ReadHeaderNumber(db, blockHash, appId); number == nil || *number != blockNumber {
+		return nil, common.Hash{}, 0, 0, ""
 	}
-	body := ReadBody(db, blockHash, blockNumber)
+	body := ReadBody(db, blockHash, blockNumber, appId)
 	if body == nil || len(body.Transactions) <= int(txIndex) {
-		log.Error("Transaction referenced missing", "number", blockNumber, "hash", blockHash, "index", txIndex)
-		return nil, common.Hash{}, 0, 0
+		log.Error("Transaction referenced missing", "number", blockNumber, "hash", blockHash, "index", txIndex, "appId", appId)
+		return nil, common.Hash{}, 0, 0, ""
 	}
-	return body.Transactions[txIndex], blockHash, blockNumber, txIndex
+	return body.Transactions[txIndex], blockHash, blockNumber, txIndex, appId
 }
 
 // ReadReceipt retrieves a specific transaction receipt from the database, along with
 // its added positional metadata.
-func ReadReceipt(db DatabaseReader, hash common.Hash , appid ...string) (*types.Receipt, common.Hash, uint64, uint64) {
-	var key string
-	if len(appid) == 1 && appid[0] != "" {
-		key = appid[0]
-	}
-	blockHash, blockNumber, receiptIndex := ReadTxLookupEntry(db, hash ,key)
-	if blockHash == (common.Hash{}) {
+func ReadReceipt(db DatabaseReader, hash common.Hash, appid ...string) (*types.Receipt, common.Hash, uint64, uint64) {
+	tx, blockHash, blockNumber, receiptIndex, appId := ReadTransactionAppId(db, hash, appid...)
+	if tx == nil {
 		return nil, common.Hash{}, 0, 0
 	}
-	receipts := ReadReceipts(db, blockHash, blockNumber , key)
+	receipts := ReadReceipts(db, blockHash, blockNumber, appId)
 	if len(receipts) <= int(receiptIndex) {
 		log.Error("Receipt refereced missing", "number", blockNumber, "hash", blockHash, "index", receiptIndex)
 		return nil, common.Hash{}, 0, 0
@@ -105,11 +147,9 @@ func ReadReceipt(db DatabaseReader, hash common.Hash , appid ...string) (*types.
 // section and bit index from the.
 func ReadBloomBits(db DatabaseReader, bit uint, section uint64, head common.Hash , appid ...string) ([]byte, error) {
 	key := append(append(bloomBitsPrefix, make([]byte, 10)...), head.Bytes()...)
-	if len(appid) == 1 && appid[0] != "" {
-		key = append([]byte(appid[0]) , key...)
-	}
 	binary.BigEndian.PutUint16(key[1:], uint16(bit))
 	binary.BigEndian.PutUint64(key[3:], section)
+	key = appKey(key, appid)
 
 	return db.Get(key)
 }
@@ -118,11 +158,9 @@ func ReadBloomBits(db DatabaseReader, bit uint, section uint64, head common.Hash
 // section and bit index.
 func WriteBloomBits(db DatabaseWriter, bit uint, section uint64, head common.Hash, bits []byte , appid ...string) {
 	key := append(append(bloomBitsPrefix, make([]byte, 10)...), head.Bytes()...)
-	if len(appid) == 1 && appid[0] != "" {
-		key = append([]byte(appid[0]) , key...)
-	}
 	binary.BigEndian.PutUint16(key[1:], uint16(bit))
 	binary.BigEndian.PutUint64(key[3:], section)
+	key = appKey(key, appid)
 
 	if err := db.Put(key, bits); err != nil {
 		log.Crit("Failed to store bloom bits", "err", err)
@@ -66,3 +66,46 @@ func TestLookupStorage(t *testing.T) {
 		}
 	}
 }
+
+// Tests that the lookups of the transactions of an app chain resolve to the app
+// chain, with and without its appId, and that the lookups written before they
+// recorded their chain resolve if the appId is given.
+func TestAppChainLookupStorage(t *testing.T) {
+	db := ethdb.NewMemDatabase()
+
+	tx1 := types.NewTransaction(1, common.BytesToAddress([]byte{0x11}), big.NewInt(111), 1111, big.NewInt(11111), []byte{0x11, 0x11, 0x11})
+	tx2 := types.NewTransaction(2, common.BytesToAddress([]byte{0x22}), big.NewInt(222), 2222, big.NewInt(22222), []byte{0x22, 0x22, 0x22})
+
+	block := types.NewBlock(&types.Header{Number: big.NewInt(314)}, []*types.Transaction{tx1}, nil, nil)
+	legacy := types.NewBlock(&types.Header{Number: big.NewInt(315)}, []*types.Transaction{tx2}, nil, nil)
+
+	WriteBlock(db, block, "app")
+	WriteTxLookupEntries(db, block, "app")
+	WriteBlock(db, legacy, "app")
+	WriteTxLookupEntries(db, legacy)
+
+	if txn, hash, _, _, appId := ReadTransactionAppId(db, tx1.Hash()); txn == nil || hash != block.Hash() || appId != "app" {
+		t.Fatalf("app chain transaction mismatch: have %v/%x/%q, want %v/%x/%q", txn, hash, appId, tx1, block.Hash(), "app")
+	}
+	if txn, _, _, _ := ReadTransaction(db, tx1.Hash(), "app"); txn == nil {
+		t.Fatalf("app chain transaction not found by appId")
+	}
+	if txn, _, _, _ := ReadTransaction(db, tx1.Hash(), ""); txn != nil {
+		t.Fatalf("app chain transaction returned for the main chain: %v", txn)
+	}
+	if txn, _, _, _ := ReadTransaction(db, tx2.Hash()); txn != nil {
+		t.Fatalf("legacy app chain transaction returned for the main chain: %v", txn)
+	}
+	if txn, hash, _, _ := ReadTransaction(db, tx2.Hash(), "app"); txn == nil || hash != legacy.Hash() {
+		t.Fatalf("legacy app chain transaction mismatch: have %v/%x, want %v/%x", txn, hash, tx2, legacy.Hash())
+	}
+	// Deleting the lookup as another chain must keep it
+	DeleteTxLookupEntry(db, tx1.Hash(), "other")
+	if txn, _, _, _ := ReadTransaction(db, tx1.Hash()); txn == nil {
+		t.Fatalf("app chain transaction deleted by another chain")
+	}
+	DeleteTxLookupEntry(db, tx1.Hash(), "app")
+	if txn, _, _, _ := ReadTransaction(db, tx1.Hash()); txn != nil {
+		t.Fatalf("deleted app chain transaction returned: %v", txn)
+	}
+}
@@ -17,11 +17,12 @@
 package rawdb
 
 import (
+	"encoding/json"
+
 	"github.com/CarLiveChainCo/goiov/common"
 	"github.com/CarLiveChainCo/goiov/log"
 	"github.com/CarLiveChainCo/goiov/params"
 	"github.com/CarLiveChainCo/goiov/rlp"
-	"encoding/json"
 )
 
 // ReadDatabaseVersion retrieves the version number of the database.
@@ -42,90 +43,102 @@ func WriteDatabaseVersion(db DatabaseWriter, version int) {
 	}
 }
 
-// ReadChainConfig retrieves the consensus settings based on the given genesis hash.
-//func ReadChainConfig(db DatabaseReader, hash common.Hash, appid ...string) *params.ChainConfig {
-//	var key = append(configPrefix, hash[:]...)
-//	if len(appid) == 1 && appid[0] != "" {
-//		key = append([]byte(appid[0]), key...)
-//	}
-//	log.Info("---读链config数据", "appid", appid[0])
-//	data, _ := db.Get(key)
-//	if len(data) == 0 {
-//		return nil
-//	}
-//	var config params.ChainConfig
-//	if err := json.Unmarshal(data, &config); err != nil {
-//		log.Error("Invalid chain config JSON", "hash", hash, "err", err)
-//		return nil
-//	}
-//	return &config
-//}
+// ReadChainConfig retrieves the consensus settings of the chain the optional
+// appid names, the main chain if it names none. A chain keeps a single config,
+// the hash of its genesis block is not part of the key.
 func ReadChainConfig(db DatabaseReader, hash common.Hash, appid ...string) *params.ChainConfig {
-	key := ""
-	if len(appid) == 1 {
-		key = appid[0]
-	}
-	if cMap := ReadAllChainConfig(db); cMap != nil {
-		if config, ok := cMap[key]; ok {
-			return config
-		}
-	}
-	return nil
-}
-
-func ReadAllChainConfig(db DatabaseReader) map[string]*params.ChainConfig {
-	var key = configPrefix
-	var cMap = make(map[string]*params.ChainConfig)
-	data, _ := db.Get(key)
+	data, _ := db.Get(appKey(chainConfigKey, appid))
 	if len(data) == 0 {
-		return cMap
+		return nil
 	}
-	if err := json.Unmarshal(data, &cMap); err != nil {
-		log.Error("Invalid chain config JSON", "err", err)
+	var config params.ChainConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		log.Error("Invalid chain config JSON", "hash", hash, "err", err)
 		return nil
 	}
-	return cMap
+	return &config
 }
 
-// WriteChainConfig writes the chain config settings to the database.
-//func WriteChainConfig(db DatabaseWriter, hash common.Hash, cfg *params.ChainConfig, appid ...string) {
-//	if cfg == nil {
-//		return
-//	}
-//	data, err := json.Marshal(cfg)
-//	if err != nil {
-//		log.Crit("Failed to JSON encode chain config", "err", err)
-//	}
-//	var key = append(configPrefix, hash[:]...)
-//	if len(appid) == 1 && appid[0] != "" {
-//		key = append([]byte(appid[0]), key...)
-//	}
-//	log.Info("---写链config数据", "appid", appid[0])
-//	if err := db.Put(key, data); err != nil {
-//		log.Crit("Failed to store chain config", "err", err)
-//	}
-//}
-func WriteChainConfig(db DatabaseWriter, hash common.Hash, cfg map[string]*params.ChainConfig, appid ...string) {
+// WriteChainConfig writes the chain config settings to the database. The
+// config of an app chain is stored in its table, ReadAllChainConfig lists it
+// once its appId is added to the app chain index with WriteAppChains.
+func WriteChainConfig(db DatabaseWriter, hash common.Hash, cfg *params.ChainConfig, appid ...string) {
 	if cfg == nil {
 		return
 	}
-	var key = configPrefix
-	enc, err := json.Marshal(cfg)
+	data, err := json.Marshal(cfg)
 	if err != nil {
 		log.Crit("Failed to JSON encode chain config", "err", err)
 	}
-	if err := db.Put(key, enc); err != nil {
+	if err := db.Put(appKey(chainConfigKey, appid), data); err != nil {
 		log.Crit("Failed to store chain config", "err", err)
 	}
-	log.Info("---写链config数据", "appId", appid[0])
+}
+
+// ReadAllChainConfig retrieves the consensus settings of the main chain, keyed
+// by the empty appId, and of every app chain of the index.
+func ReadAllChainConfig(db DatabaseReader) map[string]*params.ChainConfig {
+	cMap := make(map[string]*params.ChainConfig)
+	if config := ReadChainConfig(db, common.Hash{}); config != nil {
+		cMap[""] = config
+	}
+	for _, appId := range ReadAppChains(db) {
+		if config := ReadChainConfig(db, common.Hash{}, appId); config != nil {
+			cMap[appId] = config
+		}
+	}
+	return cMap
+}
+
+// ReadAppChains retrieves the appIds of the app chains with a stored config.
+func ReadAppChains(db DatabaseReader) []string {
+	var appIds []string
+	if enc, _ := db.Get(appChainsKey); len(enc) > 0 {
+		if err := rlp.DecodeBytes(enc, &appIds); err != nil {
+			log.Error("Invalid app chain index RLP", "err", err)
+			return nil
+		}
+	}
+	return appIds
+}
+
+// WriteAppChains stores the appIds of the app chains with a stored config.
+func WriteAppChains(db DatabaseWriter, appIds []string) {
+	enc, err := rlp.EncodeToBytes(appIds)
+	if err != nil {
+		log.Crit("Failed to RLP encode app chain index", "err", err)
+	}
+	if err := db.Put(appChainsKey, enc); err != nil {
+		log.Crit("Failed to store app chain index", "err", err)
+	}
+}
+
+// ReadLegacyChainConfigs retrieves the consensus settings of all chains from
+// the single blob of the databases created before app chains had their tables.
+func ReadLegacyChainConfigs(db DatabaseReader) map[string]*params.ChainConfig {
+	data, _ := db.Get(configPrefix)
+	if len(data) == 0 {
+		return nil
+	}
+	cMap := make(map[string]*params.ChainConfig)
+	if err := json.Unmarshal(data, &cMap); err != nil {
+		log.Error("Invalid legacy chain config JSON", "err", err)
+		return nil
+	}
+	return cMap
+}
+
+// DeleteLegacyChainConfigs removes the legacy blob of the chain configs.
+func DeleteLegacyChainConfigs(db DatabaseDeleter) {
+	if err := db.Delete(configPrefix); err != nil {
+		log.Crit("Failed to delete legacy chain configs", "err", err)
+	}
 }
 
 // ReadPreimage retrieves a single preimage of the provided hash.
 func ReadPreimage(db DatabaseReader, hash common.Hash, appid ...string) []byte {
 	var key = append(preimagePrefix, hash.Bytes()...)
-	if len(appid) == 1 && appid[0] != "" {
-		key = append([]byte(appid[0]), key...)
-	}
+	key = appKey(key, appid)
 	data, _ := db.Get(key)
 	return data
 }
@@ -135,9 +148,7 @@ func ReadPreimage(db DatabaseReader, hash common.Hash, appid ...string) []byte {
 func WritePreimages(db DatabaseWriter, number uint64, preimages map[common.Hash][]byte, appid ...string) {
 	for hash, preimage := range preimages {
 		var key = append(preimagePrefix, hash.Bytes()...)
-		if len(appid) == 1 && appid[0] != "" {
-			key = append([]byte(appid[0]), key...)
-		}
+		key = appKey(key, appid)
 		if err := db.Put(key, preimage); err != nil {
 			log.Crit("Failed to store trie preimage", "err", err)
 		}
@@ -21,6 +21,7 @@ import (
 	"encoding/binary"
 
 	"github.com/CarLiveChainCo/goiov/common"
+	"github.com/CarLiveChainCo/goiov/ethdb"
 	"github.com/CarLiveChainCo/goiov/metrics"
 )
 
@@ -32,8 +33,15 @@ var (
 	// headHeaderKey tracks the latest know header's hash.
 	headHeaderKey = []byte("LastHeader")
 
+	// appIdKey tracks the appIds of the app chains the node runs.
 	appIdKey = []byte("AppId")
 
+	// appChainsKey tracks the appIds of the app chains with a stored config.
+	appChainsKey = []byte("AppChains")
+
+	// chainConfigKey tracks the consensus settings of a chain.
+	chainConfigKey = []byte("ChainConfig")
+
 	// headBlockKey tracks the latest know full block's hash.
 	headBlockKey = []byte("LastBlock")
 
@@ -56,7 +64,8 @@ var (
 	bloomBitsPrefix = []byte("B") // bloomBitsPrefix + bit (uint16 big endian) + section (uint64 big endian) + hash -> bloom bits
 
 	preimagePrefix = []byte("secure-key-")      // preimagePrefix + hash -> preimage
-	configPrefix   = []byte("ethereum-config-") // config prefix for the db
+	configPrefix   = []byte("ethereum-config-") // configPrefix -> legacy chain configs of all chains, keyed by appId
+	appChainPrefix = []byte("appchain-")        // appChainPrefix + appId length (uint16 big endian) + appId -> table of an app chain
 
 	// Chain index prefixes (use `i` + single byte to avoid mixing data types).
 	BloomBitsIndexPrefix = []byte("iB") // BloomBitsIndexPrefix is the data table of a chain indexer to track its progress
@@ -73,6 +82,47 @@ type TxLookupEntry struct {
 	Index      uint64
 }
 
+// txLookupEntry is the stored TxLookupEntry, along with the appId of the app
+// chain holding the transaction. The appId is omitted for the main chain, so
+// the entries of the main chain keep the layout of a TxLookupEntry.
+type txLookupEntry struct {
+	BlockHash  common.Hash
+	BlockIndex uint64
+	Index      uint64
+	AppId      []string `rlp:"tail"`
+}
+
+// AppChainPrefix returns the key prefix of the table holding all the data of
+// the app chain appId, nil for the main chain.
+func AppChainPrefix(appId string) []byte {
+	if appId == "" {
+		return nil
+	}
+	prefix := make([]byte, len(appChainPrefix)+2+len(appId))
+	copy(prefix, appChainPrefix)
+	binary.BigEndian.PutUint16(prefix[len(appChainPrefix):], uint16(len(appId)))
+	copy(prefix[len(appChainPrefix)+2:], appId)
+	return prefix
+}
+
+// AppChainDatabase returns the table of the app chain appId in the database,
+// the database itself for the main chain.
+func AppChainDatabase(db ethdb.Database, appId string) ethdb.Database {
+	if appId == "" {
+		return db
+	}
+	return ethdb.NewTable(db, string(AppChainPrefix(appId)))
+}
+
+// appKey returns the key of the data of the chain the optional appid names,
+// prefixed with the table of the app chain.
+func appKey(key []byte, appid []string) []byte {
+	if len(appid) == 1 && appid[0] != "" {
+		return append(AppChainPrefix(appid[0]), key...)
+	}
+	return key
+}
+
 // encodeBlockNumber encodes a block number as big endian uint64
 func encodeBlockNumber(number uint64) []byte {
 	enc := make([]byte, 8)
@@ -0,0 +1,82 @@
+// Copyright 2018 The giov Authors
+// This file is part of the giov library.
+//
+// The giov library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The giov library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the giov library. If not, see <http://www.gnu.org/licenses/>.
+
+package pruner
+
+import (
+	"encoding/binary"
+	"math"
+
+	"github.com/CarLiveChainCo/goiov/common"
+)
+
+// bloomHashes is the number of bits set in the filter for every hash added,
+// one for every 8 bytes of the hash.
+const bloomHashes = common.HashLength / 8
+
+// stateBloom is a bloom filter of the hashes of the trie nodes and contract
+// codes marked as reachable. The hashes are keccak256 outputs, distributed
+// uniformly already, so the bits of a hash are picked by the hash itself
+// instead of hashing it again.
+type stateBloom struct {
+	bits  []uint64 // Bit vector of the filter
+	size  uint64   // Number of bits of the filter
+	added uint64   // Number of hashes added, counting the duplicates
+}
+
+// newStateBloom creates a bloom filter taking the given number of megabytes.
+func newStateBloom(megabytes uint64) *stateBloom {
+	words := megabytes * 1024 * 1024 / 8
+	return &stateBloom{
+		bits: make([]uint64, words),
+		size: words * 64,
+	}
+}
+
+// reset clears the filter for reuse.
+func (b *stateBloom) reset() {
+	for i := range b.bits {
+		b.bits[i] = 0
+	}
+	b.added = 0
+}
+
+// add adds the hash to the filter.
+func (b *stateBloom) add(hash []byte) {
+	for i := 0; i < bloomHashes; i++ {
+		bit := binary.BigEndian.Uint64(hash[i*8:]) % b.size
+		b.bits[bit/64] |= 1 << (bit % 64)
+	}
+	b.added++
+}
+
+// contains reports whether the hash may have been added to the filter. Hashes
+// added are always reported, others with the false positive rate of the filter.
+func (b *stateBloom) contains(hash []byte) bool {
+	for i := 0; i < bloomHashes; i++ {
+		bit := binary.BigEndian.Uint64(hash[i*8:]) % b.size
+		if b.bits[bit/64]&(1<<(bit%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// falsePositiveRate estimates the rate of the hashes not added the filter
+// reports anyway, overestimated as the duplicates added are counted.
+func (b *stateBloom) falsePositiveRate() float64 {
+	return math.Pow(1-math.Exp(-float64(bloomHashes)*float64(b.added)/float64(b.size)), bloomHashes)
+}
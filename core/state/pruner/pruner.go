@@ -0,0 +1,317 @@
+// Copyright 2018 The giov Authors
+// This file is part of the giov library.
+//
+// The giov library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The giov library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the giov library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package pruner removes from the chain database the state of the main chain
+// and of the app chains no longer reachable from their recent blocks.
+package pruner
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/CarLiveChainCo/goiov/common"
+	"github.com/CarLiveChainCo/goiov/core/rawdb"
+	"github.com/CarLiveChainCo/goiov/core/state"
+	"github.com/CarLiveChainCo/goiov/core/types"
+	"github.com/CarLiveChainCo/goiov/crypto"
+	"github.com/CarLiveChainCo/goiov/ethdb"
+	"github.com/CarLiveChainCo/goiov/log"
+	"github.com/CarLiveChainCo/goiov/rlp"
+	"github.com/CarLiveChainCo/goiov/trie"
+)
+
+var (
+	// emptyRoot is the known root hash of an empty trie.
+	emptyRoot = types.EmptyRootHash
+
+	// emptyCode is the known hash of the empty contract code.
+	emptyCode = crypto.Keccak256(nil)
+)
+
+var (
+	// errNotIterable is returned if the database to prune can't be iterated over.
+	errNotIterable = errors.New("database not iterable")
+
+	// errNoBlocks is returned if the state of no recent block is to be kept.
+	errNoBlocks = errors.New("no recent blocks to keep the state of")
+
+	// errNoBloom is returned if the bloom filter of the reachable state has no size.
+	errNoBloom = errors.New("bloom filter size must be at least 1 megabyte")
+)
+
+// Config are the settings of a pruning.
+type Config struct {
+	Blocks    uint64 // Number of recent blocks of every chain their state is kept
+	BloomSize uint64 // Size of the bloom filter of the reachable state, in megabytes
+}
+
+// Pruner removes the trie nodes and contract codes of the chain database not
+// reachable from the state of the recent blocks and the genesis of the main
+// chain and of every app chain. Every app chain keeps its state in its own
+// table of the database, so the chains are pruned one after the other, each in
+// two phases: first the state reachable from the chain is marked in a bloom
+// filter, then every node and code of its table the filter doesn't hold is
+// swept. The false positives of the filter are kept, which trades a little disk
+// space for a bounded memory use.
+//
+// The pruning must be run while the node is stopped. It is safe to interrupt,
+// the state marked is never deleted, so running it again resumes the sweep.
+type Pruner struct {
+	db     ethdb.Database
+	config Config
+}
+
+// chainPruner prunes the state of a single chain, kept in the table of the
+// chain database the prefix names.
+type chainPruner struct {
+	appId  string
+	prefix []byte
+	db     ethdb.Database
+	triedb *trie.Database
+	bloom  *stateBloom
+	config Config
+}
+
+// NewPruner creates a pruner of the given chain database.
+func NewPruner(db ethdb.Database, config Config) (*Pruner, error) {
+	if _, ok := db.(ethdb.Iteratee); !ok {
+		return nil, errNotIterable
+	}
+	if config.Blocks == 0 {
+		return nil, errNoBlocks
+	}
+	if config.BloomSize == 0 {
+		return nil, errNoBloom
+	}
+	return &Pruner{
+		db:     db,
+		config: config,
+	}, nil
+}
+
+// Prune marks the state reachable from the recent blocks of every chain, then
+// sweeps the rest of the state of the chain from its table of the database.
+func (p *Pruner) Prune() error {
+	start := time.Now()
+
+	var appIds []string
+	for appId := range rawdb.ReadAllChainConfig(p.db) {
+		appIds = append(appIds, appId)
+	}
+	sort.Strings(appIds)
+
+	var (
+		bloom   *stateBloom
+		deleted int
+	)
+	for _, appId := range appIds {
+		// The filter of the previous chain is cleared for reuse, all of the
+		// chains share the memory of a single one
+		if bloom == nil {
+			bloom = newStateBloom(p.config.BloomSize)
+		} else {
+			bloom.reset()
+		}
+		db := rawdb.AppChainDatabase(p.db, appId)
+		cp := &chainPruner{
+			appId:  appId,
+			prefix: rawdb.AppChainPrefix(appId),
+			db:     db,
+			triedb: trie.NewDatabase(db),
+			bloom:  bloom,
+			config: p.config,
+		}
+		if err := cp.mark(p.db); err != nil {
+			return err
+		}
+		log.Info("Marked reachable state", "appId", appId, "hashes", bloom.added, "falsepositives", fmt.Sprintf("%.4f", bloom.falsePositiveRate()), "elapsed", common.PrettyDuration(time.Since(start)))
+
+		n, err := cp.sweep(p.db.(ethdb.Iteratee))
+		deleted += n
+		if err != nil {
+			return err
+		}
+	}
+	if compacter, ok := p.db.(ethdb.Compacter); ok && deleted > 0 {
+		log.Info("Compacting database", "deleted", deleted)
+		if err := compacter.Compact(nil, nil); err != nil {
+			return err
+		}
+	}
+	log.Info("Pruned state", "chains", len(appIds), "deleted", deleted, "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
+
+// mark marks the state of the genesis and of the recent blocks of the chain.
+// The recent blocks stop at the first one its state is missing, the head block
+// must have its state.
+func (cp *chainPruner) mark(chaindb ethdb.Database) error {
+	appId := cp.appId
+	head := rawdb.ReadHeadBlockHash(chaindb, appId)
+	number := rawdb.ReadHeaderNumber(chaindb, head, appId)
+	if number == nil {
+		return fmt.Errorf("head block of chain %q missing", appId)
+	}
+	var roots []common.Hash
+	for n := *number; uint64(len(roots)) < cp.config.Blocks; n-- {
+		header := rawdb.ReadHeader(chaindb, rawdb.ReadCanonicalHash(chaindb, n, appId), n, appId)
+		if header == nil {
+			return fmt.Errorf("block #%d of chain %q missing", n, appId)
+		}
+		if !cp.hasState(header.Root) {
+			if n == *number {
+				return fmt.Errorf("state of head block #%d of chain %q missing", n, appId)
+			}
+			break
+		}
+		roots = append(roots, header.Root)
+		if n == 0 {
+			break
+		}
+	}
+	log.Info("Marking state of chain", "appId", appId, "head", *number, "blocks", len(roots))
+
+	// Mark the genesis state in full, then the state of the recent blocks,
+	// oldest first, with the nodes not in the state of the block before
+	if genesis := rawdb.ReadHeader(chaindb, rawdb.ReadCanonicalHash(chaindb, 0, appId), 0, appId); genesis != nil && cp.hasState(genesis.Root) {
+		if err := cp.markState(genesis.Root, emptyRoot); err != nil {
+			return err
+		}
+	}
+	parent := emptyRoot
+	for i := len(roots) - 1; i >= 0; i-- {
+		if err := cp.markState(roots[i], parent); err != nil {
+			return err
+		}
+		parent = roots[i]
+	}
+	return nil
+}
+
+// hasState reports whether the root node of the state with the given root is
+// in the table of the chain.
+func (cp *chainPruner) hasState(root common.Hash) bool {
+	if root == emptyRoot {
+		return true
+	}
+	has, _ := cp.db.Has(root[:])
+	return has
+}
+
+// markState marks the nodes of the state trie with the given root not in the
+// one of the parent root, along with the nodes of the storage tries and the
+// codes of the accounts the two states differ in.
+func (cp *chainPruner) markState(root, parent common.Hash) error {
+	parentTrie, err := trie.New(parent, cp.triedb)
+	if err != nil {
+		return err
+	}
+	return cp.markTrie(root, parent, func(key, blob []byte) error {
+		var account state.Account
+		if err := rlp.DecodeBytes(blob, &account); err != nil {
+			return err
+		}
+		if !bytes.Equal(account.CodeHash, emptyCode) {
+			cp.bloom.add(account.CodeHash)
+		}
+		storage := emptyRoot
+		if enc, err := parentTrie.TryGet(key); err != nil {
+			return err
+		} else if len(enc) > 0 {
+			var prev state.Account
+			if err := rlp.DecodeBytes(enc, &prev); err != nil {
+				return err
+			}
+			storage = prev.Root
+		}
+		return cp.markTrie(account.Root, storage, nil)
+	})
+}
+
+// markTrie marks the nodes of the trie with the given root not in the one of
+// the parent root, calling onLeaf with the leaves of the trie not in the parent.
+func (cp *chainPruner) markTrie(root, parent common.Hash, onLeaf func(key, blob []byte) error) error {
+	if root == parent {
+		return nil
+	}
+	rootTrie, err := trie.New(root, cp.triedb)
+	if err != nil {
+		return err
+	}
+	parentTrie, err := trie.New(parent, cp.triedb)
+	if err != nil {
+		return err
+	}
+	it, _ := trie.NewDifferenceIterator(parentTrie.NodeIterator(nil), rootTrie.NodeIterator(nil))
+	for it.Next(true) {
+		// Nodes embedded in their parent have no hash, nor a key of their own
+		if hash := it.Hash(); hash != (common.Hash{}) {
+			cp.bloom.add(hash[:])
+		}
+		if it.Leaf() && onLeaf != nil {
+			if err := onLeaf(it.LeafKey(), it.LeafBlob()); err != nil {
+				return err
+			}
+		}
+	}
+	return it.Error()
+}
+
+// sweep deletes the trie nodes and contract codes of the table of the chain not
+// marked. It returns the number of entries deleted.
+func (cp *chainPruner) sweep(chaindb ethdb.Iteratee) (int, error) {
+	iter := chaindb.NewIteratorWithPrefix(cp.prefix)
+	defer iter.Release()
+
+	var (
+		batch   = cp.db.NewBatch()
+		deleted int
+		start   = time.Now()
+		logged  = time.Now()
+	)
+	for iter.Next() {
+		// Trie nodes and codes are keyed by their hash in the table of the
+		// chain. The other entries of the table a hash long are told apart by
+		// their content.
+		key := iter.Key()[len(cp.prefix):]
+		if len(key) != common.HashLength || cp.bloom.contains(key) {
+			continue
+		}
+		if !bytes.Equal(crypto.Keccak256(iter.Value()), key) {
+			continue
+		}
+		batch.Delete(common.CopyBytes(key))
+		deleted++
+
+		if batch.ValueSize() >= ethdb.IdealBatchSize {
+			if err := batch.Write(); err != nil {
+				return deleted, err
+			}
+			batch.Reset()
+		}
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Sweeping unreachable state", "appId", cp.appId, "deleted", deleted, "elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+	}
+	if err := iter.Error(); err != nil {
+		return deleted, err
+	}
+	return deleted, batch.Write()
+}
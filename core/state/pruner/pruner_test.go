@@ -0,0 +1,133 @@
+// Copyright 2018 The giov Authors
+// This file is part of the giov library.
+//
+// The giov library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The giov library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the giov library. If not, see <http://www.gnu.org/licenses/>.
+
+package pruner
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/CarLiveChainCo/goiov/common"
+	"github.com/CarLiveChainCo/goiov/core/rawdb"
+	"github.com/CarLiveChainCo/goiov/core/state"
+	"github.com/CarLiveChainCo/goiov/core/types"
+	"github.com/CarLiveChainCo/goiov/ethdb"
+	"github.com/CarLiveChainCo/goiov/params"
+)
+
+// makeChain writes a chain of the given number of blocks on top of a genesis
+// to the database, every block changing the balance, storage and code of a few
+// accounts. The state of an app chain goes to its table, as the blockchain puts
+// it. It returns the state roots of the blocks, the genesis first.
+func makeChain(t *testing.T, db ethdb.Database, appId string, blocks int) []common.Hash {
+	rawdb.WriteChainConfig(db, common.Hash{}, params.TestChainConfig, appId)
+
+	var (
+		roots   []common.Hash
+		root    = emptyRoot
+		parent  common.Hash
+		stateDb = state.NewDatabase(rawdb.AppChainDatabase(db, appId))
+	)
+	for n := 0; n <= blocks; n++ {
+		statedb, err := state.New(root, stateDb)
+		if err != nil {
+			t.Fatalf("chain %q block %d: failed to open state: %v", appId, n, err)
+		}
+		for i := 0; i < 3; i++ {
+			addr := common.BytesToAddress([]byte{byte(i + 1)})
+			statedb.AddBalance(addr, big.NewInt(int64(n+1)))
+			statedb.SetState(addr, common.BigToHash(big.NewInt(int64(n))), common.BytesToHash([]byte(appId+"value")))
+		}
+		statedb.SetCode(common.BytesToAddress([]byte{byte(n + 0x10)}), []byte(appId+"code"+string(rune('a'+n))))
+
+		if root, err = statedb.Commit(true); err != nil {
+			t.Fatalf("chain %q block %d: failed to commit state: %v", appId, n, err)
+		}
+		if err := statedb.Database().TrieDB().Commit(root, false); err != nil {
+			t.Fatalf("chain %q block %d: failed to write state: %v", appId, n, err)
+		}
+		header := &types.Header{
+			ParentHash: parent,
+			Number:     big.NewInt(int64(n)),
+			Root:       root,
+			Time:       big.NewInt(int64(n)),
+			Difficulty: big.NewInt(1),
+			Appid:      appId,
+		}
+		rawdb.WriteHeader(db, header, appId)
+		rawdb.WriteCanonicalHash(db, header.Hash(), uint64(n), appId)
+		rawdb.WriteHeadBlockHash(db, header.Hash(), appId)
+
+		roots = append(roots, root)
+		parent = header.Hash()
+	}
+	return roots
+}
+
+// checkState fails if any node or code of the state with the given root is
+// missing from the table of the chain appId.
+func checkState(t *testing.T, db ethdb.Database, appId string, root common.Hash) {
+	statedb, err := state.New(root, state.NewDatabase(rawdb.AppChainDatabase(db, appId)))
+	if err != nil {
+		t.Fatalf("state %x: failed to open: %v", root, err)
+	}
+	it := state.NewNodeIterator(statedb)
+	for it.Next() {
+	}
+	if it.Error != nil {
+		t.Fatalf("state %x: incomplete: %v", root, it.Error)
+	}
+}
+
+// Tests that pruning keeps the state of the genesis and the recent blocks of
+// the main chain and of the app chains, and deletes the state of the older
+// blocks.
+func TestPruneState(t *testing.T) {
+	db := ethdb.NewMemDatabase()
+
+	main := makeChain(t, db, "", 6)
+	app := makeChain(t, db, "app", 4)
+	rawdb.WriteAppChains(db, []string{"app"})
+
+	size := db.Len()
+	pruner, err := NewPruner(db, Config{Blocks: 2, BloomSize: 1})
+	if err != nil {
+		t.Fatalf("failed to create pruner: %v", err)
+	}
+	if err := pruner.Prune(); err != nil {
+		t.Fatalf("failed to prune: %v", err)
+	}
+	if db.Len() >= size {
+		t.Fatalf("nothing pruned: %d entries before, %d after", size, db.Len())
+	}
+	for appId, roots := range map[string][]common.Hash{"": main, "app": app} {
+		kept := []common.Hash{roots[0], roots[len(roots)-2], roots[len(roots)-1]}
+		for _, root := range kept {
+			checkState(t, db, appId, root)
+		}
+		for _, root := range roots[1 : len(roots)-2] {
+			if has, _ := rawdb.AppChainDatabase(db, appId).Has(root[:]); has {
+				t.Errorf("chain %q: state root %x of an old block not pruned", appId, root)
+			}
+		}
+	}
+	// Pruning again must keep the same state
+	if err := pruner.Prune(); err != nil {
+		t.Fatalf("failed to prune again: %v", err)
+	}
+	checkState(t, db, "", main[len(main)-1])
+	checkState(t, db, "app", app[len(app)-1])
+}
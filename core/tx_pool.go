@@ -86,6 +86,10 @@ var (
 	ErrWrongCreate = errors.New("the appId already exists")
 	ErrNoData      = errors.New("'data' cannot be empty while creating a contract")
 	ErrNoSideChain = errors.New("this appId doesn't exist")
+
+	// ErrWrongGenesisSpec is returned if a genesis spec is carried by a transaction
+	// not creating a side chain.
+	ErrWrongGenesisSpec = errors.New("only a transaction creating a side chain may carry a genesis spec")
 )
 
 var (
@@ -140,6 +144,7 @@ type Miner interface {
 type Backend interface {
 	SideBlockChain(appId string) (*BlockChain, bool)
 	TxPool() *TxPool
+	SideTxPool(appId string) *TxPool
 	StartMining(local bool, id string) error
 	IsMining() bool
 	NewSideChain(isSync bool, appId string) error
@@ -663,6 +668,14 @@ func (pool *TxPool) validateTxFormat(tx *types.Transaction) error {
 	if err != nil {
 		return err
 	}
+	if spec := tx.GenesisSpec(); spec != nil {
+		if pool.chainconfig.AppId != "" || tx.To() != nil || tx.AppId() == "" {
+			return ErrWrongGenesisSpec
+		}
+		if _, err := DecodeSideGenesisSpec(spec, from); err != nil {
+			return err
+		}
+	}
 	store := rawdb.ReadCanonicalHash(pool.chain.Getdb(), 0, tx.AppId())
 	config := rawdb.ReadChainConfig(pool.chain.Getdb(), store, tx.AppId())
 
@@ -0,0 +1,46 @@
+// Copyright 2018 The giov Authors
+// This file is part of the giov library.
+//
+// The giov library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The giov library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the giov library. If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"github.com/CarLiveChainCo/goiov/common"
+	"github.com/CarLiveChainCo/goiov/common/hexutil"
+	"github.com/CarLiveChainCo/goiov/crypto"
+)
+
+// AppMessageTopic is the first topic of the log a sent app message is recorded
+// in, followed by the hash of the appId of the destination chain and the hash of
+// the message. The data of the log is the RLP encoded message.
+var AppMessageTopic = crypto.Keccak256Hash([]byte("AppMessage(bytes32,bytes32)"))
+
+// AppMessage is a message sent by a contract of an app chain to a contract of
+// another app chain. The main chain has the empty appId.
+type AppMessage struct {
+	SourceAppId string         `json:"sourceAppId"`
+	Nonce       uint64         `json:"nonce"`
+	Sender      common.Address `json:"sender"`
+	AppId       string         `json:"appId"`
+	To          common.Address `json:"to"`
+	GasLimit    uint64         `json:"gasLimit"`
+	Data        hexutil.Bytes  `json:"data"`
+}
+
+// Hash returns the keccak256 hash of the RLP encoding of the message, unique
+// per source chain thanks to the nonce.
+func (m *AppMessage) Hash() common.Hash {
+	return rlpHash(m)
+}
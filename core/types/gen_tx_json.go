@@ -26,6 +26,7 @@ func (t txdata) MarshalJSON() ([]byte, error) {
 		R            *hexutil.Big    `json:"r" gencodec:"required"`
 		S            *hexutil.Big    `json:"s" gencodec:"required"`
 		Hash         *common.Hash    `json:"hash" rlp:"-"`
+		Genesis      []hexutil.Bytes `json:"genesis,omitempty" rlp:"tail"`
 	}
 	var enc txdata
 	enc.AccountNonce = hexutil.Uint64(t.AccountNonce)
@@ -39,6 +40,12 @@ func (t txdata) MarshalJSON() ([]byte, error) {
 	enc.R = (*hexutil.Big)(t.R)
 	enc.S = (*hexutil.Big)(t.S)
 	enc.Hash = t.Hash
+	if t.Genesis != nil {
+		enc.Genesis = make([]hexutil.Bytes, len(t.Genesis))
+		for k, v := range t.Genesis {
+			enc.Genesis[k] = v
+		}
+	}
 	return json.Marshal(&enc)
 }
 
@@ -55,6 +62,7 @@ func (t *txdata) UnmarshalJSON(input []byte) error {
 		R            *hexutil.Big    `json:"r" gencodec:"required"`
 		S            *hexutil.Big    `json:"s" gencodec:"required"`
 		Hash         *common.Hash    `json:"hash" rlp:"-"`
+		Genesis      []hexutil.Bytes `json:"genesis,omitempty" rlp:"tail"`
 	}
 	var dec txdata
 	if err := json.Unmarshal(input, &dec); err != nil {
@@ -102,5 +110,11 @@ func (t *txdata) UnmarshalJSON(input []byte) error {
 	if dec.Hash != nil {
 		t.Hash = dec.Hash
 	}
+	if dec.Genesis != nil {
+		t.Genesis = make([][]byte, len(dec.Genesis))
+		for k, v := range dec.Genesis {
+			t.Genesis[k] = v
+		}
+	}
 	return nil
 }
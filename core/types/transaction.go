@@ -33,6 +33,8 @@ import (
 
 var (
 	ErrInvalidSig = errors.New("invalid transaction v, r, s values")
+
+	errTooManyGenesis = errors.New("transaction carries more than one genesis spec")
 )
 
 // deriveSigner makes a *best* guess about which signer to use.
@@ -50,8 +52,6 @@ type Transaction struct {
 	hash atomic.Value
 	size atomic.Value
 	from atomic.Value
-
-	alloc map[common.Address]*big.Int
 }
 
 type txdata struct {
@@ -70,6 +70,10 @@ type txdata struct {
 
 	// This is only used when marshaling to JSON.
 	Hash *common.Hash `json:"hash" rlp:"-"`
+
+	// RLP encoded genesis spec of the side chain created by the transaction.
+	// It is only encoded if present, keeping the encoding of other transactions.
+	Genesis [][]byte `json:"genesis,omitempty" rlp:"tail"`
 }
 
 type txdataMarshaling struct {
@@ -82,6 +86,7 @@ type txdataMarshaling struct {
 	V            *hexutil.Big
 	R            *hexutil.Big
 	S            *hexutil.Big
+	Genesis      []hexutil.Bytes
 }
 
 func NewTransaction(nonce uint64, to common.Address, amount *big.Int, gasLimit uint64, gasPrice *big.Int, data []byte, appId ...string) *Transaction {
@@ -150,6 +155,9 @@ func (tx *Transaction) EncodeRLP(w io.Writer) error {
 func (tx *Transaction) DecodeRLP(s *rlp.Stream) error {
 	_, size, _ := s.Kind()
 	err := s.Decode(&tx.data)
+	if err == nil && len(tx.data.Genesis) > 1 {
+		err = errTooManyGenesis
+	}
 	if err == nil {
 		tx.size.Store(common.StorageSize(rlp.ListSize(size)))
 	}
@@ -185,21 +193,35 @@ func (tx *Transaction) UnmarshalJSON(input []byte) error {
 	return nil
 }
 
-func (tx *Transaction) Data() []byte                 { return common.CopyBytes(tx.data.Payload) }
-func (tx *Transaction) Gas() uint64                  { return tx.data.GasLimit }
-func (tx *Transaction) GasPrice() *big.Int           { return new(big.Int).Set(tx.data.Price) }
-func (tx *Transaction) Value() *big.Int              { return new(big.Int).Set(tx.data.Amount) }
-func (tx *Transaction) Nonce() uint64                { return tx.data.AccountNonce }
-func (tx *Transaction) CheckNonce() bool             { return true }
-func (tx *Transaction) AppId() string                { return tx.data.AppId }
-func (tx *Transaction) Alloc() map[common.Address]*big.Int { return tx.alloc }
+func (tx *Transaction) Data() []byte       { return common.CopyBytes(tx.data.Payload) }
+func (tx *Transaction) Gas() uint64        { return tx.data.GasLimit }
+func (tx *Transaction) GasPrice() *big.Int { return new(big.Int).Set(tx.data.Price) }
+func (tx *Transaction) Value() *big.Int    { return new(big.Int).Set(tx.data.Amount) }
+func (tx *Transaction) Nonce() uint64      { return tx.data.AccountNonce }
+func (tx *Transaction) CheckNonce() bool   { return true }
+func (tx *Transaction) AppId() string      { return tx.data.AppId }
+
+// GenesisSpec returns the RLP encoded genesis spec of the side chain created by
+// the transaction, or nil if the transaction carries none.
+func (tx *Transaction) GenesisSpec() []byte {
+	if len(tx.data.Genesis) == 0 {
+		return nil
+	}
+	return common.CopyBytes(tx.data.Genesis[0])
+}
 
 func (tx *Transaction) SetAppId(appId string) {
 	tx.data.AppId = appId
 }
 
-func (tx *Transaction) SetAlloc(c map[common.Address]*big.Int) {
-	tx.alloc = c
+// SetGenesisSpec sets the RLP encoded genesis spec of the side chain created by
+// the transaction. It must be set before signing.
+func (tx *Transaction) SetGenesisSpec(spec []byte) {
+	if len(spec) == 0 {
+		tx.data.Genesis = nil
+		return
+	}
+	tx.data.Genesis = [][]byte{common.CopyBytes(spec)}
 }
 
 // To returns the recipient address of the transaction.
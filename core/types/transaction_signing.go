@@ -155,7 +155,7 @@ func (s EIP155Signer) SignatureValues(tx *Transaction, sig []byte) (R, S, V *big
 // Hash returns the hash to be signed by the sender.
 // It does not uniquely identify the transaction.
 func (s EIP155Signer) Hash(tx *Transaction) common.Hash {
-	return rlpHash([]interface{}{
+	fields := []interface{}{
 		tx.data.AccountNonce,
 		tx.data.Price,
 		tx.data.GasLimit,
@@ -163,7 +163,12 @@ func (s EIP155Signer) Hash(tx *Transaction) common.Hash {
 		tx.data.Amount,
 		tx.data.Payload,
 		s.chainId, uint(0), uint(0),
-	})
+	}
+	// The genesis spec is only signed if present, keeping the signatures of other transactions
+	if len(tx.data.Genesis) > 0 {
+		fields = append(fields, tx.data.Genesis)
+	}
+	return rlpHash(fields)
 }
 
 // HomesteadTransaction implements TransactionInterface using the
@@ -207,14 +212,18 @@ func (fs FrontierSigner) SignatureValues(tx *Transaction, sig []byte) (r, s, v *
 // Hash returns the hash to be signed by the sender.
 // It does not uniquely identify the transaction.
 func (fs FrontierSigner) Hash(tx *Transaction) common.Hash {
-	return rlpHash([]interface{}{
+	fields := []interface{}{
 		tx.data.AccountNonce,
 		tx.data.Price,
 		tx.data.GasLimit,
 		tx.data.Recipient,
 		tx.data.Amount,
 		tx.data.Payload,
-	})
+	}
+	if len(tx.data.Genesis) > 0 {
+		fields = append(fields, tx.data.Genesis)
+	}
+	return rlpHash(fields)
 }
 
 func (fs FrontierSigner) Sender(tx *Transaction) (common.Address, error) {
@@ -206,7 +206,7 @@ func TestTransactionJSON(t *testing.T) {
 		case 0:
 			tx = NewTransaction(i, common.Address{1}, common.Big0, 1, common.Big2, []byte("abcdef"))
 		case 1:
-			tx = NewContractCreation(i, common.Big0, 1, common.Big2, []byte("abcdef"))
+			tx = NewContractCreation(i, common.Big0, 1, common.Big2, []byte("abcdef"), "")
 		}
 
 		tx, err := SignTx(tx, signer, key)
@@ -233,3 +233,61 @@ func TestTransactionJSON(t *testing.T) {
 		}
 	}
 }
+
+// Tests that the genesis spec of a side chain creating transaction is signed and
+// survives the RLP and JSON encodings, without changing the encoding of other
+// transactions.
+func TestTransactionGenesisSpec(t *testing.T) {
+	key, addr := defaultTestKey()
+	signer := NewEIP155Signer(common.Big1)
+
+	plain, err := SignTx(NewContractCreation(0, common.Big0, 1, common.Big2, []byte("abcdef"), "7"), signer, key)
+	if err != nil {
+		t.Fatalf("could not sign transaction: %v", err)
+	}
+	tx := NewContractCreation(0, common.Big0, 1, common.Big2, []byte("abcdef"), "7")
+	tx.SetGenesisSpec([]byte{0xc1, 0x02})
+	if tx, err = SignTx(tx, signer, key); err != nil {
+		t.Fatalf("could not sign transaction: %v", err)
+	}
+	if signer.Hash(tx) == signer.Hash(plain) {
+		t.Errorf("genesis spec not signed")
+	}
+	plainEnc, _ := rlp.EncodeToBytes(plain)
+	txEnc, _ := rlp.EncodeToBytes(tx)
+	if len(txEnc) != len(plainEnc)+3 {
+		t.Errorf("genesis spec not encoded as the tail: %x", txEnc)
+	}
+
+	// Both encodings keep the spec, and the sender recovers from it
+	decoded, err := decodeTx(txEnc)
+	if err != nil {
+		t.Fatalf("could not decode transaction: %v", err)
+	}
+	var parsed *Transaction
+	data, _ := json.Marshal(tx)
+	if err := json.Unmarshal(data, &parsed); err != nil {
+		t.Fatalf("json.Unmarshal failed: %v", err)
+	}
+	for _, have := range []*Transaction{decoded, parsed} {
+		if !bytes.Equal(have.GenesisSpec(), []byte{0xc1, 0x02}) || have.Hash() != tx.Hash() {
+			t.Errorf("genesis spec %x with hash %x, want c102 with %x", have.GenesisSpec(), have.Hash(), tx.Hash())
+		}
+		if from, err := Sender(signer, have); err != nil || from != addr {
+			t.Errorf("sender %x (%v), want %x", from, err, addr)
+		}
+	}
+	if decoded, err := decodeTx(plainEnc); err != nil || decoded.GenesisSpec() != nil {
+		t.Errorf("plain transaction decoded with genesis spec %x (%v)", decoded.GenesisSpec(), err)
+	}
+
+	// Only a single genesis spec is accepted
+	v, r, s := tx.RawSignatureValues()
+	twice, _ := rlp.EncodeToBytes([]interface{}{
+		tx.Nonce(), tx.GasPrice(), tx.Gas(), tx.To(), tx.Value(), tx.Data(), tx.AppId(), v, r, s,
+		[]byte{0xc1, 0x02}, []byte{0xc1, 0x03},
+	})
+	if _, err := decodeTx(twice); err != errTooManyGenesis {
+		t.Errorf("error mismatch: have %v, want %v", err, errTooManyGenesis)
+	}
+}
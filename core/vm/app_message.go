@@ -0,0 +1,103 @@
+// Copyright 2018 The giov Authors
+// This file is part of the giov library.
+//
+// The giov library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The giov library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the giov library. If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"errors"
+	"math/big"
+
+	"github.com/CarLiveChainCo/goiov/common"
+	"github.com/CarLiveChainCo/goiov/core/types"
+	"github.com/CarLiveChainCo/goiov/crypto"
+	"github.com/CarLiveChainCo/goiov/params"
+	"github.com/CarLiveChainCo/goiov/rlp"
+)
+
+var (
+	// errBadAppMessageInput is returned if the input of a message is too short,
+	// or the message is sent to its own chain or with too much gas.
+	errBadAppMessageInput = errors.New("bad app message input")
+
+	// errAppMessageCall is returned if the message contract is not called by
+	// a plain call without value.
+	errAppMessageCall = errors.New("app message not sent by a plain call")
+
+	// appMessageNonceKey is the storage slot of the message contract holding
+	// the number of messages sent from the chain.
+	appMessageNonceKey = common.Hash{}
+)
+
+// isAppMessage returns whether the call goes to the message system contract.
+func (evm *EVM) isAppMessage(addr common.Address) bool {
+	return addr == params.MessageAddress && evm.ChainConfig().IsCrossApp(evm.BlockNumber)
+}
+
+// sendAppMessage implements the message system contract, recording a message
+// to a contract of another app chain in a log the relayers deliver it from.
+// The return value is the hash of the message.
+//
+// The input is the recipient contract (32 bytes, left padded), the gas limit of
+// the delivery (32 bytes), the length of the appId of the destination chain
+// (32 bytes), the appId and the data of the message.
+func (evm *EVM) sendAppMessage(contract *Contract, input []byte) ([]byte, error) {
+	if evm.interpreter.readOnly || contract.Address() != params.MessageAddress || contract.Value().Sign() != 0 {
+		return nil, errAppMessageCall
+	}
+	if !contract.UseGas(params.AppMessageGas + uint64(len(input))*params.AppMessageDataGas) {
+		return nil, ErrOutOfGas
+	}
+	if len(input) < 96 {
+		return nil, errBadAppMessageInput
+	}
+	gasLimit := new(big.Int).SetBytes(input[32:64])
+	appIdLen := new(big.Int).SetBytes(input[64:96])
+	if gasLimit.Cmp(new(big.Int).SetUint64(params.AppMessageMaxGas)) > 0 || appIdLen.Cmp(big.NewInt(int64(len(input)-96))) > 0 {
+		return nil, errBadAppMessageInput
+	}
+	msg := &types.AppMessage{
+		SourceAppId: evm.ChainConfig().AppId,
+		Sender:      contract.Caller(),
+		AppId:       string(input[96 : 96+appIdLen.Uint64()]),
+		To:          common.BytesToAddress(input[:32]),
+		GasLimit:    gasLimit.Uint64(),
+		Data:        common.CopyBytes(input[96+appIdLen.Uint64():]),
+	}
+	if msg.AppId == msg.SourceAppId {
+		return nil, errBadAppMessageInput
+	}
+	// Keep the contract alive with its storage, an account without nonce,
+	// balance and code is deleted at the end of the transaction
+	if evm.StateDB.GetNonce(params.MessageAddress) == 0 {
+		evm.StateDB.SetNonce(params.MessageAddress, 1)
+	}
+	nonce := evm.StateDB.GetState(params.MessageAddress, appMessageNonceKey).Big()
+	msg.Nonce = nonce.Uint64()
+	evm.StateDB.SetState(params.MessageAddress, appMessageNonceKey, common.BigToHash(nonce.Add(nonce, common.Big1)))
+
+	data, err := rlp.EncodeToBytes(msg)
+	if err != nil {
+		return nil, err
+	}
+	hash := msg.Hash()
+	evm.StateDB.AddLog(&types.Log{
+		Address:     params.MessageAddress,
+		Topics:      []common.Hash{types.AppMessageTopic, crypto.Keccak256Hash([]byte(msg.AppId)), hash},
+		Data:        data,
+		BlockNumber: evm.BlockNumber.Uint64(),
+	})
+	return hash.Bytes(), nil
+}
@@ -0,0 +1,87 @@
+// Copyright 2018 The giov Authors
+// This file is part of the giov library.
+//
+// The giov library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The giov library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the giov library. If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/CarLiveChainCo/goiov/common"
+	"github.com/CarLiveChainCo/goiov/core/state"
+	"github.com/CarLiveChainCo/goiov/core/types"
+	"github.com/CarLiveChainCo/goiov/ethdb"
+	"github.com/CarLiveChainCo/goiov/params"
+	"github.com/CarLiveChainCo/goiov/rlp"
+)
+
+func appMessageInput(to common.Address, gasLimit uint64, appId string, data []byte) []byte {
+	input := common.LeftPadBytes(to.Bytes(), 32)
+	input = append(input, common.LeftPadBytes(new(big.Int).SetUint64(gasLimit).Bytes(), 32)...)
+	input = append(input, common.LeftPadBytes(big.NewInt(int64(len(appId))).Bytes(), 32)...)
+	input = append(input, appId...)
+	return append(input, data...)
+}
+
+// Tests that a call to the message contract records the message in a log and
+// numbers the messages of the chain.
+func TestSendAppMessage(t *testing.T) {
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(ethdb.NewMemDatabase()))
+	config := *params.TestChainConfig
+	config.AppId = "app1"
+	config.Alien = &params.AlienConfig{CrossAppBlock: big.NewInt(0)}
+
+	ctx := Context{
+		CanTransfer: func(StateDB, common.Address, *big.Int) bool { return true },
+		Transfer:    func(StateDB, common.Address, common.Address, *big.Int) {},
+		BlockNumber: big.NewInt(1),
+	}
+	evm := NewEVM(ctx, statedb, &config, Config{})
+	sender, to := common.HexToAddress("0x01"), common.HexToAddress("0x02")
+
+	for nonce := uint64(0); nonce < 2; nonce++ {
+		ret, _, err := evm.Call(AccountRef(sender), params.MessageAddress, appMessageInput(to, 50000, "app2", []byte{1, 2}), 100000, new(big.Int))
+		if err != nil {
+			t.Fatalf("message %d: failed to send: %v", nonce, err)
+		}
+		logs := statedb.Logs()
+		if len(logs) != int(nonce+1) {
+			t.Fatalf("message %d: log count mismatch: have %d, want %d", nonce, len(logs), nonce+1)
+		}
+		msg := new(types.AppMessage)
+		if err := rlp.DecodeBytes(logs[nonce].Data, msg); err != nil {
+			t.Fatalf("message %d: failed to decode: %v", nonce, err)
+		}
+		want := &types.AppMessage{SourceAppId: "app1", Nonce: nonce, Sender: sender, AppId: "app2", To: to, GasLimit: 50000, Data: []byte{1, 2}}
+		if msg.Hash() != want.Hash() || !bytes.Equal(ret, want.Hash().Bytes()) || logs[nonce].Topics[2] != want.Hash() {
+			t.Errorf("message %d: mismatch: have %+v, want %+v", nonce, msg, want)
+		}
+	}
+	// Messages to the own chain, with too much gas or value are rejected
+	if _, _, err := evm.Call(AccountRef(sender), params.MessageAddress, appMessageInput(to, 50000, "app1", nil), 100000, new(big.Int)); err != errBadAppMessageInput {
+		t.Errorf("own chain: error mismatch: have %v, want %v", err, errBadAppMessageInput)
+	}
+	if _, _, err := evm.Call(AccountRef(sender), params.MessageAddress, appMessageInput(to, params.AppMessageMaxGas+1, "app2", nil), 100000, new(big.Int)); err != errBadAppMessageInput {
+		t.Errorf("too much gas: error mismatch: have %v, want %v", err, errBadAppMessageInput)
+	}
+	if _, _, err := evm.Call(AccountRef(sender), params.MessageAddress, appMessageInput(to, 50000, "app2", nil), 100000, big.NewInt(1)); err != errAppMessageCall {
+		t.Errorf("value: error mismatch: have %v, want %v", err, errAppMessageCall)
+	}
+	if len(statedb.Logs()) != 2 {
+		t.Errorf("rejected messages logged: have %d logs, want 2", len(statedb.Logs()))
+	}
+}
@@ -25,7 +25,10 @@ import (
 	"github.com/CarLiveChainCo/goiov/common/math"
 	"github.com/CarLiveChainCo/goiov/crypto"
 	"github.com/CarLiveChainCo/goiov/crypto/bn256"
+	"github.com/CarLiveChainCo/goiov/ethdb"
 	"github.com/CarLiveChainCo/goiov/params"
+	"github.com/CarLiveChainCo/goiov/rlp"
+	"github.com/CarLiveChainCo/goiov/trie"
 	"golang.org/x/crypto/ripemd160"
 )
 
@@ -59,6 +62,16 @@ var PrecompiledContractsByzantium = map[common.Address]PrecompiledContract{
 	common.BytesToAddress([]byte{8}): &bn256Pairing{},
 }
 
+// MerkleProofAddress is the address of the pre-compiled contract verifying the
+// Merkle proofs of the alien bridge.
+var MerkleProofAddress = common.BytesToAddress([]byte{9})
+
+// PrecompiledContractsBridge contains the pre-compiled contracts added to the ones
+// of the active release once the alien bridge is active, the Merkle proof verifier.
+var PrecompiledContractsBridge = map[common.Address]PrecompiledContract{
+	MerkleProofAddress: &merkleProof{},
+}
+
 // RunPrecompiledContract runs and evaluates the output of a precompiled contract.
 func RunPrecompiledContract(p PrecompiledContract, input []byte, contract *Contract) (ret []byte, err error) {
 	gas := p.RequiredGas(input)
@@ -358,3 +371,61 @@ func (c *bn256Pairing) Run(input []byte) ([]byte, error) {
 	}
 	return false32Byte, nil
 }
+
+var (
+	// errBadMerkleProofInput is returned if the Merkle proof input is too short
+	// or its proof nodes are not RLP encoded.
+	errBadMerkleProofInput = errors.New("bad merkle proof input")
+)
+
+// merkleProof implements a pre-compile verifying a Merkle proof of a key in a
+// Merkle Patricia trie, like the transaction and the receipt trie of a block.
+//
+// The input is the trie root (32 bytes), the length of the key (32 bytes), the
+// key and the RLP encoded trie nodes of the proof, one after the other. The
+// output is the value of the key, empty if the proof shows the key is absent.
+type merkleProof struct{}
+
+// RequiredGas returns the gas required to execute the pre-compiled contract.
+func (c *merkleProof) RequiredGas(input []byte) uint64 {
+	return uint64(len(input)+31)/32*params.MerkleProofPerWordGas + params.MerkleProofBaseGas
+}
+
+func (c *merkleProof) Run(input []byte) ([]byte, error) {
+	if len(input) < 64 {
+		return nil, errBadMerkleProofInput
+	}
+	root := common.BytesToHash(input[:32])
+	keyLen := new(big.Int).SetBytes(input[32:64])
+	if !keyLen.IsUint64() || keyLen.Uint64() > uint64(len(input)-64) {
+		return nil, errBadMerkleProofInput
+	}
+	key := input[64 : 64+keyLen.Uint64()]
+
+	proofDb := ethdb.NewMemDatabase()
+	for rest := input[64+keyLen.Uint64():]; len(rest) > 0; {
+		_, _, tail, err := rlp.Split(rest)
+		if err != nil {
+			return nil, errBadMerkleProofInput
+		}
+		node := rest[:len(rest)-len(tail)]
+		proofDb.Put(crypto.Keccak256(node), node)
+		rest = tail
+	}
+	value, _, err := trie.VerifyProof(root, key, proofDb)
+	if err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// EncodeMerkleProof returns the input of the Merkle proof pre-compiled contract
+// proving the value of key in the trie with the given root.
+func EncodeMerkleProof(root common.Hash, key []byte, proof [][]byte) []byte {
+	input := append(root.Bytes(), common.LeftPadBytes(new(big.Int).SetUint64(uint64(len(key))).Bytes(), 32)...)
+	input = append(input, key...)
+	for _, node := range proof {
+		input = append(input, node...)
+	}
+	return input
+}
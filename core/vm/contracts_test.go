@@ -22,6 +22,10 @@ import (
 	"testing"
 
 	"github.com/CarLiveChainCo/goiov/common"
+	"github.com/CarLiveChainCo/goiov/ethdb"
+	"github.com/CarLiveChainCo/goiov/params"
+	"github.com/CarLiveChainCo/goiov/rlp"
+	"github.com/CarLiveChainCo/goiov/trie"
 )
 
 // precompiledTest defines the input/output pairs for precompiled contract tests.
@@ -481,3 +485,72 @@ func BenchmarkPrecompiledBn256Pairing(bench *testing.B) {
 		benchmarkPrecompiled("08", test, bench)
 	}
 }
+
+// Tests that the Merkle proof precompile returns the proven values of a trie
+// keyed like the transaction and receipt tries, and rejects forged proofs.
+func TestPrecompiledMerkleProof(t *testing.T) {
+	tr := new(trie.Trie)
+	for i := 0; i < 20; i++ {
+		key, _ := rlp.EncodeToBytes(uint(i))
+		tr.Update(key, []byte(fmt.Sprintf("value-%d", i)))
+	}
+	root := tr.Hash()
+	p := PrecompiledContractsBridge[MerkleProofAddress]
+
+	for i := 0; i < 21; i++ {
+		key, _ := rlp.EncodeToBytes(uint(i))
+		proofDb := ethdb.NewMemDatabase()
+		if err := tr.Prove(key, 0, proofDb); err != nil {
+			t.Fatalf("key %d: failed to prove: %v", i, err)
+		}
+		var proof [][]byte
+		for _, hash := range proofDb.Keys() {
+			node, _ := proofDb.Get(hash)
+			proof = append(proof, node)
+		}
+		in := EncodeMerkleProof(root, key, proof)
+		contract := NewContract(AccountRef(common.HexToAddress("1337")), nil, new(big.Int), p.RequiredGas(in))
+		res, err := RunPrecompiledContract(p, in, contract)
+		if err != nil {
+			t.Fatalf("key %d: proof rejected: %v", i, err)
+		}
+		want := ""
+		if i < 20 {
+			want = fmt.Sprintf("value-%d", i)
+		}
+		if string(res) != want {
+			t.Errorf("key %d: value mismatch: have %q, want %q", i, res, want)
+		}
+		if _, err := p.Run(EncodeMerkleProof(common.Hash{1}, key, proof)); err == nil {
+			t.Errorf("key %d: proof accepted for wrong root", i)
+		}
+	}
+	if _, err := p.Run(make([]byte, 63)); err != errBadMerkleProofInput {
+		t.Errorf("short input: have %v, want %v", err, errBadMerkleProofInput)
+	}
+}
+
+// Tests that the Merkle proof verifier is added to the pre-compiled contracts of
+// the active release once the bridge is active, without enabling the Byzantium
+// ones early.
+func TestBridgePrecompiles(t *testing.T) {
+	config := &params.ChainConfig{
+		ByzantiumBlock: big.NewInt(10),
+		Alien:          &params.AlienConfig{BridgeBlock: big.NewInt(5)},
+	}
+	tests := []struct {
+		number int64
+		addr   byte
+		exist  bool
+	}{
+		{4, 1, true}, {4, 5, false}, {4, 9, false},
+		{5, 1, true}, {5, 5, false}, {5, 9, true},
+		{10, 5, true}, {10, 9, true}, {10, 10, false},
+	}
+	for i, tt := range tests {
+		evm := NewEVM(Context{BlockNumber: big.NewInt(tt.number)}, nil, config, Config{})
+		if p := evm.precompile(common.BytesToAddress([]byte{tt.addr})); (p != nil) != tt.exist {
+			t.Errorf("test %d: block %d precompile %d existence mismatch: have %v, want %v", i, tt.number, tt.addr, p != nil, tt.exist)
+		}
+	}
+}
@@ -356,6 +356,8 @@ func (api *PublicDebugAPI) DumpBlock(blockNr rpc.BlockNumber) (state.Dump, error
 	var block *types.Block
 	if blockNr == rpc.LatestBlockNumber {
 		block = api.eth.blockchain.CurrentBlock()
+	} else if blockNr == rpc.FinalizedBlockNumber {
+		block = api.eth.blockchain.GetBlockByNumber(api.eth.blockchain.FinalizedNumber())
 	} else {
 		block = api.eth.blockchain.GetBlockByNumber(uint64(blockNr))
 	}
@@ -411,7 +413,7 @@ type storageEntry struct {
 
 // StorageRangeAt returns the storage at the given block height and transaction index.
 func (api *PrivateDebugAPI) StorageRangeAt(ctx context.Context, blockHash common.Hash, txIndex int, contractAddress common.Address, keyStart hexutil.Bytes, maxResult int) (StorageRangeResult, error) {
-	_, _, statedb, err := api.computeTxEnv(blockHash, txIndex, 0)
+	_, _, statedb, err := api.computeTxEnv(api.eth.blockchain, blockHash, txIndex, 0)
 	if err != nil {
 		return StorageRangeResult{}, err
 	}
@@ -582,10 +584,10 @@ func initDownloader(appId string, eth *Ethereum) {
 		eth.protocolManager.fastSync[appId] = uint32(1)
 	}
 	eth.protocolManager.noMorePeers[appId] = make(chan struct{})
-	peers := eth.protocolManager.downloader.GetPeers().AllPeers()
-	for _, peer := range peers {
-		eth.protocolManager.SideDownloader[appId].RegisterPeer(peer.GetId(), peer.GetVersion(), peer.GetPeer())
+	for _, peer := range eth.protocolManager.peers.PeersWithAppChain(appId) {
+		eth.protocolManager.SideDownloader[appId].RegisterPeer(peer.id, peer.version, peer)
 	}
+	eth.protocolManager.BroadcastAppChains()
 }
 
 func MakeSideEngine(s *Ethereum, chainConfig *params.ChainConfig, db ethdb.Database) consensus.Engine {
@@ -601,7 +603,11 @@ func MakeSideEngine(s *Ethereum, chainConfig *params.ChainConfig, db ethdb.Datab
 
 	if chainConfig.Alien != nil {
 		chainConfig.Alien.AppId = chainConfig.AppId
-		return alien.New(chainConfig.Alien, db, flag, s)
+		engine := alien.New(chainConfig.Alien, db, flag, s)
+		if s.config.AlienRewardIndex {
+			engine.EnableRewardIndex(s.config.AlienRewardRetain)
+		}
+		return engine
 	}
 	return nil
 }
@@ -619,14 +625,19 @@ func (api *PrivateMinerAPI) SideMinerStart(appId string) error {
 	return nil
 }
 
+// DeleteSideChain stops the side chain appId and removes all its data from the
+// database, reclaiming its disk space.
 func (api *PublicEthereumAPI) DeleteSideChain(appId string) error {
-	if _, ok := api.e.SideBlockChain(appId); !ok {
+	if _, ok := api.e.SideBlockChain(appId); !ok || appId == "" {
 		log.Error("the side chain is not created. Please create the side chain by command 'eth.NewSideChain(appId)'")
 		return nil
 	}
 	if miner, ok := api.e.sideMiner[appId]; miner != nil && ok {
 		miner.Stop()
 	}
+	if indexer, ok := api.e.sideBloomIndexers[appId]; indexer != nil && ok {
+		indexer.Close()
+	}
 	if chain, ok := api.e.sideChains[appId]; chain != nil && ok {
 		chain.Stop()
 	}
@@ -637,15 +648,33 @@ func (api *PublicEthereumAPI) DeleteSideChain(appId string) error {
 	delete(api.e.sideMiner, appId)
 	delete(api.e.sideChains, appId)
 	delete(api.e.sideTxPool, appId)
+	delete(api.e.sideBloomIndexers, appId)
+	delete(api.e.sideBloomRequests, appId)
 	delete(api.e.protocolManager.SideDownloader, appId)
 	delete(api.e.protocolManager.noMorePeers, appId)
-	return nil
+	api.e.protocolManager.BroadcastAppChains()
+	return DeleteAppChain(api.e.chainDb, appId)
 }
 
-func (api *PublicEthereumAPI) GetAllApp() map[string]*params.ChainConfig {
+// AppInfo is the configuration of a side chain together with the genesis spec
+// carried by the transaction creating it.
+type AppInfo struct {
+	*params.ChainConfig
+	Genesis *core.SideGenesisSpec `json:"genesis,omitempty"`
+}
+
+func (api *PublicEthereumAPI) GetAllApp() map[string]*AppInfo {
 	rMap := rawdb.ReadAllChainConfig(api.e.chainDb)
 	delete(rMap, "")
-	return rMap
+	apps := make(map[string]*AppInfo, len(rMap))
+	for appId, config := range rMap {
+		app := &AppInfo{ChainConfig: config}
+		if tx, _, _, _ := rawdb.ReadTransaction(api.e.chainDb, config.TxHash); tx != nil && tx.GenesisSpec() != nil {
+			app.Genesis, _ = core.DecodeSideGenesisSpec(tx.GenesisSpec(), config.Author)
+		}
+		apps[appId] = app
+	}
+	return apps
 }
 
 func (api *PublicEthereumAPI) GetBlockRewards(blockNumber uint64, appId string) (map[common.Address]string, error) {
@@ -659,27 +688,29 @@ func (api *PublicEthereumAPI) GetBlockRewards(blockNumber uint64, appId string)
 	if header == nil || parents == nil {
 		return nil, ErrWrongNumber
 	}
-	config := chain.Config()
 	engine, _ := chain.Engine().(*alien.Alien)
-	snap, _ := engine.Snapshot(chain, blockNumber-1, parents.Hash(), nil, nil, alien.DefaultLoopCntRecalculateSigners)
-
+	snap, err := engine.Snapshot(chain, blockNumber-1, parents.Hash(), nil, nil, alien.DefaultLoopCntRecalculateSigners)
+	if err != nil {
+		return nil, err
+	}
 	//计算奖励
-	blockNumPerYear := alien.SecondsPerYear / config.Alien.Period
-	yearCount := blockNumber / blockNumPerYear
-	blockReward := new(big.Int).Rsh(alien.SignerBlockReward, uint(yearCount))
-	//计算矿工奖励
-	minerReward := new(big.Int).Set(blockReward)
-	minerReward.Mul(minerReward, big.NewInt(int64(alien.MinerRewardPerThousand)))
-	minerReward.Div(minerReward, big.NewInt(1000)) // cause the reward is calculate by cnt per thousand
-	//计算投票人的奖励
-	votersReward := blockReward.Sub(blockReward, minerReward)
-
-	for voter, reward := range snap.CalculateReward(header.Coinbase, votersReward) {
-		if voter != header.Coinbase {
-			allRewards[voter] = reward.String()
-		} else {
-			allRewards[header.Coinbase] = reward.Add(reward, minerReward).String()
+	reward := snap.BlockReward(header)
+	rewards := make(map[common.Address]*big.Int)
+	credit := func(address common.Address, amount *big.Int) {
+		if _, ok := rewards[address]; !ok {
+			rewards[address] = new(big.Int)
 		}
+		rewards[address].Add(rewards[address], amount)
+	}
+	for voter, voterReward := range reward.VoterRewards {
+		credit(voter, voterReward)
+	}
+	credit(header.Coinbase, reward.MinerReward)
+	if reward.TreasuryReward.Sign() > 0 {
+		credit(reward.Treasury, reward.TreasuryReward)
+	}
+	for address, amount := range rewards {
+		allRewards[address] = amount.String()
 	}
 
 	return allRewards, nil
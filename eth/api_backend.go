@@ -21,6 +21,7 @@ import (
 	"github.com/CarLiveChainCo/goiov/internal/ethapi"
 	"github.com/CarLiveChainCo/goiov/node"
 	"math/big"
+	"time"
 
 	"github.com/CarLiveChainCo/goiov/accounts"
 	"github.com/CarLiveChainCo/goiov/common"
@@ -59,6 +60,10 @@ func (b *EthAPIBackend) NodeConfig() *node.Config {
 	return b.eth.nodeConfig
 }
 
+func (b *EthAPIBackend) RPCEVMTimeout() time.Duration {
+	return b.eth.config.RPCEVMTimeout
+}
+
 func (b *EthAPIBackend) BlockChain() ethapi.BlockChain {
 	return b.eth.blockchain
 }
@@ -82,6 +87,9 @@ func (b *EthAPIBackend) HeaderByNumber(ctx context.Context, blockNr rpc.BlockNum
 	if blockNr == rpc.LatestBlockNumber {
 		return b.eth.blockchain.CurrentBlock().Header(), nil
 	}
+	if blockNr == rpc.FinalizedBlockNumber {
+		return b.eth.blockchain.GetHeaderByNumber(b.eth.blockchain.FinalizedNumber()), nil
+	}
 	return b.eth.blockchain.GetHeaderByNumber(uint64(blockNr)), nil
 }
 
@@ -105,6 +113,9 @@ func (b *EthAPIBackend) SideHeaderByNumber(ctx context.Context, blockNr rpc.Bloc
 	if blockNr == rpc.LatestBlockNumber {
 		return sideChain.CurrentBlock().Header(), nil
 	}
+	if blockNr == rpc.FinalizedBlockNumber {
+		return sideChain.GetHeaderByNumber(sideChain.FinalizedNumber()), nil
+	}
 	return sideChain.GetHeaderByNumber(uint64(blockNr)), nil
 }
 
@@ -118,6 +129,9 @@ func (b *EthAPIBackend) BlockByNumber(ctx context.Context, blockNr rpc.BlockNumb
 	if blockNr == rpc.LatestBlockNumber {
 		return b.eth.blockchain.CurrentBlock(), nil
 	}
+	if blockNr == rpc.FinalizedBlockNumber {
+		return b.eth.blockchain.GetBlockByNumber(b.eth.blockchain.FinalizedNumber()), nil
+	}
 	return b.eth.blockchain.GetBlockByNumber(uint64(blockNr)), nil
 }
 
@@ -137,6 +151,11 @@ func (b *EthAPIBackend) SideBlockByNumber(ctx context.Context, blockNr rpc.Block
 			return sideChain.CurrentBlock(), nil
 		}
 	}
+	if blockNr == rpc.FinalizedBlockNumber {
+		if sideChain, ok := b.eth.sideChains[appId]; sideChain != nil && ok {
+			return sideChain.GetBlockByNumber(sideChain.FinalizedNumber()), nil
+		}
+	}
 	if sideChain, ok := b.eth.sideChains[appId]; sideChain != nil && ok {
 		return sideChain.GetBlockByNumber(uint64(blockNr)), nil
 	} else {
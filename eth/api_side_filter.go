@@ -0,0 +1,135 @@
+// Copyright 2018 The giov Authors
+// This file is part of the giov library.
+//
+// The giov library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The giov library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the giov library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"context"
+
+	"github.com/CarLiveChainCo/goiov/common"
+	"github.com/CarLiveChainCo/goiov/core"
+	"github.com/CarLiveChainCo/goiov/core/bloombits"
+	"github.com/CarLiveChainCo/goiov/core/rawdb"
+	"github.com/CarLiveChainCo/goiov/core/types"
+	"github.com/CarLiveChainCo/goiov/eth/filters"
+	"github.com/CarLiveChainCo/goiov/ethdb"
+	"github.com/CarLiveChainCo/goiov/event"
+	"github.com/CarLiveChainCo/goiov/params"
+	"github.com/CarLiveChainCo/goiov/rpc"
+)
+
+// SideBackend implements filters.Backend, returning the view of the backend onto
+// the side chain with the given appId.
+func (b *EthAPIBackend) SideBackend(appId string) (filters.Backend, error) {
+	if appId == "" {
+		return b, nil
+	}
+	chain, ok := b.eth.sideChains[appId]
+	if !ok || chain == nil {
+		return nil, ErrNoSideChain
+	}
+	return &sideFilterBackend{
+		backend: b,
+		appId:   appId,
+		chain:   chain,
+		txPool:  b.eth.sideTxPool[appId],
+		mux:     new(event.TypeMux),
+	}, nil
+}
+
+// sideFilterBackend implements filters.Backend for a side chain, reading its
+// blocks and receipts through the appId prefixed database keys and watching the
+// feeds of its own block chain and transaction pool.
+type sideFilterBackend struct {
+	backend *EthAPIBackend
+	appId   string
+	chain   *core.BlockChain
+	txPool  *core.TxPool
+	mux     *event.TypeMux // side chain miners post no pending logs, so nothing is posted here
+}
+
+func (b *sideFilterBackend) ChainDb() ethdb.Database {
+	return b.backend.eth.ChainDb()
+}
+
+func (b *sideFilterBackend) EventMux() *event.TypeMux {
+	return b.mux
+}
+
+func (b *sideFilterBackend) HeaderByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*types.Header, error) {
+	return b.backend.SideHeaderByNumber(ctx, blockNr, b.appId)
+}
+
+func (b *sideFilterBackend) GetReceipts(ctx context.Context, hash common.Hash) (types.Receipts, error) {
+	if number := rawdb.ReadHeaderNumber(b.ChainDb(), hash, b.appId); number != nil {
+		return rawdb.ReadReceipts(b.ChainDb(), hash, *number, b.appId), nil
+	}
+	return nil, nil
+}
+
+func (b *sideFilterBackend) GetLogs(ctx context.Context, hash common.Hash) ([][]*types.Log, error) {
+	receipts, _ := b.GetReceipts(ctx, hash)
+	if receipts == nil {
+		return nil, nil
+	}
+	logs := make([][]*types.Log, len(receipts))
+	for i, receipt := range receipts {
+		logs[i] = receipt.Logs
+	}
+	return logs, nil
+}
+
+func (b *sideFilterBackend) SubscribeNewTxsEvent(ch chan<- core.NewTxsEvent) event.Subscription {
+	return b.txPool.SubscribeNewTxsEvent(ch)
+}
+
+func (b *sideFilterBackend) SubscribeChainEvent(ch chan<- core.ChainEvent) event.Subscription {
+	return b.chain.SubscribeChainEvent(ch)
+}
+
+func (b *sideFilterBackend) SubscribeRemovedLogsEvent(ch chan<- core.RemovedLogsEvent) event.Subscription {
+	return b.chain.SubscribeRemovedLogsEvent(ch)
+}
+
+func (b *sideFilterBackend) SubscribeLogsEvent(ch chan<- []*types.Log) event.Subscription {
+	return b.chain.SubscribeLogsEvent(ch)
+}
+
+func (b *sideFilterBackend) BloomStatus() (uint64, uint64) {
+	indexer, ok := b.backend.eth.sideBloomIndexers[b.appId]
+	if !ok {
+		return params.BloomBitsBlocks, 0
+	}
+	sections, _, _ := indexer.Sections()
+	return params.BloomBitsBlocks, sections
+}
+
+func (b *sideFilterBackend) ServiceFilter(ctx context.Context, session *bloombits.MatcherSession) {
+	requests, ok := b.backend.eth.sideBloomRequests[b.appId]
+	if !ok {
+		return
+	}
+	for i := 0; i < bloomFilterThreads; i++ {
+		go session.Multiplex(bloomRetrievalBatch, bloomRetrievalWait, requests)
+	}
+}
+
+func (b *sideFilterBackend) SideBackend(appId string) (filters.Backend, error) {
+	if appId == b.appId {
+		return b, nil
+	}
+	return b.backend.SideBackend(appId)
+}
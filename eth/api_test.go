@@ -17,12 +17,21 @@
 package eth
 
 import (
+	"context"
+	"math/big"
 	"reflect"
 	"testing"
 
 	"github.com/CarLiveChainCo/goiov/common"
+	"github.com/CarLiveChainCo/goiov/consensus/ethash"
+	"github.com/CarLiveChainCo/goiov/core"
 	"github.com/CarLiveChainCo/goiov/core/state"
+	"github.com/CarLiveChainCo/goiov/core/types"
+	"github.com/CarLiveChainCo/goiov/core/vm"
 	"github.com/CarLiveChainCo/goiov/ethdb"
+	"github.com/CarLiveChainCo/goiov/internal/ethapi"
+	"github.com/CarLiveChainCo/goiov/params"
+	"github.com/CarLiveChainCo/goiov/rpc"
 	"github.com/davecgh/go-spew/spew"
 )
 
@@ -88,3 +97,74 @@ func TestStorageRangeAt(t *testing.T) {
 		}
 	}
 }
+
+// newTestTraceChain creates a chain of the given app in db, empty for the main
+// chain, with the given number of blocks generated on top of the genesis. Only
+// the main chain can be generated on.
+func newTestTraceChain(t *testing.T, db ethdb.Database, appId string, blocks int, generator func(int, *core.BlockGen)) *core.BlockChain {
+	config := *params.TestChainConfig
+	config.AppId = appId
+
+	var (
+		engine  = ethash.NewFaker()
+		gspec   = &core.Genesis{Config: &config, Alloc: core.GenesisAlloc{testBank: {Balance: big.NewInt(1000000)}}}
+		genesis = gspec.MustCommit(db)
+	)
+	chain, err := core.NewBlockChain(db, nil, &config, engine, vm.Config{})
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	generated, _ := core.GenerateChain(&config, genesis, engine, db, blocks, generator)
+	if _, err := chain.InsertChain(generated); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	return chain
+}
+
+// Tests that the tracers run on the chain named by the appId of the trace config,
+// the main chain if it names none.
+func TestTraceAppChain(t *testing.T) {
+	var (
+		db = ethdb.NewMemDatabase()
+		tx *types.Transaction
+	)
+	main := newTestTraceChain(t, db, "", 1, func(i int, block *core.BlockGen) {
+		tx, _ = types.SignTx(types.NewTransaction(block.TxNonce(testBank), common.Address{1}, big.NewInt(1000), params.TxGas, nil, nil), types.HomesteadSigner{}, testBankKey)
+		block.AddTx(tx)
+	})
+	defer main.Stop()
+	side := newTestTraceChain(t, db, "app", 0, nil)
+	defer side.Stop()
+
+	api := NewPrivateDebugAPI(main.Config(), &Ethereum{
+		blockchain: main,
+		chainDb:    db,
+		sideChains: map[string]*core.BlockChain{"app": side},
+	})
+	// Unknown app chains are rejected
+	if _, err := api.TraceBlockByNumber(context.Background(), 1, &TraceConfig{AppId: "unknown"}); err != ErrNoSideChain {
+		t.Errorf("unknown app chain error mismatch: have %v, want %v", err, ErrNoSideChain)
+	}
+	// Blocks are traced on the chain of the config
+	results, err := api.TraceBlockByNumber(context.Background(), rpc.LatestBlockNumber, nil)
+	if err != nil {
+		t.Fatalf("failed to trace main block: %v", err)
+	}
+	if len(results) != 1 || results[0].Error != "" {
+		t.Errorf("main block trace mismatch: have %v, want 1 successful result", results)
+	}
+	if _, err := api.TraceBlockByNumber(context.Background(), 1, &TraceConfig{AppId: "app"}); err == nil {
+		t.Errorf("main block traced on the app chain")
+	}
+	// Transactions are looked up on the chain of the config
+	res, err := api.TraceTransaction(context.Background(), tx.Hash(), nil)
+	if err != nil {
+		t.Fatalf("failed to trace main transaction: %v", err)
+	}
+	if result, ok := res.(*ethapi.ExecutionResult); !ok || result.Failed || result.Gas != params.TxGas {
+		t.Errorf("main transaction trace mismatch: have %v", res)
+	}
+	if _, err := api.TraceTransaction(context.Background(), tx.Hash(), &TraceConfig{AppId: "app"}); err == nil {
+		t.Errorf("main transaction traced on the app chain")
+	}
+}
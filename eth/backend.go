@@ -99,6 +99,9 @@ type Ethereum struct {
 	sideChains map[string]*core.BlockChain //已引入的链
 	sideTxPool map[string]*core.TxPool     //已引入链的交易池
 	sideMiner  map[string]*miner.Miner     //已引入链的矿工
+
+	sideBloomRequests map[string]chan chan *bloombits.Retrieval // Channels receiving bloom data retrieval requests of the side chains
+	sideBloomIndexers map[string]*core.ChainIndexer             // Bloom indexers of the side chains
 }
 
 func (s *Ethereum) AddLesServer(ls LesServer) {
@@ -120,6 +123,10 @@ func New(ctx *node.ServiceContext, config *Config, nodeCfg *node.Config) (*Ether
 	if err != nil {
 		return nil, err
 	}
+	// Move the app chains of a legacy database into their tables
+	if err := UpgradeDatabase(chainDb); err != nil {
+		return nil, err
+	}
 	// 创建创世块，statedb，并写入数据库
 	testFlag := (config.NetworkId != 1)
 	chainConfig, genesisHash, genesisErr := core.SetupGenesisBlock(chainDb, config.Genesis, testFlag)
@@ -145,9 +152,15 @@ func New(ctx *node.ServiceContext, config *Config, nodeCfg *node.Config) (*Ether
 		sideChains:    make(map[string]*core.BlockChain),
 		sideMiner:     make(map[string]*miner.Miner),
 		sideTxPool:    make(map[string]*core.TxPool),
+
+		sideBloomRequests: make(map[string]chan chan *bloombits.Retrieval),
+		sideBloomIndexers: make(map[string]*core.ChainIndexer),
 	}
 	if mainAlien, ok := eth.engine.(*alien.Alien); ok {
 		mainAlien.SetEth(eth)
+		if config.AlienRewardIndex {
+			mainAlien.EnableRewardIndex(config.AlienRewardRetain)
+		}
 	}
 	log.Info("Initialising Ethereum protocol", "versions", ProtocolVersions, "network", config.NetworkId)
 	if !config.SkipBcVersionCheck {
@@ -170,9 +183,7 @@ func New(ctx *node.ServiceContext, config *Config, nodeCfg *node.Config) (*Ether
 	if compat, ok := genesisErr.(*params.ConfigCompatError); ok {
 		log.Warn("Rewinding chain to upgrade configuration", "err", compat)
 		eth.blockchain.SetHead(compat.RewindTo)
-		rMap := rawdb.ReadAllChainConfig(chainDb)
-		rMap[""] = chainConfig
-		rawdb.WriteChainConfig(chainDb, genesisHash, rMap)
+		rawdb.WriteChainConfig(chainDb, genesisHash, chainConfig)
 	}
 	eth.bloomIndexer.Start(eth.blockchain)
 
@@ -183,9 +194,15 @@ func New(ctx *node.ServiceContext, config *Config, nodeCfg *node.Config) (*Ether
 	if eth.protocolManager, err = NewProtocolManager(eth.chainConfig, config.SyncMode, config.NetworkId, eth.eventMux, eth.txPool, eth.engine, eth.blockchain, chainDb, eth.sideChains, eth.sideTxPool); err != nil {
 		return nil, err
 	}
-	var appId = rawdb.ReadAppId(eth.chainDb)
-	if len(appId) != 0 {
-		for _, id := range appId {
+	// Join the app chains configured, or else the ones joined when the node stopped
+	if len(config.AppChains) != 0 {
+		for _, id := range config.AppChains {
+			if err := eth.NewSideChain(false, id); err != nil {
+				return nil, err
+			}
+		}
+	} else {
+		for _, id := range rawdb.ReadAppId(eth.chainDb) {
 			eth.NewSideChain(false, id)
 		}
 	}
@@ -232,6 +249,31 @@ func CreateDB(ctx *node.ServiceContext, config *Config, name string) (ethdb.Data
 	return db, nil
 }
 
+// UpgradeDatabase moves the app chains of a chain database of the legacy layout
+// into their tables, their chain data along with the records of their engines.
+// It does nothing on a database of the current layout.
+func UpgradeDatabase(db ethdb.Database) error {
+	appIds, err := core.UpgradeAppChainDatabase(db)
+	if err != nil {
+		return err
+	}
+	for _, appId := range appIds {
+		if err := alien.UpgradeAppChain(db, appId); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DeleteAppChain deletes the app chain appId from the chain database, its chain
+// data along with the records of its engine and of the engine of the main chain.
+func DeleteAppChain(db ethdb.Database, appId string) error {
+	if err := alien.DeleteAppChain(db, appId); err != nil {
+		return err
+	}
+	return core.DeleteAppChain(db, appId)
+}
+
 // CreateConsensusEngine(ctx, &config.Ethash, chainConfig, chainDb, testFlag),
 // CreateConsensusEngine creates the required type of consensus engine instance for an Ethereum service
 func CreateConsensusEngine(ctx *node.ServiceContext, config *ethash.Config, chainConfig *params.ChainConfig, db ethdb.Database, testFlag ...bool) consensus.Engine {
@@ -511,6 +553,11 @@ func (s *Ethereum) NewSideChain(idSync bool, appId string) error {
 		return bcErr
 	}
 	s.sideChains[config.AppId] = blockChain
+	// bloom bits indexing and servicing for the log filters
+	s.sideBloomRequests[appId] = make(chan chan *bloombits.Retrieval)
+	s.sideBloomIndexers[appId] = NewBloomIndexer(s.chainDb, params.BloomBitsBlocks, appId)
+	s.sideBloomIndexers[appId].Start(blockChain)
+	s.serveBloomRequests(s.sideBloomRequests[appId], sideBloomServiceThreads, appId)
 	// tx_pool
 	sideTxPool := core.NewTxPool(core.DefaultTxPoolConfig, config, blockChain, s)
 	s.sideTxPool[config.AppId] = sideTxPool
@@ -538,6 +585,9 @@ func (s *Ethereum) Stop() error {
 	}
 	rawdb.WriteAppId(s.chainDb, appId)
 	s.bloomIndexer.Close()
+	for _, indexer := range s.sideBloomIndexers {
+		indexer.Close()
+	}
 	s.blockchain.Stop()
 	for _, sideChain := range s.sideChains {
 		if sideChain != nil {
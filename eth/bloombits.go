@@ -34,6 +34,10 @@ const (
 	// instance to service bloombits lookups for all running filters.
 	bloomServiceThreads = 16
 
+	// sideBloomServiceThreads is the number of goroutines used by every side chain
+	// to service bloombits lookups for the filters running against it.
+	sideBloomServiceThreads = 4
+
 	// bloomFilterThreads is the number of goroutines used locally per filter to
 	// multiplex requests onto the global servicing goroutines.
 	bloomFilterThreads = 3
@@ -50,19 +54,25 @@ const (
 // startBloomHandlers starts a batch of goroutines to accept bloom bit database
 // retrievals from possibly a range of filters and serving the data to satisfy.
 func (eth *Ethereum) startBloomHandlers() {
-	for i := 0; i < bloomServiceThreads; i++ {
+	eth.serveBloomRequests(eth.bloomRequests, bloomServiceThreads, "")
+}
+
+// serveBloomRequests starts the given number of goroutines serving the bloom bit
+// retrievals of the given chain, the main chain if appId is empty.
+func (eth *Ethereum) serveBloomRequests(requests chan chan *bloombits.Retrieval, threads int, appId string) {
+	for i := 0; i < threads; i++ {
 		go func() {
 			for {
 				select {
 				case <-eth.shutdownChan:
 					return
 
-				case request := <-eth.bloomRequests:
+				case request := <-requests:
 					task := <-request
 					task.Bitsets = make([][]byte, len(task.Sections))
 					for i, section := range task.Sections {
-						head := rawdb.ReadCanonicalHash(eth.chainDb, (section+1)*params.BloomBitsBlocks-1)
-						if compVector, err := rawdb.ReadBloomBits(eth.chainDb, task.Bit, section, head); err == nil {
+						head := rawdb.ReadCanonicalHash(eth.chainDb, (section+1)*params.BloomBitsBlocks-1, appId)
+						if compVector, err := rawdb.ReadBloomBits(eth.chainDb, task.Bit, section, head, appId); err == nil {
 							if blob, err := bitutil.DecompressBytes(compVector, int(params.BloomBitsBlocks)/8); err == nil {
 								task.Bitsets[i] = blob
 							} else {
@@ -92,7 +102,8 @@ const (
 // BloomIndexer implements a core.ChainIndexer, building up a rotated bloom bits index
 // for the Ethereum header bloom filters, permitting blazing fast filtering.
 type BloomIndexer struct {
-	size uint64 // section size to generate bloombits for
+	size  uint64 // section size to generate bloombits for
+	appId string // side chain to generate bloombits for, empty for the main chain
 
 	db  ethdb.Database       // database instance to write index data and metadata into
 	gen *bloombits.Generator // generator to rotate the bloom bits crating the bloom index
@@ -102,15 +113,21 @@ type BloomIndexer struct {
 }
 
 // NewBloomIndexer returns a chain indexer that generates bloom bits data for the
-// canonical chain for fast logs filtering.
-func NewBloomIndexer(db ethdb.Database, size uint64) *core.ChainIndexer {
+// canonical chain for fast logs filtering. An optional appId selects the side
+// chain to index, whose index data is kept in the table of the app chain.
+func NewBloomIndexer(db ethdb.Database, size uint64, appid ...string) *core.ChainIndexer {
+	var appId string
+	if len(appid) == 1 {
+		appId = appid[0]
+	}
 	backend := &BloomIndexer{
-		db:   db,
-		size: size,
+		db:    db,
+		size:  size,
+		appId: appId,
 	}
-	table := ethdb.NewTable(db, string(rawdb.BloomBitsIndexPrefix))
+	table := ethdb.NewTable(rawdb.AppChainDatabase(db, appId), string(rawdb.BloomBitsIndexPrefix))
 
-	return core.NewChainIndexer(db, table, backend, size, bloomConfirms, bloomThrottling, "bloombits")
+	return core.NewChainIndexer(db, table, backend, size, bloomConfirms, bloomThrottling, "bloombits", appId)
 }
 
 // Reset implements core.ChainIndexerBackend, starting a new bloombits index
@@ -138,7 +155,7 @@ func (b *BloomIndexer) Commit() error {
 		if err != nil {
 			return err
 		}
-		rawdb.WriteBloomBits(batch, uint(i), b.section, b.head, bitutil.CompressBytes(bits))
+		rawdb.WriteBloomBits(batch, uint(i), b.section, b.head, bitutil.CompressBytes(bits), b.appId)
 	}
 	return batch.Write()
 }
@@ -53,6 +53,7 @@ var DefaultConfig = Config{
 	TrieCache:     256,
 	TrieTimeout:   5 * time.Minute,
 	GasPrice:      big.NewInt(18 * params.Shannon),
+	RPCEVMTimeout: 5 * time.Second,
 
 	TxPool: core.DefaultTxPoolConfig,
 	GPO: gasprice.Config{
@@ -118,6 +119,16 @@ type Config struct {
 	// Enables tracking of SHA3 preimages in the VM
 	EnablePreimageRecording bool
 
+	// Alien reward index options
+	AlienRewardIndex  bool   `toml:",omitempty"` // Record the reward split of every block
+	AlienRewardRetain uint64 `toml:",omitempty"` // Number of recent blocks to keep reward records for (0 = all)
+
+	// App chains to join at start-up instead of the ones joined when the node stopped
+	AppChains []string `toml:",omitempty"`
+
+	// RPC options
+	RPCEVMTimeout time.Duration // Timeout of the EVM calls of eth_call and eth_callMany (0 = no timeout)
+
 	// Miscellaneous options
 	DocRoot string `toml:"-"`
 }
@@ -205,6 +205,10 @@ type BlockChain interface {
 	// InsertReceiptChain inserts a batch of receipts into the local chain.
 	InsertReceiptChain(types.Blocks, []types.Receipts) (int, error)
 
+	// FinalizedNumber retrieves the number of the last block confirmed by the
+	// consensus engine, below which the local chain is never reorganised.
+	FinalizedNumber() uint64
+
 	Config() *params.ChainConfig
 }
 
@@ -214,6 +218,8 @@ func New(mode SyncMode, stateDb ethdb.Database, mux *event.TypeMux, chain BlockC
 	if lightchain == nil {
 		lightchain = chain
 	}
+	// The state of an app chain is synced into its table
+	stateDb = rawdb.AppChainDatabase(stateDb, chain.Config().AppId)
 
 	dl := &Downloader{
 		mode:           mode,
@@ -236,7 +242,7 @@ func New(mode SyncMode, stateDb ethdb.Database, mux *event.TypeMux, chain BlockC
 		stateCh:        make(chan dataPack),
 		stateSyncStart: make(chan *stateSync),
 		syncStatsState: stateSyncStats{
-			processed: rawdb.ReadFastTrieProgress(stateDb,chain.Config().AppId),
+			processed: rawdb.ReadFastTrieProgress(stateDb),
 		},
 		trackStateReq: make(chan *stateReq),
 	}
@@ -618,6 +624,12 @@ func (d *Downloader) findAncestor(p *peerConnection, height uint64) (uint64, err
 	if ceil >= MaxForkAncestry {
 		floor = int64(ceil - MaxForkAncestry)
 	}
+	// The ancestor may not be below the finalized block either
+	if d.mode == FullSync || d.mode == FastSync {
+		if finalized := int64(d.blockchain.FinalizedNumber()) - 1; finalized > floor {
+			floor = finalized
+		}
+	}
 	p.log.Debug("Looking for common ancestor", "local", ceil, "remote", height)
 
 	// Request the topmost blocks to short circuit binary ancestor lookup
@@ -370,6 +370,12 @@ func (dl *downloadTester) InsertReceiptChain(blocks types.Blocks, receipts []typ
 	return len(blocks), nil
 }
 
+// FinalizedNumber retrieves the number of the last finalized block, the tester
+// chain has none.
+func (dl *downloadTester) FinalizedNumber() uint64 {
+	return 0
+}
+
 // Rollback removes some recently added elements from the chain.
 func (dl *downloadTester) Rollback(hashes []common.Hash) {
 	dl.lock.Lock()
@@ -59,6 +59,9 @@ type PublicFilterAPI struct {
 	events    *EventSystem
 	filtersMu sync.Mutex
 	filters   map[rpc.ID]*filter
+
+	sideEventsMu sync.Mutex
+	sideEvents   map[string]*EventSystem // event systems of the side chains, created on first use
 }
 
 // NewPublicFilterAPI returns a new PublicFilterAPI instance.
@@ -69,6 +72,8 @@ func NewPublicFilterAPI(backend Backend, lightMode bool) *PublicFilterAPI {
 		chainDb: backend.ChainDb(),
 		events:  NewEventSystem(backend.EventMux(), backend, lightMode),
 		filters: make(map[rpc.ID]*filter),
+
+		sideEvents: make(map[string]*EventSystem),
 	}
 	go api.timeoutLoop()
 
@@ -95,6 +100,33 @@ func (api *PublicFilterAPI) timeoutLoop() {
 	}
 }
 
+// eventSystem returns the event system watching the chain with the given appId,
+// creating it on the first use for a side chain. Side chains have no pending
+// logs, so their event systems listen to a mux nobody posts to. The event system
+// of a side chain stops with the chain, it is then dropped, so a side chain
+// deleted and created again gets a new one.
+func (api *PublicFilterAPI) eventSystem(appId string) (*EventSystem, error) {
+	if appId == "" {
+		return api.events, nil
+	}
+	api.sideEventsMu.Lock()
+	defer api.sideEventsMu.Unlock()
+
+	if events, ok := api.sideEvents[appId]; ok {
+		if !events.stopped() {
+			return events, nil
+		}
+		delete(api.sideEvents, appId)
+	}
+	backend, err := api.backend.SideBackend(appId)
+	if err != nil {
+		return nil, err
+	}
+	events := NewEventSystem(new(event.TypeMux), backend, false)
+	api.sideEvents[appId] = events
+	return events, nil
+}
+
 // NewPendingTransactionFilter creates a filter that fetches pending transaction hashes
 // as transactions enter the pending state.
 //
@@ -204,17 +236,26 @@ func (api *PublicFilterAPI) NewBlockFilter() rpc.ID {
 }
 
 // NewHeads send a notification each time a new (header) block is appended to the chain.
-func (api *PublicFilterAPI) NewHeads(ctx context.Context) (*rpc.Subscription, error) {
+// An optional appId selects the side chain to watch instead of the main chain.
+func (api *PublicFilterAPI) NewHeads(ctx context.Context, appId *string) (*rpc.Subscription, error) {
 	notifier, supported := rpc.NotifierFromContext(ctx)
 	if !supported {
 		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
 	}
+	var id string
+	if appId != nil {
+		id = *appId
+	}
+	events, err := api.eventSystem(id)
+	if err != nil {
+		return nil, err
+	}
 
 	rpcSub := notifier.CreateSubscription()
 
 	go func() {
 		headers := make(chan *types.Header)
-		headersSub := api.events.SubscribeNewHeads(headers)
+		headersSub := events.SubscribeNewHeads(headers)
 
 		for {
 			select {
@@ -240,12 +281,16 @@ func (api *PublicFilterAPI) Logs(ctx context.Context, crit FilterCriteria) (*rpc
 		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
 	}
 
+	events, err := api.eventSystem(crit.AppId)
+	if err != nil {
+		return nil, err
+	}
 	var (
 		rpcSub      = notifier.CreateSubscription()
 		matchedLogs = make(chan []*types.Log)
 	)
 
-	logsSub, err := api.events.SubscribeLogs(ethereum.FilterQuery(crit), matchedLogs)
+	logsSub, err := events.SubscribeLogs(ethereum.FilterQuery(crit), matchedLogs)
 	if err != nil {
 		return nil, err
 	}
@@ -289,8 +334,12 @@ type FilterCriteria ethereum.FilterQuery
 //
 // https://github.com/ethereum/wiki/wiki/JSON-RPC#eth_newfilter
 func (api *PublicFilterAPI) NewFilter(crit FilterCriteria) (rpc.ID, error) {
+	events, err := api.eventSystem(crit.AppId)
+	if err != nil {
+		return rpc.ID(""), err
+	}
 	logs := make(chan []*types.Log)
-	logsSub, err := api.events.SubscribeLogs(ethereum.FilterQuery(crit), logs)
+	logsSub, err := events.SubscribeLogs(ethereum.FilterQuery(crit), logs)
 	if err != nil {
 		return rpc.ID(""), err
 	}
@@ -331,8 +380,12 @@ func (api *PublicFilterAPI) GetLogs(ctx context.Context, crit FilterCriteria) ([
 	if crit.ToBlock == nil {
 		crit.ToBlock = big.NewInt(rpc.LatestBlockNumber.Int64())
 	}
+	backend, err := api.backend.SideBackend(crit.AppId)
+	if err != nil {
+		return nil, err
+	}
 	// Create and run the filter to get all the logs
-	filter := New(api.backend, crit.FromBlock.Int64(), crit.ToBlock.Int64(), crit.Addresses, crit.Topics)
+	filter := New(backend, crit.FromBlock.Int64(), crit.ToBlock.Int64(), crit.Addresses, crit.Topics)
 
 	logs, err := filter.Logs(ctx)
 	if err != nil {
@@ -379,8 +432,12 @@ func (api *PublicFilterAPI) GetFilterLogs(ctx context.Context, id rpc.ID) ([]*ty
 	if f.crit.ToBlock != nil {
 		end = f.crit.ToBlock.Int64()
 	}
+	backend, err := api.backend.SideBackend(f.crit.AppId)
+	if err != nil {
+		return nil, err
+	}
 	// Create and run the filter to get all the logs
-	filter := New(api.backend, begin, end, f.crit.Addresses, f.crit.Topics)
+	filter := New(backend, begin, end, f.crit.Addresses, f.crit.Topics)
 
 	logs, err := filter.Logs(ctx)
 	if err != nil {
@@ -448,6 +505,7 @@ func (args *FilterCriteria) UnmarshalJSON(data []byte) error {
 		ToBlock   *rpc.BlockNumber `json:"toBlock"`
 		Addresses interface{}      `json:"address"`
 		Topics    []interface{}    `json:"topics"`
+		AppId     string           `json:"appId"`
 	}
 
 	var raw input
@@ -463,6 +521,7 @@ func (args *FilterCriteria) UnmarshalJSON(data []byte) error {
 		args.ToBlock = big.NewInt(raw.ToBlock.Int64())
 	}
 
+	args.AppId = raw.AppId
 	args.Addresses = []common.Address{}
 
 	if raw.Addresses != nil {
@@ -182,4 +182,17 @@ func TestUnmarshalJSONNewFilterArgs(t *testing.T) {
 	if len(test7.Topics[2]) != 0 {
 		t.Fatalf("expected 0 topics, got %d topics", len(test7.Topics[2]))
 	}
+
+	// side chain
+	var test8 FilterCriteria
+	vector = fmt.Sprintf(`{"address":"%s","appId":"app1"}`, address0.Hex())
+	if err := json.Unmarshal([]byte(vector), &test8); err != nil {
+		t.Fatal(err)
+	}
+	if test8.AppId != "app1" {
+		t.Fatalf("expected appId app1, got %q", test8.AppId)
+	}
+	if test0.AppId != "" {
+		t.Fatalf("expected no appId, got %q", test0.AppId)
+	}
 }
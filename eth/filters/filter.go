@@ -43,6 +43,10 @@ type Backend interface {
 
 	BloomStatus() (uint64, uint64)
 	ServiceFilter(ctx context.Context, session *bloombits.MatcherSession)
+
+	// SideBackend returns the view of the backend onto the side chain with the
+	// given appId, the backend itself for an empty appId.
+	SideBackend(appId string) (Backend, error)
 }
 
 // Filter can be used to retrieve and filter logs.
@@ -109,6 +113,19 @@ func (f *Filter) Logs(ctx context.Context) ([]*types.Log, error) {
 	if f.end == -1 {
 		end = head
 	}
+	// Resolve the finalized tag to the block the consensus engine confirmed
+	if finalized := rpc.FinalizedBlockNumber.Int64(); f.begin == finalized || f.end == finalized {
+		header, _ := f.backend.HeaderByNumber(ctx, rpc.FinalizedBlockNumber)
+		if header == nil {
+			return nil, nil
+		}
+		if f.begin == finalized {
+			f.begin = header.Number.Int64()
+		}
+		if f.end == finalized {
+			end = header.Number.Uint64()
+		}
+	}
 	// Gather all indexed logs, and finish with non indexed ones
 	var (
 		logs []*types.Log
@@ -108,6 +108,7 @@ type EventSystem struct {
 	logsCh    chan []*types.Log          // Channel to receive new log event
 	rmLogsCh  chan core.RemovedLogsEvent // Channel to receive removed log event
 	chainCh   chan core.ChainEvent       // Channel to receive new chain event
+	done      chan struct{}              // closed when the event loop exits
 }
 
 // NewEventSystem creates a new manager that listens for event on the given mux,
@@ -115,7 +116,7 @@ type EventSystem struct {
 // work loop holds its own index that is used to forward events to filters.
 //
 // The returned manager has a loop that needs to be stopped with the Stop function
-// or by stopping the given mux.
+// or by stopping the given mux. The loop also stops with the chain of the backend.
 func NewEventSystem(mux *event.TypeMux, backend Backend, lightMode bool) *EventSystem {
 	m := &EventSystem{
 		mux:       mux,
@@ -127,6 +128,7 @@ func NewEventSystem(mux *event.TypeMux, backend Backend, lightMode bool) *EventS
 		logsCh:    make(chan []*types.Log, logsChanSize),
 		rmLogsCh:  make(chan core.RemovedLogsEvent, rmLogsChanSize),
 		chainCh:   make(chan core.ChainEvent, chainEvChanSize),
+		done:      make(chan struct{}),
 	}
 
 	// Subscribe events
@@ -172,6 +174,8 @@ func (sub *Subscription) Unsubscribe() {
 			select {
 			case sub.es.uninstall <- sub.f:
 				break uninstallLoop
+			case <-sub.es.done:
+				break uninstallLoop
 			case <-sub.f.logs:
 			case <-sub.f.hashes:
 			case <-sub.f.headers:
@@ -185,13 +189,29 @@ func (sub *Subscription) Unsubscribe() {
 	})
 }
 
-// subscribe installs the subscription in the event broadcast loop.
+// subscribe installs the subscription in the event broadcast loop. If the loop
+// has exited, the subscription is returned uninstalled already.
 func (es *EventSystem) subscribe(sub *subscription) *Subscription {
-	es.install <- sub
-	<-sub.installed
+	select {
+	case es.install <- sub:
+		<-sub.installed
+	case <-es.done:
+		close(sub.err)
+	}
 	return &Subscription{ID: sub.id, f: sub, es: es}
 }
 
+// stopped reports whether the event loop has exited, after which no event is
+// delivered to the subscriptions anymore.
+func (es *EventSystem) stopped() bool {
+	select {
+	case <-es.done:
+		return true
+	default:
+		return false
+	}
+}
+
 // SubscribeLogs creates a subscription that will write all logs matching the
 // given criteria to the given logs channel. Default value for the from and to
 // block is "latest". If the fromBlock > toBlock an error is returned.
@@ -216,6 +236,10 @@ func (es *EventSystem) SubscribeLogs(crit ethereum.FilterQuery, logs chan []*typ
 	if from == rpc.LatestBlockNumber && to == rpc.LatestBlockNumber {
 		return es.subscribeLogs(crit, logs), nil
 	}
+	// only interested in new mined logs, the finalized ones being history already
+	if from == rpc.FinalizedBlockNumber && to == rpc.LatestBlockNumber {
+		return es.subscribeLogs(crit, logs), nil
+	}
 	// only interested in mined logs within a specific block range
 	if from >= 0 && to >= 0 && to >= from {
 		return es.subscribeLogs(crit, logs), nil
@@ -447,19 +471,31 @@ func (es *EventSystem) lightFilterLogs(header *types.Header, addresses []common.
 
 // eventLoop (un)installs filters and processes mux events.
 func (es *EventSystem) eventLoop() {
-	// Ensure all subscriptions get cleaned up
+	index := make(filterIndex)
+	for i := UnknownSubscription; i < LastIndexSubscription; i++ {
+		index[i] = make(map[rpc.ID]*subscription)
+	}
+
+	// Ensure all subscriptions get cleaned up, uninstalling the filters still
+	// installed so their owners see the loop has exited
 	defer func() {
 		es.pendingLogSub.Unsubscribe()
 		es.txsSub.Unsubscribe()
 		es.logsSub.Unsubscribe()
 		es.rmLogsSub.Unsubscribe()
 		es.chainSub.Unsubscribe()
-	}()
 
-	index := make(filterIndex)
-	for i := UnknownSubscription; i < LastIndexSubscription; i++ {
-		index[i] = make(map[rpc.ID]*subscription)
-	}
+		uninstalled := make(map[rpc.ID]bool)
+		for _, filters := range index {
+			for id, f := range filters {
+				if !uninstalled[id] {
+					close(f.err)
+					uninstalled[id] = true
+				}
+			}
+		}
+		close(es.done)
+	}()
 
 	for {
 		select {
@@ -477,6 +513,8 @@ func (es *EventSystem) eventLoop() {
 				return
 			}
 			es.broadcast(index, ev)
+		case <-es.chainSub.Err(): // chain stopped
+			return
 
 		case f := <-es.install:
 			if f.typ == MinedAndPendingLogsSubscription {
@@ -143,6 +143,13 @@ func (b *testBackend) ServiceFilter(ctx context.Context, session *bloombits.Matc
 	}()
 }
 
+func (b *testBackend) SideBackend(appId string) (Backend, error) {
+	if appId == "" {
+		return b, nil
+	}
+	return nil, fmt.Errorf("unknown side chain %s", appId)
+}
+
 // TestBlockSubscription tests if a block subscription returns block hashes for posted chain events.
 // It creates multiple subscriptions:
 // - one at the start and should receive all posted chain events and a second (blockHashes)
@@ -290,6 +297,8 @@ func TestLogFilterCreation(t *testing.T) {
 			{FilterCriteria{FromBlock: big.NewInt(1), ToBlock: big.NewInt(rpc.LatestBlockNumber.Int64())}, true},
 			// new mined and pending blocks
 			{FilterCriteria{FromBlock: big.NewInt(rpc.LatestBlockNumber.Int64()), ToBlock: big.NewInt(rpc.PendingBlockNumber.Int64())}, true},
+			// new mined blocks on top of the finalized ones
+			{FilterCriteria{FromBlock: big.NewInt(rpc.FinalizedBlockNumber.Int64()), ToBlock: big.NewInt(rpc.LatestBlockNumber.Int64())}, true},
 			// from block "higher" than to block
 			{FilterCriteria{FromBlock: big.NewInt(2), ToBlock: big.NewInt(1)}, false},
 			// from block "higher" than to block
@@ -579,3 +588,54 @@ func TestPendingLogsSubscription(t *testing.T) {
 		}
 	}
 }
+
+// stoppableBackend is a testBackend whose chain can be stopped, ending the chain
+// event subscriptions as the block chain does.
+type stoppableBackend struct {
+	*testBackend
+	scope event.SubscriptionScope
+}
+
+func (b *stoppableBackend) SubscribeChainEvent(ch chan<- core.ChainEvent) event.Subscription {
+	return b.scope.Track(b.testBackend.SubscribeChainEvent(ch))
+}
+
+// TestChainStopped tests that the event system exits when the chain stops,
+// uninstalling the subscriptions left, and refuses new ones.
+func TestChainStopped(t *testing.T) {
+	t.Parallel()
+
+	var (
+		mux        = new(event.TypeMux)
+		db         = ethdb.NewMemDatabase()
+		txFeed     = new(event.Feed)
+		rmLogsFeed = new(event.Feed)
+		logsFeed   = new(event.Feed)
+		chainFeed  = new(event.Feed)
+		backend    = &stoppableBackend{testBackend: &testBackend{mux, db, 0, txFeed, rmLogsFeed, logsFeed, chainFeed}}
+		es         = NewEventSystem(mux, backend, false)
+	)
+	sub := es.SubscribeNewHeads(make(chan *types.Header))
+	if es.stopped() {
+		t.Fatal("event system stopped before the chain")
+	}
+	backend.scope.Close()
+
+	select {
+	case <-sub.Err():
+	case <-time.After(time.Second):
+		t.Fatal("subscription not uninstalled when the chain stopped")
+	}
+	if !es.stopped() {
+		t.Fatal("event system not stopped with the chain")
+	}
+	sub.Unsubscribe()
+
+	late := es.SubscribeNewHeads(make(chan *types.Header))
+	select {
+	case <-late.Err():
+	case <-time.After(time.Second):
+		t.Fatal("subscription installed in a stopped event system")
+	}
+	late.Unsubscribe()
+}
@@ -21,6 +21,7 @@ import (
 	"io/ioutil"
 	"math/big"
 	"os"
+	"reflect"
 	"testing"
 
 	"github.com/CarLiveChainCo/goiov/common"
@@ -32,6 +33,7 @@ import (
 	"github.com/CarLiveChainCo/goiov/ethdb"
 	"github.com/CarLiveChainCo/goiov/event"
 	"github.com/CarLiveChainCo/goiov/params"
+	"github.com/CarLiveChainCo/goiov/rpc"
 )
 
 func makeReceipt(addr common.Address) *types.Receipt {
@@ -230,3 +232,72 @@ func TestFilters(t *testing.T) {
 		t.Error("expected 0 log, got", len(logs))
 	}
 }
+
+// finalizedBackend is a testBackend with a consensus engine confirming the
+// blocks up to a fixed number.
+type finalizedBackend struct {
+	*testBackend
+	finalized uint64
+}
+
+func (b *finalizedBackend) HeaderByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*types.Header, error) {
+	if blockNr == rpc.FinalizedBlockNumber {
+		blockNr = rpc.BlockNumber(b.finalized)
+	}
+	return b.testBackend.HeaderByNumber(ctx, blockNr)
+}
+
+// Tests that the finalized tag is resolved to the confirmed block on either end
+// of the filtered range.
+func TestFiltersFinalized(t *testing.T) {
+	var (
+		db      = ethdb.NewMemDatabase()
+		backend = &finalizedBackend{
+			testBackend: &testBackend{new(event.TypeMux), db, 0, new(event.Feed), new(event.Feed), new(event.Feed), new(event.Feed)},
+			finalized:   5,
+		}
+		addr = common.BytesToAddress([]byte("finalized"))
+	)
+	genesis := (&core.Genesis{Config: params.TestChainConfig}).MustCommit(db)
+	chain, receipts := core.GenerateChain(params.TestChainConfig, genesis, ethash.NewFaker(), db, 10, func(i int, gen *core.BlockGen) {
+		switch i {
+		case 1, 4, 7:
+			receipt := makeReceipt(addr)
+			receipt.Logs[0].BlockNumber = gen.Number().Uint64()
+			gen.AddUncheckedReceipt(receipt)
+		}
+	})
+	for i, block := range chain {
+		rawdb.WriteBlock(db, block)
+		rawdb.WriteCanonicalHash(db, block.Hash(), block.NumberU64())
+		rawdb.WriteHeadBlockHash(db, block.Hash())
+		rawdb.WriteReceipts(db, block.Hash(), block.NumberU64(), receipts[i])
+	}
+	tests := []struct {
+		begin, end rpc.BlockNumber
+		numbers    []uint64
+	}{
+		{rpc.FinalizedBlockNumber, rpc.LatestBlockNumber, []uint64{5, 8}},
+		{0, rpc.FinalizedBlockNumber, []uint64{2, 5}},
+		{rpc.FinalizedBlockNumber, rpc.FinalizedBlockNumber, []uint64{5}},
+		{6, rpc.FinalizedBlockNumber, nil},
+	}
+	for i, tt := range tests {
+		logs, err := New(backend, tt.begin.Int64(), tt.end.Int64(), []common.Address{addr}, nil).Logs(context.Background())
+		if err != nil {
+			t.Fatalf("test %d: failed to filter logs: %v", i, err)
+		}
+		var numbers []uint64
+		for _, log := range logs {
+			numbers = append(numbers, log.BlockNumber)
+		}
+		if !reflect.DeepEqual(numbers, tt.numbers) {
+			t.Errorf("test %d: log blocks mismatch: have %v, want %v", i, numbers, tt.numbers)
+		}
+	}
+	// Without a confirmed block nothing can be filtered up to it
+	backend.finalized = 11
+	if logs, _ := New(backend, 0, rpc.FinalizedBlockNumber.Int64(), []common.Address{addr}, nil).Logs(context.Background()); len(logs) != 0 {
+		t.Errorf("logs returned for an unknown finalized block: %v", logs)
+	}
+}
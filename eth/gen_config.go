@@ -4,6 +4,7 @@ package eth
 
 import (
 	"math/big"
+	"time"
 
 	"github.com/CarLiveChainCo/goiov/common"
 	"github.com/CarLiveChainCo/goiov/common/hexutil"
@@ -33,6 +34,10 @@ func (c Config) MarshalTOML() (interface{}, error) {
 		TxPool                  core.TxPoolConfig
 		GPO                     gasprice.Config
 		EnablePreimageRecording bool
+		AlienRewardIndex        bool     `toml:",omitempty"`
+		AlienRewardRetain       uint64   `toml:",omitempty"`
+		AppChains               []string `toml:",omitempty"`
+		RPCEVMTimeout           time.Duration
 		DocRoot                 string `toml:"-"`
 	}
 	var enc Config
@@ -52,6 +57,10 @@ func (c Config) MarshalTOML() (interface{}, error) {
 	enc.TxPool = c.TxPool
 	enc.GPO = c.GPO
 	enc.EnablePreimageRecording = c.EnablePreimageRecording
+	enc.AlienRewardIndex = c.AlienRewardIndex
+	enc.AlienRewardRetain = c.AlienRewardRetain
+	enc.AppChains = c.AppChains
+	enc.RPCEVMTimeout = c.RPCEVMTimeout
 	enc.DocRoot = c.DocRoot
 	return &enc, nil
 }
@@ -74,6 +83,10 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 		TxPool                  *core.TxPoolConfig
 		GPO                     *gasprice.Config
 		EnablePreimageRecording *bool
+		AlienRewardIndex        *bool    `toml:",omitempty"`
+		AlienRewardRetain       *uint64  `toml:",omitempty"`
+		AppChains               []string `toml:",omitempty"`
+		RPCEVMTimeout           *time.Duration
 		DocRoot                 *string `toml:"-"`
 	}
 	var dec Config
@@ -128,6 +141,18 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.EnablePreimageRecording != nil {
 		c.EnablePreimageRecording = *dec.EnablePreimageRecording
 	}
+	if dec.AlienRewardIndex != nil {
+		c.AlienRewardIndex = *dec.AlienRewardIndex
+	}
+	if dec.AlienRewardRetain != nil {
+		c.AlienRewardRetain = *dec.AlienRewardRetain
+	}
+	if dec.AppChains != nil {
+		c.AppChains = dec.AppChains
+	}
+	if dec.RPCEVMTimeout != nil {
+		c.RPCEVMTimeout = *dec.RPCEVMTimeout
+	}
 	if dec.DocRoot != nil {
 		c.DocRoot = *dec.DocRoot
 	}
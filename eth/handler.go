@@ -22,12 +22,14 @@ import (
 	"fmt"
 	"math"
 	"math/big"
+	"sort"
 	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/CarLiveChainCo/goiov/common"
 	"github.com/CarLiveChainCo/goiov/consensus"
+	"github.com/CarLiveChainCo/goiov/consensus/alien"
 	"github.com/CarLiveChainCo/goiov/core"
 	"github.com/CarLiveChainCo/goiov/core/rawdb"
 	"github.com/CarLiveChainCo/goiov/core/types"
@@ -53,6 +55,10 @@ const (
 	// txChanSize是监听NewTxsEvent的通道的大小。
 	// 这个数字是从tx池的大小引用的。
 	txChanSize = 4096
+
+	// confirmChanSize is the size of channel listening to the block confirmations
+	// of the alien engine.
+	confirmChanSize = 256
 )
 
 var (
@@ -92,6 +98,8 @@ type ProtocolManager struct {
 	txsCh         chan core.NewTxsEvent
 	txsSub        event.Subscription
 	minedBlockSub *event.TypeMuxSubscription
+	confirmCh     chan *alien.SignedConfirmation
+	confirmSub    event.Subscription
 
 	// channels for fetcher, syncer, txsyncLoop
 	newPeerCh   chan *peer
@@ -279,6 +287,13 @@ func (pm *ProtocolManager) Start(maxPeers int) {
 	// broadcast mined blocks
 	pm.minedBlockSub = pm.eventMux.Subscribe(core.NewMinedBlockEvent{})
 	go pm.minedBroadcastLoop()
+
+	// broadcast block confirmations
+	if confirmer, ok := pm.blockchain.Engine().(*alien.Alien); ok {
+		pm.confirmCh = make(chan *alien.SignedConfirmation, confirmChanSize)
+		pm.confirmSub = confirmer.SubscribeConfirmations(pm.confirmCh)
+		go pm.confirmBroadcastLoop()
+	}
 	// start sync handlers
 	go pm.syncer("")
 	for id := range pm.SideChains {
@@ -292,6 +307,9 @@ func (pm *ProtocolManager) Stop() {
 
 	pm.txsSub.Unsubscribe()        // quits txBroadcastLoop
 	pm.minedBlockSub.Unsubscribe() // quits blockBroadcastLoop
+	if pm.confirmSub != nil {
+		pm.confirmSub.Unsubscribe() // quits confirmBroadcastLoop
+	}
 	// Quit the sync loop.
 	// After this send has completed, no new peers will be accepted.
 	for _, p := range pm.noMorePeers {
@@ -335,7 +353,7 @@ func (pm *ProtocolManager) handle(p *peer) error {
 		number  = head.Number.Uint64()
 		td      = pm.blockchain.GetTd(hash, number)
 	)
-	if err := p.Handshake(pm.networkId, td, hash, genesis.Hash()); err != nil {
+	if err := p.Handshake(pm.networkId, td, hash, genesis.Hash(), pm.appChainHeads()); err != nil {
 		p.Log().Debug("Ethereum handshake failed", "err", err)
 		return err
 	}
@@ -354,7 +372,10 @@ func (pm *ProtocolManager) handle(p *peer) error {
 	if err := pm.downloader.RegisterPeer(p.id, p.version, p); err != nil {
 		return err
 	}
-	for _, dl := range pm.SideDownloader {
+	for id, dl := range pm.SideDownloader {
+		if !p.HostsAppChain(id) {
+			continue
+		}
 		if err := dl.RegisterPeer(p.id, p.version, p); err != nil {
 			return err
 		}
@@ -706,6 +727,10 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 				unknown = append(unknown, block)
 			}
 		}
+		// Only the peers hosting the app chain are asked for its blocks
+		if !p.HostsAppChain(UnJointToAppId(msg.Code)) {
+			break
+		}
 		for _, block := range unknown {
 			pm.fetcher.Notify(p.id, UnJointToAppId(msg.Code), block.Hash, block.Number, time.Now(), p.RequestOneHeader, p.RequestBodies)
 		}
@@ -718,7 +743,7 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 			return errResp(ErrDecode, "%v: %v", msg, err)
 		}
 		chain, err := pm.ExistsAppId(UnJointToAppId(msg.Code))
-		if err != nil {
+		if err != nil || !p.HostsAppChain(UnJointToAppId(msg.Code)) {
 			return nil
 		}
 		request.Block.ReceivedAt = msg.ReceivedAt
@@ -780,6 +805,35 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 				pm.SideTxPool[id].AddRemotes(txs)
 			}
 		}
+	case p.version >= eth64 && msg.Code == ConfirmMsg:
+		confirmer, ok := pm.blockchain.Engine().(*alien.Alien)
+		if !ok {
+			break
+		}
+		var confirmation alien.SignedConfirmation
+		if err := msg.Decode(&confirmation); err != nil {
+			return errResp(ErrDecode, "msg %v: %v", msg, err)
+		}
+		p.MarkConfirmation(confirmation.Hash())
+
+		// Confirmations of blocks we don't have (yet) are dropped, new ones are
+		// propagated through the confirmation broadcast loop
+		if err := confirmer.AddConfirmation(pm.blockchain, &confirmation); err != nil {
+			p.Log().Trace("Discarded block confirmation", "number", confirmation.BlockNumber, "signer", confirmation.Signer, "err", err)
+		}
+
+	case p.version >= eth65 && msg.Code == AppChainsMsg:
+		// The peer joined or left app chains, sync with it the ones it improved on
+		var heads appChainsData
+		if err := msg.Decode(&heads); err != nil {
+			return errResp(ErrDecode, "msg %v: %v", msg, err)
+		}
+		improved := p.SetAppChains(heads)
+		if !p.MarkAppChainsAnnounced() {
+			improved = nil
+		}
+		pm.registerAppChainPeer(p, improved)
+
 	default:
 		return errResp(ErrInvalidMsgCode, "%v", msg.Code)
 	}
@@ -806,7 +860,7 @@ func (pm *ProtocolManager) TxShunt(idTx map[string][]*types.Transaction , tx *ty
 // will only announce it's availability (depending what's requested).
 func (pm *ProtocolManager) BroadcastBlock(block *types.Block, propagate bool) {
 	hash := block.Hash()
-	peers := pm.peers.PeersWithoutBlock(hash)
+	peers := pm.peers.PeersWithoutBlock(hash, block.Header().Appid)
 	chain, err := pm.ExistsAppId(block.Header().Appid)
 	if err != nil {
 		return
@@ -871,6 +925,29 @@ func (pm *ProtocolManager) minedBroadcastLoop() {
 	}
 }
 
+// BroadcastConfirmation will propagate a block confirmation to all peers which
+// are not known to already have it.
+func (pm *ProtocolManager) BroadcastConfirmation(confirmation *alien.SignedConfirmation) {
+	peers := pm.peers.PeersWithoutConfirmation(confirmation.Hash())
+	for _, peer := range peers {
+		peer.AsyncSendConfirmation(confirmation)
+	}
+	log.Trace("Broadcast block confirmation", "number", confirmation.BlockNumber, "signer", confirmation.Signer, "recipients", len(peers))
+}
+
+func (pm *ProtocolManager) confirmBroadcastLoop() {
+	for {
+		select {
+		case confirmation := <-pm.confirmCh:
+			pm.BroadcastConfirmation(confirmation)
+
+			// Err() channel will be closed when unsubscribing.
+		case <-pm.confirmSub.Err():
+			return
+		}
+	}
+}
+
 func (pm *ProtocolManager) txBroadcastLoop() {
 	//log.Info("---打开交易广播循环", "chainAppId", pm.chainconfig.AppId)
 	for {
@@ -907,6 +984,61 @@ func (pm *ProtocolManager) NodeInfo() *NodeInfo {
 	}
 }
 
+// appChainHeads retrieves the heads of the app chains hosted by the node.
+func (pm *ProtocolManager) appChainHeads() []appChainHead {
+	heads := make([]appChainHead, 0, len(pm.SideChains))
+	for appId, chain := range pm.SideChains {
+		if chain == nil {
+			continue
+		}
+		head := chain.CurrentHeader()
+		heads = append(heads, appChainHead{
+			AppId: appId,
+			Head:  head.Hash(),
+			TD:    chain.GetTd(head.Hash(), head.Number.Uint64()),
+		})
+	}
+	sort.Slice(heads, func(i, j int) bool { return heads[i].AppId < heads[j].AppId })
+	return heads
+}
+
+// BroadcastAppChains announces the app chains hosted by the node to all peers
+// of eth/65 and newer, after it joined or left one.
+func (pm *ProtocolManager) BroadcastAppChains() {
+	heads := pm.appChainHeads()
+	for _, p := range pm.peers.PeersWithAppChain("") {
+		if p.version >= eth65 {
+			if err := p.SendAppChains(heads); err != nil {
+				p.Log().Debug("Failed to announce app chains", "err", err)
+			}
+		}
+	}
+	log.Trace("Announced app chains", "count", len(heads))
+}
+
+// registerAppChainPeer registers the peer in the downloaders of the app chains
+// it hosts and unregisters it from the others, then syncs the given improved
+// app chains the peer is ahead on.
+func (pm *ProtocolManager) registerAppChainPeer(p *peer, improved []string) {
+	for appId, dl := range pm.SideDownloader {
+		if !p.HostsAppChain(appId) {
+			dl.UnregisterPeer(p.id)
+			continue
+		}
+		if dl.GetPeers().Peer(p.id) == nil {
+			if err := dl.RegisterPeer(p.id, p.version, p); err != nil {
+				p.Log().Debug("Failed to register app chain peer", "appId", appId, "err", err)
+				continue
+			}
+		}
+	}
+	for _, appId := range improved {
+		if _, ok := pm.SideDownloader[appId]; ok {
+			go pm.synchronise(p, appId)
+		}
+	}
+}
+
 func (pm *ProtocolManager) ExistsAppId(appId string) (*core.BlockChain, error) {
 	if appId != "" {
 		if chain, ok := pm.SideChains[appId]; chain == nil || !ok {
@@ -21,6 +21,7 @@ import (
 	"math/big"
 	"math/rand"
 	"testing"
+	"time"
 
 	"github.com/CarLiveChainCo/goiov/common"
 	"github.com/CarLiveChainCo/goiov/core"
@@ -62,6 +63,49 @@ func TestProtocolCompatibility(t *testing.T) {
 	}
 }
 
+// Tests that the app chains announced by eth/65 peers update the app chains they
+// are known to host.
+func TestAppChainsMsg(t *testing.T) {
+	pm, _ := newTestProtocolManagerMust(t, downloader.FullSync, 0, nil, nil)
+	peer, errc := newTestPeer("peer", eth65, pm, true)
+	defer pm.Stop()
+	defer peer.close()
+
+	// Joining app chains sets their heads
+	head := appChainHead{AppId: "app", Head: common.Hash{1}, TD: big.NewInt(10)}
+	if err := p2p.Send(peer.app, AppChainsMsg, appChainsData{head}); err != nil {
+		t.Fatalf("failed to announce app chains: %v", err)
+	}
+	if !peer.HostsAppChain("app") {
+		t.Fatalf("announced app chain not hosted")
+	}
+	if hash, td := peer.Head("app"); hash != head.Head || td.Cmp(head.TD) != 0 {
+		t.Errorf("app chain head mismatch: have %x, %v, want %x, %v", hash, td, head.Head, head.TD)
+	}
+	// Leaving them drops them, without touching the main chain
+	if err := p2p.Send(peer.app, AppChainsMsg, appChainsData{}); err != nil {
+		t.Fatalf("failed to announce app chains: %v", err)
+	}
+	if peer.HostsAppChain("app") {
+		t.Errorf("left app chain still hosted")
+	}
+	if hash, _ := peer.Head(""); hash != pm.blockchain.CurrentBlock().Hash() {
+		t.Errorf("main chain head mismatch: have %x, want %x", hash, pm.blockchain.CurrentBlock().Hash())
+	}
+	// Malformed announcements drop the peer
+	if err := p2p.Send(peer.app, AppChainsMsg, []uint64{1}); err != nil {
+		t.Fatalf("failed to announce app chains: %v", err)
+	}
+	select {
+	case err := <-errc:
+		if err == nil {
+			t.Errorf("peer not dropped on malformed announcement")
+		}
+	case <-time.After(2 * time.Second):
+		t.Errorf("peer not dropped within 2 seconds")
+	}
+}
+
 // Tests that block headers can be retrieved from a remote chain based on user queries.
 func TestGetBlockHeaders62(t *testing.T) { testGetBlockHeaders(t, 62) }
 func TestGetBlockHeaders63(t *testing.T) { testGetBlockHeaders(t, 63) }
@@ -20,10 +20,12 @@ import (
 	"errors"
 	"fmt"
 	"math/big"
+	"sort"
 	"sync"
 	"time"
 
 	"github.com/CarLiveChainCo/goiov/common"
+	"github.com/CarLiveChainCo/goiov/consensus/alien"
 	"github.com/CarLiveChainCo/goiov/core/types"
 	"github.com/CarLiveChainCo/goiov/p2p"
 	"github.com/CarLiveChainCo/goiov/rlp"
@@ -38,8 +40,9 @@ var (
 )
 
 const (
-	maxKnownTxs    = 32768 // Maximum transactions hashes to keep in the known list (prevent DOS)
-	maxKnownBlocks = 1024  // Maximum block hashes to keep in the known list (prevent DOS)
+	maxKnownTxs      = 32768 // Maximum transactions hashes to keep in the known list (prevent DOS)
+	maxKnownBlocks   = 1024  // Maximum block hashes to keep in the known list (prevent DOS)
+	maxKnownConfirms = 1024  // Maximum confirmation hashes to keep in the known list (prevent DOS)
 
 	// maxQueuedTxs is the maximum number of transaction lists to queue up before
 	// dropping broadcasts. This is a sensitive number as a transaction list might
@@ -56,15 +59,33 @@ const (
 	// above some healthy uncle limit, so use that.
 	maxQueuedAnns = 4
 
+	// maxQueuedConfirms is the maximum number of block confirmations to queue up
+	// before dropping broadcasts. Only the confirmations of the recent blocks are
+	// useful, one per signer.
+	maxQueuedConfirms = 64
+
+	// minAppChainsInterval is the minimum time between two app chains announcements
+	// of a peer triggering syncs. Faster ones still update the hosted app chains,
+	// leaving the syncs to the force sync cycle.
+	minAppChainsInterval = 10 * time.Second
+
 	handshakeTimeout = 5 * time.Second
 )
 
 // PeerInfo represents a short summary of the Ethereum sub-protocol metadata known
 // about a connected peer.
 type PeerInfo struct {
-	Version    int      `json:"version"`    // Ethereum protocol version negotiated
-	Difficulty *big.Int `json:"difficulty"` // Total difficulty of the peer's blockchain
-	Head       string   `json:"head"`       // SHA3 hash of the peer's best owned block
+	Version    int                          `json:"version"`             // Ethereum protocol version negotiated
+	Difficulty *big.Int                     `json:"difficulty"`          // Total difficulty of the peer's blockchain
+	Head       string                       `json:"head"`                // SHA3 hash of the peer's best owned block
+	AppChains  map[string]*AppChainPeerInfo `json:"appChains,omitempty"` // App chains hosted by the peer (eth/65+)
+}
+
+// AppChainPeerInfo represents a short summary of an app chain hosted by a
+// connected peer.
+type AppChainPeerInfo struct {
+	Difficulty *big.Int `json:"difficulty"` // Total difficulty of the peer's app chain
+	Head       string   `json:"head"`       // SHA3 hash of the peer's best owned block of the app chain
 }
 
 // propEvent is a block propagation, waiting for its turn in the broadcast queue.
@@ -82,31 +103,40 @@ type peer struct {
 	version  int         // Protocol version negotiated
 	forkDrop *time.Timer // Timed connection dropper if forks aren't validated in time
 
-	head common.Hash
-	td   map[string]*big.Int
-	lock sync.RWMutex
+	head      map[string]common.Hash
+	td        map[string]*big.Int
+	appChains map[string]struct{} // App chains hosted by the peer, announced from eth/65 on
+	lock      sync.RWMutex
+
+	appChainsAnnounced time.Time // Time of the last app chains announcement triggering syncs
 
-	knownTxs    *set.Set                  // Set of transaction hashes known to be known by this peer
-	knownBlocks *set.Set                  // Set of block hashes known to be known by this peer
-	queuedTxs   chan []*types.Transaction // Queue of transactions to broadcast to the peer
-	queuedProps chan *propEvent           // Queue of blocks to broadcast to the peer
-	queuedAnns  chan *types.Block         // Queue of blocks to announce to the peer
-	term        chan struct{}             // Termination channel to stop the broadcaster
+	knownTxs       *set.Set                       // Set of transaction hashes known to be known by this peer
+	knownBlocks    *set.Set                       // Set of block hashes known to be known by this peer
+	knownConfirms  *set.Set                       // Set of confirmation hashes known to be known by this peer
+	queuedTxs      chan []*types.Transaction      // Queue of transactions to broadcast to the peer
+	queuedProps    chan *propEvent                // Queue of blocks to broadcast to the peer
+	queuedAnns     chan *types.Block              // Queue of blocks to announce to the peer
+	queuedConfirms chan *alien.SignedConfirmation // Queue of block confirmations to broadcast to the peer
+	term           chan struct{}                  // Termination channel to stop the broadcaster
 }
 
 func newPeer(version int, p *p2p.Peer, rw p2p.MsgReadWriter) *peer {
 	return &peer{
-		Peer:        p,
-		rw:          rw,
-		version:     version,
-		id:          fmt.Sprintf("%x", p.ID().Bytes()[:8]),
-		knownTxs:    set.New(),
-		knownBlocks: set.New(),
-		queuedTxs:   make(chan []*types.Transaction, maxQueuedTxs),
-		queuedProps: make(chan *propEvent, maxQueuedProps),
-		queuedAnns:  make(chan *types.Block, maxQueuedAnns),
-		term:        make(chan struct{}),
-		td:          make(map[string]*big.Int),
+		Peer:           p,
+		rw:             rw,
+		version:        version,
+		id:             fmt.Sprintf("%x", p.ID().Bytes()[:8]),
+		knownTxs:       set.New(),
+		knownBlocks:    set.New(),
+		knownConfirms:  set.New(),
+		queuedTxs:      make(chan []*types.Transaction, maxQueuedTxs),
+		queuedProps:    make(chan *propEvent, maxQueuedProps),
+		queuedAnns:     make(chan *types.Block, maxQueuedAnns),
+		queuedConfirms: make(chan *alien.SignedConfirmation, maxQueuedConfirms),
+		term:           make(chan struct{}),
+		head:           make(map[string]common.Hash),
+		td:             make(map[string]*big.Int),
+		appChains:      make(map[string]struct{}),
 	}
 }
 
@@ -134,6 +164,12 @@ func (p *peer) broadcast() {
 			}
 			p.Log().Trace("Announced block", "number", block.Number(), "hash", block.Hash())
 
+		case confirmation := <-p.queuedConfirms:
+			if err := p.SendConfirmation(confirmation); err != nil {
+				return
+			}
+			p.Log().Trace("Broadcast confirmation", "number", confirmation.BlockNumber, "signer", confirmation.Signer)
+
 		case <-p.term:
 			return
 		}
@@ -149,33 +185,114 @@ func (p *peer) close() {
 func (p *peer) Info() *PeerInfo {
 	hash, td := p.Head("")
 
-	return &PeerInfo{
+	info := &PeerInfo{
 		Version:    p.version,
 		Difficulty: td,
 		Head:       hash.Hex(),
 	}
+	for _, appId := range p.AppChains() {
+		if info.AppChains == nil {
+			info.AppChains = make(map[string]*AppChainPeerInfo)
+		}
+		hash, td := p.Head(appId)
+		info.AppChains[appId] = &AppChainPeerInfo{Difficulty: td, Head: hash.Hex()}
+	}
+	return info
 }
 
 // Head retrieves a copy of the current head hash and total difficulty of the
-// peer.
+// peer's chain appId.
 func (p *peer) Head(appId string) (hash common.Hash, td *big.Int) {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	if t := p.td[appId]; t != nil {
+		return p.head[appId], new(big.Int).Set(t)
+	}
+	return p.head[appId], new(big.Int)
+}
+
+// SetHead updates the head hash and total difficulty of the peer's chain appId.
+func (p *peer) SetHead(hash common.Hash, td *big.Int, appId string) {
 	p.lock.Lock()
 	defer p.lock.Unlock()
 
-	copy(hash[:], p.head[:])
-	if t, ok := p.td[appId]; t == nil || !ok {
-		p.td[appId] = new(big.Int)
+	p.head[appId], p.td[appId] = hash, new(big.Int).Set(td)
+}
+
+// HostsAppChain reports whether the peer hosts the app chain appId. The peers
+// of eth/64 and older don't announce their app chains and are assumed to host
+// all of them.
+func (p *peer) HostsAppChain(appId string) bool {
+	if appId == "" || p.version < eth65 {
+		return true
 	}
-	return hash, new(big.Int).Set(p.td[appId])
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	_, ok := p.appChains[appId]
+	return ok
 }
 
-// SetHead updates the head hash and total difficulty of the peer.
-func (p *peer) SetHead(hash common.Hash, td *big.Int, appId string) {
+// AppChains retrieves the appIds of the app chains the peer announced to host.
+func (p *peer) AppChains() []string {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	appIds := make([]string, 0, len(p.appChains))
+	for appId := range p.appChains {
+		appIds = append(appIds, appId)
+	}
+	sort.Strings(appIds)
+	return appIds
+}
+
+// SetAppChains replaces the app chains hosted by the peer with the announced
+// ones, updating their heads and total difficulties. It returns the appIds of
+// the app chains the peer newly joined or advertised a higher total difficulty
+// on.
+func (p *peer) SetAppChains(heads []appChainHead) []string {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	prev := make(map[string]*big.Int, len(p.appChains))
+	for appId := range p.appChains {
+		prev[appId] = p.td[appId]
+		delete(p.head, appId)
+		delete(p.td, appId)
+	}
+	p.appChains = make(map[string]struct{})
+
+	var improved []string
+	for _, head := range heads {
+		if head.AppId == "" {
+			continue
+		}
+		p.appChains[head.AppId] = struct{}{}
+		if head.TD == nil {
+			continue
+		}
+		p.head[head.AppId], p.td[head.AppId] = head.Head, new(big.Int).Set(head.TD)
+		if td, ok := prev[head.AppId]; !ok || td == nil || head.TD.Cmp(td) > 0 {
+			improved = append(improved, head.AppId)
+		}
+	}
+	return improved
+}
+
+// MarkAppChainsAnnounced records an app chains announcement of the peer and
+// reports whether it arrived at least minAppChainsInterval after the previous
+// one.
+func (p *peer) MarkAppChainsAnnounced() bool {
 	p.lock.Lock()
 	defer p.lock.Unlock()
 
-	copy(p.head[:], hash[:])
-	p.td[appId].Set(td)
+	now := time.Now()
+	if now.Sub(p.appChainsAnnounced) < minAppChainsInterval {
+		return false
+	}
+	p.appChainsAnnounced = now
+	return true
 }
 
 // MarkBlock marks a block as known for the peer, ensuring that the block will
@@ -198,6 +315,40 @@ func (p *peer) MarkTransaction(hash common.Hash) {
 	p.knownTxs.Add(hash)
 }
 
+// MarkConfirmation marks a block confirmation as known for the peer, ensuring
+// that it will never be propagated to this particular peer.
+func (p *peer) MarkConfirmation(hash common.Hash) {
+	// If we reached the memory allowance, drop a previously known confirmation hash
+	for p.knownConfirms.Size() >= maxKnownConfirms {
+		p.knownConfirms.Pop()
+	}
+	p.knownConfirms.Add(hash)
+}
+
+// SendConfirmation sends a block confirmation to the peer and includes its hash
+// in its confirmation hash set for future reference.
+func (p *peer) SendConfirmation(confirmation *alien.SignedConfirmation) error {
+	p.knownConfirms.Add(confirmation.Hash())
+	return p2p.Send(p.rw, ConfirmMsg, confirmation)
+}
+
+// AsyncSendConfirmation queues a block confirmation for propagation to a remote
+// peer. If the peer's broadcast queue is full, the event is silently dropped.
+func (p *peer) AsyncSendConfirmation(confirmation *alien.SignedConfirmation) {
+	select {
+	case p.queuedConfirms <- confirmation:
+		p.knownConfirms.Add(confirmation.Hash())
+	default:
+		p.Log().Debug("Dropping confirmation propagation", "number", confirmation.BlockNumber, "signer", confirmation.Signer)
+	}
+}
+
+// SendAppChains announces the app chains hosted by the node along with their
+// heads to the peer.
+func (p *peer) SendAppChains(heads []appChainHead) error {
+	return p2p.Send(p.rw, AppChainsMsg, appChainsData(heads))
+}
+
 // SendTransactions sends transactions to the peer and includes the hashes
 // in its transaction hash set for future reference.
 func (p *peer) SendTransactions(txs types.Transactions) error {
@@ -334,13 +485,17 @@ func (p *peer) RequestReceipts(hashes []common.Hash, appId string) error {
 }
 
 // Handshake executes the eth protocol handshake, negotiating version number,
-// network IDs, difficulties, head and genesis blocks.
+// network IDs, difficulties, head and genesis blocks, and from eth/65 on the
+// app chains hosted.
 //握手执行eth协议握手、协商版本号、网络id、难点、head和genesis块。
-func (p *peer) Handshake(network uint64, td *big.Int, head common.Hash, genesis common.Hash) error {
+func (p *peer) Handshake(network uint64, td *big.Int, head common.Hash, genesis common.Hash, appChains []appChainHead) error {
 	// Send out own handshake in a new thread
 	errc := make(chan error, 2)
 	var status statusData // safe to read after two values have been received from errc
 
+	if p.version < eth65 {
+		appChains = nil
+	}
 	go func() {
 		errc <- p2p.Send(p.rw, StatusMsg, &statusData{
 			ProtocolVersion: uint32(p.version),
@@ -348,6 +503,7 @@ func (p *peer) Handshake(network uint64, td *big.Int, head common.Hash, genesis
 			TD:              td,
 			CurrentBlock:    head,
 			GenesisBlock:    genesis,
+			AppChains:       appChains,
 		})
 	}()
 	go func() {
@@ -365,7 +521,10 @@ func (p *peer) Handshake(network uint64, td *big.Int, head common.Hash, genesis
 			return p2p.DiscReadTimeout
 		}
 	}
-	p.td[""], p.head = status.TD, status.CurrentBlock
+	p.td[""], p.head[""] = status.TD, status.CurrentBlock
+	if p.version >= eth65 {
+		p.SetAppChains(status.AppChains)
+	}
 	return nil
 }
 
@@ -470,15 +629,29 @@ func (ps *peerSet) Len() int {
 	return len(ps.peers)
 }
 
-// PeersWithoutBlock retrieves a list of peers that do not have a given block in
-// their set of known hashes.
-func (ps *peerSet) PeersWithoutBlock(hash common.Hash) []*peer {
+// PeersWithAppChain retrieves a list of peers hosting the app chain appId.
+func (ps *peerSet) PeersWithAppChain(appId string) []*peer {
+	ps.lock.RLock()
+	defer ps.lock.RUnlock()
+
+	list := make([]*peer, 0, len(ps.peers))
+	for _, p := range ps.peers {
+		if p.HostsAppChain(appId) {
+			list = append(list, p)
+		}
+	}
+	return list
+}
+
+// PeersWithoutBlock retrieves a list of peers hosting the chain appId that do
+// not have a given block in their set of known hashes.
+func (ps *peerSet) PeersWithoutBlock(hash common.Hash, appId string) []*peer {
 	ps.lock.RLock()
 	defer ps.lock.RUnlock()
 
 	list := make([]*peer, 0, len(ps.peers))
 	for _, p := range ps.peers {
-		if !p.knownBlocks.Has(hash) {
+		if p.HostsAppChain(appId) && !p.knownBlocks.Has(hash) {
 			list = append(list, p)
 		}
 	}
@@ -500,7 +673,23 @@ func (ps *peerSet) PeersWithoutTx(hash common.Hash) []*peer {
 	return list
 }
 
-// BestPeer retrieves the known peer with the currently highest total difficulty.
+// PeersWithoutConfirmation retrieves a list of peers able to receive block
+// confirmations that do not have a given confirmation in their set of known hashes.
+func (ps *peerSet) PeersWithoutConfirmation(hash common.Hash) []*peer {
+	ps.lock.RLock()
+	defer ps.lock.RUnlock()
+
+	list := make([]*peer, 0, len(ps.peers))
+	for _, p := range ps.peers {
+		if p.version >= eth64 && !p.knownConfirms.Has(hash) {
+			list = append(list, p)
+		}
+	}
+	return list
+}
+
+// BestPeer retrieves the known peer hosting the chain appId with the currently
+// highest total difficulty of it.
 func (ps *peerSet) BestPeer(appId string) *peer {
 	ps.lock.RLock()
 	defer ps.lock.RUnlock()
@@ -510,6 +699,9 @@ func (ps *peerSet) BestPeer(appId string) *peer {
 		bestTd   *big.Int
 	)
 	for _, p := range ps.peers {
+		if !p.HostsAppChain(appId) {
+			continue
+		}
 		if _, td := p.Head(appId); bestPeer == nil || td.Cmp(bestTd) > 0 {
 			bestPeer, bestTd = p, td
 		}
@@ -32,16 +32,18 @@ import (
 const (
 	eth62 = 62
 	eth63 = 63
+	eth64 = 64
+	eth65 = 65
 )
 
 // ProtocolName is the official short name of the protocol used during capability negotiation.
 var ProtocolName = "eth"
 
 // ProtocolVersions are the upported versions of the eth protocol (first is primary).
-var ProtocolVersions = []uint{eth63, eth62}
+var ProtocolVersions = []uint{eth65, eth64, eth63, eth62}
 
 // ProtocolLengths are the number of implemented message corresponding to different protocol versions.
-var ProtocolLengths = []uint64{17, 8}
+var ProtocolLengths = []uint64{19, 18, 17, 8}
 
 const ProtocolMaxMsgSize = 10 * 1024 * 1024 // Maximum cap on the size of a protocol message
 
@@ -62,6 +64,12 @@ const (
 	NodeDataMsg    = 0x0e
 	GetReceiptsMsg = 0x0f
 	ReceiptsMsg    = 0x10
+
+	// Protocol messages belonging to eth/64
+	ConfirmMsg = 0x11
+
+	// Protocol messages belonging to eth/65
+	AppChainsMsg = 0x12
 )
 
 type errCode int
@@ -116,8 +124,20 @@ type statusData struct {
 	TD              *big.Int
 	CurrentBlock    common.Hash
 	GenesisBlock    common.Hash
+	AppChains       []appChainHead `rlp:"tail"` // App chains hosted, announced from eth/65 on
 }
 
+// appChainHead is the head of an app chain hosted by a node.
+type appChainHead struct {
+	AppId string      // AppId of the app chain
+	Head  common.Hash // Hash of the best block of the app chain
+	TD    *big.Int    // Total difficulty of the app chain
+}
+
+// appChainsData is the network packet for the app chains hosted by a node,
+// sent whenever it joins or leaves one.
+type appChainsData []appChainHead
+
 // newBlockHashesData is the network packet for the block announcements.
 type newBlockHashesData []struct {
 	Hash   common.Hash // Hash of one particular block being announced
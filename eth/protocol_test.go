@@ -18,6 +18,8 @@ package eth
 
 import (
 	"fmt"
+	"math/big"
+	"reflect"
 	"sync"
 	"testing"
 	"time"
@@ -27,6 +29,7 @@ import (
 	"github.com/CarLiveChainCo/goiov/crypto"
 	"github.com/CarLiveChainCo/goiov/eth/downloader"
 	"github.com/CarLiveChainCo/goiov/p2p"
+	"github.com/CarLiveChainCo/goiov/p2p/discover"
 	"github.com/CarLiveChainCo/goiov/rlp"
 )
 
@@ -59,15 +62,15 @@ func testStatusMsgErrors(t *testing.T, protocol int) {
 			wantError: errResp(ErrNoStatusMsg, "first msg has code 2 (!= 0)"),
 		},
 		{
-			code: StatusMsg, data: statusData{10, DefaultConfig.NetworkId, td, head.Hash(), genesis.Hash()},
+			code: StatusMsg, data: statusData{10, DefaultConfig.NetworkId, td, head.Hash(), genesis.Hash(), nil},
 			wantError: errResp(ErrProtocolVersionMismatch, "10 (!= %d)", protocol),
 		},
 		{
-			code: StatusMsg, data: statusData{uint32(protocol), 999, td, head.Hash(), genesis.Hash()},
+			code: StatusMsg, data: statusData{uint32(protocol), 999, td, head.Hash(), genesis.Hash(), nil},
 			wantError: errResp(ErrNetworkIdMismatch, "999 (!= 1)"),
 		},
 		{
-			code: StatusMsg, data: statusData{uint32(protocol), DefaultConfig.NetworkId, td, head.Hash(), common.Hash{3}},
+			code: StatusMsg, data: statusData{uint32(protocol), DefaultConfig.NetworkId, td, head.Hash(), common.Hash{3}, nil},
 			wantError: errResp(ErrGenesisBlockMismatch, "0300000000000000 (!= %x)", genesis.Hash().Bytes()[:8]),
 		},
 	}
@@ -92,6 +95,124 @@ func testStatusMsgErrors(t *testing.T, protocol int) {
 	}
 }
 
+// legacyStatusData is the status packet of the eth/64 and older peers, predating
+// the app chains announcement.
+type legacyStatusData struct {
+	ProtocolVersion uint32
+	NetworkId       uint64
+	TD              *big.Int
+	CurrentBlock    common.Hash
+	GenesisBlock    common.Hash
+}
+
+// Tests that the app chains are only exchanged in the handshake from eth/65 on,
+// keeping the status packet decodable by the older peers.
+func TestAppChainsHandshake64(t *testing.T) { testAppChainsHandshake(t, 64) }
+func TestAppChainsHandshake65(t *testing.T) { testAppChainsHandshake(t, 65) }
+
+func testAppChainsHandshake(t *testing.T, protocol int) {
+	var (
+		genesis = common.Hash{1}
+		head    = common.Hash{2}
+		td      = big.NewInt(100)
+		local   = []appChainHead{{AppId: "local", Head: common.Hash{3}, TD: big.NewInt(10)}}
+		remote  = []appChainHead{{AppId: "remote", Head: common.Hash{4}, TD: big.NewInt(20)}}
+	)
+	app, net := p2p.MsgPipe()
+	defer app.Close()
+
+	p := newPeer(protocol, p2p.NewPeer(discover.NodeID{1}, "peer", nil), net)
+
+	errc := make(chan error, 1)
+	go func() {
+		errc <- p.Handshake(DefaultConfig.NetworkId, td, head, genesis, local)
+	}()
+	msg, err := app.ReadMsg()
+	if err != nil {
+		t.Fatalf("status recv: %v", err)
+	}
+	if protocol < eth65 {
+		var status legacyStatusData
+		if err := msg.Decode(&status); err != nil {
+			t.Fatalf("failed to decode status as eth/%d: %v", protocol, err)
+		}
+	} else {
+		var status statusData
+		if err := msg.Decode(&status); err != nil {
+			t.Fatalf("failed to decode status: %v", err)
+		}
+		if len(status.AppChains) != 1 || status.AppChains[0].AppId != "local" {
+			t.Fatalf("app chains mismatch: have %v, want %v", status.AppChains, local)
+		}
+	}
+	if err := p2p.Send(app, StatusMsg, &statusData{
+		ProtocolVersion: uint32(protocol),
+		NetworkId:       DefaultConfig.NetworkId,
+		TD:              td,
+		CurrentBlock:    head,
+		GenesisBlock:    genesis,
+		AppChains:       remote,
+	}); err != nil {
+		t.Fatalf("status send: %v", err)
+	}
+	if err := <-errc; err != nil {
+		t.Fatalf("handshake failed: %v", err)
+	}
+	// The older peers are assumed to host all app chains
+	if !p.HostsAppChain("remote") {
+		t.Errorf("announced app chain not hosted")
+	}
+	if hosted := p.HostsAppChain("other"); hosted != (protocol < eth65) {
+		t.Errorf("unannounced app chain hosted: have %v, want %v", hosted, protocol < eth65)
+	}
+	hash, ptd := p.Head("remote")
+	if protocol < eth65 {
+		if hash != (common.Hash{}) || ptd.Sign() != 0 {
+			t.Errorf("app chain head set from eth/%d status: %x, %v", protocol, hash, ptd)
+		}
+	} else if hash != remote[0].Head || ptd.Cmp(remote[0].TD) != 0 {
+		t.Errorf("app chain head mismatch: have %x, %v, want %x, %v", hash, ptd, remote[0].Head, remote[0].TD)
+	}
+}
+
+// Tests that the app chains announcements report the app chains to sync, and
+// that the announcements triggering syncs are rate limited.
+func TestSetAppChains(t *testing.T) {
+	p := newPeer(eth65, p2p.NewPeer(discover.NodeID{1}, "peer", nil), nil)
+
+	tests := []struct {
+		heads    []appChainHead
+		improved []string
+	}{
+		// Joined app chains are synced
+		{[]appChainHead{{AppId: "a", TD: big.NewInt(1)}, {AppId: "b", TD: big.NewInt(1)}}, []string{"a", "b"}},
+		// Unchanged and lower total difficulties are not
+		{[]appChainHead{{AppId: "a", TD: big.NewInt(1)}, {AppId: "b", TD: big.NewInt(2)}}, []string{"b"}},
+		{[]appChainHead{{AppId: "a", TD: big.NewInt(0)}, {AppId: "b", TD: big.NewInt(2)}}, nil},
+		// Left app chains are dropped, rejoined ones synced again
+		{[]appChainHead{{AppId: "b", TD: big.NewInt(2)}}, nil},
+		{[]appChainHead{{AppId: "a", TD: big.NewInt(1)}, {AppId: "b", TD: big.NewInt(2)}}, []string{"a"}},
+	}
+	for i, tt := range tests {
+		if improved := p.SetAppChains(tt.heads); !reflect.DeepEqual(improved, tt.improved) {
+			t.Errorf("test %d: improved app chains mismatch: have %v, want %v", i, improved, tt.improved)
+		}
+		if appIds := p.AppChains(); len(appIds) != len(tt.heads) {
+			t.Errorf("test %d: hosted app chains mismatch: have %v, want %d", i, appIds, len(tt.heads))
+		}
+	}
+	if !p.MarkAppChainsAnnounced() {
+		t.Fatalf("first announcement rate limited")
+	}
+	if p.MarkAppChainsAnnounced() {
+		t.Fatalf("immediate announcement not rate limited")
+	}
+	p.appChainsAnnounced = time.Now().Add(-minAppChainsInterval)
+	if !p.MarkAppChainsAnnounced() {
+		t.Fatalf("announcement after interval rate limited")
+	}
+}
+
 // This test checks that received transactions are added to the local pool.
 func TestRecvTransactions62(t *testing.T) { testRecvTransactions(t, 62) }
 func TestRecvTransactions63(t *testing.T) { testRecvTransactions(t, 63) }
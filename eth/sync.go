@@ -205,6 +205,7 @@ func (pm *ProtocolManager) synchronise(peer *peer, appId string) {
 		// bad block) rolled back a fast sync node below the sync point. In this case
 		// however it's safe to reenable fast sync.
 		atomic.StoreUint32(&fs, 1)
+		pm.fastSync[appId] = fs
 		mode = downloader.FastSync
 	}
 
@@ -222,6 +223,7 @@ func (pm *ProtocolManager) synchronise(peer *peer, appId string) {
 	if atomic.LoadUint32(&fs) == 1 {
 		log.Info("Fast sync complete, auto disabling")
 		atomic.StoreUint32(&fs, 0)
+		pm.fastSync[appId] = fs
 	}
 	atomic.StoreUint32(&pm.acceptTxs, 1) // Mark initial sync done
 	if head := chain.CurrentBlock(); head.NumberU64() > 0 {
@@ -366,7 +366,11 @@ func New(code string) (*Tracer, error) {
 		return 1
 	})
 	tracer.vm.PushGlobalGoFunction("isPrecompiled", func(ctx *duktape.Context) int {
-		_, ok := vm.PrecompiledContractsByzantium[common.BytesToAddress(popSlice(ctx))]
+		addr := common.BytesToAddress(popSlice(ctx))
+		_, ok := vm.PrecompiledContractsByzantium[addr]
+		if !ok {
+			_, ok = vm.PrecompiledContractsBridge[addr]
+		}
 		ctx.PushBoolean(ok)
 		return 1
 	})
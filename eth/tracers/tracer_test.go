@@ -101,6 +101,21 @@ func TestOpcodes(t *testing.T) {
 	}
 }
 
+func TestIsPrecompiled(t *testing.T) {
+	tracer, err := New(`{step: function() {}, fault: function() {}, result: function() { return ["0x0000000000000000000000000000000000000001", "0x0000000000000000000000000000000000000009", "0x000000000000000000000000000000000000000a"].map(function(addr) { return isPrecompiled(toAddress(addr)); }); }}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ret, err := runTrace(tracer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(ret, []byte("[true,true,false]")) {
+		t.Errorf("Expected return value to be [true,true,false], got %s", string(ret))
+	}
+}
+
 func TestHalt(t *testing.T) {
 	t.Skip("duktape doesn't support abortion")
 
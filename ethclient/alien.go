@@ -0,0 +1,360 @@
+// Copyright 2018 The giov Authors
+// This file is part of the giov library.
+//
+// The giov library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The giov library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the giov library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethclient
+
+import (
+	"context"
+	"math/big"
+
+	"github.com/CarLiveChainCo/goiov/common"
+	"github.com/CarLiveChainCo/goiov/common/hexutil"
+	"github.com/CarLiveChainCo/goiov/consensus/alien"
+	"github.com/CarLiveChainCo/goiov/rpc"
+)
+
+// AlienClient defines typed wrappers for the alien consensus RPC API. Every
+// method querying the main chain has a GetSideX counterpart taking the appId of
+// the side chain to query.
+type AlienClient struct {
+	c *rpc.Client
+}
+
+// NewAlienClient creates an alien client that uses the given RPC client.
+func NewAlienClient(c *rpc.Client) *AlienClient {
+	return &AlienClient{c}
+}
+
+// Alien returns the alien consensus client sharing the connection of the client.
+func (ec *Client) Alien() *AlienClient {
+	return NewAlienClient(ec.c)
+}
+
+// Snapshots
+
+// GetSnapshot retrieves the state snapshot at the given block, the latest one if
+// number is nil.
+func (ac *AlienClient) GetSnapshot(ctx context.Context, number *big.Int) (*alien.Snapshot, error) {
+	var snap *alien.Snapshot
+	err := ac.c.CallContext(ctx, &snap, "alien_getSnapshot", toBlockNumArg(number))
+	return snap, err
+}
+
+// GetSideSnapshot retrieves the state snapshot at the head of the side chain.
+func (ac *AlienClient) GetSideSnapshot(ctx context.Context, appId string) (*alien.Snapshot, error) {
+	var snap *alien.Snapshot
+	err := ac.c.CallContext(ctx, &snap, "alien_getSideSnapshot", appId)
+	return snap, err
+}
+
+// GetSnapshotAtHash retrieves the state snapshot at the block with the given hash.
+func (ac *AlienClient) GetSnapshotAtHash(ctx context.Context, hash common.Hash) (*alien.Snapshot, error) {
+	var snap *alien.Snapshot
+	err := ac.c.CallContext(ctx, &snap, "alien_getSnapshotAtHash", hash)
+	return snap, err
+}
+
+// GetSnapshotAtNumber retrieves the state snapshot at the given block.
+func (ac *AlienClient) GetSnapshotAtNumber(ctx context.Context, number uint64) (*alien.Snapshot, error) {
+	var snap *alien.Snapshot
+	err := ac.c.CallContext(ctx, &snap, "alien_getSnapshotAtNumber", number)
+	return snap, err
+}
+
+// GetSideSnapshotAtNumber retrieves the state snapshot at the given block of the side chain.
+func (ac *AlienClient) GetSideSnapshotAtNumber(ctx context.Context, number uint64, appId string) (*alien.Snapshot, error) {
+	var snap *alien.Snapshot
+	err := ac.c.CallContext(ctx, &snap, "alien_getSideSnapshotAtNumber", number, appId)
+	return snap, err
+}
+
+// GetSnapshotByHeaderTime retrieves the state snapshot of the block sealed at the
+// given time.
+func (ac *AlienClient) GetSnapshotByHeaderTime(ctx context.Context, targetTime uint64) (*alien.Snapshot, error) {
+	var snap *alien.Snapshot
+	err := ac.c.CallContext(ctx, &snap, "alien_getSnapshotByHeaderTime", targetTime)
+	return snap, err
+}
+
+// Votes and stakes
+
+// GetFreezeBalance retrieves the stake the address froze by voting.
+func (ac *AlienClient) GetFreezeBalance(ctx context.Context, address common.Address) (uint64, error) {
+	var balance uint64
+	err := ac.c.CallContext(ctx, &balance, "alien_getFreezeBalance", address)
+	return balance, err
+}
+
+// GetSideFreezeBalance retrieves the stake the address froze by voting on the side chain.
+func (ac *AlienClient) GetSideFreezeBalance(ctx context.Context, address common.Address, appId string) (uint64, error) {
+	var balance uint64
+	err := ac.c.CallContext(ctx, &balance, "alien_getSideFreezeBalance", address, appId)
+	return balance, err
+}
+
+// GetRemainingFreezeTime retrieves the time left before the stake of the address unfreezes.
+func (ac *AlienClient) GetRemainingFreezeTime(ctx context.Context, address common.Address) (uint64, error) {
+	var remaining uint64
+	err := ac.c.CallContext(ctx, &remaining, "alien_getRemainingFreezeTime", address)
+	return remaining, err
+}
+
+// GetSideRemainingFreezeTime retrieves the time left before the stake of the
+// address unfreezes on the side chain.
+func (ac *AlienClient) GetSideRemainingFreezeTime(ctx context.Context, address common.Address, appId string) (uint64, error) {
+	var remaining uint64
+	err := ac.c.CallContext(ctx, &remaining, "alien_getSideRemainingFreezeTime", address, appId)
+	return remaining, err
+}
+
+// GetVote retrieves the vote cast by the address.
+func (ac *AlienClient) GetVote(ctx context.Context, address common.Address) (*alien.Vote, error) {
+	var vote *alien.Vote
+	err := ac.c.CallContext(ctx, &vote, "alien_getVote", address)
+	return vote, err
+}
+
+// GetSideVote retrieves the vote cast by the address on the side chain.
+func (ac *AlienClient) GetSideVote(ctx context.Context, address common.Address, appId string) (*alien.Vote, error) {
+	var vote *alien.Vote
+	err := ac.c.CallContext(ctx, &vote, "alien_getSideVote", address, appId)
+	return vote, err
+}
+
+// GetTally retrieves the votes received by the candidate.
+func (ac *AlienClient) GetTally(ctx context.Context, address common.Address) (uint64, error) {
+	var tally uint64
+	err := ac.c.CallContext(ctx, &tally, "alien_getTally", address)
+	return tally, err
+}
+
+// GetSideTally retrieves the votes received by the candidate on the side chain.
+func (ac *AlienClient) GetSideTally(ctx context.Context, address common.Address, appId string) (uint64, error) {
+	var tally uint64
+	err := ac.c.CallContext(ctx, &tally, "alien_getSideTally", address, appId)
+	return tally, err
+}
+
+// GetCandidatesAndTally retrieves the candidates with the votes they received.
+func (ac *AlienClient) GetCandidatesAndTally(ctx context.Context) (map[common.Address]*big.Int, error) {
+	var tally map[common.Address]*big.Int
+	err := ac.c.CallContext(ctx, &tally, "alien_getCandidatesAndTally")
+	return tally, err
+}
+
+// GetSideCandidatesAndTally retrieves the candidates of the side chain with the
+// votes they received.
+func (ac *AlienClient) GetSideCandidatesAndTally(ctx context.Context, appId string) (map[common.Address]*big.Int, error) {
+	var tally map[common.Address]*big.Int
+	err := ac.c.CallContext(ctx, &tally, "alien_getSideCandidatesAndTally", appId)
+	return tally, err
+}
+
+// GetUnlockSchedule retrieves the unlocks pending for the address.
+func (ac *AlienClient) GetUnlockSchedule(ctx context.Context, address common.Address) ([]*alien.UnlockItem, error) {
+	var items []*alien.UnlockItem
+	err := ac.c.CallContext(ctx, &items, "alien_getUnlockSchedule", address)
+	return items, err
+}
+
+// GetSideUnlockSchedule retrieves the unlocks pending for the address on the side chain.
+func (ac *AlienClient) GetSideUnlockSchedule(ctx context.Context, address common.Address, appId string) ([]*alien.UnlockItem, error) {
+	var items []*alien.UnlockItem
+	err := ac.c.CallContext(ctx, &items, "alien_getSideUnlockSchedule", address, appId)
+	return items, err
+}
+
+// GetRemainingRedelegateTime retrieves the time left before the address may redelegate.
+func (ac *AlienClient) GetRemainingRedelegateTime(ctx context.Context, address common.Address) (uint64, error) {
+	var remaining uint64
+	err := ac.c.CallContext(ctx, &remaining, "alien_getRemainingRedelegateTime", address)
+	return remaining, err
+}
+
+// GetSideRemainingRedelegateTime retrieves the time left before the address may
+// redelegate on the side chain.
+func (ac *AlienClient) GetSideRemainingRedelegateTime(ctx context.Context, address common.Address, appId string) (uint64, error) {
+	var remaining uint64
+	err := ac.c.CallContext(ctx, &remaining, "alien_getSideRemainingRedelegateTime", address, appId)
+	return remaining, err
+}
+
+// Custom transactions
+
+// GetCustomTxStatus retrieves the outcome of the custom transaction with the given hash.
+func (ac *AlienClient) GetCustomTxStatus(ctx context.Context, hash common.Hash) (*alien.CustomTxStatus, error) {
+	var status *alien.CustomTxStatus
+	err := ac.c.CallContext(ctx, &status, "alien_getCustomTxStatus", hash)
+	return status, err
+}
+
+// GetSideCustomTxStatus retrieves the outcome of the custom transaction with the
+// given hash on the side chain.
+func (ac *AlienClient) GetSideCustomTxStatus(ctx context.Context, hash common.Hash, appId string) (*alien.CustomTxStatus, error) {
+	var status *alien.CustomTxStatus
+	err := ac.c.CallContext(ctx, &status, "alien_getSideCustomTxStatus", hash, appId)
+	return status, err
+}
+
+// Rewards
+
+// GetRewardHistory retrieves the rewards credited to the address in each block
+// of the given range.
+func (ac *AlienClient) GetRewardHistory(ctx context.Context, address common.Address, fromBlock, toBlock uint64) ([]*alien.RewardEntry, error) {
+	var entries []*alien.RewardEntry
+	err := ac.c.CallContext(ctx, &entries, "alien_getRewardHistory", address, fromBlock, toBlock)
+	return entries, err
+}
+
+// GetSideRewardHistory retrieves the rewards credited to the address in each
+// block of the given range on the side chain.
+func (ac *AlienClient) GetSideRewardHistory(ctx context.Context, address common.Address, fromBlock, toBlock uint64, appId string) ([]*alien.RewardEntry, error) {
+	var entries []*alien.RewardEntry
+	err := ac.c.CallContext(ctx, &entries, "alien_getSideRewardHistory", address, fromBlock, toBlock, appId)
+	return entries, err
+}
+
+// GetRewardSummary retrieves the rewards credited to the address during the
+// last day, week, month or year.
+func (ac *AlienClient) GetRewardSummary(ctx context.Context, address common.Address, period string) (*alien.RewardSummary, error) {
+	var summary *alien.RewardSummary
+	err := ac.c.CallContext(ctx, &summary, "alien_getRewardSummary", address, period)
+	return summary, err
+}
+
+// GetSideRewardSummary retrieves the rewards credited to the address during the
+// last day, week, month or year on the side chain.
+func (ac *AlienClient) GetSideRewardSummary(ctx context.Context, address common.Address, period string, appId string) (*alien.RewardSummary, error) {
+	var summary *alien.RewardSummary
+	err := ac.c.CallContext(ctx, &summary, "alien_getSideRewardSummary", address, period, appId)
+	return summary, err
+}
+
+// Signer schedule
+
+// GetSignerSchedule forecasts the next count slots from fromTime on, now if it
+// is 0, with the signers expected to seal their blocks.
+func (ac *AlienClient) GetSignerSchedule(ctx context.Context, fromTime, count uint64) ([]*alien.SignerSlot, error) {
+	var slots []*alien.SignerSlot
+	err := ac.c.CallContext(ctx, &slots, "alien_getSignerSchedule", fromTime, count)
+	return slots, err
+}
+
+// GetSideSignerSchedule forecasts the next count slots from fromTime on of the
+// side chain with the signers expected to seal their blocks.
+func (ac *AlienClient) GetSideSignerSchedule(ctx context.Context, fromTime, count uint64, appId string) ([]*alien.SignerSlot, error) {
+	var slots []*alien.SignerSlot
+	err := ac.c.CallContext(ctx, &slots, "alien_getSideSignerSchedule", fromTime, count, appId)
+	return slots, err
+}
+
+// GetSignerStats retrieves the blocks produced, the slots missed, the block
+// confirmations and the punishment credit history of the signer in the range.
+func (ac *AlienClient) GetSignerStats(ctx context.Context, address common.Address, fromBlock, toBlock uint64) (*alien.SignerStats, error) {
+	var stats *alien.SignerStats
+	err := ac.c.CallContext(ctx, &stats, "alien_getSignerStats", address, fromBlock, toBlock)
+	return stats, err
+}
+
+// GetSideSignerStats retrieves the activity of the signer in the range of
+// blocks of the side chain.
+func (ac *AlienClient) GetSideSignerStats(ctx context.Context, address common.Address, fromBlock, toBlock uint64, appId string) (*alien.SignerStats, error) {
+	var stats *alien.SignerStats
+	err := ac.c.CallContext(ctx, &stats, "alien_getSideSignerStats", address, fromBlock, toBlock, appId)
+	return stats, err
+}
+
+// Governance
+
+// GetProposals retrieves the proposals to change the engine parameters.
+func (ac *AlienClient) GetProposals(ctx context.Context) ([]*alien.ProposalRecord, error) {
+	var proposals []*alien.ProposalRecord
+	err := ac.c.CallContext(ctx, &proposals, "alien_getProposals")
+	return proposals, err
+}
+
+// GetSideProposals retrieves the proposals to change the engine parameters of the side chain.
+func (ac *AlienClient) GetSideProposals(ctx context.Context, appId string) ([]*alien.ProposalRecord, error) {
+	var proposals []*alien.ProposalRecord
+	err := ac.c.CallContext(ctx, &proposals, "alien_getSideProposals", appId)
+	return proposals, err
+}
+
+// GetProposal retrieves the proposal with the given hash.
+func (ac *AlienClient) GetProposal(ctx context.Context, hash common.Hash) (*alien.ProposalRecord, error) {
+	var proposal *alien.ProposalRecord
+	err := ac.c.CallContext(ctx, &proposal, "alien_getProposal", hash)
+	return proposal, err
+}
+
+// GetSideProposal retrieves the proposal with the given hash on the side chain.
+func (ac *AlienClient) GetSideProposal(ctx context.Context, hash common.Hash, appId string) (*alien.ProposalRecord, error) {
+	var proposal *alien.ProposalRecord
+	err := ac.c.CallContext(ctx, &proposal, "alien_getSideProposal", hash, appId)
+	return proposal, err
+}
+
+// Side chains
+
+// GetSideChainCheckpoint retrieves the checkpoint of the given block of the side
+// chain anchored on the main chain, the latest one if number is nil.
+func (ac *AlienClient) GetSideChainCheckpoint(ctx context.Context, appId string, number *big.Int) (*alien.SideCheckpoint, error) {
+	var checkpoint *alien.SideCheckpoint
+	err := ac.c.CallContext(ctx, &checkpoint, "alien_getSideChainCheckpoint", appId, toBlockNumArg(number))
+	return checkpoint, err
+}
+
+// Bridge
+
+// GetBridgeMintData retrieves the data of the side chain transaction minting the
+// value of the given deposit on the main chain.
+func (ac *AlienClient) GetBridgeMintData(ctx context.Context, hash common.Hash) ([]byte, error) {
+	var data hexutil.Bytes
+	err := ac.c.CallContext(ctx, &data, "alien_getBridgeMintData", hash)
+	return data, err
+}
+
+// GetSideBridgeReleaseData retrieves the data of the main chain transaction
+// releasing the value of the given burn on the side chain.
+func (ac *AlienClient) GetSideBridgeReleaseData(ctx context.Context, hash common.Hash, appId string) ([]byte, error) {
+	var data hexutil.Bytes
+	err := ac.c.CallContext(ctx, &data, "alien_getSideBridgeReleaseData", hash, appId)
+	return data, err
+}
+
+// Cross app messages
+
+// GetAppMessageDeliveryData retrieves the data of the transactions delivering
+// the messages sent by the given transaction of the app chain appId.
+func (ac *AlienClient) GetAppMessageDeliveryData(ctx context.Context, hash common.Hash, appId string) ([][]byte, error) {
+	var data []hexutil.Bytes
+	if err := ac.c.CallContext(ctx, &data, "alien_getAppMessageDeliveryData", hash, appId); err != nil {
+		return nil, err
+	}
+	result := make([][]byte, len(data))
+	for i := range data {
+		result[i] = data[i]
+	}
+	return result, nil
+}
+
+// GetAppMessageReceipt retrieves the delivery receipt of the app message with the
+// given hash.
+func (ac *AlienClient) GetAppMessageReceipt(ctx context.Context, hash common.Hash) (*alien.AppMessageReceipt, error) {
+	var receipt *alien.AppMessageReceipt
+	err := ac.c.CallContext(ctx, &receipt, "alien_getAppMessageReceipt", hash)
+	return receipt, err
+}
@@ -201,6 +201,22 @@ func (ec *Client) TransactionByHash(ctx context.Context, hash common.Hash) (tx *
 	return json.tx, json.BlockNumber == nil, nil
 }
 
+// SideTransactionByHash returns the transaction with the given hash of the side
+// chain with the given appId.
+func (ec *Client) SideTransactionByHash(ctx context.Context, appId string, hash common.Hash) (tx *types.Transaction, isPending bool, err error) {
+	var json *rpcTransaction
+	err = ec.c.CallContext(ctx, &json, "eth_getSideTransactionByHash", appId, hash)
+	if err != nil {
+		return nil, false, err
+	} else if json == nil {
+		return nil, false, ethereum.NotFound
+	} else if _, r, _ := json.tx.RawSignatureValues(); r == nil {
+		return nil, false, fmt.Errorf("server returned transaction without signature")
+	}
+	setSenderFromServer(json.tx, json.From, json.BlockHash)
+	return json.tx, json.BlockNumber == nil, nil
+}
+
 // TransactionSender returns the sender address of the given transaction. The transaction
 // must be known to the remote node and included in the blockchain at the given block and
 // index. The sender is the one derived by the protocol at the time of inclusion.
@@ -261,6 +277,20 @@ func (ec *Client) TransactionReceipt(ctx context.Context, txHash common.Hash) (*
 	return r, err
 }
 
+// SideTransactionReceipt returns the receipt of a transaction by transaction hash
+// of the side chain with the given appId.
+// Note that the receipt is not available for pending transactions.
+func (ec *Client) SideTransactionReceipt(ctx context.Context, appId string, txHash common.Hash) (*types.Receipt, error) {
+	var r *types.Receipt
+	err := ec.c.CallContext(ctx, &r, "eth_getSideTransactionReceipt", appId, txHash)
+	if err == nil {
+		if r == nil {
+			return nil, ethereum.NotFound
+		}
+	}
+	return r, err
+}
+
 func toBlockNumArg(number *big.Int) string {
 	if number == nil {
 		return "latest"
@@ -386,6 +416,9 @@ func toFilterArg(q ethereum.FilterQuery) interface{} {
 	if q.FromBlock == nil {
 		arg["fromBlock"] = "0x0"
 	}
+	if q.AppId != "" {
+		arg["appId"] = q.AppId
+	}
 	return arg
 }
 
@@ -509,5 +542,8 @@ func toCallArg(msg ethereum.CallMsg) interface{} {
 	if msg.GasPrice != nil {
 		arg["gasPrice"] = (*hexutil.Big)(msg.GasPrice)
 	}
+	if msg.AppId != "" {
+		arg["appId"] = msg.AppId
+	}
 	return arg
 }
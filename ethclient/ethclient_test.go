@@ -16,7 +16,20 @@
 
 package ethclient
 
-import "github.com/CarLiveChainCo/goiov"
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/CarLiveChainCo/goiov"
+	"github.com/CarLiveChainCo/goiov/common"
+	"github.com/CarLiveChainCo/goiov/common/hexutil"
+	"github.com/CarLiveChainCo/goiov/consensus/alien"
+	"github.com/CarLiveChainCo/goiov/core/types"
+	"github.com/CarLiveChainCo/goiov/rpc"
+)
 
 // Verify that Client implements the ethereum interfaces.
 var (
@@ -32,3 +45,155 @@ var (
 	// _ = ethereum.PendingStateEventer(&Client{})
 	_ = ethereum.PendingContractCaller(&Client{})
 )
+
+// Verify that SideClient implements the ethereum interfaces needed by bound contracts.
+var (
+	_ = ethereum.ChainReader(&SideClient{})
+	_ = ethereum.TransactionReader(&SideClient{})
+	_ = ethereum.ChainStateReader(&SideClient{})
+	_ = ethereum.TransactionSender(&SideClient{})
+	_ = ethereum.ContractCaller(&SideClient{})
+	_ = ethereum.GasEstimator(&SideClient{})
+	_ = ethereum.GasPricer(&SideClient{})
+	_ = ethereum.LogFilterer(&SideClient{})
+	_ = ethereum.PendingStateReader(&SideClient{})
+	_ = ethereum.PendingContractCaller(&SideClient{})
+)
+
+// FakeSideService is an eth namespace backend serving the side chain queries of
+// a single app chain.
+type FakeSideService struct {
+	appId   string
+	balance *big.Int
+}
+
+func (s *FakeSideService) check(appId string) error {
+	if appId != s.appId {
+		return fmt.Errorf("unknown app chain %q", appId)
+	}
+	return nil
+}
+
+func (s *FakeSideService) GetSideBalance(appId string, address common.Address, number rpc.BlockNumber) (*hexutil.Big, error) {
+	if err := s.check(appId); err != nil {
+		return nil, err
+	}
+	if number == rpc.PendingBlockNumber {
+		return (*hexutil.Big)(new(big.Int).Add(s.balance, common.Big1)), nil
+	}
+	return (*hexutil.Big)(s.balance), nil
+}
+
+func (s *FakeSideService) GetSideTransactionCount(appId string, address common.Address, number rpc.BlockNumber) (hexutil.Uint64, error) {
+	return hexutil.Uint64(number.Int64()), s.check(appId)
+}
+
+func (s *FakeSideService) GetSideTransactionReceipt(appId string, hash common.Hash) (map[string]interface{}, error) {
+	return nil, s.check(appId)
+}
+
+func (s *FakeSideService) SideGasPrice(appId string) (*hexutil.Big, error) {
+	return (*hexutil.Big)(big.NewInt(7)), s.check(appId)
+}
+
+func (s *FakeSideService) Call(args map[string]interface{}, number rpc.BlockNumber) (hexutil.Bytes, error) {
+	appId, _ := args["appId"].(string)
+	return hexutil.Bytes(appId), nil
+}
+
+// FakeAlienService is an alien namespace backend serving the checkpoints of a
+// single app chain.
+type FakeAlienService struct {
+	checkpoint *alien.SideCheckpoint
+}
+
+func (s *FakeAlienService) GetSideChainCheckpoint(appId string, number rpc.BlockNumber) (*alien.SideCheckpoint, error) {
+	if appId != s.checkpoint.AppId || (number >= 0 && uint64(number) != s.checkpoint.Number) {
+		return nil, nil
+	}
+	return s.checkpoint, nil
+}
+
+func (s *FakeAlienService) GetAppMessageDeliveryData(hash common.Hash, appId string) ([]hexutil.Bytes, error) {
+	return []hexutil.Bytes{hash[:1], []byte(appId)}, nil
+}
+
+// newTestSideClient creates a client connected in process to the fake eth and
+// alien namespace backends of the app chain "app".
+func newTestSideClient(t *testing.T) (*Client, *FakeSideService, *FakeAlienService) {
+	var (
+		server   = rpc.NewServer()
+		ethApi   = &FakeSideService{appId: "app", balance: big.NewInt(100)}
+		alienApi = &FakeAlienService{checkpoint: &alien.SideCheckpoint{AppId: "app", Number: 10, Hash: common.Hash{1}}}
+	)
+	if err := server.RegisterName("eth", ethApi); err != nil {
+		t.Fatalf("failed to register eth service: %v", err)
+	}
+	if err := server.RegisterName("alien", alienApi); err != nil {
+		t.Fatalf("failed to register alien service: %v", err)
+	}
+	return NewClient(rpc.DialInProc(server)), ethApi, alienApi
+}
+
+// Tests that the side chain view queries the side chain of its appId.
+func TestSideClient(t *testing.T) {
+	client, ethApi, _ := newTestSideClient(t)
+	defer client.Close()
+
+	var (
+		ctx  = context.Background()
+		side = client.Side("app")
+	)
+	if balance, err := side.BalanceAt(ctx, common.Address{}, nil); err != nil || balance.Cmp(ethApi.balance) != 0 {
+		t.Errorf("balance mismatch: have %v, %v, want %v", balance, err, ethApi.balance)
+	}
+	if balance, err := side.PendingBalanceAt(ctx, common.Address{}); err != nil || balance.Int64() != ethApi.balance.Int64()+1 {
+		t.Errorf("pending balance mismatch: have %v, %v, want %d", balance, err, ethApi.balance.Int64()+1)
+	}
+	if nonce, err := side.NonceAt(ctx, common.Address{}, big.NewInt(5)); err != nil || nonce != 5 {
+		t.Errorf("nonce mismatch: have %d, %v, want 5", nonce, err)
+	}
+	if price, err := side.SuggestGasPrice(ctx); err != nil || price.Int64() != 7 {
+		t.Errorf("gas price mismatch: have %v, %v, want 7", price, err)
+	}
+	if res, err := side.CallContract(ctx, ethereum.CallMsg{}, nil); err != nil || string(res) != "app" {
+		t.Errorf("call not run on the side chain: have %q, %v", res, err)
+	}
+	if _, err := side.TransactionReceipt(ctx, common.Hash{}); err != ethereum.NotFound {
+		t.Errorf("missing receipt error mismatch: have %v, want %v", err, ethereum.NotFound)
+	}
+	// Other app chains are queried by their appId
+	if _, err := client.Side("other").BalanceAt(ctx, common.Address{}, nil); err == nil {
+		t.Errorf("unknown app chain queried")
+	}
+	// Transactions of other chains are rejected before reaching the node
+	tx := types.NewTransaction(0, common.Address{}, big.NewInt(1), 21000, big.NewInt(1), nil, "other")
+	if err := side.SendTransaction(ctx, tx); err == nil {
+		t.Errorf("transaction of another app chain sent")
+	}
+}
+
+// Tests that the alien client decodes the responses of the alien namespace.
+func TestAlienClient(t *testing.T) {
+	client, _, alienApi := newTestSideClient(t)
+	defer client.Close()
+
+	ctx := context.Background()
+	checkpoint, err := client.Alien().GetSideChainCheckpoint(ctx, "app", nil)
+	if err != nil {
+		t.Fatalf("failed to retrieve checkpoint: %v", err)
+	}
+	if !reflect.DeepEqual(checkpoint, alienApi.checkpoint) {
+		t.Errorf("checkpoint mismatch: have %+v, want %+v", checkpoint, alienApi.checkpoint)
+	}
+	if checkpoint, err := client.Alien().GetSideChainCheckpoint(ctx, "app", big.NewInt(11)); err != nil || checkpoint != nil {
+		t.Errorf("missing checkpoint mismatch: have %+v, %v, want nil", checkpoint, err)
+	}
+	data, err := client.Alien().GetAppMessageDeliveryData(ctx, common.Hash{2}, "app")
+	if err != nil {
+		t.Fatalf("failed to retrieve delivery data: %v", err)
+	}
+	if want := [][]byte{{2}, []byte("app")}; !reflect.DeepEqual(data, want) {
+		t.Errorf("delivery data mismatch: have %x, want %x", data, want)
+	}
+}
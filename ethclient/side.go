@@ -0,0 +1,258 @@
+// Copyright 2018 The giov Authors
+// This file is part of the giov library.
+//
+// The giov library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The giov library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the giov library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethclient
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/CarLiveChainCo/goiov"
+	"github.com/CarLiveChainCo/goiov/common"
+	"github.com/CarLiveChainCo/goiov/common/hexutil"
+	"github.com/CarLiveChainCo/goiov/core/types"
+)
+
+// SideClient is a view of a Client onto the side chain with a given appId. It
+// implements the same interfaces as Client, so that code written against them,
+// like contracts bound by accounts/abi/bind, runs unchanged on the side chain.
+type SideClient struct {
+	ec    *Client
+	appId string
+}
+
+// Side returns the view of the client onto the side chain with the given appId.
+func (ec *Client) Side(appId string) *SideClient {
+	return &SideClient{ec: ec, appId: appId}
+}
+
+// AppId returns the id of the side chain the client operates on.
+func (sc *SideClient) AppId() string {
+	return sc.appId
+}
+
+// Blockchain Access
+
+// BlockByHash returns the given full block.
+func (sc *SideClient) BlockByHash(ctx context.Context, hash common.Hash) (*types.Block, error) {
+	return sc.ec.getBlock(ctx, "eth_getSideBlockByHash", sc.appId, hash, true)
+}
+
+// BlockByNumber returns a block from the current canonical side chain. If number
+// is nil, the latest known block is returned.
+func (sc *SideClient) BlockByNumber(ctx context.Context, number *big.Int) (*types.Block, error) {
+	return sc.ec.getBlock(ctx, "eth_getSideBlockByNumber", sc.appId, toBlockNumArg(number), true)
+}
+
+// HeaderByHash returns the block header with the given hash.
+func (sc *SideClient) HeaderByHash(ctx context.Context, hash common.Hash) (*types.Header, error) {
+	var head *types.Header
+	err := sc.ec.c.CallContext(ctx, &head, "eth_getSideBlockByHash", sc.appId, hash, false)
+	if err == nil && head == nil {
+		err = ethereum.NotFound
+	}
+	return head, err
+}
+
+// HeaderByNumber returns a block header from the current canonical side chain.
+// If number is nil, the latest known header is returned.
+func (sc *SideClient) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	var head *types.Header
+	err := sc.ec.c.CallContext(ctx, &head, "eth_getSideBlockByNumber", sc.appId, toBlockNumArg(number), false)
+	if err == nil && head == nil {
+		err = ethereum.NotFound
+	}
+	return head, err
+}
+
+// TransactionByHash returns the transaction with the given hash.
+func (sc *SideClient) TransactionByHash(ctx context.Context, hash common.Hash) (tx *types.Transaction, isPending bool, err error) {
+	return sc.ec.SideTransactionByHash(ctx, sc.appId, hash)
+}
+
+// TransactionCount returns the total number of transactions in the given block.
+func (sc *SideClient) TransactionCount(ctx context.Context, blockHash common.Hash) (uint, error) {
+	var block *struct {
+		Transactions []common.Hash `json:"transactions"`
+	}
+	if err := sc.ec.c.CallContext(ctx, &block, "eth_getSideBlockByHash", sc.appId, blockHash, false); err != nil {
+		return 0, err
+	}
+	if block == nil {
+		return 0, ethereum.NotFound
+	}
+	return uint(len(block.Transactions)), nil
+}
+
+// TransactionInBlock returns a single transaction at index in the given block.
+func (sc *SideClient) TransactionInBlock(ctx context.Context, blockHash common.Hash, index uint) (*types.Transaction, error) {
+	block, err := sc.BlockByHash(ctx, blockHash)
+	if err != nil {
+		return nil, err
+	}
+	txs := block.Transactions()
+	if index >= uint(len(txs)) {
+		return nil, ethereum.NotFound
+	}
+	return txs[index], nil
+}
+
+// TransactionReceipt returns the receipt of a transaction by transaction hash.
+// Note that the receipt is not available for pending transactions.
+func (sc *SideClient) TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error) {
+	return sc.ec.SideTransactionReceipt(ctx, sc.appId, txHash)
+}
+
+// SubscribeNewHead subscribes to notifications about the current side chain head
+// on the given channel.
+func (sc *SideClient) SubscribeNewHead(ctx context.Context, ch chan<- *types.Header) (ethereum.Subscription, error) {
+	return sc.ec.c.EthSubscribe(ctx, ch, "newHeads", sc.appId)
+}
+
+// State Access
+
+// BalanceAt returns the wei balance of the given account.
+// The block number can be nil, in which case the balance is taken from the latest known block.
+func (sc *SideClient) BalanceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (*big.Int, error) {
+	return sc.ec.SideBalanceAt(ctx, sc.appId, account, blockNumber)
+}
+
+// StorageAt returns the value of key in the contract storage of the given account.
+// The block number can be nil, in which case the value is taken from the latest known block.
+func (sc *SideClient) StorageAt(ctx context.Context, account common.Address, key common.Hash, blockNumber *big.Int) ([]byte, error) {
+	var result hexutil.Bytes
+	err := sc.ec.c.CallContext(ctx, &result, "eth_getSideStorageAt", sc.appId, account, key, toBlockNumArg(blockNumber))
+	return result, err
+}
+
+// CodeAt returns the contract code of the given account.
+// The block number can be nil, in which case the code is taken from the latest known block.
+func (sc *SideClient) CodeAt(ctx context.Context, account common.Address, blockNumber *big.Int) ([]byte, error) {
+	var result hexutil.Bytes
+	err := sc.ec.c.CallContext(ctx, &result, "eth_getSideCode", sc.appId, account, toBlockNumArg(blockNumber))
+	return result, err
+}
+
+// NonceAt returns the account nonce of the given account.
+// The block number can be nil, in which case the nonce is taken from the latest known block.
+func (sc *SideClient) NonceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (uint64, error) {
+	var result hexutil.Uint64
+	err := sc.ec.c.CallContext(ctx, &result, "eth_getSideTransactionCount", sc.appId, account, toBlockNumArg(blockNumber))
+	return uint64(result), err
+}
+
+// Filters
+
+// FilterLogs executes a filter query on the side chain.
+func (sc *SideClient) FilterLogs(ctx context.Context, q ethereum.FilterQuery) ([]types.Log, error) {
+	q.AppId = sc.appId
+	return sc.ec.FilterLogs(ctx, q)
+}
+
+// SubscribeFilterLogs subscribes to the results of a streaming filter query on
+// the side chain.
+func (sc *SideClient) SubscribeFilterLogs(ctx context.Context, q ethereum.FilterQuery, ch chan<- types.Log) (ethereum.Subscription, error) {
+	q.AppId = sc.appId
+	return sc.ec.SubscribeFilterLogs(ctx, q, ch)
+}
+
+// Pending State
+
+// PendingBalanceAt returns the wei balance of the given account in the pending state.
+func (sc *SideClient) PendingBalanceAt(ctx context.Context, account common.Address) (*big.Int, error) {
+	var result hexutil.Big
+	err := sc.ec.c.CallContext(ctx, &result, "eth_getSideBalance", sc.appId, account, "pending")
+	return (*big.Int)(&result), err
+}
+
+// PendingStorageAt returns the value of key in the contract storage of the given account in the pending state.
+func (sc *SideClient) PendingStorageAt(ctx context.Context, account common.Address, key common.Hash) ([]byte, error) {
+	var result hexutil.Bytes
+	err := sc.ec.c.CallContext(ctx, &result, "eth_getSideStorageAt", sc.appId, account, key, "pending")
+	return result, err
+}
+
+// PendingCodeAt returns the contract code of the given account in the pending state.
+func (sc *SideClient) PendingCodeAt(ctx context.Context, account common.Address) ([]byte, error) {
+	var result hexutil.Bytes
+	err := sc.ec.c.CallContext(ctx, &result, "eth_getSideCode", sc.appId, account, "pending")
+	return result, err
+}
+
+// PendingNonceAt returns the account nonce of the given account in the pending state.
+// This is the nonce that should be used for the next transaction.
+func (sc *SideClient) PendingNonceAt(ctx context.Context, account common.Address) (uint64, error) {
+	var result hexutil.Uint64
+	err := sc.ec.c.CallContext(ctx, &result, "eth_getSideTransactionCount", sc.appId, account, "pending")
+	return uint64(result), err
+}
+
+// PendingTransactionCount returns the total number of transactions in the pending
+// block of the side chain.
+func (sc *SideClient) PendingTransactionCount(ctx context.Context) (uint, error) {
+	var block *struct {
+		Transactions []common.Hash `json:"transactions"`
+	}
+	if err := sc.ec.c.CallContext(ctx, &block, "eth_getSideBlockByNumber", sc.appId, "pending", false); err != nil {
+		return 0, err
+	}
+	if block == nil {
+		return 0, ethereum.NotFound
+	}
+	return uint(len(block.Transactions)), nil
+}
+
+// Contract Calling
+
+// CallContract executes a message call transaction on the side chain, which is
+// directly executed in the VM of the node, but never mined into the side chain.
+func (sc *SideClient) CallContract(ctx context.Context, msg ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	msg.AppId = sc.appId
+	return sc.ec.CallContract(ctx, msg, blockNumber)
+}
+
+// PendingCallContract executes a message call transaction using the EVM.
+// The state seen by the contract call is the pending state of the side chain.
+func (sc *SideClient) PendingCallContract(ctx context.Context, msg ethereum.CallMsg) ([]byte, error) {
+	msg.AppId = sc.appId
+	return sc.ec.PendingCallContract(ctx, msg)
+}
+
+// SuggestGasPrice retrieves the currently suggested gas price of the side chain.
+func (sc *SideClient) SuggestGasPrice(ctx context.Context) (*big.Int, error) {
+	var hex hexutil.Big
+	if err := sc.ec.c.CallContext(ctx, &hex, "eth_sideGasPrice", sc.appId); err != nil {
+		return nil, err
+	}
+	return (*big.Int)(&hex), nil
+}
+
+// EstimateGas tries to estimate the gas needed to execute a specific transaction
+// based on the current pending state of the side chain.
+func (sc *SideClient) EstimateGas(ctx context.Context, msg ethereum.CallMsg) (uint64, error) {
+	msg.AppId = sc.appId
+	return sc.ec.EstimateGas(ctx, msg)
+}
+
+// SendTransaction injects a signed transaction into the pending pool of the side
+// chain. The transaction must carry the appId of the side chain.
+func (sc *SideClient) SendTransaction(ctx context.Context, tx *types.Transaction) error {
+	if tx.AppId() != sc.appId {
+		return fmt.Errorf("transaction of app %q sent to side chain %q", tx.AppId(), sc.appId)
+	}
+	return sc.ec.SendTransaction(ctx, tx)
+}
@@ -0,0 +1,1186 @@
+// Copyright 2018 The giov Authors
+// This file is part of the giov library.
+//
+// The giov library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The giov library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the giov library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package graphql provides a GraphQL interface to the main chain, the app
+// chains and the alien state of a node.
+package graphql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/CarLiveChainCo/goiov/common"
+	"github.com/CarLiveChainCo/goiov/common/hexutil"
+	"github.com/CarLiveChainCo/goiov/consensus/alien"
+	"github.com/CarLiveChainCo/goiov/core"
+	"github.com/CarLiveChainCo/goiov/core/rawdb"
+	"github.com/CarLiveChainCo/goiov/core/state"
+	"github.com/CarLiveChainCo/goiov/core/types"
+	"github.com/CarLiveChainCo/goiov/internal/ethapi"
+	"github.com/CarLiveChainCo/goiov/rpc"
+)
+
+const maxBlockRange = 10000 // Max number of blocks returned or scanned for logs by one query
+
+var (
+	// errUnknownBlock is returned if a block or its state is not known.
+	errUnknownBlock = errors.New("unknown block")
+
+	// errInvalidBlockRange is returned if a query asks for too many blocks or
+	// a range ending before it starts.
+	errInvalidBlockRange = errors.New("invalid block range")
+
+	// errNoAlien is returned if the alien state of a chain not run by the alien
+	// engine is queried.
+	errNoAlien = errors.New("chain is not run by the alien engine")
+)
+
+// appIdArg returns the appId of an optional appId argument, the main chain if
+// it is omitted.
+func appIdArg(appId *string) string {
+	if appId == nil {
+		return ""
+	}
+	return *appId
+}
+
+// chain returns the blockchain of the app chain appId, the main chain for an
+// empty appId.
+func chain(backend ethapi.Backend, appId string) (*core.BlockChain, error) {
+	bc, ok := backend.SideBlockChain(appId)
+	if !ok || bc == nil {
+		return nil, fmt.Errorf("appId %s does not exist", appId)
+	}
+	return bc, nil
+}
+
+// headerByNumber retrieves the header with the given number of the chain appId.
+func headerByNumber(ctx context.Context, backend ethapi.Backend, number rpc.BlockNumber, appId string) (*types.Header, error) {
+	if appId == "" {
+		return backend.HeaderByNumber(ctx, number)
+	}
+	return backend.SideHeaderByNumber(ctx, number, appId)
+}
+
+// stateByNumber retrieves the state as of the block with the given number of
+// the chain appId.
+func stateByNumber(ctx context.Context, backend ethapi.Backend, number rpc.BlockNumber, appId string) (*state.StateDB, error) {
+	var (
+		statedb *state.StateDB
+		err     error
+	)
+	if appId == "" {
+		statedb, _, err = backend.StateAndHeaderByNumber(ctx, number)
+	} else {
+		statedb, _, err = backend.SideStateAndHeaderByNumber(ctx, number, appId)
+	}
+	if statedb == nil && err == nil {
+		err = errUnknownBlock
+	}
+	return statedb, err
+}
+
+// Account is an account of a chain at a particular block.
+type Account struct {
+	backend     ethapi.Backend
+	appId       string
+	address     common.Address
+	blockNumber rpc.BlockNumber
+}
+
+func (a *Account) getState(ctx context.Context) (*state.StateDB, error) {
+	return stateByNumber(ctx, a.backend, a.blockNumber, a.appId)
+}
+
+func (a *Account) AppId(ctx context.Context) string {
+	return a.appId
+}
+
+func (a *Account) Address(ctx context.Context) common.Address {
+	return a.address
+}
+
+func (a *Account) Balance(ctx context.Context) (hexutil.Big, error) {
+	statedb, err := a.getState(ctx)
+	if err != nil {
+		return hexutil.Big{}, err
+	}
+	return hexutil.Big(*statedb.GetBalance(a.address)), statedb.Error()
+}
+
+func (a *Account) TransactionCount(ctx context.Context) (hexutil.Uint64, error) {
+	statedb, err := a.getState(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return hexutil.Uint64(statedb.GetNonce(a.address)), statedb.Error()
+}
+
+func (a *Account) Code(ctx context.Context) (hexutil.Bytes, error) {
+	statedb, err := a.getState(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return hexutil.Bytes(statedb.GetCode(a.address)), statedb.Error()
+}
+
+func (a *Account) Storage(ctx context.Context, args struct{ Slot common.Hash }) (common.Hash, error) {
+	statedb, err := a.getState(ctx)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return statedb.GetState(a.address, args.Slot), statedb.Error()
+}
+
+// Log is a log entry emitted by a transaction.
+type Log struct {
+	backend     ethapi.Backend
+	transaction *Transaction
+	log         *types.Log
+}
+
+func (l *Log) Transaction(ctx context.Context) *Transaction {
+	return l.transaction
+}
+
+func (l *Log) Account(ctx context.Context, args BlockNumberArgs) *Account {
+	number := rpc.BlockNumber(l.log.BlockNumber)
+	if args.Block != nil {
+		number = rpc.BlockNumber(*args.Block)
+	}
+	return &Account{
+		backend:     l.backend,
+		appId:       l.transaction.appId,
+		address:     l.log.Address,
+		blockNumber: number,
+	}
+}
+
+func (l *Log) Index(ctx context.Context) int32 {
+	return int32(l.log.Index)
+}
+
+func (l *Log) Topics(ctx context.Context) []common.Hash {
+	return l.log.Topics
+}
+
+func (l *Log) Data(ctx context.Context) hexutil.Bytes {
+	return hexutil.Bytes(l.log.Data)
+}
+
+// Transaction is a transaction of a chain, mined in a block or pending.
+type Transaction struct {
+	backend ethapi.Backend
+	appId   string
+	tx      *types.Transaction
+	block   *Block // Block of the transaction, nil if pending
+	index   uint64
+}
+
+func (t *Transaction) AppId(ctx context.Context) string {
+	return t.appId
+}
+
+func (t *Transaction) Hash(ctx context.Context) common.Hash {
+	return t.tx.Hash()
+}
+
+func (t *Transaction) InputData(ctx context.Context) hexutil.Bytes {
+	return hexutil.Bytes(t.tx.Data())
+}
+
+func (t *Transaction) Gas(ctx context.Context) hexutil.Uint64 {
+	return hexutil.Uint64(t.tx.Gas())
+}
+
+func (t *Transaction) GasPrice(ctx context.Context) hexutil.Big {
+	return hexutil.Big(*t.tx.GasPrice())
+}
+
+func (t *Transaction) Value(ctx context.Context) hexutil.Big {
+	return hexutil.Big(*t.tx.Value())
+}
+
+func (t *Transaction) Nonce(ctx context.Context) hexutil.Uint64 {
+	return hexutil.Uint64(t.tx.Nonce())
+}
+
+func (t *Transaction) To(ctx context.Context, args BlockNumberArgs) *Account {
+	to := t.tx.To()
+	if to == nil {
+		return nil
+	}
+	return &Account{
+		backend:     t.backend,
+		appId:       t.appId,
+		address:     *to,
+		blockNumber: args.Number(),
+	}
+}
+
+func (t *Transaction) From(ctx context.Context, args BlockNumberArgs) *Account {
+	var signer types.Signer = types.FrontierSigner{}
+	if t.tx.Protected() {
+		signer = types.NewEIP155Signer(t.tx.ChainId())
+	}
+	from, _ := types.Sender(signer, t.tx)
+
+	return &Account{
+		backend:     t.backend,
+		appId:       t.appId,
+		address:     from,
+		blockNumber: args.Number(),
+	}
+}
+
+func (t *Transaction) Block(ctx context.Context) *Block {
+	return t.block
+}
+
+func (t *Transaction) Index(ctx context.Context) *int32 {
+	if t.block == nil {
+		return nil
+	}
+	index := int32(t.index)
+	return &index
+}
+
+// getReceipt retrieves the receipt of the transaction, nil if it is pending.
+func (t *Transaction) getReceipt(ctx context.Context) (*types.Receipt, error) {
+	if t.block == nil {
+		return nil, nil
+	}
+	receipts, err := t.block.resolveReceipts(ctx)
+	if err != nil || uint64(len(receipts)) <= t.index {
+		return nil, err
+	}
+	return receipts[t.index], nil
+}
+
+func (t *Transaction) Status(ctx context.Context) (*hexutil.Uint64, error) {
+	receipt, err := t.getReceipt(ctx)
+	if err != nil || receipt == nil || len(receipt.PostState) != 0 {
+		return nil, err
+	}
+	status := hexutil.Uint64(receipt.Status)
+	return &status, nil
+}
+
+func (t *Transaction) GasUsed(ctx context.Context) (*hexutil.Uint64, error) {
+	receipt, err := t.getReceipt(ctx)
+	if err != nil || receipt == nil {
+		return nil, err
+	}
+	gasUsed := hexutil.Uint64(receipt.GasUsed)
+	return &gasUsed, nil
+}
+
+func (t *Transaction) CumulativeGasUsed(ctx context.Context) (*hexutil.Uint64, error) {
+	receipt, err := t.getReceipt(ctx)
+	if err != nil || receipt == nil {
+		return nil, err
+	}
+	gasUsed := hexutil.Uint64(receipt.CumulativeGasUsed)
+	return &gasUsed, nil
+}
+
+func (t *Transaction) CreatedContract(ctx context.Context, args BlockNumberArgs) (*Account, error) {
+	if t.tx.To() != nil {
+		return nil, nil
+	}
+	receipt, err := t.getReceipt(ctx)
+	if err != nil || receipt == nil {
+		return nil, err
+	}
+	return &Account{
+		backend:     t.backend,
+		appId:       t.appId,
+		address:     receipt.ContractAddress,
+		blockNumber: args.Number(),
+	}, nil
+}
+
+func (t *Transaction) Logs(ctx context.Context) (*[]*Log, error) {
+	receipt, err := t.getReceipt(ctx)
+	if err != nil || receipt == nil {
+		return nil, err
+	}
+	logs := make([]*Log, 0, len(receipt.Logs))
+	for _, log := range receipt.Logs {
+		logs = append(logs, &Log{backend: t.backend, transaction: t, log: log})
+	}
+	return &logs, nil
+}
+
+// Block is a block of a chain, known by its number or its hash and retrieved
+// when one of its fields is queried.
+type Block struct {
+	backend  ethapi.Backend
+	appId    string
+	num      *rpc.BlockNumber
+	hash     common.Hash
+	block    *types.Block
+	receipts types.Receipts
+}
+
+// resolve retrieves the block, failing if it is not known.
+func (b *Block) resolve(ctx context.Context) (*types.Block, error) {
+	if b.block != nil {
+		return b.block, nil
+	}
+	var err error
+	switch {
+	case b.hash != (common.Hash{}) && b.appId == "":
+		b.block, err = b.backend.GetBlock(ctx, b.hash)
+	case b.hash != (common.Hash{}):
+		b.block, err = b.backend.GetSideBlock(ctx, b.hash, b.appId)
+	case b.num != nil && b.appId == "":
+		b.block, err = b.backend.BlockByNumber(ctx, *b.num)
+	case b.num != nil:
+		b.block, err = b.backend.SideBlockByNumber(ctx, *b.num, b.appId)
+	}
+	if b.block == nil && err == nil {
+		err = errUnknownBlock
+	}
+	if b.block != nil {
+		b.hash = b.block.Hash()
+	}
+	return b.block, err
+}
+
+// resolveReceipts retrieves the receipts of the transactions of the block.
+func (b *Block) resolveReceipts(ctx context.Context) (types.Receipts, error) {
+	if b.receipts != nil {
+		return b.receipts, nil
+	}
+	block, err := b.resolve(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if b.appId == "" {
+		b.receipts, err = b.backend.GetReceipts(ctx, block.Hash())
+		return b.receipts, err
+	}
+	bc, err := chain(b.backend, b.appId)
+	if err != nil {
+		return nil, err
+	}
+	b.receipts = bc.GetReceiptsByHash(block.Hash())
+	return b.receipts, nil
+}
+
+// resolveSnapshot retrieves the alien snapshot of the chain of the block as of
+// the block with the given number and hash.
+func resolveSnapshot(backend ethapi.Backend, appId string, number uint64, hash common.Hash) (*alien.Snapshot, error) {
+	bc, err := chain(backend, appId)
+	if err != nil {
+		return nil, err
+	}
+	engine, ok := bc.Engine().(*alien.Alien)
+	if !ok {
+		return nil, errNoAlien
+	}
+	return engine.Snapshot(bc, number, hash, nil, nil, alien.DefaultLoopCntRecalculateSigners)
+}
+
+func (b *Block) AppId(ctx context.Context) string {
+	return b.appId
+}
+
+func (b *Block) Number(ctx context.Context) (hexutil.Uint64, error) {
+	block, err := b.resolve(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return hexutil.Uint64(block.NumberU64()), nil
+}
+
+func (b *Block) Hash(ctx context.Context) (common.Hash, error) {
+	block, err := b.resolve(ctx)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return block.Hash(), nil
+}
+
+func (b *Block) Parent(ctx context.Context) (*Block, error) {
+	block, err := b.resolve(ctx)
+	if err != nil || block.NumberU64() == 0 {
+		return nil, err
+	}
+	return &Block{
+		backend: b.backend,
+		appId:   b.appId,
+		hash:    block.ParentHash(),
+	}, nil
+}
+
+func (b *Block) Nonce(ctx context.Context) (hexutil.Bytes, error) {
+	block, err := b.resolve(ctx)
+	if err != nil {
+		return nil, err
+	}
+	nonce := block.Header().Nonce
+	return hexutil.Bytes(nonce[:]), nil
+}
+
+func (b *Block) TransactionsRoot(ctx context.Context) (common.Hash, error) {
+	block, err := b.resolve(ctx)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return block.TxHash(), nil
+}
+
+func (b *Block) TransactionCount(ctx context.Context) (int32, error) {
+	block, err := b.resolve(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return int32(len(block.Transactions())), nil
+}
+
+func (b *Block) StateRoot(ctx context.Context) (common.Hash, error) {
+	block, err := b.resolve(ctx)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return block.Root(), nil
+}
+
+func (b *Block) ReceiptsRoot(ctx context.Context) (common.Hash, error) {
+	block, err := b.resolve(ctx)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return block.ReceiptHash(), nil
+}
+
+func (b *Block) Miner(ctx context.Context, args BlockNumberArgs) (*Account, error) {
+	block, err := b.resolve(ctx)
+	if err != nil {
+		return nil, err
+	}
+	number := rpc.BlockNumber(block.NumberU64())
+	if args.Block != nil {
+		number = rpc.BlockNumber(*args.Block)
+	}
+	return &Account{
+		backend:     b.backend,
+		appId:       b.appId,
+		address:     block.Coinbase(),
+		blockNumber: number,
+	}, nil
+}
+
+func (b *Block) ExtraData(ctx context.Context) (hexutil.Bytes, error) {
+	block, err := b.resolve(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return hexutil.Bytes(block.Extra()), nil
+}
+
+func (b *Block) GasLimit(ctx context.Context) (hexutil.Uint64, error) {
+	block, err := b.resolve(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return hexutil.Uint64(block.GasLimit()), nil
+}
+
+func (b *Block) GasUsed(ctx context.Context) (hexutil.Uint64, error) {
+	block, err := b.resolve(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return hexutil.Uint64(block.GasUsed()), nil
+}
+
+func (b *Block) Timestamp(ctx context.Context) (hexutil.Big, error) {
+	block, err := b.resolve(ctx)
+	if err != nil {
+		return hexutil.Big{}, err
+	}
+	return hexutil.Big(*block.Time()), nil
+}
+
+func (b *Block) LogsBloom(ctx context.Context) (hexutil.Bytes, error) {
+	block, err := b.resolve(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return hexutil.Bytes(block.Bloom().Bytes()), nil
+}
+
+func (b *Block) MixHash(ctx context.Context) (common.Hash, error) {
+	block, err := b.resolve(ctx)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return block.MixDigest(), nil
+}
+
+func (b *Block) Difficulty(ctx context.Context) (hexutil.Big, error) {
+	block, err := b.resolve(ctx)
+	if err != nil {
+		return hexutil.Big{}, err
+	}
+	return hexutil.Big(*block.Difficulty()), nil
+}
+
+func (b *Block) TotalDifficulty(ctx context.Context) (hexutil.Big, error) {
+	block, err := b.resolve(ctx)
+	if err != nil {
+		return hexutil.Big{}, err
+	}
+	var td *big.Int
+	if b.appId == "" {
+		td = b.backend.GetTd(block.Hash())
+	} else {
+		bc, err := chain(b.backend, b.appId)
+		if err != nil {
+			return hexutil.Big{}, err
+		}
+		td = bc.GetTd(block.Hash(), block.NumberU64())
+	}
+	if td == nil {
+		return hexutil.Big{}, fmt.Errorf("total difficulty not found for block %x", block.Hash())
+	}
+	return hexutil.Big(*td), nil
+}
+
+func (b *Block) Transactions(ctx context.Context) ([]*Transaction, error) {
+	block, err := b.resolve(ctx)
+	if err != nil {
+		return nil, err
+	}
+	txs := make([]*Transaction, 0, len(block.Transactions()))
+	for i, tx := range block.Transactions() {
+		txs = append(txs, &Transaction{
+			backend: b.backend,
+			appId:   b.appId,
+			tx:      tx,
+			block:   b,
+			index:   uint64(i),
+		})
+	}
+	return txs, nil
+}
+
+func (b *Block) TransactionAt(ctx context.Context, args struct{ Index int32 }) (*Transaction, error) {
+	block, err := b.resolve(ctx)
+	if err != nil {
+		return nil, err
+	}
+	txs := block.Transactions()
+	if args.Index < 0 || int(args.Index) >= len(txs) {
+		return nil, nil
+	}
+	return &Transaction{
+		backend: b.backend,
+		appId:   b.appId,
+		tx:      txs[args.Index],
+		block:   b,
+		index:   uint64(args.Index),
+	}, nil
+}
+
+func (b *Block) Logs(ctx context.Context, args struct{ Filter BlockFilterCriteria }) ([]*Log, error) {
+	filter := &logFilter{addresses: args.Filter.Addresses, topics: args.Filter.Topics}
+	return b.filterLogs(ctx, filter)
+}
+
+// filterLogs returns the logs of the block matching the filter.
+func (b *Block) filterLogs(ctx context.Context, filter *logFilter) ([]*Log, error) {
+	block, err := b.resolve(ctx)
+	if err != nil {
+		return nil, err
+	}
+	logs := []*Log{}
+	if !filter.matchBloom(block.Bloom()) {
+		return logs, nil
+	}
+	receipts, err := b.resolveReceipts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	txs := block.Transactions()
+	for i, receipt := range receipts {
+		if i >= len(txs) {
+			break
+		}
+		tx := &Transaction{backend: b.backend, appId: b.appId, tx: txs[i], block: b, index: uint64(i)}
+		for _, log := range receipt.Logs {
+			if filter.match(log) {
+				logs = append(logs, &Log{backend: b.backend, transaction: tx, log: log})
+			}
+		}
+	}
+	return logs, nil
+}
+
+func (b *Block) Account(ctx context.Context, args struct{ Address common.Address }) (*Account, error) {
+	block, err := b.resolve(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &Account{
+		backend:     b.backend,
+		appId:       b.appId,
+		address:     args.Address,
+		blockNumber: rpc.BlockNumber(block.NumberU64()),
+	}, nil
+}
+
+func (b *Block) Rewards(ctx context.Context) ([]*Reward, error) {
+	block, err := b.resolve(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rewards := []*Reward{}
+	if block.NumberU64() == 0 {
+		return rewards, nil
+	}
+	snap, err := resolveSnapshot(b.backend, b.appId, block.NumberU64()-1, block.ParentHash())
+	if err != nil {
+		return nil, err
+	}
+	split := snap.BlockReward(block.Header())
+
+	amounts := make(map[common.Address]*big.Int)
+	credit := func(address common.Address, amount *big.Int) {
+		if amount == nil || amount.Sign() == 0 {
+			return
+		}
+		if _, ok := amounts[address]; !ok {
+			amounts[address] = new(big.Int)
+		}
+		amounts[address].Add(amounts[address], amount)
+	}
+	credit(split.Miner, split.MinerReward)
+	credit(split.Treasury, split.TreasuryReward)
+	for voter, amount := range split.VoterRewards {
+		credit(voter, amount)
+	}
+	for address, amount := range amounts {
+		rewards = append(rewards, &Reward{address: address, amount: amount})
+	}
+	sort.Slice(rewards, func(i, j int) bool {
+		return rewards[i].amount.Cmp(rewards[j].amount) > 0
+	})
+	return rewards, nil
+}
+
+func (b *Block) Snapshot(ctx context.Context) (*Snapshot, error) {
+	block, err := b.resolve(ctx)
+	if err != nil {
+		return nil, err
+	}
+	snap, err := resolveSnapshot(b.backend, b.appId, block.NumberU64(), block.Hash())
+	if err != nil {
+		return nil, err
+	}
+	return &Snapshot{appId: b.appId, snap: snap}, nil
+}
+
+// Reward is the part of a block reward credited to an account.
+type Reward struct {
+	address common.Address
+	amount  *big.Int
+}
+
+func (r *Reward) Address(ctx context.Context) common.Address {
+	return r.address
+}
+
+func (r *Reward) Amount(ctx context.Context) hexutil.Big {
+	return hexutil.Big(*r.amount)
+}
+
+// Vote is a vote of the alien engine.
+type Vote struct {
+	vote *alien.Vote
+}
+
+func (v *Vote) Voter(ctx context.Context) common.Address {
+	return v.vote.Voter
+}
+
+func (v *Vote) Candidate(ctx context.Context) common.Address {
+	return v.vote.Candidate
+}
+
+func (v *Vote) Stake(ctx context.Context) hexutil.Big {
+	if v.vote.Stake == nil {
+		return hexutil.Big{}
+	}
+	return hexutil.Big(*v.vote.Stake)
+}
+
+func (v *Vote) Hash(ctx context.Context) common.Hash {
+	return v.vote.Hash
+}
+
+// Tally is the stake voted for a candidate in a snapshot.
+type Tally struct {
+	snap      *alien.Snapshot
+	candidate common.Address
+	stake     *big.Int
+}
+
+func (t *Tally) Candidate(ctx context.Context) common.Address {
+	return t.candidate
+}
+
+func (t *Tally) Stake(ctx context.Context) hexutil.Big {
+	return hexutil.Big(*t.stake)
+}
+
+func (t *Tally) Votes(ctx context.Context) []*Vote {
+	votes := []*Vote{}
+	for _, vote := range sortedVotes(t.snap) {
+		if vote.vote.Candidate == t.candidate {
+			votes = append(votes, vote)
+		}
+	}
+	return votes
+}
+
+// Punishment is the punishment credit of a signer in a snapshot.
+type Punishment struct {
+	signer common.Address
+	credit uint64
+}
+
+func (p *Punishment) Signer(ctx context.Context) common.Address {
+	return p.signer
+}
+
+func (p *Punishment) Credit(ctx context.Context) hexutil.Uint64 {
+	return hexutil.Uint64(p.credit)
+}
+
+// Snapshot is the state of the alien engine of a chain as of a block.
+type Snapshot struct {
+	appId string
+	snap  *alien.Snapshot
+}
+
+// sortedVotes returns the votes of the snapshot ordered by voter.
+func sortedVotes(snap *alien.Snapshot) []*Vote {
+	votes := make([]*Vote, 0, len(snap.Votes))
+	for _, vote := range snap.Votes {
+		votes = append(votes, &Vote{vote: vote})
+	}
+	sort.Slice(votes, func(i, j int) bool {
+		return votes[i].vote.Voter.Big().Cmp(votes[j].vote.Voter.Big()) < 0
+	})
+	return votes
+}
+
+func (s *Snapshot) AppId(ctx context.Context) string {
+	return s.appId
+}
+
+func (s *Snapshot) Number(ctx context.Context) hexutil.Uint64 {
+	return hexutil.Uint64(s.snap.Number)
+}
+
+func (s *Snapshot) Hash(ctx context.Context) common.Hash {
+	return s.snap.Hash
+}
+
+func (s *Snapshot) Period(ctx context.Context) hexutil.Uint64 {
+	return hexutil.Uint64(s.snap.Period)
+}
+
+func (s *Snapshot) ConfirmedNumber(ctx context.Context) hexutil.Uint64 {
+	return hexutil.Uint64(s.snap.ConfirmedNumber)
+}
+
+func (s *Snapshot) HeaderTime(ctx context.Context) hexutil.Uint64 {
+	return hexutil.Uint64(s.snap.HeaderTime)
+}
+
+func (s *Snapshot) LoopStartTime(ctx context.Context) hexutil.Uint64 {
+	return hexutil.Uint64(s.snap.LoopStartTime)
+}
+
+func (s *Snapshot) Signers(ctx context.Context) []common.Address {
+	signers := make([]common.Address, 0, len(s.snap.Signers))
+	for _, signer := range s.snap.Signers {
+		signers = append(signers, *signer)
+	}
+	return signers
+}
+
+func (s *Snapshot) Votes(ctx context.Context) []*Vote {
+	return sortedVotes(s.snap)
+}
+
+func (s *Snapshot) Vote(ctx context.Context, args struct{ Voter common.Address }) *Vote {
+	if vote, ok := s.snap.Votes[args.Voter]; ok {
+		return &Vote{vote: vote}
+	}
+	return nil
+}
+
+func (s *Snapshot) Tallies(ctx context.Context) []*Tally {
+	tallies := make([]*Tally, 0, len(s.snap.Tally))
+	for candidate, stake := range s.snap.Tally {
+		tallies = append(tallies, &Tally{snap: s.snap, candidate: candidate, stake: stake})
+	}
+	sort.Slice(tallies, func(i, j int) bool {
+		if cmp := tallies[i].stake.Cmp(tallies[j].stake); cmp != 0 {
+			return cmp > 0
+		}
+		return tallies[i].candidate.Big().Cmp(tallies[j].candidate.Big()) < 0
+	})
+	return tallies
+}
+
+func (s *Snapshot) Tally(ctx context.Context, args struct{ Candidate common.Address }) *Tally {
+	if stake, ok := s.snap.Tally[args.Candidate]; ok {
+		return &Tally{snap: s.snap, candidate: args.Candidate, stake: stake}
+	}
+	return nil
+}
+
+func (s *Snapshot) Punishments(ctx context.Context) []*Punishment {
+	punishments := make([]*Punishment, 0, len(s.snap.Punished))
+	for signer, credit := range s.snap.Punished {
+		punishments = append(punishments, &Punishment{signer: signer, credit: credit})
+	}
+	sort.Slice(punishments, func(i, j int) bool {
+		return punishments[i].signer.Big().Cmp(punishments[j].signer.Big()) < 0
+	})
+	return punishments
+}
+
+func (s *Snapshot) Minted(ctx context.Context) hexutil.Big {
+	if s.snap.Minted == nil {
+		return hexutil.Big{}
+	}
+	return hexutil.Big(*s.snap.Minted)
+}
+
+// BlockNumberArgs is the optional block argument of the fields of an account.
+type BlockNumberArgs struct {
+	Block *hexutil.Uint64
+}
+
+// Number returns the block number of the arguments, the latest block if it is
+// omitted.
+func (a BlockNumberArgs) Number() rpc.BlockNumber {
+	if a.Block != nil {
+		return rpc.BlockNumber(*a.Block)
+	}
+	return rpc.LatestBlockNumber
+}
+
+// BlockFilterCriteria is the log filter applied to a single block.
+type BlockFilterCriteria struct {
+	Addresses *[]common.Address
+	Topics    *[][]common.Hash
+}
+
+// FilterCriteria is the log filter applied to a range of blocks.
+type FilterCriteria struct {
+	FromBlock *hexutil.Uint64
+	ToBlock   *hexutil.Uint64
+	Addresses *[]common.Address
+	Topics    *[][]common.Hash
+}
+
+// logFilter matches the logs emitted by any of the addresses, with any of the
+// topics at each position.
+type logFilter struct {
+	addresses *[]common.Address
+	topics    *[][]common.Hash
+}
+
+// matchBloom reports whether a block with the bloom may hold matching logs.
+func (f *logFilter) matchBloom(bloom types.Bloom) bool {
+	if f.addresses != nil && len(*f.addresses) > 0 {
+		included := false
+		for _, address := range *f.addresses {
+			if types.BloomLookup(bloom, address) {
+				included = true
+				break
+			}
+		}
+		if !included {
+			return false
+		}
+	}
+	if f.topics != nil {
+		for _, sub := range *f.topics {
+			included := len(sub) == 0
+			for _, topic := range sub {
+				if types.BloomLookup(bloom, topic) {
+					included = true
+					break
+				}
+			}
+			if !included {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// match reports whether the log matches the filter.
+func (f *logFilter) match(log *types.Log) bool {
+	if f.addresses != nil && len(*f.addresses) > 0 {
+		included := false
+		for _, address := range *f.addresses {
+			if log.Address == address {
+				included = true
+				break
+			}
+		}
+		if !included {
+			return false
+		}
+	}
+	if f.topics != nil {
+		if len(*f.topics) > len(log.Topics) {
+			return false
+		}
+		for i, sub := range *f.topics {
+			included := len(sub) == 0
+			for _, topic := range sub {
+				if log.Topics[i] == topic {
+					included = true
+					break
+				}
+			}
+			if !included {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Resolver is the root resolver of the queries.
+type Resolver struct {
+	backend ethapi.Backend
+}
+
+// BlockArgs selects a block of a chain by number or hash, the latest block if
+// neither is given.
+type BlockArgs struct {
+	Number *hexutil.Uint64
+	Hash   *common.Hash
+	AppId  *string
+}
+
+// block returns the block selected by the arguments, without retrieving it.
+func (r *Resolver) block(args BlockArgs) *Block {
+	block := &Block{backend: r.backend, appId: appIdArg(args.AppId)}
+	switch {
+	case args.Hash != nil:
+		block.hash = *args.Hash
+	case args.Number != nil:
+		number := rpc.BlockNumber(*args.Number)
+		block.num = &number
+	default:
+		number := rpc.LatestBlockNumber
+		block.num = &number
+	}
+	return block
+}
+
+// head returns the number of the latest block of the chain appId.
+func (r *Resolver) head(ctx context.Context, appId string) (uint64, error) {
+	header, err := headerByNumber(ctx, r.backend, rpc.LatestBlockNumber, appId)
+	if err != nil {
+		return 0, err
+	}
+	if header == nil {
+		return 0, errUnknownBlock
+	}
+	return header.Number.Uint64(), nil
+}
+
+func (r *Resolver) Block(ctx context.Context, args BlockArgs) (*Block, error) {
+	block := r.block(args)
+	if _, err := block.resolve(ctx); err != nil {
+		if err == errUnknownBlock {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return block, nil
+}
+
+func (r *Resolver) Blocks(ctx context.Context, args struct {
+	From  hexutil.Uint64
+	To    *hexutil.Uint64
+	AppId *string
+}) ([]*Block, error) {
+	appId := appIdArg(args.AppId)
+
+	var to uint64
+	if args.To != nil {
+		to = uint64(*args.To)
+	} else {
+		head, err := r.head(ctx, appId)
+		if err != nil {
+			return nil, err
+		}
+		to = head
+	}
+	from := uint64(args.From)
+	if to < from || to-from >= maxBlockRange {
+		return nil, errInvalidBlockRange
+	}
+	blocks := []*Block{}
+	for number := from; number <= to; number++ {
+		num := rpc.BlockNumber(number)
+		block := &Block{backend: r.backend, appId: appId, num: &num}
+		if _, err := block.resolve(ctx); err != nil {
+			if err == errUnknownBlock {
+				break
+			}
+			return nil, err
+		}
+		blocks = append(blocks, block)
+	}
+	return blocks, nil
+}
+
+func (r *Resolver) Transaction(ctx context.Context, args struct {
+	Hash  common.Hash
+	AppId *string
+}) (*Transaction, error) {
+	var (
+		tx                 *types.Transaction
+		blockHash          common.Hash
+		blockNumber, index uint64
+		appId              string
+	)
+	if args.AppId != nil {
+		tx, blockHash, blockNumber, index, appId = rawdb.ReadTransactionAppId(r.backend.ChainDb(), args.Hash, *args.AppId)
+	} else {
+		tx, blockHash, blockNumber, index, appId = rawdb.ReadTransactionAppId(r.backend.ChainDb(), args.Hash)
+	}
+	if tx != nil {
+		num := rpc.BlockNumber(blockNumber)
+		return &Transaction{
+			backend: r.backend,
+			appId:   appId,
+			tx:      tx,
+			block:   &Block{backend: r.backend, appId: appId, num: &num, hash: blockHash},
+			index:   index,
+		}, nil
+	}
+	// No mined transaction, try the pool
+	tx = r.backend.GetPoolTransaction(args.Hash)
+	if tx == nil || (args.AppId != nil && tx.AppId() != *args.AppId) {
+		return nil, nil
+	}
+	return &Transaction{backend: r.backend, appId: tx.AppId(), tx: tx}, nil
+}
+
+func (r *Resolver) Account(ctx context.Context, args struct {
+	Address     common.Address
+	BlockNumber *hexutil.Uint64
+	AppId       *string
+}) *Account {
+	return &Account{
+		backend:     r.backend,
+		appId:       appIdArg(args.AppId),
+		address:     args.Address,
+		blockNumber: BlockNumberArgs{Block: args.BlockNumber}.Number(),
+	}
+}
+
+func (r *Resolver) Logs(ctx context.Context, args struct {
+	Filter FilterCriteria
+	AppId  *string
+}) ([]*Log, error) {
+	appId := appIdArg(args.AppId)
+
+	head, err := r.head(ctx, appId)
+	if err != nil {
+		return nil, err
+	}
+	from, to := head, head
+	if args.Filter.FromBlock != nil {
+		from = uint64(*args.Filter.FromBlock)
+	}
+	if args.Filter.ToBlock != nil {
+		to = uint64(*args.Filter.ToBlock)
+	}
+	if to > head {
+		to = head
+	}
+	if to < from || to-from >= maxBlockRange {
+		return nil, errInvalidBlockRange
+	}
+	filter := &logFilter{addresses: args.Filter.Addresses, topics: args.Filter.Topics}
+
+	logs := []*Log{}
+	for number := from; number <= to; number++ {
+		header, err := headerByNumber(ctx, r.backend, rpc.BlockNumber(number), appId)
+		if err != nil {
+			return nil, err
+		}
+		if header == nil || !filter.matchBloom(header.Bloom) {
+			continue
+		}
+		num := rpc.BlockNumber(number)
+		block := &Block{backend: r.backend, appId: appId, num: &num}
+		matched, err := block.filterLogs(ctx, filter)
+		if err != nil {
+			return nil, err
+		}
+		logs = append(logs, matched...)
+	}
+	return logs, nil
+}
+
+func (r *Resolver) Snapshot(ctx context.Context, args BlockArgs) (*Snapshot, error) {
+	return r.block(args).Snapshot(ctx)
+}
+
+func (r *Resolver) AppChains(ctx context.Context) []string {
+	appIds := []string{}
+	for appId := range r.backend.GetSideChains() {
+		appIds = append(appIds, appId)
+	}
+	sort.Strings(appIds)
+	return appIds
+}
+
+func (r *Resolver) GasPrice(ctx context.Context, args struct{ AppId *string }) (hexutil.Big, error) {
+	price, err := r.backend.SuggestPrice(ctx, appIdArg(args.AppId))
+	if err != nil {
+		return hexutil.Big{}, err
+	}
+	return hexutil.Big(*price), nil
+}
+
+func (r *Resolver) ProtocolVersion(ctx context.Context) int32 {
+	return int32(r.backend.ProtocolVersion())
+}
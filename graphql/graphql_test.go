@@ -0,0 +1,28 @@
+// Copyright 2018 The giov Authors
+// This file is part of the giov library.
+//
+// The giov library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The giov library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the giov library. If not, see <http://www.gnu.org/licenses/>.
+
+package graphql
+
+import (
+	"testing"
+)
+
+// Tests that the schema parses and that every field of it has a resolver.
+func TestBuildSchema(t *testing.T) {
+	if _, err := newHandler(nil); err != nil {
+		t.Fatalf("could not create the GraphQL handler: %v", err)
+	}
+}
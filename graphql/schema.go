@@ -0,0 +1,298 @@
+// Copyright 2018 The giov Authors
+// This file is part of the giov library.
+//
+// The giov library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The giov library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the giov library. If not, see <http://www.gnu.org/licenses/>.
+
+package graphql
+
+// schema is the GraphQL schema of the service. Every query scoped to a chain
+// takes an optional appId, the main chain if it is omitted.
+const schema string = `
+    # Bytes32 is a 32 byte binary string, represented as 0x-prefixed hexadecimal.
+    scalar Bytes32
+    # Address is a 20 byte account address, represented as 0x-prefixed hexadecimal.
+    scalar Address
+    # Bytes is an arbitrary length binary string, represented as 0x-prefixed hexadecimal.
+    # An empty byte string is represented as '0x'.
+    scalar Bytes
+    # BigInt is a large integer, represented as a 0x-prefixed hexadecimal string.
+    # Decimal integers are accepted as input.
+    scalar BigInt
+    # Long is a 64 bit unsigned integer, represented as a 0x-prefixed hexadecimal string.
+    # Decimal integers are accepted as input.
+    scalar Long
+
+    schema {
+        query: Query
+    }
+
+    # Account is an account of a chain at a particular block.
+    type Account {
+        # AppId is the appId of the chain of the account, empty for the main chain.
+        appId: String!
+        # Address is the address owning the account.
+        address: Address!
+        # Balance is the balance of the account, in wei.
+        balance: BigInt!
+        # TransactionCount is the number of transactions sent from this account,
+        # or in the case of a contract, the number of contracts created.
+        transactionCount: Long!
+        # Code contains the smart contract code of this account, if it is a contract.
+        code: Bytes!
+        # Storage provides access to the storage of a contract account, indexed
+        # by its 32 byte slot identifier.
+        storage(slot: Bytes32!): Bytes32!
+    }
+
+    # Log is a log entry emitted by a contract.
+    type Log {
+        # Index is the index of this log in the block.
+        index: Int!
+        # Account is the account which generated this log, at the block of the
+        # log unless a block is given.
+        account(block: Long): Account!
+        # Topics is the list of 0-4 indexed topics of the log.
+        topics: [Bytes32!]!
+        # Data is the unindexed data of the log.
+        data: Bytes!
+        # Transaction is the transaction that generated this log.
+        transaction: Transaction!
+    }
+
+    # Transaction is a transaction of a chain.
+    type Transaction {
+        # AppId is the appId of the chain of the transaction, empty for the main chain.
+        appId: String!
+        # Hash is the hash of this transaction.
+        hash: Bytes32!
+        # Nonce is the nonce of the account this transaction was generated with.
+        nonce: Long!
+        # Index is the index of this transaction in the block, null for pending
+        # transactions.
+        index: Int
+        # From is the account that sent this transaction, at the latest block
+        # unless a block is given.
+        from(block: Long): Account!
+        # To is the account the transaction was sent to, null for contract
+        # creations.
+        to(block: Long): Account
+        # Value is the value, in wei, sent along with this transaction.
+        value: BigInt!
+        # GasPrice is the price offered to miners for gas, in wei per unit.
+        gasPrice: BigInt!
+        # Gas is the maximum amount of gas this transaction can consume.
+        gas: Long!
+        # InputData is the data supplied to the target of the transaction.
+        inputData: Bytes!
+        # Block is the block this transaction was mined in, null for pending
+        # transactions.
+        block: Block
+        # Status is the return status of the transaction, 1 on success and 0 on
+        # failure, null for pending transactions.
+        status: Long
+        # GasUsed is the amount of gas used by this transaction, null for
+        # pending transactions.
+        gasUsed: Long
+        # CumulativeGasUsed is the total gas used in the block up to and
+        # including this transaction, null for pending transactions.
+        cumulativeGasUsed: Long
+        # CreatedContract is the account created by this contract creation, null
+        # for other transactions and pending ones.
+        createdContract(block: Long): Account
+        # Logs is the list of logs emitted by this transaction, null for pending
+        # transactions.
+        logs: [Log!]
+    }
+
+    # BlockFilterCriteria encapsulates log filter criteria for a filter applied
+    # to a single block.
+    input BlockFilterCriteria {
+        # Addresses is the list of addresses to match, any address if empty.
+        addresses: [Address!]
+        # Topics is the list of topics to match at each position, any topic at
+        # a position if the list of the position is empty.
+        topics: [[Bytes32!]!]
+    }
+
+    # FilterCriteria encapsulates log filter criteria for searching a range of
+    # blocks.
+    input FilterCriteria {
+        # FromBlock is the first block of the range, the latest block if omitted.
+        fromBlock: Long
+        # ToBlock is the last block of the range, the latest block if omitted.
+        toBlock: Long
+        # Addresses is the list of addresses to match, any address if empty.
+        addresses: [Address!]
+        # Topics is the list of topics to match at each position, any topic at
+        # a position if the list of the position is empty.
+        topics: [[Bytes32!]!]
+    }
+
+    # Block is a block of a chain.
+    type Block {
+        # AppId is the appId of the chain of the block, empty for the main chain.
+        appId: String!
+        # Number is the number of this block.
+        number: Long!
+        # Hash is the block hash of this block.
+        hash: Bytes32!
+        # Parent is the parent block of this block, null for the genesis block.
+        parent: Block
+        # Nonce is the block nonce, an 8 byte sequence.
+        nonce: Bytes!
+        # TransactionsRoot is the keccak256 hash of the root of the trie of
+        # transactions in this block.
+        transactionsRoot: Bytes32!
+        # TransactionCount is the number of transactions in this block.
+        transactionCount: Int!
+        # StateRoot is the keccak256 hash of the state trie after this block was
+        # processed.
+        stateRoot: Bytes32!
+        # ReceiptsRoot is the keccak256 hash of the trie of transaction receipts
+        # in this block.
+        receiptsRoot: Bytes32!
+        # Miner is the account that sealed this block, at this block unless a
+        # block is given.
+        miner(block: Long): Account!
+        # ExtraData is an arbitrary data field supplied by the miner, holding the
+        # alien header extra.
+        extraData: Bytes!
+        # GasLimit is the maximum amount of gas that was available to
+        # transactions in this block.
+        gasLimit: Long!
+        # GasUsed is the amount of gas that was used executing transactions in
+        # this block.
+        gasUsed: Long!
+        # Timestamp is the unix timestamp at which this block was mined.
+        timestamp: BigInt!
+        # LogsBloom is a bloom filter of the logs of this block.
+        logsBloom: Bytes!
+        # MixHash is the hash that was used as an input to the PoW process.
+        mixHash: Bytes32!
+        # Difficulty is a measure of the difficulty of mining this block.
+        difficulty: BigInt!
+        # TotalDifficulty is the sum of all difficulty values up to and including
+        # this block.
+        totalDifficulty: BigInt!
+        # Transactions is the list of transactions in this block.
+        transactions: [Transaction!]!
+        # TransactionAt returns the transaction at the given index, null if
+        # there is none.
+        transactionAt(index: Int!): Transaction
+        # Logs returns the logs of this block matching the filter.
+        logs(filter: BlockFilterCriteria!): [Log!]!
+        # Account returns the account with the given address at this block.
+        account(address: Address!): Account!
+        # Rewards is the split of the block reward of this block.
+        rewards: [Reward!]!
+        # Snapshot is the alien snapshot as of this block.
+        snapshot: Snapshot!
+    }
+
+    # Reward is the part of a block reward credited to an account.
+    type Reward {
+        # Address is the account credited.
+        address: Address!
+        # Amount is the amount credited, in wei.
+        amount: BigInt!
+    }
+
+    # Vote is a vote of a voter for a candidate signer, staking its balance.
+    type Vote {
+        # Voter is the account that voted.
+        voter: Address!
+        # Candidate is the candidate voted for.
+        candidate: Address!
+        # Stake is the stake of the vote, in wei.
+        stake: BigInt!
+        # Hash is the hash of the transaction of the vote.
+        hash: Bytes32!
+    }
+
+    # Tally is the total stake voted for a candidate signer.
+    type Tally {
+        # Candidate is the candidate voted for.
+        candidate: Address!
+        # Stake is the stake voted for the candidate, in wei.
+        stake: BigInt!
+        # Votes is the list of votes for the candidate.
+        votes: [Vote!]!
+    }
+
+    # Punishment is the punishment credit of a signer for missing its slots.
+    type Punishment {
+        # Signer is the signer punished.
+        signer: Address!
+        # Credit is the punishment credit of the signer.
+        credit: Long!
+    }
+
+    # Snapshot is the state of the alien engine as of a block.
+    type Snapshot {
+        # AppId is the appId of the chain of the snapshot, empty for the main chain.
+        appId: String!
+        # Number is the number of the block of the snapshot.
+        number: Long!
+        # Hash is the hash of the block of the snapshot.
+        hash: Bytes32!
+        # Period is the time between blocks, in seconds.
+        period: Long!
+        # ConfirmedNumber is the number of the last block confirmed by the signers.
+        confirmedNumber: Long!
+        # HeaderTime is the time of the block of the snapshot.
+        headerTime: Long!
+        # LoopStartTime is the time the current loop of the signer queue started.
+        loopStartTime: Long!
+        # Signers is the signer queue of the current loop.
+        signers: [Address!]!
+        # Votes is the list of votes, ordered by voter.
+        votes: [Vote!]!
+        # Vote returns the vote of the voter, null if it has not voted.
+        vote(voter: Address!): Vote
+        # Tallies is the list of tallies of the candidates, ordered by stake.
+        tallies: [Tally!]!
+        # Tally returns the tally of the candidate, null if it is not a candidate.
+        tally(candidate: Address!): Tally
+        # Punishments is the list of punishment credits of the signers.
+        punishments: [Punishment!]!
+        # Minted is the total of the block rewards paid up to this block, in wei.
+        minted: BigInt!
+    }
+
+    type Query {
+        # Block returns the block of the chain with the given number or hash,
+        # the latest block if neither is given.
+        block(number: Long, hash: Bytes32, appId: String): Block
+        # Blocks returns all the blocks of the chain between two numbers,
+        # inclusive, up to the latest block if the last number is omitted.
+        blocks(from: Long!, to: Long, appId: String): [Block!]!
+        # Transaction returns the transaction with the given hash, of any chain
+        # unless an appId is given.
+        transaction(hash: Bytes32!, appId: String): Transaction
+        # Account returns the account of the chain with the given address, at
+        # the latest block unless a block number is given.
+        account(address: Address!, blockNumber: Long, appId: String): Account!
+        # Logs returns the logs of the chain matching the filter.
+        logs(filter: FilterCriteria!, appId: String): [Log!]!
+        # Snapshot returns the alien snapshot of the chain as of the block with
+        # the given number or hash, the latest block if neither is given.
+        snapshot(number: Long, hash: Bytes32, appId: String): Snapshot!
+        # AppChains is the list of appIds of the app chains the node joined.
+        appChains: [String!]!
+        # GasPrice returns the suggested gas price of the chain, in wei.
+        gasPrice(appId: String): BigInt!
+        # ProtocolVersion returns the current wire protocol version number.
+        protocolVersion: Int!
+    }
+`
@@ -53,6 +53,16 @@ const (
 	defaultGasPrice = 50 * params.Shannon
 )
 
+var errNoSideChain = errors.New("the side chain is not created. Please create the side chain by command 'eth.NewSideChain(appId)'")
+
+// errMixedChainCalls is returned if the calls of a bundle are not all on the
+// same chain.
+var errMixedChainCalls = errors.New("calls of a bundle must all be on the same chain")
+
+// errPendingProof is returned if a Merkle proof is requested in the pending state,
+// whose root no header commits to.
+var errPendingProof = errors.New("proofs of the pending state are not supported")
+
 // PublicEthereumAPI provides an API to access Ethereum related information.
 // It offers only methods that operate on public data that is freely available to anyone.
 type PublicEthereumAPI struct {
@@ -69,6 +79,14 @@ func (s *PublicEthereumAPI) GasPrice(ctx context.Context) (*big.Int, error) {
 	return s.b.SuggestPrice(ctx,"")
 }
 
+// SideGasPrice returns a suggestion for a gas price on the side chain with the given appId.
+func (s *PublicEthereumAPI) SideGasPrice(ctx context.Context, appId string) (*big.Int, error) {
+	if _, ok := s.b.SideBlockChain(appId); !ok {
+		return nil, errNoSideChain
+	}
+	return s.b.SuggestPrice(ctx, appId)
+}
+
 // ProtocolVersion returns the current Ethereum protocol version this node supports
 func (s *PublicEthereumAPI) ProtocolVersion() hexutil.Uint {
 	return hexutil.Uint(s.b.ProtocolVersion())
@@ -486,6 +504,11 @@ func NewPublicBlockChainAPI(b Backend) *PublicBlockChainAPI {
 	return &PublicBlockChainAPI{b}
 }
 
+// ChainId returns the chainID value for transaction replay protection.
+func (s *PublicBlockChainAPI) ChainId() *hexutil.Big {
+	return (*hexutil.Big)(s.b.ChainConfig().ChainId)
+}
+
 // BlockNumber returns the block number of the chain head.
 func (s *PublicBlockChainAPI) BlockNumber() *big.Int {
 	header, _ := s.b.HeaderByNumber(context.Background(), rpc.LatestBlockNumber) // latest header should always be available
@@ -664,6 +687,135 @@ func (s *PublicBlockChainAPI) GetStorageAt(ctx context.Context, address common.A
 	return res[:], state.Error()
 }
 
+// GetSideCode returns the code stored at the given address in the state of the
+// side chain with the given appId for the given block number.
+func (s *PublicBlockChainAPI) GetSideCode(ctx context.Context, appId string, address common.Address, blockNr rpc.BlockNumber) (hexutil.Bytes, error) {
+	if _, ok := s.b.SideBlockChain(appId); !ok {
+		return nil, errNoSideChain
+	}
+	state, _, err := s.b.SideStateAndHeaderByNumber(ctx, blockNr, appId)
+	if state == nil || err != nil {
+		return nil, err
+	}
+	code := state.GetCode(address)
+	return code, state.Error()
+}
+
+// GetSideStorageAt returns the storage from the state of the side chain with the
+// given appId at the given address, key and block number.
+func (s *PublicBlockChainAPI) GetSideStorageAt(ctx context.Context, appId string, address common.Address, key string, blockNr rpc.BlockNumber) (hexutil.Bytes, error) {
+	if _, ok := s.b.SideBlockChain(appId); !ok {
+		return nil, errNoSideChain
+	}
+	state, _, err := s.b.SideStateAndHeaderByNumber(ctx, blockNr, appId)
+	if state == nil || err != nil {
+		return nil, err
+	}
+	res := state.GetState(address, common.HexToHash(key))
+	return res[:], state.Error()
+}
+
+// AccountResult is the Merkle proof of an account and of some of its storage
+// slots, as defined by EIP-1186.
+type AccountResult struct {
+	Address      common.Address  `json:"address"`
+	AccountProof []string        `json:"accountProof"`
+	Balance      *hexutil.Big    `json:"balance"`
+	CodeHash     common.Hash     `json:"codeHash"`
+	Nonce        hexutil.Uint64  `json:"nonce"`
+	StorageHash  common.Hash     `json:"storageHash"`
+	StorageProof []StorageResult `json:"storageProof"`
+}
+
+// StorageResult is the Merkle proof of a storage slot of an account.
+type StorageResult struct {
+	Key   string       `json:"key"`
+	Value *hexutil.Big `json:"value"`
+	Proof []string     `json:"proof"`
+}
+
+// GetProof returns the Merkle proof of the account at the given address and of
+// its storage slots with the given keys in the state of the given block number.
+// The pending state can't be proven.
+func (s *PublicBlockChainAPI) GetProof(ctx context.Context, address common.Address, storageKeys []string, blockNr rpc.BlockNumber) (*AccountResult, error) {
+	if blockNr == rpc.PendingBlockNumber {
+		return nil, errPendingProof
+	}
+	state, _, err := s.b.StateAndHeaderByNumber(ctx, blockNr)
+	if state == nil || err != nil {
+		return nil, err
+	}
+	return proveAccount(state, address, storageKeys)
+}
+
+// GetSideProof returns the Merkle proof of the account at the given address and
+// of its storage slots with the given keys in the state of the side chain with
+// the given appId for the given block number.
+func (s *PublicBlockChainAPI) GetSideProof(ctx context.Context, appId string, address common.Address, storageKeys []string, blockNr rpc.BlockNumber) (*AccountResult, error) {
+	if _, ok := s.b.SideBlockChain(appId); !ok {
+		return nil, errNoSideChain
+	}
+	if blockNr == rpc.PendingBlockNumber {
+		return nil, errPendingProof
+	}
+	state, _, err := s.b.SideStateAndHeaderByNumber(ctx, blockNr, appId)
+	if state == nil || err != nil {
+		return nil, err
+	}
+	return proveAccount(state, address, storageKeys)
+}
+
+// proveAccount builds the Merkle proofs of the account and of its storage slots
+// in statedb. The proof of an account that doesn't exist proves its absence.
+func proveAccount(statedb *state.StateDB, address common.Address, storageKeys []string) (*AccountResult, error) {
+	var (
+		storageTrie  = statedb.StorageTrie(address)
+		storageHash  = types.EmptyRootHash
+		codeHash     = statedb.GetCodeHash(address)
+		storageProof = make([]StorageResult, len(storageKeys))
+	)
+	if storageTrie != nil {
+		storageHash = storageTrie.Hash()
+	} else {
+		// The account doesn't exist, its code is empty
+		codeHash = crypto.Keccak256Hash(nil)
+	}
+	for i, key := range storageKeys {
+		if storageTrie == nil {
+			storageProof[i] = StorageResult{key, &hexutil.Big{}, []string{}}
+			continue
+		}
+		proof, err := statedb.GetStorageProof(address, common.HexToHash(key))
+		if err != nil {
+			return nil, err
+		}
+		value := statedb.GetState(address, common.HexToHash(key))
+		storageProof[i] = StorageResult{key, (*hexutil.Big)(value.Big()), encodeProof(proof)}
+	}
+	accountProof, err := statedb.GetProof(address)
+	if err != nil {
+		return nil, err
+	}
+	return &AccountResult{
+		Address:      address,
+		AccountProof: encodeProof(accountProof),
+		Balance:      (*hexutil.Big)(statedb.GetBalance(address)),
+		CodeHash:     codeHash,
+		Nonce:        hexutil.Uint64(statedb.GetNonce(address)),
+		StorageHash:  storageHash,
+		StorageProof: storageProof,
+	}, statedb.Error()
+}
+
+// encodeProof hex encodes the trie nodes of a Merkle proof.
+func encodeProof(proof [][]byte) []string {
+	nodes := make([]string, len(proof))
+	for i, node := range proof {
+		nodes[i] = hexutil.Encode(node)
+	}
+	return nodes
+}
+
 // CallArgs represents the arguments for a call.
 type CallArgs struct {
 	From     common.Address  `json:"from"`
@@ -675,13 +827,80 @@ type CallArgs struct {
 	AppId    string          `json:"appId"`
 }
 
-func (s *PublicBlockChainAPI) doCall(ctx context.Context, args CallArgs, blockNr rpc.BlockNumber, vmCfg vm.Config, timeout time.Duration) ([]byte, uint64, bool, error) {
+// OverrideAccount specifies the fields of an account overridden for the duration
+// of a call, the ones left out keep their value in the state. State replaces the
+// entire storage of the account while StateDiff only replaces the given slots,
+// so at most one of them may be set.
+type OverrideAccount struct {
+	Nonce     *hexutil.Uint64              `json:"nonce"`
+	Code      *hexutil.Bytes               `json:"code"`
+	Balance   *hexutil.Big                 `json:"balance"`
+	State     *map[common.Hash]common.Hash `json:"state"`
+	StateDiff *map[common.Hash]common.Hash `json:"stateDiff"`
+}
+
+// StateOverride is the set of accounts overridden for the duration of a call.
+type StateOverride map[common.Address]OverrideAccount
+
+// Apply overrides the accounts in the given state.
+func (diff *StateOverride) Apply(statedb *state.StateDB) error {
+	if diff == nil {
+		return nil
+	}
+	for addr, account := range *diff {
+		if account.State != nil && account.StateDiff != nil {
+			return fmt.Errorf("account %s has both 'state' and 'stateDiff'", addr.Hex())
+		}
+		if account.Nonce != nil {
+			statedb.SetNonce(addr, uint64(*account.Nonce))
+		}
+		if account.Code != nil {
+			statedb.SetCode(addr, *account.Code)
+		}
+		if account.Balance != nil {
+			statedb.SetBalance(addr, (*big.Int)(account.Balance))
+		}
+		if account.State != nil {
+			statedb.SetStorage(addr, *account.State)
+		}
+		if account.StateDiff != nil {
+			for key, value := range *account.StateDiff {
+				statedb.SetState(addr, key, value)
+			}
+		}
+	}
+	return nil
+}
+
+func (s *PublicBlockChainAPI) doCall(ctx context.Context, args CallArgs, blockNr rpc.BlockNumber, overrides *StateOverride, vmCfg vm.Config, timeout time.Duration) ([]byte, uint64, bool, error) {
 	defer func(start time.Time) { log.Debug("Executing EVM call finished", "runtime", time.Since(start)) }(time.Now())
 
 	state, header, err := s.b.SideStateAndHeaderByNumber(ctx, blockNr, args.AppId)
 	if state == nil || err != nil {
 		return nil, 0, false, err
 	}
+	if err := overrides.Apply(state); err != nil {
+		return nil, 0, false, err
+	}
+
+	// Setup context so it may be cancelled the call has completed
+	// or, in case of unmetered gas, setup a context with a timeout.
+	var cancel context.CancelFunc
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+	} else {
+		ctx, cancel = context.WithCancel(ctx)
+	}
+	// Make sure the context is cancelled when the call has completed
+	// this makes sure resources are cleaned up.
+	defer cancel()
+
+	return s.applyCall(ctx, state, header, args, vmCfg)
+}
+
+// applyCall executes the call on the given state, on top of the block of the
+// given header. The EVM is cancelled once the context is done.
+func (s *PublicBlockChainAPI) applyCall(ctx context.Context, state *state.StateDB, header *types.Header, args CallArgs, vmCfg vm.Config) ([]byte, uint64, bool, error) {
 	// Set sender address or use a default if none specified
 	addr := args.From
 	if addr == (common.Address{}) {
@@ -703,18 +922,6 @@ func (s *PublicBlockChainAPI) doCall(ctx context.Context, args CallArgs, blockNr
 	// Create new call message
 	msg := types.NewMessage(addr, args.AppId, args.To, 0, args.Value.ToInt(), gas, gasPrice, args.Data, false)
 
-	// Setup context so it may be cancelled the call has completed
-	// or, in case of unmetered gas, setup a context with a timeout.
-	var cancel context.CancelFunc
-	if timeout > 0 {
-		ctx, cancel = context.WithTimeout(ctx, timeout)
-	} else {
-		ctx, cancel = context.WithCancel(ctx)
-	}
-	// Make sure the context is cancelled when the call has completed
-	// this makes sure resources are cleaned up.
-	defer cancel()
-
 	// Get a new instance of the EVM.
 	evm, vmError, err := s.b.GetEVM(ctx, msg, state, header, vmCfg)
 	if err != nil {
@@ -737,13 +944,87 @@ func (s *PublicBlockChainAPI) doCall(ctx context.Context, args CallArgs, blockNr
 	return res, gas, failed, err
 }
 
-// Call executes the given transaction on the state for the given block number.
+// Call executes the given transaction on the state for the given block number,
+// with the accounts of the optional state overrides replaced first.
 // It doesn't make and changes in the state/blockchain and is useful to execute and retrieve values.
-func (s *PublicBlockChainAPI) Call(ctx context.Context, args CallArgs, blockNr rpc.BlockNumber) (hexutil.Bytes, error) {
-	result, _, _, err := s.doCall(ctx, args, blockNr, vm.Config{}, 5*time.Second)
+func (s *PublicBlockChainAPI) Call(ctx context.Context, args CallArgs, blockNr rpc.BlockNumber, overrides *StateOverride) (hexutil.Bytes, error) {
+	result, _, _, err := s.doCall(ctx, args, blockNr, overrides, vm.Config{}, s.b.RPCEVMTimeout())
 	return (hexutil.Bytes)(result), err
 }
 
+// CallResult is the outcome of one call of a bundle executed by CallMany.
+type CallResult struct {
+	ReturnData hexutil.Bytes  `json:"returnData"`
+	Logs       []*types.Log   `json:"logs"`
+	GasUsed    hexutil.Uint64 `json:"gasUsed"`
+	Failed     bool           `json:"failed"`
+	Error      string         `json:"error,omitempty"`
+}
+
+// CallMany executes the given calls in order on one copy of the state for the
+// given block number, each call seeing the changes of the ones before it. The
+// accounts of the optional state overrides are replaced first. The calls must
+// all be on the same chain, the main chain or the app chain of their appId.
+func (s *PublicBlockChainAPI) CallMany(ctx context.Context, calls []CallArgs, blockNr rpc.BlockNumber, overrides *StateOverride) ([]*CallResult, error) {
+	defer func(start time.Time) { log.Debug("Executing EVM call bundle finished", "runtime", time.Since(start)) }(time.Now())
+
+	results := make([]*CallResult, 0, len(calls))
+	if len(calls) == 0 {
+		return results, nil
+	}
+	appId := calls[0].AppId
+	for _, call := range calls[1:] {
+		if call.AppId != appId {
+			return nil, errMixedChainCalls
+		}
+	}
+	state, header, err := s.b.SideStateAndHeaderByNumber(ctx, blockNr, appId)
+	if state == nil || err != nil {
+		return nil, err
+	}
+	if err := overrides.Apply(state); err != nil {
+		return nil, err
+	}
+	// The timeout applies to the whole bundle
+	var (
+		timeout = s.b.RPCEVMTimeout()
+		cancel  context.CancelFunc
+	)
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+	} else {
+		ctx, cancel = context.WithCancel(ctx)
+	}
+	defer cancel()
+
+	for i, call := range calls {
+		// Calls have no transaction hash, their logs are gathered under the
+		// zero hash and told apart by their transaction index
+		state.Prepare(common.Hash{}, header.Hash(), i)
+		logged := len(state.GetLogs(common.Hash{}))
+
+		res, gas, failed, err := s.applyCall(ctx, state, header, call, vm.Config{})
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("execution aborted (timeout = %v)", timeout)
+		}
+		result := &CallResult{
+			ReturnData: res,
+			Logs:       append([]*types.Log{}, state.GetLogs(common.Hash{})[logged:]...),
+			GasUsed:    hexutil.Uint64(gas),
+			Failed:     failed,
+		}
+		if err != nil {
+			result.Failed = true
+			result.Error = err.Error()
+		}
+		results = append(results, result)
+
+		// Clear the journal and refund as between the transactions of a block
+		state.Finalise(true)
+	}
+	return results, nil
+}
+
 // EstimateGas returns an estimate of the amount of gas needed to execute the
 // given transaction against the current pending block.
 func (s *PublicBlockChainAPI) EstimateGas(ctx context.Context, args CallArgs) (hexutil.Uint64, error) {
@@ -769,7 +1050,7 @@ func (s *PublicBlockChainAPI) EstimateGas(ctx context.Context, args CallArgs) (h
 	executable := func(gas uint64) bool {
 		args.Gas = hexutil.Uint64(gas)
 
-		_, _, failed, err := s.doCall(ctx, args, rpc.PendingBlockNumber, vm.Config{}, 0)
+		_, _, failed, err := s.doCall(ctx, args, rpc.PendingBlockNumber, nil, vm.Config{}, 0)
 		if err != nil || failed {
 			return false
 		}
@@ -928,6 +1209,7 @@ type RPCTransaction struct {
 	V                *hexutil.Big    `json:"v"`
 	R                *hexutil.Big    `json:"r"`
 	S                *hexutil.Big    `json:"s"`
+	Genesis          hexutil.Bytes   `json:"genesis,omitempty"`
 }
 
 // newRPCTransaction returns a transaction that will serialize to the RPC
@@ -952,6 +1234,7 @@ func newRPCTransaction(tx *types.Transaction, blockHash common.Hash, blockNumber
 		V:        (*hexutil.Big)(v),
 		R:        (*hexutil.Big)(r),
 		S:        (*hexutil.Big)(s),
+		Genesis:  tx.GenesisSpec(),
 	}
 	if blockHash != (common.Hash{}) {
 		result.BlockHash = blockHash
@@ -1075,7 +1358,8 @@ func (s *PublicTransactionPoolAPI) GetSideTransactionCount(ctx context.Context,
 	return (*hexutil.Uint64)(&nonce), state.Error()
 }
 
-// GetTransactionByHash returns the transaction for the given hash
+// GetTransactionByHash returns the transaction for the given hash, of the main
+// chain or of a side chain
 func (s *PublicTransactionPoolAPI) GetTransactionByHash(ctx context.Context, hash common.Hash) *RPCTransaction {
 	// Try to return an already finalized transaction
 	if tx, blockHash, blockNumber, index := rawdb.ReadTransaction(s.b.ChainDb(), hash); tx != nil {
@@ -1089,6 +1373,21 @@ func (s *PublicTransactionPoolAPI) GetTransactionByHash(ctx context.Context, has
 	return nil
 }
 
+// GetSideTransactionByHash returns the transaction for the given hash of the
+// side chain with the given appId
+func (s *PublicTransactionPoolAPI) GetSideTransactionByHash(ctx context.Context, appId string, hash common.Hash) (*RPCTransaction, error) {
+	if _, ok := s.b.SideBlockChain(appId); !ok {
+		return nil, errNoSideChain
+	}
+	if tx, blockHash, blockNumber, index := rawdb.ReadTransaction(s.b.ChainDb(), hash, appId); tx != nil {
+		return newRPCTransaction(tx, blockHash, blockNumber, index), nil
+	}
+	if tx := s.b.GetPoolTransaction(hash); tx != nil && tx.AppId() == appId {
+		return newRPCPendingTransaction(tx), nil
+	}
+	return nil, nil
+}
+
 // GetRawTransactionByHash returns the bytes of the transaction for the given hash.
 func (s *PublicTransactionPoolAPI) GetRawTransactionByHash(ctx context.Context, hash common.Hash) (hexutil.Bytes, error) {
 	var tx *types.Transaction
@@ -1104,15 +1403,46 @@ func (s *PublicTransactionPoolAPI) GetRawTransactionByHash(ctx context.Context,
 	return rlp.EncodeToBytes(tx)
 }
 
-// GetTransactionReceipt returns the transaction receipt for the given transaction hash.
+// GetTransactionReceipt returns the transaction receipt for the given transaction
+// hash, of the main chain or of a side chain.
 func (s *PublicTransactionPoolAPI) GetTransactionReceipt(ctx context.Context, hash common.Hash) (map[string]interface{}, error) {
-	tx, blockHash, blockNumber, index := rawdb.ReadTransaction(s.b.ChainDb(), hash)
+	tx, blockHash, blockNumber, index, appId := rawdb.ReadTransactionAppId(s.b.ChainDb(), hash)
 	if tx == nil {
 		return nil, nil
 	}
-	receipts, err := s.b.GetReceipts(ctx, blockHash)
-	if err != nil {
-		return nil, err
+	return s.receiptFields(ctx, tx, blockHash, blockNumber, index, appId)
+}
+
+// GetSideTransactionReceipt returns the transaction receipt for the given
+// transaction hash of the side chain with the given appId.
+func (s *PublicTransactionPoolAPI) GetSideTransactionReceipt(ctx context.Context, appId string, hash common.Hash) (map[string]interface{}, error) {
+	if _, ok := s.b.SideBlockChain(appId); !ok {
+		return nil, errNoSideChain
+	}
+	tx, blockHash, blockNumber, index, _ := rawdb.ReadTransactionAppId(s.b.ChainDb(), hash, appId)
+	if tx == nil {
+		return nil, nil
+	}
+	return s.receiptFields(ctx, tx, blockHash, blockNumber, index, appId)
+}
+
+// receiptFields retrieves the receipt of the transaction from the chain with the
+// given appId and formats it.
+func (s *PublicTransactionPoolAPI) receiptFields(ctx context.Context, tx *types.Transaction, blockHash common.Hash, blockNumber uint64, index uint64, appId string) (map[string]interface{}, error) {
+	var (
+		receipts types.Receipts
+		err      error
+	)
+	if appId == "" {
+		if receipts, err = s.b.GetReceipts(ctx, blockHash); err != nil {
+			return nil, err
+		}
+	} else {
+		sideChain, ok := s.b.SideBlockChain(appId)
+		if !ok {
+			return nil, errNoSideChain
+		}
+		receipts = sideChain.GetReceiptsByHash(blockHash)
 	}
 	if len(receipts) <= int(index) {
 		return nil, nil
@@ -1128,7 +1458,7 @@ func (s *PublicTransactionPoolAPI) GetTransactionReceipt(ctx context.Context, ha
 	fields := map[string]interface{}{
 		"blockHash":         blockHash,
 		"blockNumber":       hexutil.Uint64(blockNumber),
-		"transactionHash":   hash,
+		"transactionHash":   tx.Hash(),
 		"transactionIndex":  hexutil.Uint64(index),
 		"from":              from,
 		"to":                tx.To(),
@@ -1137,6 +1467,7 @@ func (s *PublicTransactionPoolAPI) GetTransactionReceipt(ctx context.Context, ha
 		"contractAddress":   nil,
 		"logs":              receipt.Logs,
 		"logsBloom":         receipt.Bloom,
+		"appId":             appId,
 	}
 
 	// Assign receipt status or post state.
@@ -1148,9 +1479,9 @@ func (s *PublicTransactionPoolAPI) GetTransactionReceipt(ctx context.Context, ha
 	if receipt.Logs == nil {
 		fields["logs"] = [][]*types.Log{}
 	}
-	// If the ContractAddress is 20 0x0 bytes, assume it is not a contract creation
-	if receipt.ContractAddress != (common.Address{}) {
-		fields["contractAddress"] = receipt.ContractAddress
+	// The address of a contract created on a side chain depends on its appId
+	if tx.To() == nil {
+		fields["contractAddress"] = crypto.CreateAddress(from, tx.Nonce(), appId)
 	}
 	return fields, nil
 }
@@ -1185,6 +1516,8 @@ type SendTxArgs struct {
 	// newer name and should be preferred by clients.
 	Data  *hexutil.Bytes `json:"data"`
 	Input *hexutil.Bytes `json:"input"`
+	// RLP encoded genesis spec of the side chain created by the transaction
+	Genesis *hexutil.Bytes `json:"genesis"`
 }
 
 // setDefaults is a helper function that fills in default values for unspecified tx fields.
@@ -1240,7 +1573,11 @@ func (args *SendTxArgs) toTransaction() *types.Transaction {
 		input = *args.Input
 	}
 	if args.To == nil {
-		return types.NewContractCreation(uint64(*args.Nonce), (*big.Int)(args.Value), uint64(*args.Gas), (*big.Int)(args.GasPrice), input, args.AppId)
+		tx := types.NewContractCreation(uint64(*args.Nonce), (*big.Int)(args.Value), uint64(*args.Gas), (*big.Int)(args.GasPrice), input, args.AppId)
+		if args.Genesis != nil {
+			tx.SetGenesisSpec(*args.Genesis)
+		}
+		return tx
 	}
 	return types.NewTransaction(uint64(*args.Nonce), *args.To, (*big.Int)(args.Value), uint64(*args.Gas), (*big.Int)(args.GasPrice), input, args.AppId)
 }
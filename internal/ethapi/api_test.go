@@ -0,0 +1,288 @@
+// Copyright 2018 The giov Authors
+// This file is part of the giov library.
+//
+// The giov library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The giov library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the giov library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"context"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/CarLiveChainCo/goiov/accounts"
+	"github.com/CarLiveChainCo/goiov/common"
+	"github.com/CarLiveChainCo/goiov/common/hexutil"
+	"github.com/CarLiveChainCo/goiov/common/math"
+	"github.com/CarLiveChainCo/goiov/core"
+	"github.com/CarLiveChainCo/goiov/core/state"
+	"github.com/CarLiveChainCo/goiov/core/types"
+	"github.com/CarLiveChainCo/goiov/core/vm"
+	"github.com/CarLiveChainCo/goiov/ethdb"
+	"github.com/CarLiveChainCo/goiov/params"
+	"github.com/CarLiveChainCo/goiov/rpc"
+)
+
+var (
+	// counterCode increments the slot 0 of its storage, logs the new value and
+	// returns it.
+	counterCode = common.FromHex("6000546001018060005560005260206000a060206000f3")
+
+	// loopCode loops until it runs out of gas or the EVM is cancelled.
+	loopCode = common.FromHex("5b600056")
+
+	// invalidCode fails on an invalid opcode.
+	invalidCode = common.FromHex("fe")
+)
+
+// testBackend is a Backend executing calls on a fixed state and header, the
+// methods not needed by the calls are left unimplemented.
+type testBackend struct {
+	Backend
+
+	state   *state.StateDB
+	header  *types.Header
+	timeout time.Duration
+	manager *accounts.Manager
+}
+
+func newTestBackend(t *testing.T, codes map[common.Address][]byte) *testBackend {
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(ethdb.NewMemDatabase()))
+	for addr, code := range codes {
+		statedb.SetCode(addr, code)
+	}
+	root, err := statedb.Commit(true)
+	if err != nil {
+		t.Fatalf("failed to commit state: %v", err)
+	}
+	statedb, _ = state.New(root, statedb.Database())
+
+	return &testBackend{
+		state: statedb,
+		header: &types.Header{
+			Number:     big.NewInt(1),
+			Time:       big.NewInt(0),
+			Difficulty: big.NewInt(1),
+			GasLimit:   params.GenesisGasLimit,
+			Root:       root,
+		},
+		timeout: 5 * time.Second,
+		manager: accounts.NewManager(),
+	}
+}
+
+func (b *testBackend) AccountManager() *accounts.Manager { return b.manager }
+func (b *testBackend) RPCEVMTimeout() time.Duration      { return b.timeout }
+
+func (b *testBackend) SideStateAndHeaderByNumber(ctx context.Context, blockNr rpc.BlockNumber, appId string) (*state.StateDB, *types.Header, error) {
+	header := types.CopyHeader(b.header)
+	header.Appid = appId
+	return b.state.Copy(), header, nil
+}
+
+func (b *testBackend) GetEVM(ctx context.Context, msg core.Message, state *state.StateDB, header *types.Header, vmCfg vm.Config) (*vm.EVM, func() error, error) {
+	state.SetBalance(msg.From(), math.MaxBig256)
+	context := core.NewEVMContext(msg, header, nil, &header.Coinbase)
+	return vm.NewEVM(context, state, params.TestChainConfig, vmCfg), func() error { return nil }, nil
+}
+
+// Tests that the state overrides replace the overridden fields of the accounts
+// only, and that an account may not override both its storage and slots of it.
+func TestStateOverrideApply(t *testing.T) {
+	var (
+		db       = state.NewDatabase(ethdb.NewMemDatabase())
+		funded   = common.Address{1}
+		replaced = common.Address{2}
+		diffed   = common.Address{3}
+		key1     = common.Hash{1}
+		key2     = common.Hash{2}
+	)
+	statedb, _ := state.New(common.Hash{}, db)
+	statedb.SetBalance(funded, big.NewInt(1))
+	statedb.SetNonce(funded, 1)
+	for _, addr := range []common.Address{replaced, diffed} {
+		statedb.SetNonce(addr, 1)
+		statedb.SetState(addr, key1, common.Hash{0x11})
+		statedb.SetState(addr, key2, common.Hash{0x22})
+	}
+	root, err := statedb.Commit(true)
+	if err != nil {
+		t.Fatalf("failed to commit state: %v", err)
+	}
+	statedb, _ = state.New(root, db)
+
+	// A missing override leaves the state untouched
+	if err := (*StateOverride)(nil).Apply(statedb); err != nil {
+		t.Fatalf("failed to apply missing override: %v", err)
+	}
+	var (
+		balance = hexutil.Big(*big.NewInt(1000))
+		nonce   = hexutil.Uint64(42)
+		code    = hexutil.Bytes(counterCode)
+		storage = map[common.Hash]common.Hash{key1: {0x33}}
+		diff    = map[common.Hash]common.Hash{key1: {0x33}}
+	)
+	override := StateOverride{
+		funded:   {Balance: &balance, Nonce: &nonce, Code: &code},
+		replaced: {State: &storage},
+		diffed:   {StateDiff: &diff},
+	}
+	if err := override.Apply(statedb); err != nil {
+		t.Fatalf("failed to apply override: %v", err)
+	}
+	if have := statedb.GetBalance(funded); have.Cmp(big.NewInt(1000)) != 0 {
+		t.Errorf("balance mismatch: have %v, want %v", have, 1000)
+	}
+	if have := statedb.GetNonce(funded); have != 42 {
+		t.Errorf("nonce mismatch: have %d, want %d", have, 42)
+	}
+	if have := statedb.GetCode(funded); string(have) != string(counterCode) {
+		t.Errorf("code mismatch: have %x, want %x", have, counterCode)
+	}
+	// State replaces the whole storage, StateDiff only the given slots
+	for i, tt := range []struct {
+		addr common.Address
+		key  common.Hash
+		want common.Hash
+	}{
+		{replaced, key1, common.Hash{0x33}},
+		{replaced, key2, common.Hash{}},
+		{diffed, key1, common.Hash{0x33}},
+		{diffed, key2, common.Hash{0x22}},
+	} {
+		if have := statedb.GetState(tt.addr, tt.key); have != tt.want {
+			t.Errorf("test %d: slot %x of %x mismatch: have %x, want %x", i, tt.key, tt.addr, have, tt.want)
+		}
+	}
+	// Fields left out keep their value
+	if have := statedb.GetNonce(replaced); have != 1 {
+		t.Errorf("untouched nonce mismatch: have %d, want %d", have, 1)
+	}
+	// Overriding both the storage and slots of it is rejected
+	conflict := StateOverride{funded: {State: &storage, StateDiff: &diff}}
+	if err := conflict.Apply(statedb); err == nil || !strings.Contains(err.Error(), "stateDiff") {
+		t.Errorf("conflicting override error mismatch: have %v", err)
+	}
+}
+
+// Tests that applyCall executes a call with the default sender, gas and gas
+// price, and reports the failed ones.
+func TestApplyCall(t *testing.T) {
+	var (
+		counter = common.Address{0xc0}
+		invalid = common.Address{0xfe}
+		app     = common.Address{0xa0}
+	)
+	backend := newTestBackend(t, map[common.Address][]byte{counter: counterCode, invalid: invalidCode, app: counterCode})
+	backend.state.SetAppId(app, "app")
+	api := NewPublicBlockChainAPI(backend)
+
+	// A successful call returns its output and uses gas
+	statedb := backend.state.Copy()
+	res, gas, failed, err := api.applyCall(context.Background(), statedb, backend.header, CallArgs{To: &counter}, vm.Config{})
+	if err != nil || failed {
+		t.Fatalf("call failed: %v, %v", failed, err)
+	}
+	if have := new(big.Int).SetBytes(res); have.Int64() != 1 {
+		t.Errorf("output mismatch: have %v, want %v", have, 1)
+	}
+	if gas <= params.TxGas {
+		t.Errorf("gas used too low: have %d, want > %d", gas, params.TxGas)
+	}
+	if have := statedb.GetState(counter, common.Hash{}); have != common.BigToHash(big.NewInt(1)) {
+		t.Errorf("call did not change the state: have %x", have)
+	}
+	// A failing call is reported as such
+	if _, _, failed, err := api.applyCall(context.Background(), backend.state.Copy(), backend.header, CallArgs{To: &invalid}, vm.Config{}); err != nil || !failed {
+		t.Errorf("invalid call mismatch: have %v, %v, want failed", failed, err)
+	}
+	// A call to a contract of another app chain is rejected
+	if _, _, _, err := api.applyCall(context.Background(), backend.state.Copy(), backend.header, CallArgs{To: &app}, vm.Config{}); err != core.ErrWrongAppId {
+		t.Errorf("other app chain call error mismatch: have %v, want %v", err, core.ErrWrongAppId)
+	}
+	if _, _, failed, err := api.applyCall(context.Background(), backend.state.Copy(), backend.header, CallArgs{To: &app, AppId: "app"}, vm.Config{}); err != nil || failed {
+		t.Errorf("app chain call failed: %v, %v", failed, err)
+	}
+}
+
+// Tests that the calls of a bundle are executed in order on one state, each
+// seeing the changes of the ones before it, on top of the state overrides.
+func TestCallMany(t *testing.T) {
+	var (
+		counter = common.Address{0xc0}
+		invalid = common.Address{0xfe}
+		loop    = common.Address{0x5b}
+	)
+	backend := newTestBackend(t, map[common.Address][]byte{counter: counterCode, invalid: invalidCode, loop: loopCode})
+	api := NewPublicBlockChainAPI(backend)
+
+	// An empty bundle has no results
+	results, err := api.CallMany(context.Background(), nil, rpc.LatestBlockNumber, nil)
+	if err != nil || len(results) != 0 {
+		t.Fatalf("empty bundle mismatch: have %v, %v", results, err)
+	}
+	// The calls carry over their changes, the failed ones don't stop the bundle
+	start := map[common.Hash]common.Hash{{}: common.BigToHash(big.NewInt(10))}
+	overrides := &StateOverride{counter: {StateDiff: &start}}
+
+	calls := []CallArgs{{To: &counter}, {To: &invalid}, {To: &counter}, {To: &counter}}
+	if results, err = api.CallMany(context.Background(), calls, rpc.LatestBlockNumber, overrides); err != nil {
+		t.Fatalf("failed to execute bundle: %v", err)
+	}
+	if len(results) != len(calls) {
+		t.Fatalf("result count mismatch: have %d, want %d", len(results), len(calls))
+	}
+	if !results[1].Failed || len(results[1].Logs) != 0 {
+		t.Errorf("invalid call mismatch: have %+v", results[1])
+	}
+	for i, want := range map[int]int64{0: 11, 2: 12, 3: 13} {
+		result := results[i]
+		if result.Failed || result.Error != "" {
+			t.Errorf("call %d failed: %s", i, result.Error)
+			continue
+		}
+		if have := new(big.Int).SetBytes(result.ReturnData); have.Int64() != want {
+			t.Errorf("call %d: output mismatch: have %v, want %v", i, have, want)
+		}
+		if len(result.Logs) != 1 {
+			t.Errorf("call %d: log count mismatch: have %d, want 1", i, len(result.Logs))
+			continue
+		}
+		if log := result.Logs[0]; log.TxIndex != uint(i) || new(big.Int).SetBytes(log.Data).Int64() != want {
+			t.Errorf("call %d: log mismatch: have index %d, data %x", i, log.TxIndex, log.Data)
+		}
+		if result.GasUsed == 0 {
+			t.Errorf("call %d: no gas used", i)
+		}
+	}
+	// The bundle doesn't change the state of the backend
+	if have := backend.state.GetState(counter, common.Hash{}); have != (common.Hash{}) {
+		t.Errorf("bundle changed the backend state: have %x", have)
+	}
+	// Calls on different chains and conflicting overrides are rejected
+	if _, err := api.CallMany(context.Background(), []CallArgs{{To: &counter}, {To: &counter, AppId: "app"}}, rpc.LatestBlockNumber, nil); err != errMixedChainCalls {
+		t.Errorf("mixed chain bundle error mismatch: have %v, want %v", err, errMixedChainCalls)
+	}
+	conflict := &StateOverride{counter: {State: &start, StateDiff: &start}}
+	if _, err := api.CallMany(context.Background(), calls, rpc.LatestBlockNumber, conflict); err == nil {
+		t.Errorf("conflicting override accepted")
+	}
+	// The configured timeout applies to the whole bundle
+	backend.timeout = 50 * time.Millisecond
+	if _, err := api.CallMany(context.Background(), []CallArgs{{To: &counter}, {To: &loop}}, rpc.LatestBlockNumber, nil); err == nil || !strings.Contains(err.Error(), "timeout") {
+		t.Errorf("timed out bundle error mismatch: have %v", err)
+	}
+}
@@ -20,6 +20,7 @@ package ethapi
 import (
 	"context"
 	"math/big"
+	"time"
 
 	"github.com/CarLiveChainCo/goiov/accounts"
 	"github.com/CarLiveChainCo/goiov/common"
@@ -48,6 +49,7 @@ type Backend interface {
 
 	NodeConfig() *node.Config
 	BlockChain() BlockChain
+	RPCEVMTimeout() time.Duration // Timeout of the EVM calls of eth_call and eth_callMany (0 = no timeout)
 
 	// BlockChain API
 	SetHead(number uint64)
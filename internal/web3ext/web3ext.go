@@ -194,6 +194,122 @@ web3._extend({
 			call: 'alien_getSnapshotByHeaderTime',
 			params: 1
 		}),
+		new web3._extend.Method({
+			name: 'getCustomTxStatus',
+			call: 'alien_getCustomTxStatus',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'getSideCustomTxStatus',
+			call: 'alien_getSideCustomTxStatus',
+			params: 2
+		}),
+		new web3._extend.Method({
+			name: 'getUnlockSchedule',
+			call: 'alien_getUnlockSchedule',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'getSideUnlockSchedule',
+			call: 'alien_getSideUnlockSchedule',
+			params: 2
+		}),
+		new web3._extend.Method({
+			name: 'getRemainingRedelegateTime',
+			call: 'alien_getRemainingRedelegateTime',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'getSideRemainingRedelegateTime',
+			call: 'alien_getSideRemainingRedelegateTime',
+			params: 2
+		}),
+		new web3._extend.Method({
+			name: 'getRewardHistory',
+			call: 'alien_getRewardHistory',
+			params: 3
+		}),
+		new web3._extend.Method({
+			name: 'getSideRewardHistory',
+			call: 'alien_getSideRewardHistory',
+			params: 4
+		}),
+		new web3._extend.Method({
+			name: 'getRewardSummary',
+			call: 'alien_getRewardSummary',
+			params: 2
+		}),
+		new web3._extend.Method({
+			name: 'getSideRewardSummary',
+			call: 'alien_getSideRewardSummary',
+			params: 3
+		}),
+		new web3._extend.Method({
+			name: 'getSignerSchedule',
+			call: 'alien_getSignerSchedule',
+			params: 2
+		}),
+		new web3._extend.Method({
+			name: 'getSideSignerSchedule',
+			call: 'alien_getSideSignerSchedule',
+			params: 3
+		}),
+		new web3._extend.Method({
+			name: 'getSignerStats',
+			call: 'alien_getSignerStats',
+			params: 3
+		}),
+		new web3._extend.Method({
+			name: 'getSideSignerStats',
+			call: 'alien_getSideSignerStats',
+			params: 4
+		}),
+		new web3._extend.Method({
+			name: 'getProposals',
+			call: 'alien_getProposals',
+			params: 0
+		}),
+		new web3._extend.Method({
+			name: 'getSideProposals',
+			call: 'alien_getSideProposals',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'getProposal',
+			call: 'alien_getProposal',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'getSideProposal',
+			call: 'alien_getSideProposal',
+			params: 2
+		}),
+		new web3._extend.Method({
+			name: 'getSideChainCheckpoint',
+			call: 'alien_getSideChainCheckpoint',
+			params: 2,
+			inputFormatter: [null, web3._extend.formatters.inputDefaultBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'getBridgeMintData',
+			call: 'alien_getBridgeMintData',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'getSideBridgeReleaseData',
+			call: 'alien_getSideBridgeReleaseData',
+			params: 2
+		}),
+		new web3._extend.Method({
+			name: 'getAppMessageDeliveryData',
+			call: 'alien_getAppMessageDeliveryData',
+			params: 2
+		}),
+		new web3._extend.Method({
+			name: 'getAppMessageReceipt',
+			call: 'alien_getAppMessageReceipt',
+			params: 1
+		}),
 	]
 });
 `
@@ -483,6 +599,11 @@ const Eth_JS = `
 web3._extend({
 	property: 'eth',
 	methods: [
+		new web3._extend.Method({
+			name: 'chainId',
+			call: 'eth_chainId',
+			params: 0
+		}),
 		new web3._extend.Method({
 			name: 'sign',
 			call: 'eth_sign',
@@ -502,6 +623,43 @@ web3._extend({
 			inputFormatter: [null, web3._extend.formatters.inputAddressFormatter, web3._extend.formatters.inputDefaultBlockNumberFormatter],
       		outputFormatter: web3._extend.formatters.outputBigNumberFormatter
 		}),
+		new web3._extend.Method({
+			name: 'getSideCode',
+			call: 'eth_getSideCode',
+			params: 3,
+			inputFormatter: [null, web3._extend.formatters.inputAddressFormatter, web3._extend.formatters.inputDefaultBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'getSideStorageAt',
+			call: 'eth_getSideStorageAt',
+			params: 4,
+			inputFormatter: [null, web3._extend.formatters.inputAddressFormatter, web3._extend.utils.toHex, web3._extend.formatters.inputDefaultBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'getProof',
+			call: 'eth_getProof',
+			params: 3,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, null, web3._extend.formatters.inputDefaultBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'getSideProof',
+			call: 'eth_getSideProof',
+			params: 4,
+			inputFormatter: [null, web3._extend.formatters.inputAddressFormatter, null, web3._extend.formatters.inputDefaultBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'callMany',
+			call: 'eth_callMany',
+			params: 3,
+			inputFormatter: [null, web3._extend.formatters.inputDefaultBlockNumberFormatter, null]
+		}),
+		new web3._extend.Method({
+			name: 'sideGasPrice',
+			call: 'eth_sideGasPrice',
+			params: 1,
+			inputFormatter: [null],
+			outputFormatter: web3._extend.formatters.outputBigNumberFormatter
+		}),
 		new web3._extend.Method({
 			name: 'getSideBlock',
 			call: function(args) {
@@ -563,6 +721,18 @@ web3._extend({
             inputFormatter: [null,null, web3._extend.formatters.inputDefaultBlockNumberFormatter],
     		outputFormatter: web3._extend.utils.toDecimal
 		}),
+		new web3._extend.Method({
+			name: 'getSideTransaction',
+			call: 'eth_getSideTransactionByHash',
+			params: 2,
+			outputFormatter: web3._extend.formatters.outputTransactionFormatter
+		}),
+		new web3._extend.Method({
+			name: 'getSideTransactionReceipt',
+			call: 'eth_getSideTransactionReceipt',
+			params: 2,
+			outputFormatter: web3._extend.formatters.outputTransactionReceiptFormatter
+		}),
 		new web3._extend.Method({
 			name: 'getRawTransactionFromBlock',
 			call: function(args) {
@@ -18,9 +18,11 @@ package les
 
 import (
 	"context"
+	"errors"
 	"github.com/CarLiveChainCo/goiov/internal/ethapi"
 	"github.com/CarLiveChainCo/goiov/node"
 	"math/big"
+	"time"
 
 	"github.com/CarLiveChainCo/goiov/accounts"
 	"github.com/CarLiveChainCo/goiov/common"
@@ -32,6 +34,7 @@ import (
 	"github.com/CarLiveChainCo/goiov/core/types"
 	"github.com/CarLiveChainCo/goiov/core/vm"
 	"github.com/CarLiveChainCo/goiov/eth/downloader"
+	"github.com/CarLiveChainCo/goiov/eth/filters"
 	"github.com/CarLiveChainCo/goiov/eth/gasprice"
 	"github.com/CarLiveChainCo/goiov/ethdb"
 	"github.com/CarLiveChainCo/goiov/event"
@@ -53,6 +56,10 @@ func (b *LesApiBackend) NodeConfig() *node.Config {
 	return b.eth.nodeConfig
 }
 
+func (b *LesApiBackend) RPCEVMTimeout() time.Duration {
+	return b.eth.config.RPCEVMTimeout
+}
+
 func (b *LesApiBackend) BlockChain() ethapi.BlockChain {
 	return b.eth.blockchain
 }
@@ -70,6 +77,9 @@ func (b *LesApiBackend) HeaderByNumber(ctx context.Context, blockNr rpc.BlockNum
 	if blockNr == rpc.LatestBlockNumber || blockNr == rpc.PendingBlockNumber {
 		return b.eth.blockchain.CurrentHeader(), nil
 	}
+	if blockNr == rpc.FinalizedBlockNumber {
+		return b.eth.blockchain.GetHeaderByNumberOdr(ctx, b.eth.blockchain.FinalizedNumber())
+	}
 
 	return b.eth.blockchain.GetHeaderByNumberOdr(ctx, uint64(blockNr))
 }
@@ -208,6 +218,17 @@ func (b *LesApiBackend) ServiceFilter(ctx context.Context, session *bloombits.Ma
 	}
 }
 
+var errNoSideFilters = errors.New("side chains can not be filtered in light mode")
+
+// SideBackend implements filters.Backend. Light clients do not follow side
+// chains, so only the main chain can be filtered.
+func (b *LesApiBackend) SideBackend(appId string) (filters.Backend, error) {
+	if appId == "" {
+		return b, nil
+	}
+	return nil, errNoSideFilters
+}
+
 func (b *LesApiBackend) SideBlockChain(appId string) (*core.BlockChain, bool) {
 	block, ok := b.eth.sideChains[appId]
 	return block, ok
@@ -207,6 +207,15 @@ func (bc *LightChain) ResetWithGenesisBlock(genesis *types.Block) {
 // Engine retrieves the light chain's consensus engine.
 func (bc *LightChain) Engine() consensus.Engine { return bc.engine }
 
+// FinalizedNumber retrieves the number of the last block confirmed as of the
+// current head.
+func (bc *LightChain) FinalizedNumber() uint64 {
+	if finality, ok := bc.engine.(consensus.Finality); ok {
+		return finality.ConfirmedNumber(bc.CurrentHeader())
+	}
+	return 0
+}
+
 // Genesis returns the genesis block
 func (bc *LightChain) Genesis() *types.Block {
 	return bc.genesisBlock
@@ -0,0 +1,70 @@
+// Copyright 2018 The giov Authors
+// This file is part of the giov library.
+//
+// The giov library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The giov library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the giov library. If not, see <http://www.gnu.org/licenses/>.
+
+package light
+
+import (
+	"fmt"
+
+	"github.com/CarLiveChainCo/goiov/common"
+	"github.com/CarLiveChainCo/goiov/core/state"
+	"github.com/CarLiveChainCo/goiov/crypto"
+	"github.com/CarLiveChainCo/goiov/ethdb"
+	"github.com/CarLiveChainCo/goiov/rlp"
+	"github.com/CarLiveChainCo/goiov/trie"
+)
+
+// VerifyAccountProof checks the Merkle proof of the account at address, as
+// returned by eth_getProof or eth_getSideProof, against the state root of a
+// header. It returns the account proven, nil if the proof shows the account
+// doesn't exist.
+func VerifyAccountProof(root common.Hash, address common.Address, proof [][]byte) (*state.Account, error) {
+	blob, err := verifyProof(root, crypto.Keccak256(address.Bytes()), proof)
+	if err != nil || blob == nil {
+		return nil, err
+	}
+	account := new(state.Account)
+	if err := rlp.DecodeBytes(blob, account); err != nil {
+		return nil, fmt.Errorf("invalid account %x: %v", address, err)
+	}
+	return account, nil
+}
+
+// VerifyStorageProof checks the Merkle proof of the storage slot key against
+// the storage root of an account, as proven by VerifyAccountProof. It returns
+// the value of the slot, zero if the proof shows the slot is empty.
+func VerifyStorageProof(storageRoot common.Hash, key common.Hash, proof [][]byte) (common.Hash, error) {
+	blob, err := verifyProof(storageRoot, crypto.Keccak256(key.Bytes()), proof)
+	if err != nil || blob == nil {
+		return common.Hash{}, err
+	}
+	_, content, _, err := rlp.Split(blob)
+	if err != nil {
+		return common.Hash{}, fmt.Errorf("invalid storage slot %x: %v", key, err)
+	}
+	return common.BytesToHash(content), nil
+}
+
+// verifyProof checks the trie nodes of a Merkle proof for the hashed key against
+// the root, returning the value proven.
+func verifyProof(root common.Hash, key []byte, proof [][]byte) ([]byte, error) {
+	nodes := ethdb.NewMemDatabase()
+	for _, node := range proof {
+		nodes.Put(crypto.Keccak256(node), node)
+	}
+	value, _, err := trie.VerifyProof(root, key, nodes)
+	return value, err
+}
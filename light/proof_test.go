@@ -0,0 +1,109 @@
+// Copyright 2018 The giov Authors
+// This file is part of the giov library.
+//
+// The giov library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The giov library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the giov library. If not, see <http://www.gnu.org/licenses/>.
+
+package light
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/CarLiveChainCo/goiov/common"
+	"github.com/CarLiveChainCo/goiov/core/state"
+	"github.com/CarLiveChainCo/goiov/ethdb"
+)
+
+// Tests that the proofs of the accounts and storage slots of a state are
+// verified against its root, and that tampered proofs are rejected.
+func TestVerifyProof(t *testing.T) {
+	var (
+		db       = state.NewDatabase(ethdb.NewMemDatabase())
+		addr     = common.Address{1}
+		missing  = common.Address{2}
+		key      = common.Hash{3}
+		value    = common.Hash{4}
+		empty    = common.Hash{5}
+		statedb  *state.StateDB
+		root     common.Hash
+		err      error
+		accounts = 16
+	)
+	statedb, _ = state.New(common.Hash{}, db)
+	for i := 0; i < accounts; i++ {
+		statedb.AddBalance(common.BigToAddress(big.NewInt(int64(100+i))), big.NewInt(int64(i+1)))
+	}
+	statedb.AddBalance(addr, big.NewInt(42))
+	statedb.SetNonce(addr, 7)
+	statedb.SetState(addr, key, value)
+	if root, err = statedb.Commit(true); err != nil {
+		t.Fatalf("failed to commit state: %v", err)
+	}
+	statedb, _ = state.New(root, db)
+
+	// A valid account proof proves the account
+	proof, err := statedb.GetProof(addr)
+	if err != nil {
+		t.Fatalf("failed to prove account: %v", err)
+	}
+	account, err := VerifyAccountProof(root, addr, proof)
+	if err != nil {
+		t.Fatalf("failed to verify account proof: %v", err)
+	}
+	if account == nil || account.Balance.Int64() != 42 || account.Nonce != 7 {
+		t.Fatalf("proven account mismatch: have %+v", account)
+	}
+	// A valid storage proof proves the slots, set or empty
+	for _, slot := range []struct{ key, want common.Hash }{{key, value}, {empty, common.Hash{}}} {
+		storageProof, err := statedb.GetStorageProof(addr, slot.key)
+		if err != nil {
+			t.Fatalf("failed to prove slot %x: %v", slot.key, err)
+		}
+		have, err := VerifyStorageProof(account.Root, slot.key, storageProof)
+		if err != nil {
+			t.Fatalf("failed to verify storage proof of slot %x: %v", slot.key, err)
+		}
+		if have != slot.want {
+			t.Errorf("proven slot %x mismatch: have %x, want %x", slot.key, have, slot.want)
+		}
+	}
+	// A valid proof of a missing account proves its absence
+	missingProof, err := statedb.GetProof(missing)
+	if err != nil {
+		t.Fatalf("failed to prove missing account: %v", err)
+	}
+	if account, err := VerifyAccountProof(root, missing, missingProof); err != nil || account != nil {
+		t.Errorf("missing account proven: have %+v, %v", account, err)
+	}
+	// Tampered proofs, truncated ones and proofs against another root are rejected
+	tampered := make([][]byte, len(proof))
+	copy(tampered, proof)
+	last := common.CopyBytes(proof[len(proof)-1])
+	last[len(last)-1] ^= 0xff
+	tampered[len(tampered)-1] = last
+
+	if _, err := VerifyAccountProof(root, addr, tampered); err == nil {
+		t.Errorf("tampered account proof verified")
+	}
+	if _, err := VerifyAccountProof(root, addr, proof[:len(proof)-1]); err == nil {
+		t.Errorf("truncated account proof verified")
+	}
+	if _, err := VerifyAccountProof(common.Hash{1}, addr, proof); err == nil {
+		t.Errorf("account proof verified against another root")
+	}
+	storageProof, _ := statedb.GetStorageProof(addr, key)
+	if _, err := VerifyStorageProof(root, key, storageProof); err == nil {
+		t.Errorf("storage proof verified against the state root")
+	}
+}
@@ -550,9 +550,16 @@ func (self *worker) commitNewWork() {
 
 
 	if self.config.Alien != nil && !reflect.ValueOf(self.eth.Miner()).IsNil() && self.eth.IsMining() && !self.config.Alien.SideChain {
-		err = self.sendConfirmTx(parent.Number())
+		// confirm by message once the engine supports it, by transaction before
+		confirmed := false
+		if confirmer, ok := self.engine.(consensus.Confirmer); ok {
+			confirmed, err = confirmer.ConfirmBlock(self.chain, parent.Header())
+		}
+		if !confirmed {
+			err = self.sendConfirmTx(parent.Number())
+		}
 		if err != nil {
-			log.Info("Fail to Sign the transaction by coinbase", "err", err)
+			log.Info("Fail to confirm the block by coinbase", "err", err)
 		}
 	}
 
@@ -29,8 +29,19 @@ var (
 	SideExtraData               = hexutil.MustDecode("0x00000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000")
 	SideGasLimit         uint64 = 50000000
 	SideDifficulty              = big.NewInt(17179869184)
+	SidePeriod           uint64 = 2
+	SideFreeze           uint64 = 20
+	SideMinVoteValue            = new(big.Int).Mul(big.NewInt(1), big.NewInt(1e+18))
 	DefaultSelfVoteValue        = new(big.Int).Mul(big.NewInt(10), big.NewInt(1e+18))
 	SideDefaultBalance          = new(big.Int).Mul(big.NewInt(2), DefaultSelfVoteValue)
+
+	// BridgeAddress is the system address holding the funds locked on the main
+	// chain for the side chains, and burning the funds sent to it on a side chain.
+	BridgeAddress = common.BytesToAddress([]byte("alien-bridge"))
+
+	// MessageAddress is the system address contracts call to send a message to
+	// a contract of another app chain, and the sender of the delivered messages.
+	MessageAddress = common.BytesToAddress([]byte("alien-message"))
 )
 var (
 	MainnetGenesisHash = common.HexToHash("0xd4e56740f876aef8c010b86a40d5f56745a118d0906a34e69aec8c0db1cb8fa3") // Mainnet genesis hash to enforce below configs on
@@ -265,6 +276,36 @@ type AlienConfig struct {
 	MCRPCClient      *rpc.Client                                // Main chain rpc client for side chain
 	AppId            string
 	UnMine           bool
+
+	CustomTxV2Block  *big.Int `json:"customTxV2Block,omitempty"`  // Block from which "ufo:2" RLP encoded custom transactions are accepted (nil = never)
+	StakeChangeBlock *big.Int `json:"stakeChangeBlock,omitempty"` // Block from which voters may add to or partially withdraw their stake (nil = never)
+	RedelegateBlock  *big.Int `json:"redelegateBlock,omitempty"`  // Block from which voters may move their vote to another candidate (nil = never)
+	SlashBlock       *big.Int `json:"slashBlock,omitempty"`       // Block from which the stake of misbehaving signers is slashed (nil = never)
+	FinalityBlock    *big.Int `json:"finalityBlock,omitempty"`    // Block from which confirmations are p2p messages and the confirmed block is final (nil = never)
+	ProposalBlock    *big.Int `json:"proposalBlock,omitempty"`    // Block from which the candidates may change the engine parameters by proposals (nil = never)
+	CheckpointBlock  *big.Int `json:"checkpointBlock,omitempty"`  // Block from which the main chain records the checkpoints sent by the side chain signers (nil = never)
+	BridgeBlock      *big.Int `json:"bridgeBlock,omitempty"`      // Block from which funds are moved between the main chain and the side chains over the bridge (nil = never)
+	CrossAppBlock    *big.Int `json:"crossAppBlock,omitempty"`    // Block from which contracts send messages to the contracts of other app chains (nil = never)
+
+	RedelegateCooldown    uint64 `json:"redelegateCooldown,omitempty"`    // Number of seconds a vote must stay with a candidate before it can be moved again
+	DoubleSignSlashRate   uint64 `json:"doubleSignSlashRate,omitempty"`   // Per mille of the stake voted for a signer slashed when it signs two blocks at the same height
+	MissingSlashRate      uint64 `json:"missingSlashRate,omitempty"`      // Per mille of the stake voted for a signer slashed when it keeps missing its turn
+	MissingSlashThreshold uint64 `json:"missingSlashThreshold,omitempty"` // Punished credit from which a missing signer is slashed
+	SlashRedistribute     bool   `json:"slashRedistribute,omitempty"`     // Pay the slashed stake to the reporter instead of burning it
+	ProposalDeadline      uint64 `json:"proposalDeadline,omitempty"`      // Number of seconds the candidates may declare for a proposal
+
+	RewardSchedule  []RewardStep    `json:"rewardSchedule,omitempty"`  // Block reward steps replacing the yearly halving of the signer block reward
+	RewardSupplyCap *big.Int        `json:"rewardSupplyCap,omitempty"` // Max total of the block rewards (nil = no cap)
+	Treasury        *common.Address `json:"treasury,omitempty"`        // Address receiving TreasuryShare of each block reward (nil = no treasury)
+	TreasuryShare   uint64          `json:"treasuryShare,omitempty"`   // Per mille of each block reward paid to the treasury
+}
+
+// RewardStep is a step of the block reward schedule of an alien chain. From
+// FromBlock on each block pays Reward, until the FromBlock of the next step.
+type RewardStep struct {
+	FromBlock  uint64   `json:"fromBlock"`  // First block paying the reward
+	Reward     *big.Int `json:"reward"`     // Block reward in wei
+	MinerShare uint64   `json:"minerShare"` // Per mille of the reward for the miner, the rest is for the voters of the miner
 }
 
 // String implements the stringer interface, returning the consensus engine details.
@@ -272,6 +313,72 @@ func (c *AlienConfig) String() string {
 	return "alien"
 }
 
+// IsCustomTxV2 returns whether num is either equal to the "ufo:2" custom
+// transaction fork block or greater.
+func (c *AlienConfig) IsCustomTxV2(num *big.Int) bool {
+	return isForked(c.CustomTxV2Block, num)
+}
+
+// IsStakeChange returns whether num is either equal to the partial stake change
+// fork block or greater.
+func (c *AlienConfig) IsStakeChange(num *big.Int) bool {
+	return isForked(c.StakeChangeBlock, num)
+}
+
+// IsRedelegate returns whether num is either equal to the redelegation fork
+// block or greater.
+func (c *AlienConfig) IsRedelegate(num *big.Int) bool {
+	return isForked(c.RedelegateBlock, num)
+}
+
+// IsSlash returns whether num is either equal to the slashing fork block or greater.
+func (c *AlienConfig) IsSlash(num *big.Int) bool {
+	return isForked(c.SlashBlock, num)
+}
+
+// IsFinality returns whether num is either equal to the finality fork block or greater.
+func (c *AlienConfig) IsFinality(num *big.Int) bool {
+	return isForked(c.FinalityBlock, num)
+}
+
+// RewardStepAt returns the step of the reward schedule the block with the given
+// number belongs to, or false if the schedule has not started yet.
+func (c *AlienConfig) RewardStepAt(num uint64) (RewardStep, bool) {
+	var (
+		step  RewardStep
+		found bool
+	)
+	for _, s := range c.RewardSchedule {
+		if s.FromBlock <= num && (!found || s.FromBlock >= step.FromBlock) {
+			step, found = s, true
+		}
+	}
+	return step, found
+}
+
+// IsProposal returns whether num is either equal to the governance proposal fork
+// block or greater.
+func (c *AlienConfig) IsProposal(num *big.Int) bool {
+	return isForked(c.ProposalBlock, num)
+}
+
+// IsCheckpoint returns whether num is either equal to the side chain checkpoint
+// fork block or greater.
+func (c *AlienConfig) IsCheckpoint(num *big.Int) bool {
+	return isForked(c.CheckpointBlock, num)
+}
+
+// IsBridge returns whether num is either equal to the bridge fork block or greater.
+func (c *AlienConfig) IsBridge(num *big.Int) bool {
+	return isForked(c.BridgeBlock, num)
+}
+
+// IsCrossApp returns whether num is either equal to the cross app message fork
+// block or greater.
+func (c *AlienConfig) IsCrossApp(num *big.Int) bool {
+	return isForked(c.CrossAppBlock, num)
+}
+
 // String implements the fmt.Stringer interface.
 func (c *ChainConfig) String() string {
 	var engine interface{}
@@ -322,6 +429,18 @@ func (c *ChainConfig) IsConstantinople(num *big.Int) bool {
 	return isForked(c.ConstantinopleBlock, num)
 }
 
+// IsBridge returns whether num is either equal to the alien bridge fork block or
+// greater, enabling the precompiled contract the bridge proofs go through.
+func (c *ChainConfig) IsBridge(num *big.Int) bool {
+	return c.Alien != nil && c.Alien.IsBridge(num)
+}
+
+// IsCrossApp returns whether num is either equal to the alien cross app message
+// fork block or greater, enabling the message system contract.
+func (c *ChainConfig) IsCrossApp(num *big.Int) bool {
+	return c.Alien != nil && c.Alien.IsCrossApp(num)
+}
+
 // GasTable returns the gas table corresponding to the current phase (homestead or homestead reprice).
 //
 // The returned GasTable's fields shouldn't, under any circumstances, be changed.
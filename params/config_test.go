@@ -79,3 +79,32 @@ func TestCheckCompatible(t *testing.T) {
 		}
 	}
 }
+
+func TestRewardStepAt(t *testing.T) {
+	config := &AlienConfig{RewardSchedule: []RewardStep{
+		{FromBlock: 100, Reward: big.NewInt(2), MinerShare: 500},
+		{FromBlock: 10, Reward: big.NewInt(1), MinerShare: 618},
+	}}
+	tests := []struct {
+		number uint64
+		found  bool
+		reward int64
+	}{
+		{number: 0, found: false},
+		{number: 9, found: false},
+		{number: 10, found: true, reward: 1},
+		{number: 99, found: true, reward: 1},
+		{number: 100, found: true, reward: 2},
+		{number: 1000, found: true, reward: 2},
+	}
+	for _, test := range tests {
+		step, found := config.RewardStepAt(test.number)
+		if found != test.found {
+			t.Errorf("number %d: found mismatch: have %v, want %v", test.number, found, test.found)
+			continue
+		}
+		if found && step.Reward.Int64() != test.reward {
+			t.Errorf("number %d: reward mismatch: have %v, want %d", test.number, step.Reward, test.reward)
+		}
+	}
+}
@@ -77,6 +77,14 @@ const (
 	Bn256ScalarMulGas       uint64 = 40000  // Gas needed for an elliptic curve scalar multiplication
 	Bn256PairingBaseGas     uint64 = 100000 // Base price for an elliptic curve pairing check
 	Bn256PairingPerPointGas uint64 = 80000  // Per-point price for an elliptic curve pairing check
+	MerkleProofBaseGas      uint64 = 3000   // Base price for a Merkle proof verification
+	MerkleProofPerWordGas   uint64 = 6      // Per-word price for a Merkle proof verification
+
+	// Cross app message gas prices
+
+	AppMessageGas     uint64 = 20000   // Price for sending a message to another app chain
+	AppMessageDataGas uint64 = 16      // Per-byte price of the data of a message sent to another app chain
+	AppMessageMaxGas  uint64 = 1000000 // Maximum gas a message may be delivered with on its app chain
 )
 
 var (